@@ -5,7 +5,10 @@ import (
 	. "github.com/yu-org/yu/common"
 	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/keypair"
 	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"math"
 	"math/big"
 	"net/http"
 )
@@ -22,39 +25,80 @@ func NewAsset(tokenName string) *Asset {
 	a.SetWritings(a.Transfer, a.CreateAccount)
 	a.SetReadings(a.QueryBalance)
 
-	//a.SetTxnChecker(func(txn *SignedTxn) error {
-	//	if txn.Raw.WrCall.LeiPrice == 0 {
-	//		return nil
-	//	}
-	//
-	//	if !a.ExistAccount(txn.Raw.Caller) {
-	//		return AccountNotFound(txn.Raw.Caller)
-	//	}
-	//
-	//	balance := a.GetBalance(txn.Raw.Caller)
-	//	leiPrice := new(big.Int).SetUint64(txn.Raw.WrCall.LeiPrice)
-	//	if balance.Cmp(leiPrice) < 0 {
-	//		return InsufficientFunds
-	//	}
-	//
-	//	validatorsCount := len(validators)
-	//	if validatorsCount > 0 {
-	//		validatorsCountBigInt := new(big.Int).SetInt64(int64(validatorsCount))
-	//		rewards := new(big.Int).Div(leiPrice, validatorsCountBigInt)
-	//		for _, validator := range validators {
-	//			err := a.transfer(txn.Raw.Caller, validator.Address(), rewards)
-	//			if err != nil {
-	//				return err
-	//			}
-	//		}
-	//	}
-	//
-	//	return nil
-	//})
-
+	a.SetTxnCycle(a)
 	return a
 }
 
+// StartTxn checks whether the caller can pay for the txn.
+// When the fee market is enabled, the caller must have an account.
+func (a *Asset) StartTxn(ctx *WriteContext) error {
+	wrCall := ctx.Txn.Raw.WrCall
+	basePrice := ctx.Block.BaseLeiPrice
+	if basePrice == 0 && wrCall.Tips == 0 {
+		return nil
+	}
+	if wrCall.LeiPrice < basePrice {
+		return LeiPriceTooLow(wrCall.LeiPrice, basePrice)
+	}
+	st := a.TxnState(ctx)
+	caller := ctx.GetCaller()
+	if !existAccount(st, caller) {
+		return AccountNotFound(*caller)
+	}
+	if getBalance(st, caller).Cmp(new(big.Int).SetUint64(wrCall.Tips)) < 0 {
+		return InsufficientFunds
+	}
+	return nil
+}
+
+// EndTxn charges LeiCost * BaseLeiPrice as fee from the caller and pays the tips to the miner.
+// If the balance is not enough, the caller pays all its balance.
+func (a *Asset) EndTxn(ctx *WriteContext, receipt *Receipt) {
+	wrCall := ctx.Txn.Raw.WrCall
+	basePrice := ctx.Block.BaseLeiPrice
+	if wrCall.LeiPrice < basePrice {
+		return
+	}
+	st := a.TxnState(ctx)
+	caller := ctx.GetCaller()
+	if !existAccount(st, caller) {
+		return
+	}
+	balance := getBalance(st, caller)
+
+	fee := new(big.Int).SetUint64(ctx.LeiCost)
+	fee.Mul(fee, new(big.Int).SetUint64(basePrice))
+	if !fee.IsUint64() {
+		fee.SetUint64(math.MaxUint64)
+	}
+	if fee.Cmp(balance) > 0 {
+		fee.Set(balance)
+	}
+	balance.Sub(balance, fee)
+
+	tips := new(big.Int).SetUint64(wrCall.Tips)
+	miner, err := keypair.PubKeyFromBytes(ctx.Block.MinerPubkey)
+	if err != nil {
+		tips.SetUint64(0)
+	}
+	if tips.Cmp(balance) > 0 {
+		tips.Set(balance)
+	}
+	balance.Sub(balance, tips)
+
+	if fee.Sign() == 0 && tips.Sign() == 0 {
+		return
+	}
+	setBalance(st, caller, balance)
+	if tips.Sign() > 0 {
+		minerAddr := miner.Address()
+		_ = addBalance(st, &minerAddr, tips)
+	}
+
+	receipt.Fee = fee.Uint64()
+	receipt.Tips = tips.Uint64()
+}
+
 type AccountRequest struct {
 	Account string `json:"account"`
 }
@@ -82,7 +126,7 @@ func (a *Asset) Transfer(ctx *WriteContext) (err error) {
 	amount := big.NewInt(int64(ctx.GetUint64("amount")))
 
 	logrus.WithField("asset", "transfer").Debugf("from(%s) to(%s) amount(%d)", from.String(), to.String(), amount)
-	err = a.transfer(from, to, amount)
+	err = transfer(a.TxnState(ctx), from, to, amount)
 	if err != nil {
 		return
 	}
@@ -90,26 +134,26 @@ func (a *Asset) Transfer(ctx *WriteContext) (err error) {
 	return
 }
 
-func (a *Asset) transfer(from, to *Address, amount *big.Int) error {
-	if !a.ExistAccount(from) {
+func transfer(st TripodKV, from, to *Address, amount *big.Int) error {
+	if !existAccount(st, from) {
 		return AccountNotFound(*from)
 	}
 
-	fromBalance := a.GetBalance(from)
+	fromBalance := getBalance(st, from)
 	if fromBalance.Cmp(amount) < 0 {
 		return InsufficientFunds
 	}
 
-	if !a.ExistAccount(to) {
-		a.SetBalance(to, amount)
+	if !existAccount(st, to) {
+		setBalance(st, to, amount)
 	} else {
-		toBalance := a.GetBalance(to)
+		toBalance := getBalance(st, to)
 		toAdd := new(big.Int).Add(toBalance, amount)
-		a.SetBalance(to, toAdd)
+		setBalance(st, to, toAdd)
 	}
 
 	fromSub := new(big.Int).Sub(fromBalance, amount)
-	a.SetBalance(from, fromSub)
+	setBalance(st, from, fromSub)
 	return nil
 }
 
@@ -123,22 +167,64 @@ func (a *Asset) CreateAccount(ctx *WriteContext) error {
 
 	logrus.WithField("asset", "create-account").Debugf("ACCOUNT(%s) amount(%d)", addr.String(), amount)
 
-	if a.ExistAccount(addr) {
+	st := a.TxnState(ctx)
+	if existAccount(st, addr) {
 		ctx.EmitStringEvent("Account Exists!")
 		return nil
 	}
 
-	a.SetBalance(addr, amount)
+	setBalance(st, addr, amount)
 	ctx.EmitStringEvent("Account Created Success!")
 	return nil
 }
 
 func (a *Asset) ExistAccount(addr *Address) bool {
-	return a.Exist(addr.Bytes())
+	return existAccount(a, addr)
 }
 
 func (a *Asset) GetBalance(addr *Address) *big.Int {
-	balanceByt, err := a.State.Get(a, addr.Bytes())
+	return getBalance(a, addr)
+}
+
+func (a *Asset) SetBalance(addr *Address, amount *big.Int) {
+	setBalance(a, addr, amount)
+}
+
+func (a *Asset) AddBalance(addr *Address, amount *big.Int) error {
+	return addBalance(a, addr, amount)
+}
+
+func (a *Asset) SubBalance(addr *Address, amount *big.Int) error {
+	if amount.Sign() < 0 {
+		return AmountNeg(amount)
+	}
+	balance := a.GetBalance(addr)
+	balance.Sub(balance, amount)
+	a.SetBalance(addr, balance)
+	return nil
+}
+
+// TxnSubBalance subtracts the balance of account in the state seen by the executing txn,
+// it is for the writings of other tripods.
+func (a *Asset) TxnSubBalance(ctx *WriteContext, addr *Address, amount *big.Int) error {
+	st := a.TxnState(ctx)
+	if !existAccount(st, addr) {
+		return AccountNotFound(*addr)
+	}
+	balance := getBalance(st, addr)
+	if balance.Cmp(amount) < 0 {
+		return InsufficientFunds
+	}
+	setBalance(st, addr, balance.Sub(balance, amount))
+	return nil
+}
+
+func existAccount(st TripodKV, addr *Address) bool {
+	return st.Exist(addr.Bytes())
+}
+
+func getBalance(st TripodKV, addr *Address) *big.Int {
+	balanceByt, err := st.Get(addr.Bytes())
 	if err != nil {
 		logrus.Panic("get balance error: ", err)
 	}
@@ -155,32 +241,22 @@ func (a *Asset) GetBalance(addr *Address) *big.Int {
 	return b
 }
 
-func (a *Asset) SetBalance(addr *Address, amount *big.Int) {
+func setBalance(st TripodKV, addr *Address, amount *big.Int) {
 	amountText, err := amount.MarshalText()
 	if err != nil {
 		logrus.Panic("amount marshal error: ", err)
 	}
 
-	a.Set(addr.Bytes(), amountText)
+	st.Set(addr.Bytes(), amountText)
 }
 
-func (a *Asset) AddBalance(addr *Address, amount *big.Int) error {
+func addBalance(st TripodKV, addr *Address, amount *big.Int) error {
 	if amount.Sign() < 0 {
 		return AmountNeg(amount)
 	}
-	balance := a.GetBalance(addr)
+	balance := getBalance(st, addr)
 	balance.Add(balance, amount)
-	a.SetBalance(addr, balance)
-	return nil
-}
-
-func (a *Asset) SubBalance(addr *Address, amount *big.Int) error {
-	if amount.Sign() < 0 {
-		return AmountNeg(amount)
-	}
-	balance := a.GetBalance(addr)
-	balance.Sub(balance, amount)
-	a.SetBalance(addr, balance)
+	setBalance(st, addr, balance)
 	return nil
 }
 
@@ -0,0 +1,35 @@
+package dpos
+
+import (
+	"github.com/yu-org/yu/apps/poa"
+	. "github.com/yu-org/yu/common"
+)
+
+type DposConfig struct {
+	// keypair, genesis validators, block interval and epoch length.
+	// The genesis validators produce blocks until the first election.
+	Poa *poa.PoaConfig `toml:"poa"`
+	// the max number of validators elected in every epoch.
+	MaxValidators int `toml:"max_validators"`
+	// candidates whose self stake is lower than it are not elected.
+	MinSelfStake uint64 `toml:"min_self_stake"`
+	// the unbonded stake is returned after UnbondingPeriod blocks.
+	UnbondingPeriod BlockNum `toml:"unbonding_period"`
+	// reward for the producer of every block.
+	BlockReward uint64 `toml:"block_reward"`
+	// percentage of block reward to the validator before sharing with delegators.
+	Commission uint64 `toml:"commission"`
+}
+
+func DefaultCfg(idx int) *DposConfig {
+	poaCfg := poa.DefaultCfg(idx)
+	poaCfg.EpochLength = 100
+	return &DposConfig{
+		Poa:             poaCfg,
+		MaxValidators:   21,
+		MinSelfStake:    1000,
+		UnbondingPeriod: 1000,
+		BlockReward:     100,
+		Commission:      10,
+	}
+}
@@ -0,0 +1,169 @@
+package dpos
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/yu-org/yu/apps/asset"
+	"github.com/yu-org/yu/apps/poa"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/types"
+	"math/big"
+	"sort"
+)
+
+// Dpos produces blocks in the same way as Poa,
+// but its validators are elected by stake in every epoch.
+type Dpos struct {
+	*poa.Poa
+
+	asset *asset.Asset `tripod:"asset"`
+
+	epochLength     BlockNum
+	maxValidators   int
+	minSelfStake    uint64
+	unbondingPeriod BlockNum
+	blockReward     uint64
+	commission      uint64
+}
+
+func NewDpos(cfg *DposConfig) *Dpos {
+	if cfg.Poa.EpochLength == 0 {
+		logrus.Fatal("dpos needs a positive epoch length")
+	}
+	d := &Dpos{
+		Poa:             poa.NewPoaBase(cfg.Poa),
+		epochLength:     cfg.Poa.EpochLength,
+		maxValidators:   cfg.MaxValidators,
+		minSelfStake:    cfg.MinSelfStake,
+		unbondingPeriod: cfg.UnbondingPeriod,
+		blockReward:     cfg.BlockReward,
+		commission:      cfg.Commission,
+	}
+	d.SetBlockTransition(d)
+	d.SetWritings(d.Bond, d.Delegate, d.Unbond)
+	d.SetReadings(d.GetCandidates, d.GetUnbondings)
+	return d
+}
+
+// ApplyBlock settles the rewards, unbondings and elections of the block before executing its txns.
+func (d *Dpos) ApplyBlock(block *Block) error {
+	err := d.releaseUnbondings(block.Height)
+	if err != nil {
+		return err
+	}
+	err = d.reward(block)
+	if err != nil {
+		return err
+	}
+	if block.Height%d.epochLength == 0 {
+		return d.elect(block.Height)
+	}
+	return nil
+}
+
+// reward pays the block reward to the producer and its delegators.
+// The validator takes the commission first, then the rest is shared by stake.
+func (d *Dpos) reward(block *Block) error {
+	if d.blockReward == 0 {
+		return nil
+	}
+	minerPubkey, err := PubKeyFromBytes(block.MinerPubkey)
+	if err != nil {
+		return err
+	}
+	candidate, err := getCandidate(d, minerPubkey.Address())
+	if err != nil {
+		return err
+	}
+	if candidate == nil {
+		// the genesis validators have no accounts for rewards.
+		return nil
+	}
+
+	total := candidate.TotalStake()
+	shared := d.blockReward - d.blockReward*d.commission/100
+	var paid uint64
+	if total > 0 {
+		for _, delegator := range candidate.delegators() {
+			share := mulDiv(shared, candidate.Delegations[delegator], total)
+			if share == 0 {
+				continue
+			}
+			err = d.asset.AddBalance(&delegator, new(big.Int).SetUint64(share))
+			if err != nil {
+				return err
+			}
+			paid += share
+		}
+	}
+	// the commission, the share of self stake and the remainder of division.
+	return d.asset.AddBalance(&candidate.Owner, new(big.Int).SetUint64(d.blockReward-paid))
+}
+
+// elect makes the candidates with the most stake the validators of the next epoch.
+func (d *Dpos) elect(height BlockNum) error {
+	candidates, err := getCandidates(d)
+	if err != nil {
+		return err
+	}
+	elected := make([]*Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.SelfStake >= d.minSelfStake && candidate.SelfStake > 0 {
+			elected = append(elected, candidate)
+		}
+	}
+	if len(elected) == 0 {
+		// keep the current validators.
+		return nil
+	}
+	sort.SliceStable(elected, func(i, j int) bool {
+		si, sj := elected[i].TotalStake(), elected[j].TotalStake()
+		if si != sj {
+			return si > sj
+		}
+		return elected[i].Address.String() < elected[j].Address.String()
+	})
+	if d.maxValidators > 0 && len(elected) > d.maxValidators {
+		elected = elected[:d.maxValidators]
+	}
+
+	validators := make([]*poa.ValidatorConf, 0, len(elected))
+	for _, candidate := range elected {
+		validators = append(validators, &poa.ValidatorConf{
+			Pubkey: candidate.Pubkey,
+			P2pIp:  candidate.P2pID,
+		})
+	}
+	logrus.Infof("elect %d validators for the epoch from height(%d)", len(validators), height+d.epochLength)
+	return d.ScheduleValidators(height+d.epochLength, validators)
+}
+
+func (d *Dpos) releaseUnbondings(height BlockNum) error {
+	unbondings, err := getUnbondings(d)
+	if err != nil {
+		return err
+	}
+	remain := make([]*Unbonding, 0, len(unbondings))
+	for _, unbonding := range unbondings {
+		if unbonding.MatureHeight > height {
+			remain = append(remain, unbonding)
+			continue
+		}
+		err = d.asset.AddBalance(&unbonding.Owner, new(big.Int).SetUint64(unbonding.Amount))
+		if err != nil {
+			return err
+		}
+	}
+	if len(remain) == len(unbondings) {
+		return nil
+	}
+	return setUnbondings(d, remain)
+}
+
+// mulDiv returns a * b / c without overflow.
+func mulDiv(a, b, c uint64) uint64 {
+	result := new(big.Int).SetUint64(a)
+	result.Mul(result, new(big.Int).SetUint64(b))
+	result.Div(result, new(big.Int).SetUint64(c))
+	return result.Uint64()
+}
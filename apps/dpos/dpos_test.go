@@ -0,0 +1,232 @@
+package dpos
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/yu-org/yu/apps/asset"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/blockchain"
+	"github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/env"
+	"github.com/yu-org/yu/core/kernel"
+	. "github.com/yu-org/yu/core/keypair"
+	"github.com/yu-org/yu/core/state"
+	"github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/txdb"
+	"github.com/yu-org/yu/core/txpool"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func newTestDpos(t *testing.T) *Dpos {
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(t.TempDir(), "state.db")})
+	assert.NoError(t, err)
+	chainEnv := &env.ChainEnv{State: state.NewSpmtKV(kvdb)}
+
+	cfg := DefaultCfg(0)
+	for _, validator := range cfg.Poa.Validators {
+		validator.P2pIp = ""
+	}
+	d := NewDpos(cfg)
+	d.SetChainEnv(chainEnv)
+	d.SetInstance(d)
+
+	a := asset.NewAsset("YuCoin")
+	a.SetChainEnv(chainEnv)
+	a.SetInstance(a)
+	d.asset = a
+	return d
+}
+
+// commitBlock commits the state of the block and reloads the validators at the block boundary.
+func commitBlock(t *testing.T, d *Dpos, blockHash Hash) {
+	d.State.StartBlock(blockHash)
+	_, err := d.State.Commit()
+	assert.NoError(t, err)
+	d.HandleReorg(&Reorg{Ancestor: &CompactBlock{Header: &Header{Hash: blockHash}}})
+}
+
+func writeCtx(t *testing.T, pubkey PubKey, height BlockNum, params any) *context.WriteContext {
+	byt, err := json.Marshal(params)
+	assert.NoError(t, err)
+	stxn, err := NewSignedTxn(&WrCall{TripodName: "dpos", Params: string(byt)}, pubkey.BytesWithType(), nil)
+	assert.NoError(t, err)
+	ctx, err := context.NewWriteContext(stxn, &Block{Header: &Header{Height: height}})
+	assert.NoError(t, err)
+	return ctx
+}
+
+func TestStakeAndReward(t *testing.T) {
+	d := newTestDpos(t)
+
+	validatorPub, _ := GenSrKeyWithSecret([]byte("node4"))
+	delegatorPub, _ := GenSrKeyWithSecret([]byte("delegator"))
+	owner := writeCtx(t, validatorPub, 1, nil).GetCaller()
+	delegator := writeCtx(t, delegatorPub, 1, nil).GetCaller()
+	d.asset.SetBalance(owner, big.NewInt(5000))
+	d.asset.SetBalance(delegator, big.NewInt(5000))
+
+	err := d.Bond(writeCtx(t, validatorPub, 1, BondRequest{Amount: 2000}))
+	assert.NoError(t, err)
+	err = d.Delegate(writeCtx(t, delegatorPub, 1, DelegateRequest{Validator: validatorPub.Address().String(), Amount: 1000}))
+	assert.NoError(t, err)
+	err = d.Delegate(writeCtx(t, delegatorPub, 1, DelegateRequest{Validator: validatorPub.Address().String(), Amount: 9000}))
+	assert.Error(t, err)
+	assert.Equal(t, big.NewInt(3000), d.asset.GetBalance(owner))
+	assert.Equal(t, big.NewInt(4000), d.asset.GetBalance(delegator))
+
+	candidate, err := getCandidate(d, validatorPub.Address())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3000), candidate.TotalStake())
+
+	// 10% commission, the rest 90 is shared by 2000:1000
+	block := &Block{Header: &Header{Height: 2, MinerPubkey: validatorPub.BytesWithType()}}
+	assert.NoError(t, d.reward(block))
+	assert.Equal(t, big.NewInt(3070), d.asset.GetBalance(owner))
+	assert.Equal(t, big.NewInt(4030), d.asset.GetBalance(delegator))
+
+	assert.NoError(t, d.elect(100))
+	// the elected validators are loaded after the block is committed.
+	assert.Len(t, d.ValidatorsAt(200).Validators, 3)
+	commitBlock(t, d, HexToHash("0a"))
+	assert.Len(t, d.ValidatorsAt(199).Validators, 3)
+	validators := d.ValidatorsAt(200).Validators
+	assert.Len(t, validators, 1)
+	assert.Equal(t, validatorPub.StringWithType(), validators[0].Pubkey)
+
+	err = d.Unbond(writeCtx(t, delegatorPub, 10, DelegateRequest{Validator: validatorPub.Address().String(), Amount: 1000}))
+	assert.NoError(t, err)
+	candidate, err = getCandidate(d, validatorPub.Address())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2000), candidate.TotalStake())
+
+	assert.NoError(t, d.releaseUnbondings(10+d.unbondingPeriod-1))
+	assert.Equal(t, big.NewInt(4030), d.asset.GetBalance(delegator))
+	assert.NoError(t, d.releaseUnbondings(10+d.unbondingPeriod))
+	assert.Equal(t, big.NewInt(5030), d.asset.GetBalance(delegator))
+}
+
+// newTestNode makes a node executing blocks by the kernel with dpos and asset.
+func newTestNode(t *testing.T) (*kernel.Kernel, *Dpos) {
+	dir := t.TempDir()
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(dir, "state.db")})
+	assert.NoError(t, err)
+	cfg := config.InitDefaultCfg()
+	base := txdb.NewTxDB(FullNode, kvdb)
+	chain := blockchain.NewBlockChain(FullNode, &config.BlockchainConf{
+		ChainDB:      config.SqlDbConf{SqlDbType: "sqlite", Dsn: filepath.Join(dir, "chain.db")},
+		ConvergeType: "longest",
+	}, base)
+	assert.NoError(t, chain.SetGenesis(&Block{Header: &Header{Hash: HexToHash("0f")}}))
+	chainEnv := &env.ChainEnv{
+		State:      state.NewSpmtKV(kvdb),
+		Chain:      chain,
+		TxDB:       base,
+		Pool:       txpool.NewTxPool(FullNode, &cfg.Txpool, base),
+		P2pNetwork: p2p.NewMockP2p(1),
+	}
+
+	dposCfg := DefaultCfg(0)
+	dposCfg.Poa.EpochLength = 2
+	dposCfg.UnbondingPeriod = 2
+	for _, validator := range dposCfg.Poa.Validators {
+		validator.P2pIp = ""
+	}
+	d := NewDpos(dposCfg)
+	a := asset.NewAsset("YuCoin")
+	d.asset = a
+	land := tripod.NewLand()
+	for _, v := range []interface{}{a, d} {
+		tri := tripod.ResolveTripod(v)
+		tri.SetChainEnv(chainEnv)
+		tri.SetLand(land)
+		tri.SetInstance(v)
+		land.SetTripods(tri)
+	}
+	return kernel.NewKernel(cfg, chainEnv, land), d
+}
+
+func newTestBlock(t *testing.T, height BlockNum, hash, prevHash Hash, miner PubKey, txns ...*SignedTxn) *Block {
+	block := &Block{Header: &Header{
+		Height:      height,
+		Hash:        hash,
+		PrevHash:    prevHash,
+		MinerPubkey: miner.BytesWithType(),
+		LeiLimit:    1000,
+	}}
+	block.SetTxns(txns)
+	return block
+}
+
+func newTestTxn(t *testing.T, pubkey PubKey, tripodName, funcName string, params any) *SignedTxn {
+	byt, err := json.Marshal(params)
+	assert.NoError(t, err)
+	stxn, err := NewSignedTxn(&WrCall{TripodName: tripodName, FuncName: funcName, Params: string(byt)}, pubkey.BytesWithType(), nil)
+	assert.NoError(t, err)
+	return stxn
+}
+
+func TestSyncDposChain(t *testing.T) {
+	validatorPub, _ := GenSrKeyWithSecret([]byte("node4"))
+	delegatorPub, _ := GenSrKeyWithSecret([]byte("delegator"))
+	validator := validatorPub.Address().String()
+	// the blocks pay rewards, elect validators and release the unbonded stake besides the txns.
+	chain := []*Block{
+		newTestBlock(t, 1, HexToHash("01"), HexToHash("0f"), validatorPub,
+			newTestTxn(t, validatorPub, "asset", "CreateAccount", map[string]uint64{"amount": 5000}),
+			newTestTxn(t, delegatorPub, "asset", "CreateAccount", map[string]uint64{"amount": 5000})),
+		newTestBlock(t, 2, HexToHash("02"), HexToHash("01"), validatorPub,
+			newTestTxn(t, validatorPub, "dpos", "Bond", BondRequest{Amount: 2000}),
+			newTestTxn(t, delegatorPub, "dpos", "Delegate", DelegateRequest{Validator: validator, Amount: 1000})),
+		newTestBlock(t, 3, HexToHash("03"), HexToHash("02"), validatorPub,
+			newTestTxn(t, delegatorPub, "dpos", "Unbond", DelegateRequest{Validator: validator, Amount: 500})),
+		newTestBlock(t, 4, HexToHash("04"), HexToHash("03"), validatorPub),
+		newTestBlock(t, 5, HexToHash("05"), HexToHash("04"), validatorPub),
+	}
+	copyBlock := func(block *Block) *Block {
+		header := *block.Header
+		header.StateRoot = NullHash
+		return &Block{Header: &header, Txns: block.Txns}
+	}
+	execute := func(k *kernel.Kernel, block *Block) {
+		k.State.StartBlock(block.Hash)
+		assert.NoError(t, k.Execute(block))
+		assert.NoError(t, k.Chain.AppendBlock(block))
+	}
+
+	producer, _ := newTestNode(t)
+	for _, block := range chain {
+		execute(producer, block)
+	}
+
+	// the syncing node executes a fork at height 3 before the rest of chain,
+	// so the blocks after the fork are executed on the state reverted to block 2.
+	syncer, d := newTestNode(t)
+	for _, block := range chain[:3] {
+		synced := copyBlock(block)
+		execute(syncer, synced)
+		assert.Equal(t, block.StateRoot, synced.StateRoot)
+	}
+	execute(syncer, newTestBlock(t, 3, HexToHash("13"), HexToHash("02"), validatorPub))
+	for _, block := range chain[3:] {
+		synced := copyBlock(block)
+		execute(syncer, synced)
+		assert.Equal(t, block.StateRoot, synced.StateRoot)
+	}
+
+	// the rewards of blocks 3-5 and the stake unbonded at block 3 are paid.
+	owner := newTestTxn(t, validatorPub, "", "", nil).GetCallerAddr()
+	delegator := newTestTxn(t, delegatorPub, "", "", nil).GetCallerAddr()
+	assert.Equal(t, big.NewInt(3000+70+82+82), d.asset.GetBalance(owner))
+	assert.Equal(t, big.NewInt(4000+30+18+18+500), d.asset.GetBalance(delegator))
+	// the validators elected at block 4 are loaded at the block boundary.
+	d.HandleReorg(&Reorg{Ancestor: chain[4].Compact()})
+	validators := d.ValidatorsAt(6).Validators
+	assert.Len(t, validators, 1)
+	assert.Equal(t, validatorPub.StringWithType(), validators[0].Pubkey)
+}
@@ -0,0 +1,346 @@
+package dpos
+
+import (
+	"encoding/json"
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/tripod"
+	"math/big"
+	"net/http"
+	"sort"
+)
+
+var (
+	candidatesKey   = []byte("dpos-candidates")
+	candidatePrefix = "dpos-candidate-"
+	unbondingsKey   = []byte("dpos-unbondings")
+)
+
+// Candidate could be elected as a validator by its self stake and the stake delegated to it.
+type Candidate struct {
+	Pubkey string `json:"pubkey"`
+	P2pID  string `json:"p2p_id"`
+	// the address of validator key
+	Address Address `json:"address"`
+	// the account receiving the rewards and the unbonded self stake, it may differ from the validator key.
+	Owner       Address            `json:"owner"`
+	SelfStake   uint64             `json:"self_stake"`
+	Delegations map[Address]uint64 `json:"delegations"`
+}
+
+func (c *Candidate) TotalStake() uint64 {
+	total := c.SelfStake
+	for _, amount := range c.Delegations {
+		total += amount
+	}
+	return total
+}
+
+// delegators are sorted so that the rewards are paid in the same order on every node.
+func (c *Candidate) delegators() []Address {
+	delegators := make([]Address, 0, len(c.Delegations))
+	for delegator := range c.Delegations {
+		delegators = append(delegators, delegator)
+	}
+	sort.Slice(delegators, func(i, j int) bool {
+		return delegators[i].String() < delegators[j].String()
+	})
+	return delegators
+}
+
+// Unbonding is the stake returned to the owner at MatureHeight.
+type Unbonding struct {
+	Owner        Address  `json:"owner"`
+	Amount       uint64   `json:"amount"`
+	MatureHeight BlockNum `json:"mature_height"`
+}
+
+type BondRequest struct {
+	Amount uint64 `json:"amount"`
+	P2pID  string `json:"p2p_id"`
+	// the owner of a new candidate, the caller by default.
+	Owner string `json:"owner"`
+}
+
+// Bond locks the stake of caller and makes the key signing the txn a candidate of validators.
+// The owner is only set when the candidate is created.
+func (d *Dpos) Bond(ctx *WriteContext) error {
+	var req BondRequest
+	err := ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	if req.Amount == 0 {
+		return AmountZero
+	}
+	if req.P2pID != "" {
+		_, err = peer.Decode(req.P2pID)
+		if err != nil {
+			return err
+		}
+	}
+	pubkey, err := PubKeyFromBytes(ctx.Txn.Pubkey)
+	if err != nil {
+		return err
+	}
+	st := d.TxnState(ctx)
+	err = d.lockStake(ctx, ctx.GetCaller(), req.Amount)
+	if err != nil {
+		return err
+	}
+
+	candidate, err := getCandidate(st, pubkey.Address())
+	if err != nil {
+		return err
+	}
+	if candidate == nil {
+		owner := *ctx.GetCaller()
+		if req.Owner != "" {
+			owner = HexToAddress(req.Owner)
+		}
+		candidate = &Candidate{
+			Pubkey:      pubkey.StringWithType(),
+			Address:     pubkey.Address(),
+			Owner:       owner,
+			Delegations: make(map[Address]uint64),
+		}
+		err = addCandidate(st, candidate.Address)
+		if err != nil {
+			return err
+		}
+	}
+	if req.P2pID != "" {
+		candidate.P2pID = req.P2pID
+	}
+	candidate.SelfStake += req.Amount
+	err = setCandidate(st, candidate)
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(candidate)
+}
+
+type DelegateRequest struct {
+	Validator string `json:"validator"`
+	Amount    uint64 `json:"amount"`
+}
+
+// Delegate locks the stake of caller and delegates it to the candidate.
+func (d *Dpos) Delegate(ctx *WriteContext) error {
+	var req DelegateRequest
+	err := ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	if req.Amount == 0 {
+		return AmountZero
+	}
+	st := d.TxnState(ctx)
+	candidate, err := mustGetCandidate(st, HexToAddress(req.Validator))
+	if err != nil {
+		return err
+	}
+	delegator := ctx.GetCaller()
+	err = d.lockStake(ctx, delegator, req.Amount)
+	if err != nil {
+		return err
+	}
+	candidate.Delegations[*delegator] += req.Amount
+	err = setCandidate(st, candidate)
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(candidate)
+}
+
+// Unbond withdraws the stake of caller from the candidate,
+// the stake is returned to caller after the unbonding period.
+func (d *Dpos) Unbond(ctx *WriteContext) error {
+	var req DelegateRequest
+	err := ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	if req.Amount == 0 {
+		return AmountZero
+	}
+	st := d.TxnState(ctx)
+	candidate, err := mustGetCandidate(st, HexToAddress(req.Validator))
+	if err != nil {
+		return err
+	}
+	caller := *ctx.GetCaller()
+	if caller == candidate.Owner {
+		if candidate.SelfStake < req.Amount {
+			return StakeNotEnough
+		}
+		candidate.SelfStake -= req.Amount
+	} else {
+		if candidate.Delegations[caller] < req.Amount {
+			return StakeNotEnough
+		}
+		candidate.Delegations[caller] -= req.Amount
+		if candidate.Delegations[caller] == 0 {
+			delete(candidate.Delegations, caller)
+		}
+	}
+	err = setCandidate(st, candidate)
+	if err != nil {
+		return err
+	}
+
+	unbondings, err := getUnbondings(st)
+	if err != nil {
+		return err
+	}
+	unbonding := &Unbonding{
+		Owner:        caller,
+		Amount:       req.Amount,
+		MatureHeight: ctx.Block.Height + d.unbondingPeriod,
+	}
+	err = setUnbondings(st, append(unbondings, unbonding))
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(unbonding)
+}
+
+// lockStake moves the stake out of the balance of account.
+func (d *Dpos) lockStake(ctx *WriteContext, account *Address, amount uint64) error {
+	return d.asset.TxnSubBalance(ctx, account, new(big.Int).SetUint64(amount))
+}
+
+// GetCandidates returns all the candidates with their stake.
+func (d *Dpos) GetCandidates(ctx *ReadContext) {
+	candidates, err := getCandidates(d)
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	ctx.JsonOk(candidates)
+}
+
+type UnbondingsRequest struct {
+	Account string `json:"account"`
+}
+
+// GetUnbondings returns the stake of the account waiting for being returned.
+func (d *Dpos) GetUnbondings(ctx *ReadContext) {
+	var req UnbondingsRequest
+	err := ctx.BindJson(&req)
+	if err != nil {
+		ctx.Err(http.StatusBadRequest, err)
+		return
+	}
+	unbondings, err := getUnbondings(d)
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	account := HexToAddress(req.Account)
+	result := make([]*Unbonding, 0)
+	for _, unbonding := range unbondings {
+		if unbonding.Owner == account {
+			result = append(result, unbonding)
+		}
+	}
+	ctx.JsonOk(result)
+}
+
+func getCandidates(st TripodKV) ([]*Candidate, error) {
+	addrs, err := getCandidateAddrs(st)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]*Candidate, 0, len(addrs))
+	for _, addr := range addrs {
+		candidate, err := mustGetCandidate(st, addr)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+func getCandidateAddrs(st TripodKV) ([]Address, error) {
+	byt, err := st.Get(candidatesKey)
+	if err != nil || byt == nil {
+		return nil, err
+	}
+	var addrs []Address
+	err = json.Unmarshal(byt, &addrs)
+	return addrs, err
+}
+
+func addCandidate(st TripodKV, addr Address) error {
+	addrs, err := getCandidateAddrs(st)
+	if err != nil {
+		return err
+	}
+	byt, err := json.Marshal(append(addrs, addr))
+	if err != nil {
+		return err
+	}
+	st.Set(candidatesKey, byt)
+	return nil
+}
+
+func mustGetCandidate(st TripodKV, addr Address) (*Candidate, error) {
+	candidate, err := getCandidate(st, addr)
+	if err != nil {
+		return nil, err
+	}
+	if candidate == nil {
+		return nil, CandidateNotFound
+	}
+	return candidate, nil
+}
+
+// getCandidate returns nil if the address is not a candidate.
+func getCandidate(st TripodKV, addr Address) (*Candidate, error) {
+	byt, err := st.Get([]byte(candidatePrefix + addr.String()))
+	if err != nil || byt == nil {
+		return nil, err
+	}
+	candidate := new(Candidate)
+	err = json.Unmarshal(byt, candidate)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Delegations == nil {
+		candidate.Delegations = make(map[Address]uint64)
+	}
+	return candidate, nil
+}
+
+func setCandidate(st TripodKV, candidate *Candidate) error {
+	byt, err := json.Marshal(candidate)
+	if err != nil {
+		return err
+	}
+	st.Set([]byte(candidatePrefix+candidate.Address.String()), byt)
+	return nil
+}
+
+func getUnbondings(st TripodKV) ([]*Unbonding, error) {
+	byt, err := st.Get(unbondingsKey)
+	if err != nil || byt == nil {
+		return nil, err
+	}
+	var unbondings []*Unbonding
+	err = json.Unmarshal(byt, &unbondings)
+	return unbondings, err
+}
+
+func setUnbondings(st TripodKV, unbondings []*Unbonding) error {
+	byt, err := json.Marshal(unbondings)
+	if err != nil {
+		return err
+	}
+	st.Set(unbondingsKey, byt)
+	return nil
+}
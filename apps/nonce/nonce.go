@@ -0,0 +1,133 @@
+package nonce
+
+import (
+	"encoding/binary"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"net/http"
+)
+
+// Nonce keeps the next nonce of every account.
+// A txn can be executed only if its nonce is the next nonce of the caller,
+// and the next nonce increases after the txn is executed, no matter the txn succeeds or not.
+type Nonce struct {
+	*Tripod
+}
+
+func NewNonce() *Nonce {
+	tri := NewTripod()
+	n := &Nonce{tri}
+	n.SetInit(n)
+	n.SetTxnChecker(n)
+	n.SetTxnCycle(n)
+	n.SetWritings(n.Cancel)
+	n.SetReadings(n.GetNonce)
+	return n
+}
+
+// InitChain lets txpool read the next nonces of callers from the finalized state.
+func (n *Nonce) InitChain() {
+	n.Pool.WithNonceReader(n)
+}
+
+// NextNonce returns the finalized next nonce of the caller.
+func (n *Nonce) NextNonce(caller Address) uint64 {
+	return n.getFinalizedNonce(&caller)
+}
+
+// CheckTxn rejects the txns whose nonces have been used.
+// The txns with future nonces are kept in txpool until their turns.
+func (n *Nonce) CheckTxn(stxn *SignedTxn) error {
+	caller := stxn.GetCallerAddr()
+	if caller == nil {
+		return nil
+	}
+	next := n.getFinalizedNonce(caller)
+	if stxn.GetNonce() < next {
+		return NonceTooLow(stxn.GetNonce(), next)
+	}
+	return nil
+}
+
+func (n *Nonce) StartTxn(ctx *WriteContext) error {
+	caller := ctx.GetCaller()
+	if caller == nil {
+		return nil
+	}
+	next := getNonce(n.TxnState(ctx), caller)
+	if ctx.Txn.GetNonce() != next {
+		return NonceMismatch(ctx.Txn.GetNonce(), next)
+	}
+	return nil
+}
+
+func (n *Nonce) EndTxn(ctx *WriteContext, _ *Receipt) {
+	caller := ctx.GetCaller()
+	if caller == nil {
+		return
+	}
+	st := n.TxnState(ctx)
+	next := getNonce(st, caller)
+	if ctx.Txn.GetNonce() == next {
+		setNonce(st, caller, next+1)
+	}
+}
+
+// Cancel does nothing but consumes the nonce of the caller.
+// To cancel a pending txn, send Cancel with the same nonce and more tips or lei price,
+// it replaces the pending txn in txpool.
+func (n *Nonce) Cancel(ctx *WriteContext) error {
+	ctx.EmitStringEvent("txn with nonce %d is cancelled", ctx.Txn.GetNonce())
+	return nil
+}
+
+type AccountRequest struct {
+	Account string `json:"account"`
+}
+
+// GetNonce returns the next nonce of the account.
+func (n *Nonce) GetNonce(ctx *ReadContext) {
+	var req AccountRequest
+	err := ctx.BindJson(&req)
+	if err != nil {
+		ctx.Err(http.StatusBadRequest, err)
+		return
+	}
+	account := HexToAddress(req.Account)
+	ctx.JsonOk(H{"nonce": getNonce(n, &account)})
+}
+
+func getNonce(st TripodKV, addr *Address) uint64 {
+	byt, err := st.Get(addr.Bytes())
+	if err != nil {
+		logrus.Panic("get nonce error: ", err)
+	}
+	return decodeNonce(byt)
+}
+
+func (n *Nonce) getFinalizedNonce(addr *Address) uint64 {
+	byt, err := n.GetFinalized(addr.Bytes())
+	if err != nil {
+		// let the execution check the nonce.
+		logrus.Warn("get finalized nonce error: ", err)
+		return 0
+	}
+	return decodeNonce(byt)
+}
+
+func setNonce(st TripodKV, addr *Address, nonce uint64) {
+	byt := make([]byte, 8)
+	binary.BigEndian.PutUint64(byt, nonce)
+	st.Set(addr.Bytes(), byt)
+}
+
+func decodeNonce(byt []byte) uint64 {
+	if len(byt) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(byt)
+}
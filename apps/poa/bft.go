@@ -0,0 +1,409 @@
+package poa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/types"
+	"sync"
+	"time"
+)
+
+type VoteType int
+
+const (
+	Prevote VoteType = iota + 1
+	Precommit
+)
+
+// Vote is signed by a validator for a block in the Tendermint-style voting rounds.
+// Validators vote after executing the block, so the votes also commit to the StateRoot of the block.
+type Vote struct {
+	Type      VoteType `json:"type"`
+	Height    BlockNum `json:"height"`
+	Round     uint32   `json:"round"`
+	BlockHash Hash     `json:"block_hash"`
+	StateRoot Hash     `json:"state_root"`
+	Pubkey    []byte   `json:"pubkey"`
+	Signature []byte   `json:"signature"`
+}
+
+func (v *Vote) SignBytes() []byte {
+	return voteSignBytes(v.Type, v.Height, v.Round, v.BlockHash, v.StateRoot)
+}
+
+func voteSignBytes(typ VoteType, height BlockNum, round uint32, blockHash, stateRoot Hash) []byte {
+	byt := make([]byte, 0, 20+2*HashLen)
+	byt = binary.BigEndian.AppendUint64(byt, uint64(typ))
+	byt = binary.BigEndian.AppendUint64(byt, uint64(height))
+	byt = binary.BigEndian.AppendUint32(byt, round)
+	byt = append(byt, blockHash.Bytes()...)
+	byt = append(byt, stateRoot.Bytes()...)
+	return Sha256(byt)
+}
+
+func (v *Vote) Encode() ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func DecodeVote(byt []byte) (*Vote, error) {
+	v := new(Vote)
+	err := json.Unmarshal(byt, v)
+	return v, err
+}
+
+// roundKey identifies a voting round at a height.
+type roundKey struct {
+	height BlockNum
+	round  uint32
+}
+
+// roundLock is the block local node precommits and the round it precommits in.
+type roundLock struct {
+	round     uint32
+	blockHash Hash
+}
+
+// voteBook collects the votes of validators by height and round.
+type voteBook struct {
+	sync.Mutex
+	// round -> block hash -> voter -> vote
+	prevotes   map[roundKey]map[Hash]map[Address]*Vote
+	precommits map[roundKey]map[Hash]map[Address]*Vote
+	// the block local node prevotes in the round, local node never prevotes two blocks in the same round.
+	voted map[roundKey]Hash
+	// the block local node precommits at the height, local node only votes it in the later rounds
+	// until 2/3+ validators prevote another block in a later round.
+	locked map[BlockNum]roundLock
+	// votes at or below the committed height are dropped.
+	committed BlockNum
+
+	updated chan struct{}
+	// the timeout of waiting for votes in every round
+	timeout time.Duration
+	// the rounds of voting for a block before giving up
+	rounds uint32
+}
+
+func newVoteBook(timeout time.Duration, rounds uint32) *voteBook {
+	return &voteBook{
+		prevotes:   make(map[roundKey]map[Hash]map[Address]*Vote),
+		precommits: make(map[roundKey]map[Hash]map[Address]*Vote),
+		voted:      make(map[roundKey]Hash),
+		locked:     make(map[BlockNum]roundLock),
+		updated:    make(chan struct{}, 1),
+		timeout:    timeout,
+		rounds:     rounds,
+	}
+}
+
+func (vb *voteBook) add(vote *Vote, voter Address) {
+	vb.Lock()
+	if vote.Height <= vb.committed {
+		vb.Unlock()
+		return
+	}
+	votes := vb.prevotes
+	if vote.Type == Precommit {
+		votes = vb.precommits
+	}
+	key := roundKey{height: vote.Height, round: vote.Round}
+	if votes[key] == nil {
+		votes[key] = make(map[Hash]map[Address]*Vote)
+	}
+	if votes[key][vote.BlockHash] == nil {
+		votes[key][vote.BlockHash] = make(map[Address]*Vote)
+	}
+	votes[key][vote.BlockHash][voter] = vote
+	vb.Unlock()
+
+	select {
+	case vb.updated <- struct{}{}:
+	default:
+	}
+}
+
+// prevote records the block local node prevotes in the round, returns false if another block has been prevoted
+// in the round, or another block is precommitted in an earlier round without 2/3+ prevotes for this block after it.
+func (vb *voteBook) prevote(height BlockNum, round uint32, blockHash Hash, validatorsNum int) bool {
+	vb.Lock()
+	defer vb.Unlock()
+	key := roundKey{height: height, round: round}
+	if voted, ok := vb.voted[key]; ok {
+		return voted == blockHash
+	}
+	if lock, ok := vb.locked[height]; ok && lock.blockHash != blockHash && !vb.unlocked(height, lock, round, blockHash, validatorsNum) {
+		return false
+	}
+	vb.voted[key] = blockHash
+	return true
+}
+
+// unlocked reports whether 2/3+ validators prevote the block in a round after the locked one.
+func (vb *voteBook) unlocked(height BlockNum, lock roundLock, round uint32, blockHash Hash, validatorsNum int) bool {
+	for r := lock.round + 1; r <= round; r++ {
+		if hasQuorum(len(vb.prevotes[roundKey{height: height, round: r}][blockHash]), validatorsNum) {
+			return true
+		}
+	}
+	return false
+}
+
+// precommit locks local node on the block after 2/3+ validators prevote it in the round.
+func (vb *voteBook) precommit(height BlockNum, round uint32, blockHash Hash) {
+	vb.Lock()
+	defer vb.Unlock()
+	vb.locked[height] = roundLock{round: round, blockHash: blockHash}
+}
+
+// votesOf returns the votes for the block with the state root, the votes for another state root
+// come from the validators who execute the block into a different state.
+func (vb *voteBook) votesOf(typ VoteType, height BlockNum, round uint32, blockHash, stateRoot Hash) []*Vote {
+	vb.Lock()
+	defer vb.Unlock()
+	votes := vb.prevotes
+	if typ == Precommit {
+		votes = vb.precommits
+	}
+	result := make([]*Vote, 0)
+	for _, vote := range votes[roundKey{height: height, round: round}][blockHash] {
+		if vote.StateRoot == stateRoot {
+			result = append(result, vote)
+		}
+	}
+	return result
+}
+
+// waitQuorum waits for the 2/3+ votes of validators in the round until timeout.
+func (vb *voteBook) waitQuorum(typ VoteType, round uint32, block *Header, validatorsNum int) ([]*Vote, bool) {
+	timer := time.NewTimer(vb.timeout)
+	defer timer.Stop()
+	for {
+		votes := vb.votesOf(typ, block.Height, round, block.Hash, block.StateRoot)
+		if hasQuorum(len(votes), validatorsNum) {
+			return votes, true
+		}
+		select {
+		case <-vb.updated:
+		case <-timer.C:
+			return nil, false
+		}
+	}
+}
+
+func (vb *voteBook) commit(height BlockNum) {
+	vb.Lock()
+	defer vb.Unlock()
+	vb.committed = height
+	for key := range vb.prevotes {
+		if key.height <= height {
+			delete(vb.prevotes, key)
+		}
+	}
+	for key := range vb.precommits {
+		if key.height <= height {
+			delete(vb.precommits, key)
+		}
+	}
+	for key := range vb.voted {
+		if key.height <= height {
+			delete(vb.voted, key)
+		}
+	}
+	for h := range vb.locked {
+		if h <= height {
+			delete(vb.locked, h)
+		}
+	}
+}
+
+func hasQuorum(votesNum, validatorsNum int) bool {
+	return votesNum*3 > validatorsNum*2
+}
+
+func (h *Poa) handleVotes() {
+	for {
+		msg, err := h.P2pNetwork.SubP2P(ConsensusVoteTopic)
+		if err != nil {
+			logrus.Error("subscribe votes from P2P error: ", err)
+			continue
+		}
+		vote, err := DecodeVote(msg)
+		if err != nil {
+			logrus.Error("decode vote from p2p error: ", err)
+			continue
+		}
+		voter, ok := h.verifyVote(vote)
+		if !ok {
+			logrus.Warnf("illegal vote for block(%s) from %s", vote.BlockHash.String(), ToHex(vote.Pubkey))
+			continue
+		}
+		h.bft.add(vote, voter)
+	}
+}
+
+func (h *Poa) verifyVote(vote *Vote) (Address, bool) {
+	pubkey, err := PubKeyFromBytes(vote.Pubkey)
+	if err != nil {
+		return NullAddress, false
+	}
+	// the jailed validators are out of the votes.
+	if indexOf(h.votersAt(vote.Height), pubkey.Address()) < 0 {
+		return NullAddress, false
+	}
+	return pubkey.Address(), pubkey.VerifySignature(vote.SignBytes(), vote.Signature)
+}
+
+func (h *Poa) vote(typ VoteType, round uint32, block *Block) error {
+	// a jailed validator only waits for the votes of others.
+	if indexOf(h.votersAt(block.Height), h.LocalAddress()) < 0 {
+		return nil
+	}
+	vote := &Vote{
+		Type:      typ,
+		Height:    block.Height,
+		Round:     round,
+		BlockHash: block.Hash,
+		StateRoot: block.StateRoot,
+		Pubkey:    h.myPubkey.BytesWithType(),
+	}
+	sig, err := h.myPrivKey.SignData(vote.SignBytes())
+	if err != nil {
+		return err
+	}
+	vote.Signature = sig
+	h.bft.add(vote, h.LocalAddress())
+
+	byt, err := vote.Encode()
+	if err != nil {
+		return err
+	}
+	return h.P2pNetwork.PubP2P(ConsensusVoteTopic, byt)
+}
+
+// commitBlock runs the voting rounds for the block until it is committed,
+// and fills the 2/3+ precommits into the header if the block is committed.
+// A round times out if the votes are not enough, then the next round starts.
+func (h *Poa) commitBlock(block *Block) bool {
+	validatorsNum := len(h.votersAt(block.Height))
+	for round := uint32(0); round < h.bft.rounds; round++ {
+		if !h.bft.prevote(block.Height, round, block.Hash, validatorsNum) {
+			logrus.Warnf("another block has been locked at height(%d), refuse to vote block(%s)",
+				block.Height, block.Hash.String())
+			return false
+		}
+		precommits, ok := h.voteRound(block, round, validatorsNum)
+		if ok {
+			h.bft.commit(block.Height)
+			block.Proof = encodeCommit(block.Validators, round, precommits)
+			return true
+		}
+	}
+	return false
+}
+
+// voteRound prevotes and precommits the block in the round, returns the 2/3+ precommits if the block is committed.
+func (h *Poa) voteRound(block *Block, round uint32, validatorsNum int) ([]*Vote, bool) {
+	err := h.vote(Prevote, round, block)
+	if err != nil {
+		logrus.Error("prevote error: ", err)
+		return nil, false
+	}
+	_, ok := h.bft.waitQuorum(Prevote, round, block.Header, validatorsNum)
+	if !ok {
+		logrus.Warnf("wait prevotes of block(%s) in round(%d) timeout", block.Hash.String(), round)
+		return nil, false
+	}
+
+	h.bft.precommit(block.Height, round, block.Hash)
+	err = h.vote(Precommit, round, block)
+	if err != nil {
+		logrus.Error("precommit error: ", err)
+		return nil, false
+	}
+	precommits, ok := h.bft.waitQuorum(Precommit, round, block.Header, validatorsNum)
+	if !ok {
+		logrus.Warnf("wait precommits of block(%s) in round(%d) timeout", block.Hash.String(), round)
+		return nil, false
+	}
+	return precommits, true
+}
+
+// commitProof is the Proof in header of a committed block.
+type commitProof struct {
+	// the round the block is committed in
+	Round uint32 `json:"round"`
+	// the precommit signatures in the order of validators in header
+	Sigs [][]byte `json:"sigs"`
+}
+
+// encodeCommit puts the precommit signatures into Proof in the order of validators in header,
+// the signatures of validators who did not precommit are empty.
+func encodeCommit(validators []*Validator, round uint32, precommits []*Vote) []byte {
+	sigs := make([][]byte, len(validators))
+	for _, vote := range precommits {
+		for i, validator := range validators {
+			if bytes.Equal(validator.PubKey, vote.Pubkey) {
+				sigs[i] = vote.Signature
+			}
+		}
+	}
+	proof, _ := json.Marshal(&commitProof{Round: round, Sigs: sigs})
+	return proof
+}
+
+// VerifyCommit checks the block has the 2/3+ precommits of the validators active at its height,
+// the jailed validators are not counted.
+func (h *Poa) VerifyCommit(block *Block) bool {
+	return verifyCommit(block, h.validatorsAt(block.Height), h.jailedSnapshot())
+}
+
+// VerifyStateRoot checks the StateRoot of the verified header is precommitted by its validators,
+// so that the synchronizer restores a snapshot only at a committed StateRoot.
+func (h *Poa) VerifyStateRoot(header *Header) error {
+	return verifyStateRoot(header)
+}
+
+func verifyCommit(block *Block, validators *validatorSet, jailed map[Address]BlockNum) bool {
+	if !validators.matches(block.Validators) {
+		return false
+	}
+	signers, ok := commitSigners(block.Header)
+	if !ok {
+		return false
+	}
+	voters := unjailed(validators.addrs, jailed, block.Height)
+	count := 0
+	for _, signer := range signers {
+		if indexOf(voters, signer) >= 0 {
+			count++
+		}
+	}
+	return hasQuorum(count, len(voters))
+}
+
+// commitSigners returns the validators in header who precommit the block, false if any signature is illegal.
+func commitSigners(header *Header) ([]Address, bool) {
+	var proof commitProof
+	err := json.Unmarshal(header.Proof, &proof)
+	sigs := proof.Sigs
+	if err != nil || len(sigs) != len(header.Validators) {
+		return nil, false
+	}
+	signBytes := voteSignBytes(Precommit, header.Height, proof.Round, header.Hash, header.StateRoot)
+	signers := make([]Address, 0, len(sigs))
+	for i, validator := range header.Validators {
+		if len(sigs[i]) == 0 {
+			continue
+		}
+		pubkey, err := PubKeyFromBytes(validator.PubKey)
+		if err != nil || !pubkey.VerifySignature(signBytes, sigs[i]) {
+			return nil, false
+		}
+		signers = append(signers, pubkey.Address())
+	}
+	return signers, true
+}
@@ -0,0 +1,177 @@
+package poa
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/blockchain"
+	"github.com/yu-org/yu/core/env"
+	"github.com/yu-org/yu/core/kernel"
+	"github.com/yu-org/yu/core/state"
+	"github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/txdb"
+	"github.com/yu-org/yu/core/txpool"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newVote(t *testing.T, node *Poa, typ VoteType, header *Header, round uint32) *Vote {
+	vote := &Vote{
+		Type:      typ,
+		Height:    header.Height,
+		Round:     round,
+		BlockHash: header.Hash,
+		StateRoot: header.StateRoot,
+		Pubkey:    node.myPubkey.BytesWithType(),
+	}
+	sig, err := node.myPrivKey.SignData(vote.SignBytes())
+	assert.NoError(t, err)
+	vote.Signature = sig
+	return vote
+}
+
+func TestVerifyCommit(t *testing.T) {
+	initGlobalVars()
+
+	block := &Block{Header: &Header{Hash: HexToHash("abcd"), Height: 1, StateRoot: HexToHash("0a")}}
+	precommits := make([]*Vote, 0)
+	for _, node := range []*Poa{node1, node2, node3} {
+		precommits = append(precommits, newVote(t, node, Precommit, block.Header, 1))
+	}
+
+	block.Validators = node1.validatorsAt(block.Height).toHeader()
+	block.Proof = encodeCommit(block.Validators, 1, precommits)
+	assert.True(t, node1.VerifyCommit(block))
+
+	// 2 of 3 validators are not 2/3+
+	block.Proof = encodeCommit(block.Validators, 1, precommits[:2])
+	assert.False(t, node1.VerifyCommit(block))
+
+	// the commit cannot be used for another round
+	block.Proof = encodeCommit(block.Validators, 0, precommits)
+	assert.False(t, node1.VerifyCommit(block))
+
+	// the commit cannot be used for another state root
+	block.Proof = encodeCommit(block.Validators, 1, precommits)
+	block.StateRoot = HexToHash("0b")
+	assert.False(t, node1.VerifyCommit(block))
+
+	// the commit cannot be used for another block
+	block.StateRoot = HexToHash("0a")
+	block.Hash = HexToHash("dcba")
+	assert.False(t, node1.VerifyCommit(block))
+}
+
+func TestVoteLock(t *testing.T) {
+	initGlobalVars()
+	vb := newVoteBook(0, 3)
+	blockA := &Header{Height: 1, Hash: HexToHash("01")}
+	blockB := &Header{Height: 1, Hash: HexToHash("02")}
+	assert.True(t, vb.prevote(1, 0, blockA.Hash, 3))
+	assert.True(t, vb.prevote(1, 0, blockA.Hash, 3))
+	assert.False(t, vb.prevote(1, 0, blockB.Hash, 3))
+
+	// local node prevotes again in the next round.
+	assert.True(t, vb.prevote(1, 1, blockB.Hash, 3))
+	vb.precommit(1, 1, blockB.Hash)
+	assert.False(t, vb.prevote(1, 2, blockA.Hash, 3))
+
+	// 2/3+ prevotes for another block in a later round unlock local node.
+	for _, node := range []*Poa{node1, node2, node3} {
+		vb.add(newVote(t, node, Prevote, blockA, 3), node.LocalAddress())
+	}
+	assert.True(t, vb.prevote(1, 3, blockA.Hash, 3))
+
+	vb.commit(1)
+	vb.add(newVote(t, node1, Prevote, blockA, 0), node1.LocalAddress())
+	assert.Empty(t, vb.votesOf(Prevote, 1, 0, blockA.Hash, blockA.StateRoot))
+}
+
+func TestCommitInNextRound(t *testing.T) {
+	initGlobalVars()
+	net := p2p.NewMockP2p(1)
+	net.AddTopic(ConsensusVoteTopic)
+	// nobody else receives the votes of node1.
+	go func() {
+		for {
+			net.SubP2P(ConsensusVoteTopic)
+		}
+	}()
+	node1.SetChainEnv(&env.ChainEnv{P2pNetwork: net})
+	node1.bft = newVoteBook(100*time.Millisecond, 3)
+
+	block := &Block{Header: &Header{Hash: HexToHash("abcd"), Height: 1}}
+	block.Validators = node1.validatorsAt(block.Height).toHeader()
+	// the other validators miss the first round.
+	for _, node := range []*Poa{node2, node3} {
+		node1.bft.add(newVote(t, node, Prevote, block.Header, 1), node.LocalAddress())
+		node1.bft.add(newVote(t, node, Precommit, block.Header, 1), node.LocalAddress())
+	}
+
+	assert.True(t, node1.commitBlock(block))
+	assert.True(t, node1.VerifyCommit(block))
+	signers, ok := commitSigners(block.Header)
+	assert.True(t, ok)
+	assert.Len(t, signers, 3)
+}
+
+func TestRevertUncommittedBlock(t *testing.T) {
+	initGlobalVars()
+	dir := t.TempDir()
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(dir, "state.db")})
+	assert.NoError(t, err)
+	cfg := config.InitDefaultCfg()
+	base := txdb.NewTxDB(FullNode, kvdb)
+	chain := blockchain.NewBlockChain(FullNode, &config.BlockchainConf{
+		ChainDB:      config.SqlDbConf{SqlDbType: "sqlite", Dsn: filepath.Join(dir, "chain.db")},
+		ConvergeType: "longest",
+	}, base)
+	genesis := &Block{Header: &Header{Hash: HexToHash("0f")}}
+	assert.NoError(t, chain.SetGenesis(genesis))
+	net := p2p.NewMockP2p(1)
+	net.AddTopic(ConsensusVoteTopic)
+	go func() {
+		for {
+			net.SubP2P(ConsensusVoteTopic)
+		}
+	}()
+	chainEnv := &env.ChainEnv{
+		State:      state.NewSpmtKV(kvdb),
+		Chain:      chain,
+		TxDB:       base,
+		Pool:       txpool.NewTxPool(FullNode, &cfg.Txpool, base),
+		P2pNetwork: net,
+	}
+	node1.SetChainEnv(chainEnv)
+	land := tripod.NewLand()
+	land.SetTripods(node1.Tripod)
+	kernel.NewKernel(cfg, chainEnv, land)
+	node1.bft = newVoteBook(50*time.Millisecond, 1)
+
+	block := &Block{Header: &Header{Hash: HexToHash("01"), PrevHash: genesis.Hash, Height: 1}}
+	block.Validators = node1.validatorsAt(block.Height).toHeader()
+	node1.State.StartBlock(block.Hash)
+	node1.EndBlock(block)
+	// the other validators do not vote.
+	node1.FinalizeBlock(block)
+	end, err := chain.GetEndBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, genesis.Hash, end.Hash)
+
+	// the block is executed again from its parent, then committed.
+	node1.State.StartBlock(block.Hash)
+	node1.EndBlock(block)
+	for _, node := range []*Poa{node2, node3} {
+		node1.bft.add(newVote(t, node, Prevote, block.Header, 0), node.LocalAddress())
+		node1.bft.add(newVote(t, node, Precommit, block.Header, 0), node.LocalAddress())
+	}
+	node1.FinalizeBlock(block)
+	end, err = chain.GetEndBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, block.Hash, end.Hash)
+	assert.NotEmpty(t, end.Proof)
+}
@@ -3,6 +3,7 @@ package poa
 import (
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
 	. "github.com/yu-org/yu/core/keypair"
 )
 
@@ -15,6 +16,14 @@ type PoaConfig struct {
 	BlockInterval int `toml:"block_interval"`
 	// the number of packing txns from txpool, default 5000
 	PackNum uint64 `toml:"pack_num"`
+	// if true, blocks are finalized only after 2/3+ validators precommit them.
+	BftFinality bool `toml:"bft_finality"`
+	// timeout of waiting for votes in every round, seconds. default is twice the block interval.
+	VoteTimeout int `toml:"vote_timeout"`
+	// the rounds of voting for a block before giving up, default 3.
+	VoteRounds uint32 `toml:"vote_rounds"`
+	// the approved validator changes take effect at the boundaries of epochs, 0 means two blocks later.
+	EpochLength BlockNum `toml:"epoch_length"`
 }
 
 var DefaultSecrets = []string{
@@ -49,8 +58,8 @@ func DefaultCfg(idx int) *PoaConfig {
 }
 
 type ValidatorConf struct {
-	Pubkey string `toml:"pubkey"`
-	P2pIp  string `toml:"p2p_ip"`
+	Pubkey string `toml:"pubkey" json:"pubkey"`
+	P2pIp  string `toml:"p2p_ip" json:"p2p_ip"`
 }
 
 func resolveConfig(cfg *PoaConfig) (PubKey, PrivKey, []ValidatorInfo, error) {
@@ -58,11 +67,19 @@ func resolveConfig(cfg *PoaConfig) (PubKey, PrivKey, []ValidatorInfo, error) {
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	infos, err := resolveValidators(cfg.Validators)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pub, priv, infos, nil
+}
+
+func resolveValidators(validators []*ValidatorConf) ([]ValidatorInfo, error) {
 	infos := make([]ValidatorInfo, 0)
-	for _, validator := range cfg.Validators {
+	for _, validator := range validators {
 		pubkey, err := PubkeyFromStr(validator.Pubkey)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, err
 		}
 		if validator.P2pIp == "" {
 			infos = append(infos, ValidatorInfo{
@@ -71,7 +88,7 @@ func resolveConfig(cfg *PoaConfig) (PubKey, PrivKey, []ValidatorInfo, error) {
 		} else {
 			peerID, err := peer.Decode(validator.P2pIp)
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, err
 			}
 			infos = append(infos, ValidatorInfo{
 				Pubkey: pubkey,
@@ -79,5 +96,5 @@ func resolveConfig(cfg *PoaConfig) (PubKey, PrivKey, []ValidatorInfo, error) {
 			})
 		}
 	}
-	return pub, priv, infos, nil
+	return infos, nil
 }
@@ -0,0 +1,407 @@
+package poa
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"net/http"
+	"sync"
+)
+
+// the signed headers below currentHeight - keepHeadersHeights are dropped from the evidence pool.
+const keepHeadersHeights = 64
+
+var (
+	jailedValidatorsKey = []byte("jailed-validators")
+	evidencePrefix      = "double-sign-evidence-"
+)
+
+// DoubleSignEvidence holds two different headers signed by the same validator at the same height,
+// the headers are encoded in protobuf.
+type DoubleSignEvidence struct {
+	HeaderA []byte `json:"header_a"`
+	HeaderB []byte `json:"header_b"`
+}
+
+func newDoubleSignEvidence(a, b *Header) (*DoubleSignEvidence, error) {
+	bytA, err := proto.Marshal(a.ToPb())
+	if err != nil {
+		return nil, err
+	}
+	bytB, err := proto.Marshal(b.ToPb())
+	if err != nil {
+		return nil, err
+	}
+	return &DoubleSignEvidence{HeaderA: bytA, HeaderB: bytB}, nil
+}
+
+// evidenceID is the same no matter which order the two headers are in.
+func evidenceID(a, b Hash) Hash {
+	if bytes.Compare(a.Bytes(), b.Bytes()) > 0 {
+		a, b = b, a
+	}
+	return BytesToHash(Sha256(append(a.Bytes(), b.Bytes()...)))
+}
+
+// JailedValidator is removed from the leader rotation for double signing.
+type JailedValidator struct {
+	Address Address `json:"address"`
+	Pubkey  string  `json:"pubkey"`
+	// the height the validator signed two blocks at.
+	Height   BlockNum `json:"height"`
+	Evidence Hash     `json:"evidence"`
+	// the validator is out of the leader rotation after this height.
+	JailedAt BlockNum `json:"jailed_at"`
+}
+
+// evidencePool collects the signed headers from p2p to catch the validators signing two blocks at the same height.
+type evidencePool struct {
+	sync.Mutex
+	// height -> miner -> the first header seen
+	headers   map[BlockNum]map[Address]*Header
+	evidences map[Hash]*DoubleSignEvidence
+}
+
+func newEvidencePool() *evidencePool {
+	return &evidencePool{
+		headers:   make(map[BlockNum]map[Address]*Header),
+		evidences: make(map[Hash]*DoubleSignEvidence),
+	}
+}
+
+// observe records the header signed by miner, returns the evidence if miner has signed another header at the same height.
+func (ep *evidencePool) observe(header *Header, miner Address, currentHeight BlockNum) (*DoubleSignEvidence, error) {
+	ep.Lock()
+	defer ep.Unlock()
+	for height := range ep.headers {
+		if height+keepHeadersHeights < currentHeight {
+			delete(ep.headers, height)
+		}
+	}
+	if ep.headers[header.Height] == nil {
+		ep.headers[header.Height] = make(map[Address]*Header)
+	}
+	seen, ok := ep.headers[header.Height][miner]
+	if !ok {
+		ep.headers[header.Height][miner] = header
+		return nil, nil
+	}
+	if seen.Hash == header.Hash {
+		return nil, nil
+	}
+	evidence, err := newDoubleSignEvidence(seen, header)
+	if err != nil {
+		return nil, err
+	}
+	ep.evidences[evidenceID(seen.Hash, header.Hash)] = evidence
+	return evidence, nil
+}
+
+func (ep *evidencePool) remove(id Hash) {
+	ep.Lock()
+	defer ep.Unlock()
+	delete(ep.evidences, id)
+}
+
+func (ep *evidencePool) pending() []*DoubleSignEvidence {
+	ep.Lock()
+	defer ep.Unlock()
+	evidences := make([]*DoubleSignEvidence, 0, len(ep.evidences))
+	for _, evidence := range ep.evidences {
+		evidences = append(evidences, evidence)
+	}
+	return evidences
+}
+
+// observeHeader puts the header from p2p into evidence pool, the header must have passed VerifyBlock.
+func (h *Poa) observeHeader(header *Header) {
+	hash, err := signedHash(header)
+	if err != nil || hash != header.Hash {
+		return
+	}
+	minerPubkey, err := PubKeyFromBytes(header.MinerPubkey)
+	if err != nil {
+		return
+	}
+	evidence, err := h.evidences.observe(header, minerPubkey.Address(), h.getCurrentHeight())
+	if err != nil {
+		logrus.Error("make double-sign evidence error: ", err)
+		return
+	}
+	if evidence != nil {
+		logrus.Warnf("validator(%s) signed two blocks at height(%d)", minerPubkey.Address().String(), header.Height)
+	}
+}
+
+// signedHash recomputes the hash signed by miner, the fields filled after signing are excluded.
+func signedHash(header *Header) (Hash, error) {
+	unsigned := *header
+	unsigned.Hash = NullHash
+	unsigned.StateRoot = NullHash
+	unsigned.ReceiptRoot = NullHash
+	unsigned.LeiUsed = 0
+	unsigned.MinerPubkey = nil
+	unsigned.MinerSignature = nil
+	unsigned.Proof = nil
+	byt, err := (&Block{Header: &unsigned}).Encode()
+	if err != nil {
+		return NullHash, err
+	}
+	return BytesToHash(Sha256(byt)), nil
+}
+
+func decodeSignedHeader(byt []byte) (*Header, error) {
+	var pb goproto.Header
+	err := proto.Unmarshal(byt, &pb)
+	if err != nil {
+		return nil, err
+	}
+	// HeaderFromPb panics on the illegal peer id.
+	if pb.PeerId != "" {
+		_, err = peer.Decode(pb.PeerId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return HeaderFromPb(&pb), nil
+}
+
+// verifyEvidence checks the two headers are different blocks at the same height signed by the same validator.
+func (h *Poa) verifyEvidence(st TripodKV, evidence *DoubleSignEvidence) (*JailedValidator, error) {
+	a, err := decodeSignedHeader(evidence.HeaderA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeSignedHeader(evidence.HeaderB)
+	if err != nil {
+		return nil, err
+	}
+	if a.Height != b.Height {
+		return nil, EvidenceIllegal("headers are at different heights")
+	}
+	if a.Hash == b.Hash {
+		return nil, EvidenceIllegal("headers are the same block")
+	}
+	if !bytes.Equal(a.MinerPubkey, b.MinerPubkey) {
+		return nil, EvidenceIllegal("headers are signed by different miners")
+	}
+	minerPubkey, err := PubKeyFromBytes(a.MinerPubkey)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := h.validatorsInState(st, a.Height)
+	if err != nil {
+		return nil, err
+	}
+	if !validators.contains(minerPubkey.Address()) {
+		return nil, EvidenceIllegal("miner is not a validator")
+	}
+	for _, header := range []*Header{a, b} {
+		hash, err := signedHash(header)
+		if err != nil {
+			return nil, err
+		}
+		if hash != header.Hash || !minerPubkey.VerifySignature(header.Hash.Bytes(), header.MinerSignature) {
+			return nil, EvidenceIllegal("signature of block(" + header.Hash.String() + ") mismatched")
+		}
+	}
+	return &JailedValidator{
+		Address:  minerPubkey.Address(),
+		Pubkey:   minerPubkey.StringWithType(),
+		Height:   a.Height,
+		Evidence: evidenceID(a.Hash, b.Hash),
+	}, nil
+}
+
+// leadersAt returns the validators in the leader rotation at the height, they are the voters at the height as well.
+func (h *Poa) leadersAt(height BlockNum) []Address {
+	return h.votersAt(height)
+}
+
+// votersAt returns the validators voting for the blocks at the height, the jailed ones are excluded.
+func (h *Poa) votersAt(height BlockNum) []Address {
+	h.jailedLock.RLock()
+	defer h.jailedLock.RUnlock()
+	return unjailed(h.validatorsAt(height).addrs, h.jailed, height)
+}
+
+// unjailed returns the validators not jailed before the height.
+func unjailed(validators []Address, jailed map[Address]BlockNum, height BlockNum) []Address {
+	result := make([]Address, 0, len(validators))
+	for _, addr := range validators {
+		jailedAt, ok := jailed[addr]
+		if !ok || jailedAt >= height {
+			result = append(result, addr)
+		}
+	}
+	// never stop producing blocks even if all the validators are jailed.
+	if len(result) == 0 {
+		return validators
+	}
+	return result
+}
+
+// loadJailed reloads the jailed validators from the state committed by the block,
+// it is called only at block boundaries like loadValidatorSets.
+func (h *Poa) loadJailed(blockHash Hash) {
+	jailed, err := h.committedJailed(blockHash)
+	if err != nil {
+		logrus.Error("load jailed validators from state error: ", err)
+		return
+	}
+	h.cacheJailed(jailed)
+}
+
+// jailedSnapshot returns the loaded jailed validators, the map is replaced by loadJailed but never modified.
+func (h *Poa) jailedSnapshot() map[Address]BlockNum {
+	h.jailedLock.RLock()
+	defer h.jailedLock.RUnlock()
+	return h.jailed
+}
+
+func (h *Poa) cacheJailed(jailed map[Address]BlockNum) {
+	h.jailedLock.Lock()
+	h.jailed = jailed
+	h.jailedLock.Unlock()
+}
+
+// committedJailed returns the heights the validators are jailed at in the state committed by the block.
+func (h *Poa) committedJailed(blockHash Hash) (map[Address]BlockNum, error) {
+	jailedMap := make(map[Address]BlockNum)
+	if h.ChainEnv == nil || h.State == nil {
+		return jailedMap, nil
+	}
+	byt, err := h.GetByBlockHash(jailedValidatorsKey, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	jailed, err := decodeJailed(byt)
+	if err != nil {
+		return nil, err
+	}
+	for _, validator := range jailed {
+		jailedMap[validator.Address] = validator.JailedAt
+	}
+	return jailedMap, nil
+}
+
+func (h *Poa) getJailed(st TripodKV) ([]*JailedValidator, error) {
+	if h.ChainEnv == nil || h.State == nil {
+		return nil, nil
+	}
+	byt, err := st.Get(jailedValidatorsKey)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJailed(byt)
+}
+
+func decodeJailed(byt []byte) ([]*JailedValidator, error) {
+	if byt == nil {
+		return nil, nil
+	}
+	var jailed []*JailedValidator
+	err := json.Unmarshal(byt, &jailed)
+	return jailed, err
+}
+
+func setJailed(st TripodKV, jailed []*JailedValidator) error {
+	byt, err := json.Marshal(jailed)
+	if err != nil {
+		return err
+	}
+	st.Set(jailedValidatorsKey, byt)
+	return nil
+}
+
+// SubmitEvidence verifies the double-sign evidence, stores it on chain and jails the validator.
+func (h *Poa) SubmitEvidence(ctx *WriteContext) error {
+	var evidence DoubleSignEvidence
+	err := ctx.BindJson(&evidence)
+	if err != nil {
+		return err
+	}
+	st := h.TxnState(ctx)
+	validator, err := h.verifyEvidence(st, &evidence)
+	if err != nil {
+		return err
+	}
+	jailed, err := h.getJailed(st)
+	if err != nil {
+		return err
+	}
+	for _, v := range jailed {
+		if v.Address == validator.Address {
+			return ValidatorJailed
+		}
+	}
+
+	byt, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	st.Set([]byte(evidencePrefix+validator.Evidence.String()), byt)
+
+	validator.JailedAt = ctx.Block.Height
+	err = setJailed(st, append(jailed, validator))
+	if err != nil {
+		return err
+	}
+	h.evidences.remove(validator.Evidence)
+	logrus.Warnf("jail validator(%s) for signing two blocks at height(%d)", validator.Address.String(), validator.Height)
+	return ctx.EmitJsonEvent(validator)
+}
+
+// GetJailedValidators returns the validators jailed on chain.
+func (h *Poa) GetJailedValidators(ctx *ReadContext) {
+	jailed, err := h.getJailed(h)
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	ctx.JsonOk(jailed)
+}
+
+type EvidenceQuery struct {
+	ID string `json:"id"`
+}
+
+// GetEvidence returns the double-sign evidence stored on chain.
+func (h *Poa) GetEvidence(ctx *ReadContext) {
+	var req EvidenceQuery
+	err := ctx.BindJson(&req)
+	if err != nil {
+		ctx.Err(http.StatusBadRequest, err)
+		return
+	}
+	byt, err := h.Get([]byte(evidencePrefix + HexToHash(req.ID).String()))
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	if byt == nil {
+		ctx.ErrOk(EvidenceNotFound)
+		return
+	}
+	evidence := new(DoubleSignEvidence)
+	err = json.Unmarshal(byt, evidence)
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	ctx.JsonOk(evidence)
+}
+
+// GetCollectedEvidences returns the evidences collected by local node from p2p and not submitted on chain yet.
+func (h *Poa) GetCollectedEvidences(ctx *ReadContext) {
+	ctx.JsonOk(h.evidences.pending())
+}
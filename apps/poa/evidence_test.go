@@ -0,0 +1,125 @@
+package poa
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/env"
+	"github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"path/filepath"
+	"testing"
+)
+
+func signHeader(t *testing.T, node *Poa, header *Header) *Header {
+	hash, err := signedHash(header)
+	assert.NoError(t, err)
+	header.Hash = hash
+	header.MinerPubkey = node.myPubkey.BytesWithType()
+	header.MinerSignature, err = node.myPrivKey.SignData(hash.Bytes())
+	assert.NoError(t, err)
+	return header
+}
+
+func TestVerifyEvidence(t *testing.T) {
+	initGlobalVars()
+
+	a := signHeader(t, node1, &Header{Height: 5, Timestamp: 1})
+	b := signHeader(t, node1, &Header{Height: 5, Timestamp: 2})
+
+	pool := newEvidencePool()
+	evidence, err := pool.observe(a, node1.LocalAddress(), 5)
+	assert.NoError(t, err)
+	assert.Nil(t, evidence)
+	evidence, err = pool.observe(b, node1.LocalAddress(), 5)
+	assert.NoError(t, err)
+	assert.NotNil(t, evidence)
+	assert.Len(t, pool.pending(), 1)
+
+	jailed, err := node2.verifyEvidence(node2, evidence)
+	assert.NoError(t, err)
+	assert.Equal(t, node1.LocalAddress(), jailed.Address)
+	assert.Equal(t, BlockNum(5), jailed.Height)
+
+	// the signature does not cover another height.
+	c := signHeader(t, node1, &Header{Height: 6, Timestamp: 2})
+	c.Height = 5
+	evidence, err = newDoubleSignEvidence(a, c)
+	assert.NoError(t, err)
+	_, err = node2.verifyEvidence(node2, evidence)
+	assert.Error(t, err)
+
+	evidence, err = newDoubleSignEvidence(a, a)
+	assert.NoError(t, err)
+	_, err = node2.verifyEvidence(node2, evidence)
+	assert.Error(t, err)
+}
+
+func TestJailedLeader(t *testing.T) {
+	initGlobalVars()
+
+	node2.cacheJailed(map[Address]BlockNum{node1.LocalAddress(): 3})
+	assert.Equal(t, node1.LocalAddress(), node2.CompeteLeader(1))
+	for i := 4; i <= 10; i++ {
+		assert.NotEqual(t, node1.LocalAddress(), node2.CompeteLeader(BlockNum(i)))
+	}
+}
+
+func TestJailedVoter(t *testing.T) {
+	initGlobalVars()
+	node2.cacheJailed(map[Address]BlockNum{node1.LocalAddress(): 3})
+
+	header := &Header{Hash: HexToHash("abcd"), Height: 3}
+	_, ok := node2.verifyVote(newVote(t, node1, Prevote, header, 0))
+	assert.True(t, ok)
+	header.Height = 4
+	_, ok = node2.verifyVote(newVote(t, node1, Prevote, header, 0))
+	assert.False(t, ok)
+
+	// the quorum is 2/3+ of the validators not jailed.
+	block := &Block{Header: header}
+	block.Validators = node2.validatorsAt(block.Height).toHeader()
+	commit := func(nodes ...*Poa) []byte {
+		precommits := make([]*Vote, 0)
+		for _, node := range nodes {
+			precommits = append(precommits, newVote(t, node, Precommit, header, 0))
+		}
+		return encodeCommit(block.Validators, 0, precommits)
+	}
+	block.Proof = commit(node2, node3)
+	assert.True(t, node2.VerifyCommit(block))
+	block.Proof = commit(node1, node2)
+	assert.False(t, node2.VerifyCommit(block))
+}
+
+func TestSubmitEvidence(t *testing.T) {
+	initGlobalVars()
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(t.TempDir(), "state.db")})
+	assert.NoError(t, err)
+	node2.SetChainEnv(&env.ChainEnv{State: state.NewSpmtKV(kvdb)})
+	node2.SetInstance(node2)
+
+	a := signHeader(t, node1, &Header{Height: 5, Timestamp: 1})
+	b := signHeader(t, node1, &Header{Height: 5, Timestamp: 2})
+	evidence, err := newDoubleSignEvidence(a, b)
+	assert.NoError(t, err)
+	params, err := json.Marshal(evidence)
+	assert.NoError(t, err)
+	stxn, err := NewSignedTxn(&WrCall{Params: string(params)}, nil, nil)
+	assert.NoError(t, err)
+	ctx, err := context.NewWriteContext(stxn, &Block{Header: &Header{Height: 6}})
+	assert.NoError(t, err)
+
+	block := HexToHash("0a")
+	node2.State.StartBlock(block)
+	assert.NoError(t, node2.SubmitEvidence(ctx))
+	// the jailing of the executing block never leaks into the leader rotation and votes.
+	assert.Contains(t, node2.votersAt(7), node1.LocalAddress())
+	_, err = node2.State.Commit()
+	assert.NoError(t, err)
+	node2.loadJailed(block)
+	assert.NotContains(t, node2.votersAt(7), node1.LocalAddress())
+}
@@ -0,0 +1,115 @@
+package poa
+
+import (
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/types"
+)
+
+// LightVerifier verifies the headers synced by the light client,
+// it follows the validator sets from the ones in config.
+type LightVerifier struct {
+	validators *validatorSet
+}
+
+func NewLightVerifier(cfg *PoaConfig) (*LightVerifier, error) {
+	infos, err := resolveValidators(cfg.Validators)
+	if err != nil {
+		return nil, err
+	}
+	return &LightVerifier{validators: newValidatorSet(0, infos)}, nil
+}
+
+// VerifyHeader checks the header is signed by a trusted validator.
+// The trusted validators are replaced by the ones in header only if the header is committed
+// by 2/3+ of both the trusted and the new validators.
+func (v *LightVerifier) VerifyHeader(header *Header) error {
+	hash, err := signedHash(header)
+	if err != nil {
+		return err
+	}
+	if hash != header.Hash {
+		return BlockIllegal(header.Hash)
+	}
+	minerPubkey, err := PubKeyFromBytes(header.MinerPubkey)
+	if err != nil {
+		return err
+	}
+	if !minerPubkey.VerifySignature(header.Hash.Bytes(), header.MinerSignature) {
+		return BlockIllegal(header.Hash)
+	}
+
+	trusted := v.validators
+	validators := trusted
+	if !trusted.matches(header.Validators) {
+		validators, err = validatorSetFromHeader(header)
+		if err != nil {
+			return err
+		}
+	}
+	if !validators.contains(minerPubkey.Address()) {
+		return BlockIllegal(header.Hash)
+	}
+	if validators == trusted {
+		if len(header.Proof) > 0 && !verifyHeaderCommit(header, trusted) {
+			return BlockIllegal(header.Hash)
+		}
+		return nil
+	}
+
+	if len(header.Proof) == 0 || !verifyHeaderCommit(header, validators) || !verifyHeaderCommit(header, trusted) {
+		return ValidatorsUncommitted
+	}
+	logrus.Infof("light client follows the validators changed at height(%d)", header.Height)
+	v.validators = validators
+	return nil
+}
+
+// VerifyStateRoot checks 2/3+ of the validators in the verified header precommit it with its StateRoot,
+// since the miner signs the block before executing it.
+func (v *LightVerifier) VerifyStateRoot(header *Header) error {
+	return verifyStateRoot(header)
+}
+
+// verifyStateRoot checks the commit in the header whose validators have been verified.
+func verifyStateRoot(header *Header) error {
+	if len(header.Proof) == 0 {
+		return StateRootUncommitted
+	}
+	validators, err := validatorSetFromHeader(header)
+	if err != nil {
+		return err
+	}
+	if !verifyHeaderCommit(header, validators) {
+		return StateRootUncommitted
+	}
+	return nil
+}
+
+// verifyHeaderCommit checks 2/3+ of the validators precommit the header.
+func verifyHeaderCommit(header *Header, validators *validatorSet) bool {
+	signers, ok := commitSigners(header)
+	if !ok {
+		return false
+	}
+	count := 0
+	for _, signer := range signers {
+		if validators.contains(signer) {
+			count++
+		}
+	}
+	return hasQuorum(count, len(validators.addrs))
+}
+
+func validatorSetFromHeader(header *Header) (*validatorSet, error) {
+	infos := make([]ValidatorInfo, 0, len(header.Validators))
+	for _, validator := range header.Validators {
+		pubkey, err := PubKeyFromBytes(validator.PubKey)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, ValidatorInfo{Pubkey: pubkey})
+	}
+	return newValidatorSet(header.Height, infos), nil
+}
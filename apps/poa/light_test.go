@@ -0,0 +1,64 @@
+package poa
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/types"
+	"testing"
+)
+
+func commitHeader(t *testing.T, header *Header, nodes ...*Poa) {
+	precommits := make([]*Vote, 0)
+	for _, node := range nodes {
+		precommits = append(precommits, newVote(t, node, Precommit, header, 0))
+	}
+	header.Proof = encodeCommit(header.Validators, 0, precommits)
+}
+
+func TestLightVerifier(t *testing.T) {
+	initGlobalVars()
+	v, err := NewLightVerifier(DefaultCfg(0))
+	assert.NoError(t, err)
+	oldValidators := node1.validatorsAt(1).toHeader()
+
+	header := signHeader(t, node1, &Header{Height: 1, Validators: oldValidators})
+	// the roots are filled after signing.
+	header.StateRoot = HexToHash("0a")
+	byt, err := EncodeBlocks([]*Block{{Header: header}})
+	assert.NoError(t, err)
+	blocks, err := DecodeBlocks(byt)
+	assert.NoError(t, err)
+	assert.NoError(t, v.VerifyHeader(blocks[0].Header))
+	assert.Equal(t, StateRootUncommitted, v.VerifyStateRoot(header))
+	commitHeader(t, header, node1, node2, node3)
+	assert.NoError(t, v.VerifyStateRoot(header))
+	// the state root is forged after the validators commit it.
+	header.StateRoot = HexToHash("0b")
+	assert.Equal(t, StateRootUncommitted, v.VerifyStateRoot(header))
+
+	header.Height = 2
+	assert.ErrorAs(t, v.VerifyHeader(header), new(ErrBlockIllegal))
+
+	pub4, priv4 := GenSrKeyWithSecret([]byte("node4"))
+	node4 := newPoa(pub4, priv4, validators, 3, 5000)
+	header = signHeader(t, node4, &Header{Height: 2, Validators: oldValidators})
+	assert.ErrorAs(t, v.VerifyHeader(header), new(ErrBlockIllegal))
+
+	newValidators := append(oldValidators, &Validator{PubKey: pub4.BytesWithType(), ProposeWeight: 1, VoteWeight: 1})
+	header = signHeader(t, node2, &Header{Height: 2, Validators: newValidators})
+	assert.Equal(t, ValidatorsUncommitted, v.VerifyHeader(header))
+
+	// 3/4 of the new validators, but only 2/3 of the trusted ones.
+	commitHeader(t, header, node1, node2, node4)
+	assert.Equal(t, ValidatorsUncommitted, v.VerifyHeader(header))
+
+	commitHeader(t, header, node1, node2, node3)
+	assert.NoError(t, v.VerifyHeader(header))
+
+	header = signHeader(t, node4, &Header{Height: 3, Validators: newValidators})
+	assert.NoError(t, v.VerifyHeader(header))
+	header = signHeader(t, node1, &Header{Height: 4, Validators: oldValidators})
+	assert.Equal(t, ValidatorsUncommitted, v.VerifyHeader(header))
+}
@@ -5,32 +5,44 @@ import (
 	"fmt"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/sirupsen/logrus"
+	"github.com/yu-org/yu/apps/synchronizer"
 	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/core/keypair"
 	. "github.com/yu-org/yu/core/tripod"
 	. "github.com/yu-org/yu/core/types"
 	"github.com/yu-org/yu/utils/log"
 	"go.uber.org/atomic"
+	"sync"
 	"time"
 )
 
 type Poa struct {
 	*Tripod
 
-	// key: crypto address, generate from pubkey
-	validatorsMap map[Address]peer.ID
-	myPubkey      PubKey
-	myPrivKey     PrivKey
+	myPubkey  PubKey
+	myPrivKey PrivKey
 
-	validatorsList []Address
+	// validator sets ordered by their active heights, the first one is from config.
+	sets        []*validatorSet
+	setsLock    sync.RWMutex
+	epochLength BlockNum
+
+	// validator address -> the height it is jailed at
+	jailed     map[Address]BlockNum
+	jailedLock sync.RWMutex
+	evidences  *evidencePool
 
 	currentHeight *atomic.Uint32
 
 	blockInterval int
 	packNum       uint64
 	recvChan      chan *Block
-	// local node index in addrs
-	nodeIdx int
+	// the missing txns of a block from P2P are useless after a block interval.
+	fetchTimeout time.Duration
+
+	// nil if BFT finality is disabled
+	bft *voteBook
 }
 
 type ValidatorInfo struct {
@@ -39,52 +51,63 @@ type ValidatorInfo struct {
 }
 
 func NewPoa(cfg *PoaConfig) *Poa {
+	p := NewPoaBase(cfg)
+	p.SetWritings(p.ProposeValidator, p.VoteValidator)
+	p.SetReadings(p.GetValidatorProposal)
+	return p
+}
+
+// NewPoaBase makes a Poa without the validator proposals,
+// its validator sets are scheduled by another tripod built on it, such as DPoS.
+func NewPoaBase(cfg *PoaConfig) *Poa {
 	pub, priv, infos, err := resolveConfig(cfg)
 	if err != nil {
 		logrus.Fatal("resolve poa config error: ", err)
 	}
-	return newPoa(pub, priv, infos, cfg.BlockInterval, cfg.PackNum)
+	p := newPoa(pub, priv, infos, cfg.BlockInterval, cfg.PackNum)
+	p.epochLength = cfg.EpochLength
+	if cfg.BftFinality {
+		voteTimeout := cfg.VoteTimeout
+		if voteTimeout == 0 {
+			voteTimeout = 2 * cfg.BlockInterval
+		}
+		voteRounds := cfg.VoteRounds
+		if voteRounds == 0 {
+			voteRounds = 3
+		}
+		p.bft = newVoteBook(time.Duration(voteTimeout)*time.Second, voteRounds)
+	}
+	return p
 }
 
 func newPoa(myPubkey PubKey, myPrivkey PrivKey, addrIps []ValidatorInfo, interval int, packNum uint64) *Poa {
 	tri := NewTripod()
 
-	var nodeIdx int
-
-	validatorsAddr := make([]Address, 0)
-	validators := make(map[Address]peer.ID)
-	for _, addrIp := range addrIps {
-		addr := addrIp.Pubkey.Address()
-		validators[addr] = addrIp.P2pID
-
-		if addr == myPubkey.Address() {
-			nodeIdx = len(validatorsAddr)
-		}
-
-		validatorsAddr = append(validatorsAddr, addr)
-	}
-
 	p := &Poa{
-		Tripod:         tri,
-		validatorsMap:  validators,
-		validatorsList: validatorsAddr,
-		myPubkey:       myPubkey,
-		myPrivKey:      myPrivkey,
-		currentHeight:  atomic.NewUint32(0),
-		blockInterval:  interval,
-		packNum:        packNum,
-		recvChan:       make(chan *Block, 10),
-		nodeIdx:        nodeIdx,
+		Tripod:        tri,
+		myPubkey:      myPubkey,
+		myPrivKey:     myPrivkey,
+		sets:          []*validatorSet{newValidatorSet(0, addrIps)},
+		currentHeight: atomic.NewUint32(0),
+		blockInterval: interval,
+		packNum:       packNum,
+		recvChan:      make(chan *Block, 10),
+		fetchTimeout:  time.Duration(interval) * time.Second,
+		jailed:        make(map[Address]BlockNum),
+		evidences:     newEvidencePool(),
 	}
 	p.SetInit(p)
 	p.SetTxnChecker(p)
 	p.SetBlockCycle(p)
 	p.SetBlockVerifier(p)
+	p.SetReorgHandler(p)
+	p.SetWritings(p.SubmitEvidence)
+	p.SetReadings(p.GetValidators, p.GetJailedValidators, p.GetEvidence, p.GetCollectedEvidences)
 	return p
 }
 
 func (h *Poa) ValidatorsP2pID() (peers []peer.ID) {
-	for _, id := range h.validatorsMap {
+	for _, id := range h.validatorsAt(h.getCurrentHeight()).peers {
 		peers = append(peers, id)
 	}
 	return
@@ -99,20 +122,99 @@ func (h *Poa) CheckTxn(txn *SignedTxn) error {
 	return nil
 }
 
+// VerifyBlock verifies the block whose parent is in the chain, such as the blocks synced from peers.
 func (h *Poa) VerifyBlock(block *Block) bool {
+	// the genesis block has no parent.
+	if block.Height == 0 {
+		return h.verifyHeader(block, h.validatorsAt(0), nil)
+	}
+	parent, err := h.Chain.GetBlock(block.PrevHash)
+	if err != nil {
+		logrus.Warnf("get parent of block(%s) error: %v", block.Hash.String(), err)
+		return false
+	}
+	// the block is verified against the validators committed by its parent,
+	// the loaded sets may be behind when syncing blocks.
+	sets, err := h.committedValidatorSets(block.PrevHash)
+	if err != nil {
+		logrus.Warnf("get validators of block(%s) error: %v", block.Hash.String(), err)
+		return false
+	}
+	jailed, err := h.committedJailed(block.PrevHash)
+	if err != nil {
+		logrus.Warnf("get jailed validators of block(%s) error: %v", block.Hash.String(), err)
+		return false
+	}
+	if !h.verifyHeader(block, selectValidators(sets, block.Height), jailed) {
+		return false
+	}
+	return h.verifyBaseLeiPrice(block, NextBaseLeiPrice(parent.Header, h.MinLeiPrice))
+}
+
+// verifyBaseLeiPrice checks the base lei price follows the parent block, the leader must not set any price.
+func (h *Poa) verifyBaseLeiPrice(block *Block, expected uint64) bool {
+	if block.BaseLeiPrice != expected {
+		logrus.Warnf("base lei price of block(%s) is %d, expect %d", block.Hash.String(), block.BaseLeiPrice, expected)
+		return false
+	}
+	return true
+}
+
+// verifyHeader verifies the miner, validators and signatures of the block against the validators active at its height,
+// the blocks in chain must carry their commits if the bft finality is on, the jailed validators are not counted in the commit.
+func (h *Poa) verifyHeader(block *Block, validators *validatorSet, jailed map[Address]BlockNum) bool {
+	if !h.verifyProposal(block, validators, jailed) {
+		return false
+	}
+	// the genesis block is not voted.
+	if h.bft != nil && block.Height > 0 && len(block.Proof) == 0 {
+		logrus.Warnf("block(%s) is not committed", block.Hash.String())
+		return false
+	}
+	if len(block.Proof) > 0 && !verifyCommit(block, validators, jailed) {
+		logrus.Warnf("illegal commit of block(%s)", block.Hash.String())
+		return false
+	}
+	return true
+}
+
+// verifyProposal verifies the header of the block proposed for voting, which has no commit yet.
+func (h *Poa) verifyProposal(block *Block, validators *validatorSet, jailed map[Address]BlockNum) bool {
 	minerPubkey, err := PubKeyFromBytes(block.MinerPubkey)
 	if err != nil {
 		logrus.Warnf("parse pubkey(%s) error: %v", block.MinerPubkey, err)
 		return false
 	}
-	if _, ok := h.validatorsMap[minerPubkey.Address()]; !ok {
+	if !validators.contains(minerPubkey.Address()) {
 		logrus.Warn("illegal miner: ", minerPubkey.StringWithType())
 		return false
 	}
+	// only the proposer of the round in Nonce produces the block, the jailed validators are out of the rotation.
+	if block.Height > 0 {
+		leaders := unjailed(validators.addrs, jailed, block.Height)
+		if block.Nonce >= uint64(len(leaders)) || proposerOf(leaders, block.Height, block.Nonce) != minerPubkey.Address() {
+			logrus.Warnf("miner(%s) is not the proposer of block(%s) in round(%d)",
+				minerPubkey.StringWithType(), block.Hash.String(), block.Nonce)
+			return false
+		}
+	}
+	// the header must record the active validators, so that syncing nodes verify it against them.
+	if !validators.matches(block.Validators) {
+		logrus.Warnf("validators of block(%s) mismatched", block.Hash.String())
+		return false
+	}
 	return minerPubkey.VerifySignature(block.Hash.Bytes(), block.MinerSignature)
 }
 
 func (h *Poa) InitChain() {
+	end, err := h.Chain.GetEndBlock()
+	if err == nil {
+		h.loadValidatorSets(end.Hash)
+		h.loadJailed(end.Hash)
+	}
+	if h.bft != nil {
+		go h.handleVotes()
+	}
 
 	go func() {
 		for {
@@ -121,33 +223,56 @@ func (h *Poa) InitChain() {
 				logrus.Error("subscribe message from P2P error: ", err)
 				continue
 			}
-			p2pBlock, err := DecodeBlock(msg)
+			compactBlock, err := DecodeCompactBlock(msg)
 			if err != nil {
-				logrus.Error("decode p2pBlock from p2p error: ", err)
+				logrus.Error("decode compact block from p2p error: ", err)
 				continue
 			}
-			if bytes.Equal(p2pBlock.MinerPubkey, h.myPubkey.BytesWithType()) {
+			if bytes.Equal(compactBlock.MinerPubkey, h.myPubkey.BytesWithType()) {
 				continue
 			}
 
 			logrus.Debugf("accept block(%s), height(%d), miner(%s)",
-				p2pBlock.Hash.String(), p2pBlock.Height, ToHex(p2pBlock.MinerPubkey))
+				compactBlock.Hash.String(), compactBlock.Height, ToHex(compactBlock.MinerPubkey))
 
-			if h.getCurrentHeight() > p2pBlock.Height {
+			// the parent may be still executing, the base lei price is checked when the block is used.
+			ok := h.verifyProposal(&Block{Header: compactBlock.Header}, h.validatorsAt(compactBlock.Height), h.jailedSnapshot())
+			if !ok {
+				logrus.Warnf("p2pBlock(%s) verify failed", compactBlock.Hash.String())
 				continue
 			}
+			// the stale blocks are also checked for double signing.
+			h.observeHeader(compactBlock.Header)
 
-			ok := h.VerifyBlock(p2pBlock)
-			if !ok {
-				logrus.Warnf("p2pBlock(%s) verify failed", p2pBlock.Hash.String())
+			if h.getCurrentHeight() > compactBlock.Height {
 				continue
 			}
 
-			h.recvChan <- p2pBlock
+			txns, missing, err := h.txnsInPool(compactBlock)
+			if err != nil {
+				logrus.Errorf("rebuild block(%s) error: %v", compactBlock.Hash.String(), err)
+				continue
+			}
+			if len(missing) == 0 {
+				h.deliverBlock(compactBlock, txns, nil)
+				continue
+			}
+			// fetching the missing txns must not hold back the blocks behind.
+			go h.deliverBlock(compactBlock, txns, missing)
 		}
 	}()
 }
 
+// deliverBlock rebuilds the compact block and sends it to StartBlock.
+func (h *Poa) deliverBlock(cb *CompactBlock, txns SignedTxns, missing []Hash) {
+	p2pBlock, err := h.rebuildBlock(cb, txns, missing)
+	if err != nil {
+		logrus.Errorf("rebuild block(%s) error: %v", cb.Hash.String(), err)
+		return
+	}
+	h.recvChan <- p2pBlock
+}
+
 func (h *Poa) StartBlock(block *Block) {
 	now := time.Now()
 	defer func() {
@@ -156,14 +281,26 @@ func (h *Poa) StartBlock(block *Block) {
 	}()
 
 	h.setCurrentHeight(block.Height)
+	h.loadValidatorSets(block.PrevHash)
+	h.loadJailed(block.PrevHash)
 
 	log.StarConsole.Info(fmt.Sprintf("start a new block, height=%d", block.Height))
 
 	if !h.AmILeader(block.Height) {
-		if h.useP2pOrSkip(block) {
-			logrus.Infof("--------USE P2P Height(%d) block(%s) miner(%s)",
-				block.Height, block.Hash.String(), ToHex(block.MinerPubkey))
-			return
+		inRotation := indexOf(h.leadersAt(block.Height), h.LocalAddress()) >= 0
+		for {
+			if h.useP2pOrSkip(block) {
+				logrus.Infof("--------USE P2P Height(%d) block(%s) miner(%s)",
+					block.Height, block.Hash.String(), ToHex(block.MinerPubkey))
+				return
+			}
+			if h.caughtUp(block) {
+				return
+			}
+			// the nodes out of leader rotation never produce blocks, they keep waiting for the blocks from validators.
+			if inRotation {
+				break
+			}
 		}
 	}
 
@@ -180,9 +317,14 @@ func (h *Poa) StartBlock(block *Block) {
 		logrus.Panic("make txn-root failed: ", err)
 	}
 	block.TxnRoot = txnRoot
+	block.Validators = h.validatorsAt(block.Height).toHeader()
+	// the validators produce the block in turn after the leader times out.
+	block.Nonce = h.proposerRound(block.Height)
 
-	byt, _ := block.Encode()
-	block.Hash = BytesToHash(Sha256(byt))
+	block.Hash, err = signedHash(block.Header)
+	if err != nil {
+		logrus.Panic("hash block failed: ", err)
+	}
 
 	// miner signs block
 	block.MinerSignature, err = h.myPrivKey.SignData(block.Hash.Bytes())
@@ -195,9 +337,10 @@ func (h *Poa) StartBlock(block *Block) {
 
 	h.State.StartBlock(block.Hash)
 
-	blockByt, err := block.Encode()
+	// peers have most of the txns in their txpool, so only the txns hashes are published.
+	blockByt, err := block.Compact().Encode()
 	if err != nil {
-		logrus.Panic("encode raw-block failed: ", err)
+		logrus.Panic("encode compact block failed: ", err)
 	}
 
 	err = h.P2pNetwork.PubP2P(StartBlockTopic, blockByt)
@@ -206,25 +349,98 @@ func (h *Poa) StartBlock(block *Block) {
 	}
 }
 
-func (h *Poa) EndBlock(block *Block) {
-	chain := h.Chain
+// txnsInPool returns the txns of the compact block in local txpool, in the order of the block,
+// and the hashes of the missing ones.
+func (h *Poa) txnsInPool(cb *CompactBlock) (SignedTxns, []Hash, error) {
+	txns := make(SignedTxns, len(cb.TxnsHashes))
+	missing := make([]Hash, 0)
+	for i, hash := range cb.TxnsHashes {
+		stxn, err := h.Pool.GetTxn(hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if stxn == nil {
+			missing = append(missing, hash)
+			continue
+		}
+		txns[i] = stxn
+	}
+	return txns, missing, nil
+}
 
-	err := h.Execute(block)
+// rebuildBlock fills the compact block with the txns from local txpool,
+// and fetches the missing txns from the block producer.
+func (h *Poa) rebuildBlock(cb *CompactBlock, txns SignedTxns, missing []Hash) (*Block, error) {
+	if len(missing) > 0 {
+		logrus.Debugf("fetch %d missing txns of block(%s) from producer", len(missing), cb.Hash.String())
+		minerPubkey, err := PubKeyFromBytes(cb.MinerPubkey)
+		if err != nil {
+			return nil, err
+		}
+		producer := h.validatorsAt(cb.Height).peers[minerPubkey.Address()]
+		fetched, err := h.fetchTxns(producer, missing)
+		if err != nil {
+			return nil, err
+		}
+		fetchedMap := make(map[Hash]*SignedTxn)
+		for _, stxn := range fetched {
+			fetchedMap[stxn.TxnHash] = stxn
+		}
+		for i, hash := range cb.TxnsHashes {
+			if txns[i] != nil {
+				continue
+			}
+			stxn, ok := fetchedMap[hash]
+			if !ok {
+				return nil, NoTxnInP2P(hash)
+			}
+			txns[i] = stxn
+		}
+	}
+
+	txnRoot, err := MakeTxnRoot(txns)
 	if err != nil {
-		logrus.Panic("execute block failed: ", err)
+		return nil, err
 	}
+	if txnRoot != cb.TxnRoot {
+		return nil, BlockIllegal(cb.Hash)
+	}
+	return &Block{Header: cb.Header, Txns: txns}, nil
+}
 
-	// TODO: sync the state (execute receipt) with other nodes
+// fetchTxns requests the txns from the block producer, and gives up after fetchTimeout.
+func (h *Poa) fetchTxns(producer peer.ID, hashes []Hash) (SignedTxns, error) {
+	type response struct {
+		txns SignedTxns
+		err  error
+	}
+	respChan := make(chan response, 1)
+	go func() {
+		txns, err := synchronizer.RequestTxns(h.P2pNetwork, producer, producer, hashes)
+		respChan <- response{txns: txns, err: err}
+	}()
 
-	err = chain.AppendBlock(block)
-	if err != nil {
-		logrus.Panic("append block failed: ", err)
+	select {
+	case resp := <-respChan:
+		return resp.txns, resp.err
+	case <-time.After(h.fetchTimeout):
+		return nil, RequestPeerTimeout
 	}
+}
 
-	err = h.Pool.Reset(block.Txns)
+func (h *Poa) EndBlock(block *Block) {
+	err := h.Execute(block)
 	if err != nil {
-		logrus.Panic("reset pool failed: ", err)
+		logrus.Panic("execute block failed: ", err)
+	}
+
+	// TODO: sync the state (execute receipt) with other nodes
+
+	// the block is appended after it is committed, so that no block is built on an uncommitted one.
+	if h.bft != nil {
+		return
 	}
+	h.appendBlock(block)
 
 	// log.PlusLog().Info(fmt.Sprintf("append block, height=%d, hash=%s", block.Height, block.Hash.String()))
 
@@ -234,28 +450,78 @@ func (h *Poa) EndBlock(block *Block) {
 	h.State.FinalizeBlock(block.Hash)
 }
 
+func (h *Poa) appendBlock(block *Block) {
+	err := h.Chain.AppendBlock(block)
+	if err != nil {
+		logrus.Panic("append block failed: ", err)
+	}
+
+	err = h.Pool.Reset(block.Txns)
+	if err != nil {
+		logrus.Panic("reset pool failed: ", err)
+	}
+}
+
 func (h *Poa) FinalizeBlock(block *Block) {
 	//logrus.WithField("block-height", block.Height).WithField("block-hash", block.Hash.String()).
 	//	Info("finalize block")
 
+	if h.bft != nil {
+		if !h.commitBlock(block) {
+			logrus.Warnf("block(%s) height(%d) is not committed, revert to its parent", block.Hash.String(), block.Height)
+			// the txns stay in txpool for the next block at the height.
+			err := h.State.RevertTo(block.PrevHash)
+			if err != nil {
+				logrus.Panic("revert the uncommitted block failed: ", err)
+			}
+			return
+		}
+		h.appendBlock(block)
+		h.State.FinalizeBlock(block.Hash)
+	}
+
 	log.DoubleLineConsole.Info(fmt.Sprintf("finalize block, height=%d, hash=%s", block.Height, block.Hash.String()))
 	h.Chain.Finalize(block.Hash)
 }
 
+// HandleReorg reloads the validator sets and jailed validators from the reverted state.
+func (h *Poa) HandleReorg(reorg *Reorg) {
+	h.loadValidatorSets(reorg.Ancestor.Hash)
+	h.loadJailed(reorg.Ancestor.Hash)
+}
+
+// CompeteLeader rotates the leader among the validators, the jailed validators are skipped.
 func (h *Poa) CompeteLeader(blockHeight BlockNum) Address {
-	idx := (int(blockHeight) - 1) % len(h.validatorsList)
-	leader := h.validatorsList[idx]
+	leader := proposerOf(h.leadersAt(blockHeight), blockHeight, 0)
 	logrus.Debugf("compete a leader(%s) in round(%d)", leader.String(), blockHeight)
 	return leader
 }
 
+// proposerOf returns the validator producing the block at the height in the round,
+// the next one in the rotation proposes in the next round.
+func proposerOf(leaders []Address, height BlockNum, round uint64) Address {
+	idx := (uint64(height) - 1 + round) % uint64(len(leaders))
+	return leaders[idx]
+}
+
+// proposerRound returns the round in which the local node proposes the block at the height,
+// it is len(leaders) if the node is out of the rotation.
+func (h *Poa) proposerRound(height BlockNum) uint64 {
+	leaders := h.leadersAt(height)
+	nodeIdx := indexOf(leaders, h.LocalAddress())
+	if nodeIdx < 0 {
+		return uint64(len(leaders))
+	}
+	leaderIdx := (int(height) - 1) % len(leaders)
+	return uint64((nodeIdx - leaderIdx + len(leaders)) % len(leaders))
+}
+
 func (h *Poa) AmILeader(blockHeight BlockNum) bool {
 	return h.CompeteLeader(blockHeight) == h.LocalAddress()
 }
 
 func (h *Poa) IsValidator(addr Address) bool {
-	_, ok := h.validatorsMap[addr]
-	return ok
+	return h.validatorsAt(h.getCurrentHeight()).contains(addr)
 }
 
 func (h *Poa) useP2pOrSkip(localBlock *Block) bool {
@@ -265,6 +531,17 @@ LOOP:
 		if h.getCurrentHeight() > p2pBlock.Height {
 			goto LOOP
 		}
+		// the node falls behind others, it must not use or produce the block in a wrong height.
+		if p2pBlock.Height > localBlock.Height {
+			logrus.Warnf("receive block height(%d) higher than local height(%d), catch up from peers",
+				p2pBlock.Height, localBlock.Height)
+			h.Gate.WaitCatchUp()
+			return false
+		}
+		// the base lei price of local block is calculated from the parent.
+		if !h.verifyBaseLeiPrice(p2pBlock, localBlock.BaseLeiPrice) {
+			goto LOOP
+		}
 		localBlock.CopyFrom(p2pBlock)
 		h.State.StartBlock(localBlock.Hash)
 		return true
@@ -273,15 +550,18 @@ LOOP:
 	}
 }
 
-func (h *Poa) calulateWaitTime(block *Block) time.Duration {
-	height := int(block.Height)
-	shouldLeaderIdx := (height - 1) % len(h.validatorsList)
-	n := shouldLeaderIdx - h.nodeIdx
-	if n < 0 {
-		n = -n
+// caughtUp reports whether the block height has been synced from peers.
+func (h *Poa) caughtUp(block *Block) bool {
+	end, err := h.Chain.GetEndBlock()
+	if err != nil {
+		logrus.Panic("get end block failed: ", err)
 	}
+	return end.Height >= block.Height
+}
 
-	return time.Duration(h.blockInterval+n) * time.Second
+// calulateWaitTime waits one more second for every round before the local node proposes.
+func (h *Poa) calulateWaitTime(block *Block) time.Duration {
+	return time.Duration(uint64(h.blockInterval)+h.proposerRound(block.Height)) * time.Second
 }
 
 func (h *Poa) getCurrentHeight() BlockNum {
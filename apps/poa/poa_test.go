@@ -2,7 +2,9 @@ package poa
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/yu-org/yu/apps/synchronizer"
 	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/config"
 	"github.com/yu-org/yu/core/blockchain"
 	"github.com/yu-org/yu/core/env"
@@ -11,13 +13,17 @@ import (
 	"github.com/yu-org/yu/core/state"
 	"github.com/yu-org/yu/core/subscribe"
 	"github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/tripod/dev"
 	"github.com/yu-org/yu/core/txdb"
 	"github.com/yu-org/yu/core/txpool"
 	. "github.com/yu-org/yu/core/types"
 	"github.com/yu-org/yu/infra/p2p"
 	"github.com/yu-org/yu/infra/storage/kv"
+	"go.uber.org/atomic"
+	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 var (
@@ -87,7 +93,7 @@ func TestVerifyBlock(t *testing.T) {
 			LeiUsed:        0,
 			MinerPubkey:    miner.BytesWithType(),
 			MinerSignature: signer,
-			Validators:     nil,
+			Validators:     node1.validatorsAt(0).toHeader(),
 			ProofBlockHash: NullHash,
 			ProofHeight:    0,
 			Proof:          nil,
@@ -104,6 +110,72 @@ func TestVerifyBlock(t *testing.T) {
 	assert.True(t, node1.VerifyBlock(block))
 	assert.True(t, node2.VerifyBlock(block))
 	assert.True(t, node3.VerifyBlock(block))
+
+	// the header must record the active validators.
+	block.Validators = nil
+	assert.False(t, node1.VerifyBlock(block))
+}
+
+func TestVerifyProposer(t *testing.T) {
+	initGlobalVars()
+	set := node1.validatorsAt(2)
+	newBlock := func(miner *Poa, round uint64) *Block {
+		return &Block{Header: signHeader(t, miner, &Header{Height: 2, Nonce: round, Validators: set.toHeader()})}
+	}
+
+	// node2 leads height 2, node3 and node1 propose in the later rounds.
+	assert.True(t, node1.verifyHeader(newBlock(node2, 0), set, nil))
+	assert.False(t, node1.verifyHeader(newBlock(node1, 0), set, nil))
+	assert.True(t, node1.verifyHeader(newBlock(node3, 1), set, nil))
+	assert.True(t, node1.verifyHeader(newBlock(node1, 2), set, nil))
+	assert.False(t, node1.verifyHeader(newBlock(node1, 3), set, nil))
+
+	// the jailed leader is out of the rotation.
+	jailed := map[Address]BlockNum{node2.LocalAddress(): 1}
+	assert.False(t, node1.verifyHeader(newBlock(node2, 0), set, jailed))
+	assert.True(t, node1.verifyHeader(newBlock(node3, 0), set, jailed))
+	assert.True(t, node1.verifyHeader(newBlock(node1, 1), set, jailed))
+	assert.False(t, node1.verifyHeader(newBlock(node2, 1), set, jailed))
+
+	// the blocks synced must carry their commits if the bft finality is on, but the proposals have none.
+	node1.bft = newVoteBook(0, 1)
+	assert.False(t, node1.verifyHeader(newBlock(node2, 0), set, nil))
+	assert.True(t, node1.verifyProposal(newBlock(node2, 0), set, nil))
+
+	assert.Equal(t, uint64(0), node2.proposerRound(2))
+	assert.Equal(t, uint64(1), node3.proposerRound(2))
+	assert.Equal(t, uint64(2), node1.proposerRound(2))
+}
+
+func TestVerifyBaseLeiPrice(t *testing.T) {
+	initGlobalVars()
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: "./test-price.db"})
+	assert.NoError(t, err)
+	defer os.RemoveAll("./test-price.db")
+	defer os.RemoveAll("./test-price-chain.db")
+	base := txdb.NewTxDB(FullNode, kvdb)
+	chain := blockchain.NewBlockChain(FullNode, &config.BlockchainConf{
+		ChainDB:      config.SqlDbConf{SqlDbType: "sqlite", Dsn: "./test-price-chain.db"},
+		ConvergeType: "longest",
+	}, base)
+	node1.SetChainEnv(&env.ChainEnv{Chain: chain, MinLeiPrice: 10})
+
+	genesis := &Block{Header: &Header{Hash: HexToHash("0f"), LeiLimit: 100, LeiUsed: 100, BaseLeiPrice: 80}}
+	assert.NoError(t, chain.SetGenesis(genesis))
+
+	newBlock := func(price uint64) *Block {
+		header := signHeader(t, node1, &Header{
+			Height:       1,
+			PrevHash:     genesis.Hash,
+			BaseLeiPrice: price,
+			Validators:   node1.validatorsAt(1).toHeader(),
+		})
+		return &Block{Header: header}
+	}
+	// the parent is full, so the price goes up by 1/8.
+	assert.True(t, node1.VerifyBlock(newBlock(90)))
+	assert.False(t, node1.VerifyBlock(newBlock(80)))
+	assert.False(t, node1.VerifyBlock(newBlock(1000)))
 }
 
 func TestChainNet(t *testing.T) {
@@ -160,3 +232,80 @@ func runNode(poaNode *Poa, mockP2P *p2p.MockP2p, wg *sync.WaitGroup) {
 
 	wg.Done()
 }
+
+func TestRebuildCompactBlock(t *testing.T) {
+	net := p2p.NewSimNetwork(1)
+	producerNode, followerNode := net.NewNode(), net.NewNode()
+	pub1, _ := GenSrKeyWithSecret([]byte("node1"))
+	pub2, priv2 := GenSrKeyWithSecret([]byte("node2"))
+	infos := []ValidatorInfo{
+		{Pubkey: pub1, P2pID: producerNode.LocalID()},
+		{Pubkey: pub2, P2pID: followerNode.LocalID()},
+	}
+
+	txns := make(SignedTxns, 0)
+	for i := 0; i < 3; i++ {
+		stxn, err := NewSignedTxn(&WrCall{TripodName: "test", Nonce: uint64(i)}, nil, nil)
+		assert.NoError(t, err)
+		txns = append(txns, stxn)
+	}
+	txnRoot, err := MakeTxnRoot(txns)
+	assert.NoError(t, err)
+	header := &Header{Height: 1, TxnRoot: txnRoot, Hash: HexToHash("0b"), MinerPubkey: pub1.BytesWithType()}
+	byt, err := (&Block{Header: header, Txns: txns}).Compact().Encode()
+	assert.NoError(t, err)
+	cb, err := DecodeCompactBlock(byt)
+	assert.NoError(t, err)
+
+	// the producer serves the missing txns until it hangs.
+	hang := make(chan struct{})
+	defer close(hang)
+	var hanging atomic.Bool
+	producerNode.SetHandlers(map[int]dev.P2pHandler{
+		synchronizer.SyncTxnsCode: func(req []byte) ([]byte, error) {
+			if hanging.Load() {
+				<-hang
+			}
+			txnsReq, err := synchronizer.DecodeTxnsRequest(req)
+			if err != nil {
+				return nil, err
+			}
+			resp := make(SignedTxns, 0)
+			for _, stxn := range txns {
+				for _, hash := range txnsReq.Hashes {
+					if stxn.TxnHash == hash {
+						resp = append(resp, stxn)
+					}
+				}
+			}
+			return resp.Encode()
+		},
+	})
+
+	cfg := config.InitDefaultCfg()
+	follower := newPoa(pub2, priv2, infos, 3, 5000)
+	follower.SetChainEnv(&env.ChainEnv{
+		Pool:       txpool.NewTxPool(FullNode, &cfg.Txpool, nil),
+		P2pNetwork: followerNode,
+	})
+	follower.fetchTimeout = 100 * time.Millisecond
+	assert.NoError(t, follower.Pool.Insert(txns[1]))
+
+	local, missing, err := follower.txnsInPool(cb)
+	assert.NoError(t, err)
+	assert.Equal(t, []Hash{txns[0].TxnHash, txns[2].TxnHash}, missing)
+	go follower.deliverBlock(cb, local, missing)
+	select {
+	case block := <-follower.recvChan:
+		assert.Equal(t, header.Hash, block.Hash)
+		assert.Equal(t, txns.Hashes(), block.Txns.Hashes())
+	case <-time.After(time.Second):
+		t.Fatal("the rebuilt block is not delivered")
+	}
+
+	hanging.Store(true)
+	local, missing, err = follower.txnsInPool(cb)
+	assert.NoError(t, err)
+	_, err = follower.rebuildBlock(cb, local, missing)
+	assert.Equal(t, RequestPeerTimeout, err)
+}
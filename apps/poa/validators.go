@@ -0,0 +1,488 @@
+package poa
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"net/http"
+)
+
+const (
+	AddValidator    = "add"
+	RemoveValidator = "remove"
+)
+
+var (
+	validatorSetsKey        = []byte("validator-sets")
+	validatorProposalPrefix = "validator-proposal-"
+)
+
+// ValidatorProposal proposes to add or remove a validator,
+// it is applied at a future height after 2/3+ validators vote for it.
+type ValidatorProposal struct {
+	ID        Hash           `json:"id"`
+	Op        string         `json:"op"`
+	Validator *ValidatorConf `json:"validator"`
+	Votes     []Address      `json:"votes"`
+	// the height from which the change takes effect, 0 if it is not approved yet.
+	EffectiveHeight BlockNum `json:"effective_height"`
+}
+
+// ValidatorSetConf is the validators active since the height, stored in the state.
+type ValidatorSetConf struct {
+	Height     BlockNum         `json:"height"`
+	Validators []*ValidatorConf `json:"validators"`
+}
+
+// validatorSet is the parsed ValidatorSetConf.
+type validatorSet struct {
+	height BlockNum
+	infos  []ValidatorInfo
+	addrs  []Address
+	peers  map[Address]peer.ID
+}
+
+func newValidatorSet(height BlockNum, infos []ValidatorInfo) *validatorSet {
+	set := &validatorSet{
+		height: height,
+		infos:  infos,
+		addrs:  make([]Address, 0, len(infos)),
+		peers:  make(map[Address]peer.ID),
+	}
+	for _, info := range infos {
+		addr := info.Pubkey.Address()
+		set.addrs = append(set.addrs, addr)
+		set.peers[addr] = info.P2pID
+	}
+	return set
+}
+
+func (s *validatorSet) contains(addr Address) bool {
+	_, ok := s.peers[addr]
+	return ok
+}
+
+func indexOf(addrs []Address, addr Address) int {
+	for i, a := range addrs {
+		if a == addr {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *validatorSet) toHeader() []*Validator {
+	validators := make([]*Validator, 0, len(s.infos))
+	for _, info := range s.infos {
+		validators = append(validators, &Validator{
+			PubKey:        info.Pubkey.BytesWithType(),
+			ProposeWeight: 1,
+			VoteWeight:    1,
+		})
+	}
+	return validators
+}
+
+func (s *validatorSet) matches(validators []*Validator) bool {
+	if len(validators) != len(s.infos) {
+		return false
+	}
+	for i, info := range s.infos {
+		if !bytes.Equal(validators[i].PubKey, info.Pubkey.BytesWithType()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *validatorSet) toConf() *ValidatorSetConf {
+	validators := make([]*ValidatorConf, 0, len(s.infos))
+	for _, info := range s.infos {
+		validators = append(validators, &ValidatorConf{
+			Pubkey: info.Pubkey.StringWithType(),
+			P2pIp:  info.P2pID.String(),
+		})
+	}
+	return &ValidatorSetConf{Height: s.height, Validators: validators}
+}
+
+// validatorsAt returns the validators active at the height, from the sets loaded at the last block boundary.
+func (h *Poa) validatorsAt(height BlockNum) *validatorSet {
+	h.setsLock.RLock()
+	defer h.setsLock.RUnlock()
+	return selectValidators(h.sets, height)
+}
+
+// selectValidators returns the validators active at the height from the sets ordered by their active heights.
+func selectValidators(sets []*validatorSet, height BlockNum) *validatorSet {
+	for i := len(sets) - 1; i > 0; i-- {
+		if sets[i].height <= height {
+			return sets[i]
+		}
+	}
+	return sets[0]
+}
+
+// ValidatorsAt returns the validators active at the height.
+func (h *Poa) ValidatorsAt(height BlockNum) *ValidatorSetConf {
+	return h.validatorsAt(height).toConf()
+}
+
+// loadValidatorSets reloads the validator sets from the state committed by the block,
+// it is called only at block boundaries, so the changes of the executing block never leak into them.
+func (h *Poa) loadValidatorSets(blockHash Hash) {
+	sets, err := h.committedValidatorSets(blockHash)
+	if err != nil {
+		logrus.Error("load validator sets from state error: ", err)
+		return
+	}
+	h.setsLock.Lock()
+	h.sets = sets
+	h.setsLock.Unlock()
+}
+
+// committedValidatorSets returns the validator sets in the state committed by the block.
+func (h *Poa) committedValidatorSets(blockHash Hash) ([]*validatorSet, error) {
+	if h.ChainEnv == nil || h.State == nil {
+		return h.withConfigSet(nil)
+	}
+	byt, err := h.GetByBlockHash(validatorSetsKey, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	confs, err := decodeValidatorSets(byt)
+	if err != nil {
+		return nil, err
+	}
+	return h.withConfigSet(confs)
+}
+
+// validatorsInState returns the validators active at the height from the state being executed,
+// the writings use them instead of the sets loaded at block boundaries.
+func (h *Poa) validatorsInState(st TripodKV, height BlockNum) (*validatorSet, error) {
+	confs, err := h.getValidatorSets(st)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := h.withConfigSet(confs)
+	if err != nil {
+		return nil, err
+	}
+	return selectValidators(sets, height), nil
+}
+
+// withConfigSet parses the validator sets changed on chain after the one from config.
+func (h *Poa) withConfigSet(confs []*ValidatorSetConf) ([]*validatorSet, error) {
+	parsed, err := parseValidatorSets(confs)
+	if err != nil {
+		return nil, err
+	}
+	h.setsLock.RLock()
+	sets := []*validatorSet{h.sets[0]}
+	h.setsLock.RUnlock()
+	return append(sets, parsed...), nil
+}
+
+func parseValidatorSets(confs []*ValidatorSetConf) ([]*validatorSet, error) {
+	sets := make([]*validatorSet, 0, len(confs))
+	for _, conf := range confs {
+		infos, err := resolveValidators(conf.Validators)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, newValidatorSet(conf.Height, infos))
+	}
+	return sets, nil
+}
+
+func (h *Poa) getValidatorSets(st TripodKV) ([]*ValidatorSetConf, error) {
+	if h.ChainEnv == nil || h.State == nil {
+		return nil, nil
+	}
+	byt, err := st.Get(validatorSetsKey)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValidatorSets(byt)
+}
+
+func decodeValidatorSets(byt []byte) ([]*ValidatorSetConf, error) {
+	if byt == nil {
+		return nil, nil
+	}
+	var confs []*ValidatorSetConf
+	err := json.Unmarshal(byt, &confs)
+	return confs, err
+}
+
+func setValidatorSets(st TripodKV, confs []*ValidatorSetConf) error {
+	byt, err := json.Marshal(confs)
+	if err != nil {
+		return err
+	}
+	st.Set(validatorSetsKey, byt)
+	return nil
+}
+
+// effectiveHeight returns the first epoch boundary at least 2 blocks later,
+// so that the proposer of next block has known the change.
+func (h *Poa) effectiveHeight(height BlockNum) BlockNum {
+	effective := height + 2
+	if h.epochLength > 0 && effective%h.epochLength != 0 {
+		effective = (effective/h.epochLength + 1) * h.epochLength
+	}
+	return effective
+}
+
+// callerValidator returns the address of caller if it is an active validator.
+func (h *Poa) callerValidator(ctx *WriteContext) (Address, error) {
+	pubkey, err := PubKeyFromBytes(ctx.Txn.Pubkey)
+	if err != nil {
+		return NullAddress, err
+	}
+	addr := pubkey.Address()
+	active, err := h.validatorsInState(h.TxnState(ctx), ctx.Block.Height)
+	if err != nil {
+		return NullAddress, err
+	}
+	if !active.contains(addr) {
+		return NullAddress, NoPermission
+	}
+	return addr, nil
+}
+
+type ValidatorProposalRequest struct {
+	Op     string `json:"op"`
+	Pubkey string `json:"pubkey"`
+	P2pID  string `json:"p2p_id"`
+}
+
+// ProposeValidator proposes to add or remove a validator, the proposer votes for it at the same time.
+func (h *Poa) ProposeValidator(ctx *WriteContext) error {
+	proposer, err := h.callerValidator(ctx)
+	if err != nil {
+		return err
+	}
+	var req ValidatorProposalRequest
+	err = ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	validator := &ValidatorConf{Pubkey: req.Pubkey, P2pIp: req.P2pID}
+	_, err = resolveValidators([]*ValidatorConf{validator})
+	if err != nil {
+		return err
+	}
+	if req.Op != AddValidator && req.Op != RemoveValidator {
+		return ValidatorOpIllegal(req.Op)
+	}
+
+	proposal := &ValidatorProposal{
+		ID:        ctx.GetTxnHash(),
+		Op:        req.Op,
+		Validator: validator,
+	}
+	// check the proposal could be applied on the latest validators.
+	_, err = h.applyProposal(h.TxnState(ctx), proposal)
+	if err != nil {
+		return err
+	}
+	err = h.voteProposal(ctx, proposal, proposer)
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(proposal)
+}
+
+type ValidatorVoteRequest struct {
+	ID string `json:"id"`
+}
+
+// VoteValidator votes for a validator proposal.
+func (h *Poa) VoteValidator(ctx *WriteContext) error {
+	voter, err := h.callerValidator(ctx)
+	if err != nil {
+		return err
+	}
+	var req ValidatorVoteRequest
+	err = ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	proposal, err := getProposal(h.TxnState(ctx), HexToHash(req.ID))
+	if err != nil {
+		return err
+	}
+	if proposal.EffectiveHeight > 0 {
+		return ProposalApproved
+	}
+	for _, addr := range proposal.Votes {
+		if addr == voter {
+			return AlreadyVoted
+		}
+	}
+	err = h.voteProposal(ctx, proposal, voter)
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(proposal)
+}
+
+// voteProposal adds the vote into proposal, and schedules the change once 2/3+ active validators vote for it.
+func (h *Poa) voteProposal(ctx *WriteContext, proposal *ValidatorProposal, voter Address) error {
+	proposal.Votes = append(proposal.Votes, voter)
+
+	st := h.TxnState(ctx)
+	active, err := h.validatorsInState(st, ctx.Block.Height)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, addr := range proposal.Votes {
+		if active.contains(addr) {
+			count++
+		}
+	}
+	if hasQuorum(count, len(active.addrs)) {
+		validators, err := h.applyProposal(st, proposal)
+		if err != nil {
+			return err
+		}
+		proposal.EffectiveHeight = h.effectiveHeight(ctx.Block.Height)
+		err = h.scheduleValidators(st, proposal.EffectiveHeight, validators)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("validator proposal(%s) approved, %s validator(%s) from height(%d)",
+			proposal.ID.String(), proposal.Op, proposal.Validator.Pubkey, proposal.EffectiveHeight)
+	}
+	return setProposal(st, proposal)
+}
+
+// applyProposal returns the validators after applying the proposal on the latest scheduled validators in the state.
+func (h *Poa) applyProposal(st TripodKV, proposal *ValidatorProposal) ([]*ValidatorConf, error) {
+	confs, err := h.getValidatorSets(st)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := h.withConfigSet(confs)
+	if err != nil {
+		return nil, err
+	}
+	latest := sets[len(sets)-1].toConf().Validators
+
+	idx := -1
+	for i, validator := range latest {
+		if validator.Pubkey == proposal.Validator.Pubkey {
+			idx = i
+			break
+		}
+	}
+	switch proposal.Op {
+	case AddValidator:
+		if idx >= 0 {
+			return nil, ValidatorExists
+		}
+		return append(latest, proposal.Validator), nil
+	case RemoveValidator:
+		if idx < 0 {
+			return nil, ValidatorNotFound
+		}
+		if len(latest) == 1 {
+			return nil, LastValidator
+		}
+		return append(latest[:idx], latest[idx+1:]...), nil
+	default:
+		return nil, ValidatorOpIllegal(proposal.Op)
+	}
+}
+
+// ScheduleValidators makes the validators active from the height.
+func (h *Poa) ScheduleValidators(height BlockNum, validators []*ValidatorConf) error {
+	_, err := resolveValidators(validators)
+	if err != nil {
+		return err
+	}
+	return h.scheduleValidators(h, height, validators)
+}
+
+func (h *Poa) scheduleValidators(st TripodKV, height BlockNum, validators []*ValidatorConf) error {
+	confs, err := h.getValidatorSets(st)
+	if err != nil {
+		return err
+	}
+	if len(confs) > 0 && confs[len(confs)-1].Height == height {
+		confs[len(confs)-1].Validators = validators
+	} else {
+		confs = append(confs, &ValidatorSetConf{Height: height, Validators: validators})
+	}
+	return setValidatorSets(st, confs)
+}
+
+func getProposal(st TripodKV, id Hash) (*ValidatorProposal, error) {
+	byt, err := st.Get([]byte(validatorProposalPrefix + id.String()))
+	if err != nil {
+		return nil, err
+	}
+	if byt == nil {
+		return nil, ProposalNotFound
+	}
+	proposal := new(ValidatorProposal)
+	err = json.Unmarshal(byt, proposal)
+	return proposal, err
+}
+
+func setProposal(st TripodKV, proposal *ValidatorProposal) error {
+	byt, err := json.Marshal(proposal)
+	if err != nil {
+		return err
+	}
+	st.Set([]byte(validatorProposalPrefix+proposal.ID.String()), byt)
+	return nil
+}
+
+type ValidatorsRequest struct {
+	// 0 means the current height
+	Height BlockNum `json:"height"`
+}
+
+// GetValidators returns the validators active at the height.
+func (h *Poa) GetValidators(ctx *ReadContext) {
+	var req ValidatorsRequest
+	err := ctx.BindJson(&req)
+	if err != nil {
+		ctx.Err(http.StatusBadRequest, err)
+		return
+	}
+	height := req.Height
+	if height == 0 {
+		height = h.getCurrentHeight()
+	}
+	ctx.JsonOk(h.validatorsAt(height).toConf())
+}
+
+type ValidatorProposalQuery struct {
+	ID string `json:"id"`
+}
+
+func (h *Poa) GetValidatorProposal(ctx *ReadContext) {
+	var req ValidatorProposalQuery
+	err := ctx.BindJson(&req)
+	if err != nil {
+		ctx.Err(http.StatusBadRequest, err)
+		return
+	}
+	proposal, err := getProposal(h, HexToHash(req.ID))
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	ctx.JsonOk(proposal)
+}
@@ -0,0 +1,70 @@
+package poa
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/env"
+	. "github.com/yu-org/yu/core/keypair"
+	"github.com/yu-org/yu/core/state"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"path/filepath"
+	"testing"
+)
+
+func TestEffectiveHeight(t *testing.T) {
+	initGlobalVars()
+
+	assert.Equal(t, BlockNum(7), node1.effectiveHeight(5))
+	node1.epochLength = 10
+	assert.Equal(t, BlockNum(10), node1.effectiveHeight(5))
+	assert.Equal(t, BlockNum(10), node1.effectiveHeight(8))
+	assert.Equal(t, BlockNum(20), node1.effectiveHeight(9))
+}
+
+func TestValidatorSets(t *testing.T) {
+	myPubkey, myPrivkey, infos := InitDefaultKeypairs(0)
+	for i := range infos {
+		infos[i].P2pID = ""
+	}
+	node := newPoa(myPubkey, myPrivkey, infos, 3, 5000)
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(t.TempDir(), "state.db")})
+	assert.NoError(t, err)
+	node.SetChainEnv(&env.ChainEnv{State: state.NewSpmtKV(kvdb)})
+	node.SetInstance(node)
+
+	newPub, _ := GenSrKeyWithSecret([]byte("node4"))
+	add := &ValidatorProposal{
+		Op:        AddValidator,
+		Validator: &ValidatorConf{Pubkey: newPub.StringWithType()},
+	}
+	validators, err := node.applyProposal(node, add)
+	assert.NoError(t, err)
+	assert.Len(t, validators, 4)
+
+	block := HexToHash("0a")
+	node.State.StartBlock(block)
+	assert.NoError(t, node.scheduleValidators(node, 10, validators))
+	// the changes of the executing block never leak into the loaded sets.
+	assert.Len(t, node.validatorsAt(10).addrs, 3)
+	_, err = node.State.Commit()
+	assert.NoError(t, err)
+	node.loadValidatorSets(block)
+
+	assert.Len(t, node.validatorsAt(9).addrs, 3)
+	assert.Len(t, node.validatorsAt(10).addrs, 4)
+	assert.True(t, node.validatorsAt(10).contains(newPub.Address()))
+	assert.False(t, node.validatorsAt(9).matches(node.validatorsAt(10).toHeader()))
+
+	_, err = node.applyProposal(node, add)
+	assert.Equal(t, ValidatorExists, err)
+
+	remove := &ValidatorProposal{
+		Op:        RemoveValidator,
+		Validator: &ValidatorConf{Pubkey: infos[1].Pubkey.StringWithType()},
+	}
+	validators, err = node.applyProposal(node, remove)
+	assert.NoError(t, err)
+	assert.Len(t, validators, 3)
+}
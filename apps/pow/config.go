@@ -0,0 +1,44 @@
+package pow
+
+import (
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/keypair"
+	"runtime"
+)
+
+type PowConfig struct {
+	KeyType string `toml:"key_type"`
+	// secret for generating keypair.
+	MySecret string `toml:"my_secret"`
+	// expected block out interval, seconds
+	BlockInterval int `toml:"block_interval"`
+	// the number of packing txns from txpool, default 5000
+	PackNum uint64 `toml:"pack_num"`
+	// difficulty of the first block, the expected number of hashes for mining it.
+	InitDifficulty uint64 `toml:"init_difficulty"`
+	// the difficulty is retargeted by the timestamps of the recent RetargetWindow blocks.
+	RetargetWindow BlockNum `toml:"retarget_window"`
+	// the number of mining goroutines, default is the number of CPUs.
+	MineThreads int `toml:"mine_threads"`
+	// a block is finalized after Confirmations blocks are appended after it.
+	Confirmations BlockNum `toml:"confirmations"`
+	// the timestamp of a block must be later than the median timestamp of the recent MedianTimeBlocks blocks.
+	MedianTimeBlocks BlockNum `toml:"median_time_blocks"`
+	// the timestamp of a block must not be later than local time plus MaxTimeDrift, seconds.
+	MaxTimeDrift uint64 `toml:"max_time_drift"`
+}
+
+func DefaultCfg(mySecret string) *PowConfig {
+	return &PowConfig{
+		KeyType:          Sr25519,
+		MySecret:         mySecret,
+		BlockInterval:    5,
+		PackNum:          5000,
+		InitDifficulty:   1 << 20,
+		RetargetWindow:   10,
+		MineThreads:      runtime.NumCPU(),
+		Confirmations:    6,
+		MedianTimeBlocks: 11,
+		MaxTimeDrift:     15,
+	}
+}
@@ -1,235 +1,420 @@
 package pow
 
-//
-//import (
-//	"github.com/sirupsen/logrus"
-//	. "github.com/yu-org/yu/common"
-//	spow "github.com/yu-org/yu/consensus/pow"
-//	. "github.com/yu-org/yu/core/chain_env"
-//	. "github.com/yu-org/yu/core/keypair"
-//	. "github.com/yu-org/yu/core/tripod"
-//	"github.com/yu-org/yu/core/types"
-//	"math/big"
-//	"time"
-//)
-//
-//type Pow struct {
-//	meta       *Tripod
-//	target     *big.Int
-//	targetBits int64
-//
-//	myPrivKey PrivKey
-//	myPubkey  PubKey
-//
-//	env *ChainEnv
-//
-//	packLimit uint64
-//	blockTick *time.Ticker
-//	p2pTick   *time.Ticker
-//	msgChan   chan []byte
-//}
-//
-//func NewPow(packLimit uint64) *Pow {
-//	meta := NewTripod("pow")
-//	var targetBits int64 = 16
-//	target := big.NewInt(1)
-//	target.Lsh(target, uint(256-targetBits))
-//
-//	pubkey, privkey, err := GenKeyPair(Sr25519)
-//	if err != nil {
-//		logrus.Fatalf("generate my keypair error: %s", err.Error())
-//	}
-//
-//	return &Pow{
-//		meta:       meta,
-//		target:     target,
-//		targetBits: targetBits,
-//		myPrivKey:  privkey,
-//		myPubkey:   pubkey,
-//
-//		packLimit: packLimit,
-//		blockTick: time.NewTicker(time.Second * 2),
-//		p2pTick:   time.NewTicker(time.Second),
-//		msgChan:   make(chan []byte, 100),
-//	}
-//}
-//
-//func (p *Pow) GetTripodHeader() *Tripod {
-//	return p.meta
-//}
-//
-//func (p *Pow) Name() string {
-//	return p.meta.Name()
-//}
-//
-//func (p *Pow) SetChainEnv(env *ChainEnv) {
-//	p.env = env
-//}
-//
-//func (*Pow) CheckTxn(*types.SignedTxn) error {
-//	return nil
-//}
-//
-//func (p *Pow) VerifyBlock(block *types.CompactBlock) bool {
-//	return spow.Validate(block, p.target, p.targetBits)
-//}
-//
-//func (p *Pow) InitChain() error {
-//	chain := p.env.Chain
-//	gensisBlock := &types.CompactBlock{
-//		Header: &types.Header{},
-//	}
-//	err := chain.SetGenesis(gensisBlock)
-//	if err != nil {
-//		return err
-//	}
-//	go func() {
-//		for {
-//			msg, err := p.env.P2pNetwork.SubP2P(StartBlockTopic)
-//			if err != nil {
-//				logrus.Error("subscribe message from P2P error: ", err)
-//				continue
-//			}
-//			p.msgChan <- msg
-//		}
-//
-//	}()
-//	return nil
-//}
-//
-//func (p *Pow) StartBlock(block *types.CompactBlock) error {
-//	time.Sleep(2 * time.Second)
-//
-//	pool := p.env.Pool
-//
-//	logrus.Info("start block...................")
-//
-//	logrus.Infof("prev-block hash is (%s), height is (%d)", block.PrevHash.String(), block.Height-1)
-//
-//	if p.UseBlocksFromP2P(block) {
-//		logrus.Infof("--------USE P2P block(%s)", block.Hash.String())
-//		return nil
-//	}
-//
-//	txns, err := pool.Pack(p.packLimit)
-//	if err != nil {
-//		return err
-//	}
-//
-//	hashes := types.FromArray(txns...).Hashes()
-//	block.TxnsHashes = hashes
-//
-//	txnRoot, err := types.MakeTxnRoot(txns)
-//	if err != nil {
-//		return err
-//	}
-//	block.TxnRoot = txnRoot
-//
-//	nonce, hash, err := spow.Run(block, p.target, p.targetBits)
-//	if err != nil {
-//		return err
-//	}
-//
-//	block.Nonce = uint64(nonce)
-//	block.Hash = hash
-//
-//	p.env.State.StartBlock(hash)
-//
-//	rawBlock := &types.Block{
-//		CompactBlock: block,
-//		Txns:         txns,
-//	}
-//
-//	rawBlockByt, err := rawBlock.Encode()
-//	if err != nil {
-//		return err
-//	}
-//
-//	return p.env.P2pNetwork.PubP2P(StartBlockTopic, rawBlockByt)
-//}
-//
-//func (p *Pow) EndBlock(block *types.CompactBlock) error {
-//	chain := p.env.Chain
-//	pool := p.env.Pool
-//
-//	err := p.env.Execute(block)
-//	if err != nil {
-//		return err
-//	}
-//
-//	err = chain.AppendCompactBlock(block)
-//	if err != nil {
-//		return err
-//	}
-//
-//	err = pool.Reset(block)
-//	if err != nil {
-//		return err
-//	}
-//
-//	logrus.Infof("append block(%d) (%s)", block.Height, block.Hash.String())
-//
-//	p.env.State.FinalizeBlock(block.Hash)
-//
-//	return nil
-//}
-//
-//func (*Pow) FinalizeBlock(_ *types.CompactBlock) error {
-//	return nil
-//}
-//
-//// return TRUE if we use the p2p-block
-//func (p *Pow) UseBlocksFromP2P(block *types.CompactBlock) bool {
-//	msgCount := len(p.msgChan)
-//	if msgCount > 0 {
-//		for i := 0; i < msgCount; i++ {
-//			msg := <-p.msgChan
-//			if p.useP2pBlock(msg, block) {
-//				return true
-//			}
-//		}
-//	}
-//	return false
-//}
-//
-//func (p *Pow) useP2pBlock(msg []byte, block *types.CompactBlock) bool {
-//
-//	p2pRawBlock, err := types.DecodeBlock(msg)
-//	if err != nil {
-//		logrus.Error("decode p2p-raw-block error: ", err)
-//		return false
-//	}
-//
-//	p2pBlock := p2pRawBlock.CompactBlock
-//
-//	if p2pBlock.PeerID == block.PeerID {
-//		logrus.Infof("Accept [LOCAL-P2P] block(%s) height(%d)", p2pBlock.Hash.String(), p2pBlock.Height)
-//		return false
-//	}
-//
-//	logrus.Infof("Accept [P2P] block(%s) height(%d)", p2pBlock.Hash.String(), p2pBlock.Height)
-//
-//	if p2pBlock.Height == block.Height {
-//		if !p.VerifyBlock(p2pBlock) {
-//			logrus.Error("verify p2p-block error: ", err)
-//			return false
-//		}
-//
-//		block.CopyFrom(p2pBlock)
-//		stxns := p2pRawBlock.Txns
-//		err = p.env.TxDB.SetTxns(block.Hash, stxns)
-//		if err != nil {
-//			logrus.Error("set txns of p2p-block into base error: ", err)
-//			return false
-//		}
-//		p.env.State.StartBlock(block.Hash)
-//		err = p.env.Pool.Reset(block)
-//		if err != nil {
-//			logrus.Error("clear txpool error: ", err)
-//			return false
-//		}
-//		return true
-//	}
-//
-//	return false
-//}
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	spow "github.com/yu-org/yu/consensus/pow"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/utils/log"
+	ytime "github.com/yu-org/yu/utils/time"
+	"sort"
+	"sync"
+)
+
+// the max number of fork blocks from p2p waiting for being appended.
+const maxForkBlocks = 64
+
+type Pow struct {
+	*Tripod
+
+	myPubkey  PubKey
+	myPrivKey PrivKey
+
+	blockInterval  int
+	packNum        uint64
+	initDifficulty uint64
+	retargetWindow BlockNum
+	mineThreads    int
+	confirmations  BlockNum
+	medianBlocks   BlockNum
+	maxTimeDrift   uint64
+
+	recvChan chan *Block
+	// the blocks from p2p not on top of local end block, appended after the local block.
+	forkBlocks []*Block
+	forkLock   sync.Mutex
+}
+
+func NewPow(cfg *PowConfig) *Pow {
+	pub, priv, err := GenKeyPairWithSecret(cfg.KeyType, []byte(cfg.MySecret))
+	if err != nil {
+		logrus.Fatal("generate pow keypair error: ", err)
+	}
+	tri := NewTripod()
+	p := &Pow{
+		Tripod:         tri,
+		myPubkey:       pub,
+		myPrivKey:      priv,
+		blockInterval:  cfg.BlockInterval,
+		packNum:        cfg.PackNum,
+		initDifficulty: cfg.InitDifficulty,
+		retargetWindow: cfg.RetargetWindow,
+		mineThreads:    cfg.MineThreads,
+		confirmations:  cfg.Confirmations,
+		medianBlocks:   cfg.MedianTimeBlocks,
+		maxTimeDrift:   cfg.MaxTimeDrift,
+		recvChan:       make(chan *Block, 10),
+	}
+	p.SetInit(p)
+	p.SetBlockCycle(p)
+	p.SetBlockVerifier(p)
+	return p
+}
+
+func (p *Pow) LocalAddress() Address {
+	return p.myPubkey.Address()
+}
+
+func (p *Pow) VerifyBlock(block *Block) bool {
+	parent, err := p.Chain.GetBlock(block.PrevHash)
+	if err != nil {
+		logrus.Warnf("get parent of block(%s) error: %v", block.Hash.String(), err)
+		return false
+	}
+	if block.Height != parent.Height+1 {
+		return false
+	}
+	if !p.verifyTimestamp(block, parent) {
+		return false
+	}
+	difficulty, err := p.nextDifficulty(parent)
+	if err != nil {
+		logrus.Errorf("retarget difficulty after block(%s) error: %v", parent.Hash.String(), err)
+		return false
+	}
+	if block.Difficulty != difficulty {
+		logrus.Warnf("difficulty of block(%s) should be %d, not %d", block.Hash.String(), difficulty, block.Difficulty)
+		return false
+	}
+	seal, err := sealHash(block.Header)
+	if err != nil {
+		return false
+	}
+	if !spow.Validate(seal, block.Nonce, block.Hash, block.Difficulty) {
+		logrus.Warnf("illegal pow of block(%s)", block.Hash.String())
+		return false
+	}
+	minerPubkey, err := PubKeyFromBytes(block.MinerPubkey)
+	if err != nil {
+		return false
+	}
+	return minerPubkey.VerifySignature(block.Hash.Bytes(), block.MinerSignature)
+}
+
+func (p *Pow) InitChain() {
+	if p.Chain.ConvergeType() != Heaviest {
+		logrus.Fatal(`pow needs the heaviest converge type, set "converge_type = heaviest" in [block_chain]`)
+	}
+	go func() {
+		for {
+			msg, err := p.P2pNetwork.SubP2P(StartBlockTopic)
+			if err != nil {
+				logrus.Error("subscribe message from P2P error: ", err)
+				continue
+			}
+			p2pBlock, err := DecodeBlock(msg)
+			if err != nil {
+				logrus.Error("decode block from p2p error: ", err)
+				continue
+			}
+			if p2pBlock.PeerID == p.P2pNetwork.LocalID() {
+				continue
+			}
+			logrus.Debugf("accept block(%s), height(%d) from p2p", p2pBlock.Hash.String(), p2pBlock.Height)
+			// blocks are verified when they are used, because their parents may be not appended yet.
+			p.recvChan <- p2pBlock
+		}
+	}()
+}
+
+func (p *Pow) StartBlock(block *Block) {
+	log.StarConsole.Info(fmt.Sprintf("start a new block, height=%d", block.Height))
+
+	if fork := p.takeForkBlock(block); fork != nil && p.VerifyBlock(fork) {
+		p.useP2pBlock(block, fork)
+		return
+	}
+
+	parent, err := p.Chain.GetBlock(block.PrevHash)
+	if err != nil {
+		logrus.Panic("get parent block failed: ", err)
+	}
+	block.Difficulty, err = p.nextDifficulty(parent)
+	if err != nil {
+		logrus.Panic("retarget difficulty failed: ", err)
+	}
+	median, err := p.medianTime(parent)
+	if err != nil {
+		logrus.Panic("get median time failed: ", err)
+	}
+	if block.Timestamp <= median {
+		block.Timestamp = median + 1
+	}
+
+	txns, err := p.Pool.Pack(p.packNum)
+	if err != nil {
+		logrus.Panic("pack txns from pool: ", err)
+	}
+	block.TxnRoot, err = MakeTxnRoot(txns)
+	if err != nil {
+		logrus.Panic("make txn-root failed: ", err)
+	}
+	block.MinerPubkey = p.myPubkey.BytesWithType()
+
+	seal, err := sealHash(block.Header)
+	if err != nil {
+		logrus.Panic("seal block failed: ", err)
+	}
+
+	// mining is cancelled once a valid block at the same height arrives from p2p.
+	ctx, cancel := context.WithCancel(context.Background())
+	p2pBlockChan := make(chan *Block, 1)
+	go p.watchP2pBlocks(ctx, cancel, block, p2pBlockChan)
+	nonce, hash, err := spow.Run(ctx, seal, block.Difficulty, p.mineThreads)
+	cancel()
+
+	if err != nil {
+		p.useP2pBlock(block, <-p2pBlockChan)
+		return
+	}
+	select {
+	case p2pBlock := <-p2pBlockChan:
+		// the block arrived when local node found the nonce, it competes with the local block as a fork.
+		p.addForkBlock(p2pBlock)
+	default:
+	}
+
+	block.Nonce = nonce
+	block.Hash = hash
+	block.MinerSignature, err = p.myPrivKey.SignData(block.Hash.Bytes())
+	if err != nil {
+		logrus.Panic("sign block failed: ", err)
+	}
+	block.SetTxns(txns)
+
+	p.State.StartBlock(block.Hash)
+
+	blockByt, err := block.Encode()
+	if err != nil {
+		logrus.Panic("encode block failed: ", err)
+	}
+	err = p.P2pNetwork.PubP2P(StartBlockTopic, blockByt)
+	if err != nil {
+		logrus.Panic("publish block to p2p failed: ", err)
+	}
+}
+
+func (p *Pow) useP2pBlock(block, p2pBlock *Block) {
+	block.CopyFrom(p2pBlock)
+	p.State.StartBlock(block.Hash)
+	logrus.Infof("--------USE P2P Height(%d) block(%s)", block.Height, block.Hash.String())
+}
+
+// watchP2pBlocks sends the valid p2p block on top of the same parent into found and cancels mining,
+// the other blocks are kept as forks.
+func (p *Pow) watchP2pBlocks(ctx context.Context, cancel context.CancelFunc, block *Block, found chan *Block) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p2pBlock := <-p.recvChan:
+			if p2pBlock.Height == block.Height && p2pBlock.PrevHash == block.PrevHash && p.VerifyBlock(p2pBlock) {
+				found <- p2pBlock
+				cancel()
+				return
+			}
+			p.addForkBlock(p2pBlock)
+		}
+	}
+}
+
+func (p *Pow) EndBlock(block *Block) {
+	err := p.Execute(block)
+	if err != nil {
+		logrus.Panic("execute block failed: ", err)
+	}
+
+	err = p.Chain.AppendBlock(block)
+	if err != nil {
+		logrus.Panic("append block failed: ", err)
+	}
+
+	err = p.Pool.Reset(block.Txns)
+	if err != nil {
+		logrus.Panic("reset pool failed: ", err)
+	}
+
+	// the heavier fork reorgs the chain when it is appended.
+	p.appendForkBlocks()
+}
+
+// FinalizeBlock finalizes the canonical block which has enough confirmations.
+func (p *Pow) FinalizeBlock(*Block) {
+	end, err := p.Chain.GetEndBlock()
+	if err != nil {
+		logrus.Error("get end block error: ", err)
+		return
+	}
+	if end.Height <= p.confirmations {
+		return
+	}
+	height := end.Height - p.confirmations
+	finalized, err := p.Chain.LastFinalized()
+	if err == nil && finalized.Height >= height {
+		return
+	}
+	confirmed, err := p.Chain.GetBlockByHeight(height)
+	if err != nil {
+		logrus.Error("get confirmed block error: ", err)
+		return
+	}
+	p.State.FinalizeBlock(confirmed.Hash)
+	err = p.Chain.Finalize(confirmed.Hash)
+	if err != nil {
+		logrus.Error("finalize block error: ", err)
+		return
+	}
+	log.DoubleLineConsole.Info(fmt.Sprintf("finalize block, height=%d, hash=%s", confirmed.Height, confirmed.Hash.String()))
+}
+
+func (p *Pow) addForkBlock(block *Block) {
+	p.forkLock.Lock()
+	defer p.forkLock.Unlock()
+	if len(p.forkBlocks) >= maxForkBlocks {
+		logrus.Warnf("too many fork blocks, drop block(%s)", block.Hash.String())
+		return
+	}
+	p.forkBlocks = append(p.forkBlocks, block)
+}
+
+// takeForkBlock takes out the fork block on top of the same parent as the local block.
+func (p *Pow) takeForkBlock(block *Block) *Block {
+	p.forkLock.Lock()
+	defer p.forkLock.Unlock()
+	for i, fork := range p.forkBlocks {
+		if fork.Height == block.Height && fork.PrevHash == block.PrevHash {
+			p.forkBlocks = append(p.forkBlocks[:i], p.forkBlocks[i+1:]...)
+			return fork
+		}
+	}
+	return nil
+}
+
+// appendForkBlocks appends the fork blocks from lower heights, so that their parents are appended first.
+// The blocks on top of the end block are kept for the next round, because they must be executed.
+func (p *Pow) appendForkBlocks() {
+	p.forkLock.Lock()
+	blocks := p.forkBlocks
+	p.forkBlocks = nil
+	p.forkLock.Unlock()
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Height < blocks[j].Height
+	})
+	for _, block := range blocks {
+		if exist, _ := p.Chain.ExistsBlock(block.Hash); exist {
+			continue
+		}
+		end, err := p.Chain.GetEndBlock()
+		if err != nil {
+			logrus.Error("get end block error: ", err)
+			return
+		}
+		if block.PrevHash == end.Hash {
+			p.addForkBlock(block)
+			continue
+		}
+		if !p.VerifyBlock(block) {
+			logrus.Warnf("fork block(%s) height(%d) verify failed", block.Hash.String(), block.Height)
+			continue
+		}
+		err = p.Chain.AppendBlock(block)
+		if err != nil {
+			logrus.Errorf("append fork block(%s) error: %v", block.Hash.String(), err)
+		}
+	}
+}
+
+// verifyTimestamp checks the timestamp of block is later than the median timestamp of the recent blocks,
+// and not too far in the future, so that the miners cannot lower the difficulty by faking timestamps.
+func (p *Pow) verifyTimestamp(block *Block, parent *CompactBlock) bool {
+	median, err := p.medianTime(parent)
+	if err != nil {
+		logrus.Errorf("get median time before block(%s) error: %v", block.Hash.String(), err)
+		return false
+	}
+	if block.Timestamp <= median {
+		logrus.Warnf("timestamp(%d) of block(%s) is not later than median time(%d)", block.Timestamp, block.Hash.String(), median)
+		return false
+	}
+	if block.Timestamp > ytime.NowTsU64()+p.maxTimeDrift {
+		logrus.Warnf("timestamp(%d) of block(%s) is too far in the future", block.Timestamp, block.Hash.String())
+		return false
+	}
+	return true
+}
+
+// medianTime returns the median timestamp of the recent medianBlocks blocks until parent.
+func (p *Pow) medianTime(parent *CompactBlock) (uint64, error) {
+	timestamps := []uint64{parent.Timestamp}
+	ancestor := parent
+	for BlockNum(len(timestamps)) < p.medianBlocks && ancestor.Height > 0 {
+		var err error
+		ancestor, err = p.Chain.GetBlock(ancestor.PrevHash)
+		if err != nil {
+			return 0, err
+		}
+		timestamps = append(timestamps, ancestor.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2], nil
+}
+
+// nextDifficulty retargets the difficulty of the block after parent,
+// by the timestamps of the recent retargetWindow blocks.
+func (p *Pow) nextDifficulty(parent *CompactBlock) (uint64, error) {
+	if parent.Difficulty == 0 {
+		// parent is the genesis block.
+		return p.initDifficulty, nil
+	}
+	if parent.Height <= p.retargetWindow {
+		return parent.Difficulty, nil
+	}
+	ancestor := parent
+	for i := BlockNum(0); i < p.retargetWindow; i++ {
+		var err error
+		ancestor, err = p.Chain.GetBlock(ancestor.PrevHash)
+		if err != nil {
+			return 0, err
+		}
+	}
+	var actualSpan uint64
+	if parent.Timestamp > ancestor.Timestamp {
+		actualSpan = parent.Timestamp - ancestor.Timestamp
+	}
+	expectedSpan := uint64(p.retargetWindow) * uint64(p.blockInterval)
+	return spow.Retarget(parent.Difficulty, actualSpan, expectedSpan), nil
+}
+
+// sealHash is the hash of the header fields fixed before mining.
+func sealHash(header *Header) (Hash, error) {
+	sealed := *header
+	sealed.Hash = NullHash
+	sealed.Nonce = 0
+	sealed.StateRoot = NullHash
+	sealed.ReceiptRoot = NullHash
+	sealed.LeiUsed = 0
+	sealed.MinerSignature = nil
+	sealed.Proof = nil
+	byt, err := (&Block{Header: &sealed}).Encode()
+	if err != nil {
+		return NullHash, err
+	}
+	return BytesToHash(Sha256(byt)), nil
+}
@@ -0,0 +1,96 @@
+package raft
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/yu-org/yu/config"
+	. "github.com/yu-org/yu/core/keypair"
+)
+
+type RaftConfig struct {
+	KeyType string `toml:"key_type"`
+	// secret for generating keypair.
+	MySecret string `toml:"my_secret"`
+	// the initial members, the later changes are recorded in state.
+	Members []*MemberConf `toml:"members"`
+	// block out interval, milliseconds
+	BlockInterval int `toml:"block_interval"`
+	// the number of packing txns from txpool, default 5000
+	PackNum uint64 `toml:"pack_num"`
+	// followers start an election if they hear nothing from the leader in a random time
+	// between ElectionTimeout and twice of it, milliseconds.
+	ElectionTimeout int `toml:"election_timeout"`
+	// interval of the leader replicating blocks to followers, milliseconds.
+	HeartbeatInterval int `toml:"heartbeat_interval"`
+	// the db keeping the term, the vote and the blocks not appended into chain.
+	DB config.KVconf `toml:"db"`
+}
+
+var DefaultSecrets = []string{
+	"node1",
+	"node2",
+	"node3",
+}
+
+func DefaultCfg(idx int) *RaftConfig {
+	cfg := &RaftConfig{
+		KeyType:  Sr25519,
+		MySecret: DefaultSecrets[idx],
+		Members: []*MemberConf{
+			{Pubkey: "", P2pID: "12D3KooWHHzSeKaY8xuZVzkLbKFfvNgPPeKhFBGrMbNzbm5akpqu"},
+			{Pubkey: "", P2pID: "12D3KooWSKPs95miv8wzj3fa5HkJ1tH7oEGumsEiD92n2MYwRtQG"},
+			{Pubkey: "", P2pID: "12D3KooWRuwP7nXaRhZrmoFJvPPGat2xPafVmGpQpZs5zKMtwqPH"},
+		},
+		BlockInterval:     500,
+		PackNum:           5000,
+		ElectionTimeout:   1000,
+		HeartbeatInterval: 100,
+		DB:                config.KVconf{KvType: "bolt", Path: "raft.db"},
+	}
+	for i, secret := range DefaultSecrets {
+		pub, _ := GenSrKeyWithSecret([]byte(secret))
+		cfg.Members[i].Pubkey = pub.StringWithType()
+	}
+	return cfg
+}
+
+type MemberConf struct {
+	Pubkey string `toml:"pubkey" json:"pubkey"`
+	P2pID  string `toml:"p2p_id" json:"p2p_id"`
+}
+
+type member struct {
+	pubkey PubKey
+	p2pID  peer.ID
+}
+
+func resolveMembers(confs []*MemberConf) ([]*member, error) {
+	members := make([]*member, 0, len(confs))
+	for _, conf := range confs {
+		m, err := resolveMember(conf)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func resolveMember(conf *MemberConf) (*member, error) {
+	pubkey, err := PubkeyFromStr(conf.Pubkey)
+	if err != nil {
+		return nil, err
+	}
+	p2pID, err := peer.Decode(conf.P2pID)
+	if err != nil {
+		return nil, err
+	}
+	return &member{pubkey: pubkey, p2pID: p2pID}, nil
+}
+
+func memberIDs(members []*member) []peer.ID {
+	ids := make([]peer.ID, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.p2pID)
+	}
+	return ids
+}
@@ -0,0 +1,163 @@
+package raft
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/tripod"
+)
+
+var (
+	membersKey       = []byte("raft-members")
+	memberChangedKey = []byte("raft-member-changed")
+)
+
+// AddMember adds a member into raft, the caller must be a member.
+func (r *Raft) AddMember(ctx *WriteContext) error {
+	confs, err := r.changeMembers(ctx)
+	if err != nil {
+		return err
+	}
+	var req MemberConf
+	err = ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	added, err := resolveMember(&req)
+	if err != nil {
+		return err
+	}
+	for _, conf := range confs {
+		m, err := resolveMember(conf)
+		if err != nil {
+			return err
+		}
+		if m.p2pID == added.p2pID || m.pubkey.Address() == added.pubkey.Address() {
+			return MemberExists
+		}
+	}
+	err = r.setMemberConfs(ctx, append(confs, &req))
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(req)
+}
+
+// RemoveMember removes a member from raft, the caller must be a member.
+func (r *Raft) RemoveMember(ctx *WriteContext) error {
+	confs, err := r.changeMembers(ctx)
+	if err != nil {
+		return err
+	}
+	var req MemberConf
+	err = ctx.BindJson(&req)
+	if err != nil {
+		return err
+	}
+	remain := make([]*MemberConf, 0, len(confs))
+	for _, conf := range confs {
+		if conf.P2pID == req.P2pID {
+			continue
+		}
+		remain = append(remain, conf)
+	}
+	if len(remain) == len(confs) {
+		return MemberNotFound
+	}
+	if len(remain) == 0 {
+		return LastMember
+	}
+	err = r.setMemberConfs(ctx, remain)
+	if err != nil {
+		return err
+	}
+	return ctx.EmitJsonEvent(req)
+}
+
+// changeMembers checks the caller could change the members in this block, and returns the current members.
+// Only one member is changed in a block, so that the old and new majorities always overlap.
+func (r *Raft) changeMembers(ctx *WriteContext) ([]*MemberConf, error) {
+	pubkey, err := PubKeyFromBytes(ctx.Txn.Pubkey)
+	if err != nil {
+		return nil, err
+	}
+	if r.memberByAddress(pubkey.Address()) == nil {
+		return nil, NoPermission
+	}
+	st := r.TxnState(ctx)
+	changed, err := st.Get(memberChangedKey)
+	if err != nil {
+		return nil, err
+	}
+	if changed != nil && BytesToBlockNum(changed) == ctx.Block.Height {
+		return nil, MemberChanging
+	}
+	return r.getMemberConfs(st)
+}
+
+func (r *Raft) setMemberConfs(ctx *WriteContext, confs []*MemberConf) error {
+	byt, err := json.Marshal(confs)
+	if err != nil {
+		return err
+	}
+	st := r.TxnState(ctx)
+	st.Set(membersKey, byt)
+	st.Set(memberChangedKey, ctx.Block.Height.Bytes())
+	return nil
+}
+
+func (r *Raft) getMemberConfs(st TripodKV) ([]*MemberConf, error) {
+	if r.ChainEnv == nil || r.State == nil {
+		return r.initMembers, nil
+	}
+	byt, err := st.Get(membersKey)
+	if err != nil {
+		return nil, err
+	}
+	if byt == nil {
+		return r.initMembers, nil
+	}
+	var confs []*MemberConf
+	err = json.Unmarshal(byt, &confs)
+	return confs, err
+}
+
+func (r *Raft) loadMembers() {
+	confs, err := r.getMemberConfs(r)
+	if err != nil {
+		logrus.Error("load raft members from state error: ", err)
+		return
+	}
+	members, err := resolveMembers(confs)
+	if err != nil {
+		logrus.Error("resolve raft members error: ", err)
+		return
+	}
+	r.membersLock.Lock()
+	r.members = members
+	r.membersLock.Unlock()
+}
+
+// GetMembers returns the current raft members.
+func (r *Raft) GetMembers(ctx *ReadContext) {
+	confs, err := r.getMemberConfs(r)
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	ctx.JsonOk(confs)
+}
+
+type LeaderInfo struct {
+	Leader string `json:"leader"`
+	Term   uint64 `json:"term"`
+}
+
+// GetLeader returns the leader known by this node and the current term.
+func (r *Raft) GetLeader(ctx *ReadContext) {
+	leader, term := r.node.leaderInfo()
+	ctx.JsonOk(LeaderInfo{Leader: leader.String(), Term: term})
+}
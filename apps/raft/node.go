@@ -0,0 +1,682 @@
+package raft
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type role int
+
+const (
+	follower role = iota
+	candidate
+	leader
+)
+
+// maxAppendBlocks limits the blocks replicated in one AppendEntries request.
+const maxAppendBlocks = 64
+
+type entry struct {
+	term  uint64
+	block *Block
+}
+
+func newEntry(block *Block) *entry {
+	return &entry{term: blockTerm(block.Header), block: block}
+}
+
+func (e *entry) height() BlockNum {
+	return e.block.Height
+}
+
+// node is the raft state machine, its log is the chain:
+// the blocks up to base have been appended into the chain,
+// the blocks after base are kept in entries until they are committed and appended.
+type node struct {
+	sync.Mutex
+
+	id    peer.ID
+	net   p2p.P2pNetwork
+	store *logStore
+	// loads the appended blocks from chain for the lagging followers.
+	loadBlocks func(start, end BlockNum) ([]*Block, error)
+
+	role     role
+	term     uint64
+	votedFor peer.ID
+	leader   peer.ID
+	members  []peer.ID
+
+	base    *entry
+	entries []*entry
+	commit  BlockNum
+
+	// the replication progress of followers, only used by leader.
+	nextHeight  map[peer.ID]BlockNum
+	matchHeight map[peer.ID]BlockNum
+	replicating map[peer.ID]bool
+
+	electionTimeout time.Duration
+	heartbeat       time.Duration
+	lastHeard       time.Time
+
+	// closed when the commit or role changes.
+	changed chan struct{}
+	stop    chan struct{}
+}
+
+func newNode(store *logStore, electionTimeout, heartbeat time.Duration) *node {
+	return &node{
+		store:           store,
+		nextHeight:      make(map[peer.ID]BlockNum),
+		matchHeight:     make(map[peer.ID]BlockNum),
+		replicating:     make(map[peer.ID]bool),
+		electionTimeout: electionTimeout,
+		heartbeat:       heartbeat,
+		changed:         make(chan struct{}),
+		stop:            make(chan struct{}),
+	}
+}
+
+// start runs the node from the end block of chain and the persisted state.
+func (n *node) start(id peer.ID, net p2p.P2pNetwork, members []peer.ID, end *Block, loadBlocks func(start, end BlockNum) ([]*Block, error)) error {
+	term, votedFor, blocks, err := n.store.load()
+	if err != nil {
+		return err
+	}
+
+	n.Lock()
+	n.id = id
+	n.net = net
+	n.members = members
+	n.loadBlocks = loadBlocks
+	n.base = newEntry(end)
+	n.commit = end.Height
+	n.term = n.base.term
+	if term >= n.term {
+		n.term = term
+		n.votedFor = votedFor
+	}
+	// the saved blocks after the end block of chain are still in the log.
+	for _, block := range blocks {
+		last := n.lastEntry()
+		if block.Height <= last.height() {
+			continue
+		}
+		if block.Height != last.height()+1 || block.PrevHash != last.block.Hash {
+			break
+		}
+		n.entries = append(n.entries, newEntry(block))
+	}
+	n.lastHeard = time.Now()
+	n.Unlock()
+
+	go n.run()
+	return nil
+}
+
+// persist saves the term, the vote and the log, it must be called before replying to the other nodes.
+func (n *node) persist() error {
+	return n.store.save(n.term, n.votedFor, n.entries)
+}
+
+func (n *node) close() {
+	close(n.stop)
+}
+
+func (n *node) run() {
+	ticker := time.NewTicker(n.heartbeat)
+	defer ticker.Stop()
+	timeout := n.randomTimeout()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+		n.Lock()
+		if n.role == leader {
+			n.broadcast()
+		} else if n.isMember(n.id) && time.Since(n.lastHeard) >= timeout {
+			timeout = n.randomTimeout()
+			n.campaign()
+		}
+		n.Unlock()
+	}
+}
+
+func (n *node) randomTimeout() time.Duration {
+	return n.electionTimeout + time.Duration(rand.Int63n(int64(n.electionTimeout)))
+}
+
+func (n *node) campaign() {
+	n.term++
+	n.role = candidate
+	n.votedFor = n.id
+	n.leader = ""
+	n.lastHeard = time.Now()
+	err := n.persist()
+	if err != nil {
+		logrus.Error("persist raft state error: ", err)
+		n.role = follower
+		return
+	}
+	logrus.Infof("raft node(%s) campaigns for term(%d)", n.id, n.term)
+
+	last := n.lastEntry()
+	req := &VoteRequest{
+		Term:       n.term,
+		Candidate:  n.id,
+		LastHeight: last.height(),
+		LastTerm:   last.term,
+	}
+	votes := 1
+	if votes >= n.quorum() {
+		n.becomeLeader()
+		return
+	}
+	for _, p := range n.members {
+		if p == n.id {
+			continue
+		}
+		go func(p peer.ID) {
+			resp := new(VoteResponse)
+			err := n.request(p, RequestVoteCode, req, resp)
+			if err != nil {
+				logrus.Debugf("request vote from %s error: %v", p, err)
+				return
+			}
+			n.Lock()
+			defer n.Unlock()
+			if resp.Term > n.term {
+				n.stepDown(resp.Term)
+				return
+			}
+			if n.role != candidate || n.term != req.Term || !resp.Granted {
+				return
+			}
+			votes++
+			if votes >= n.quorum() {
+				n.becomeLeader()
+			}
+		}(p)
+	}
+}
+
+func (n *node) becomeLeader() {
+	logrus.Infof("raft node(%s) becomes the leader of term(%d)", n.id, n.term)
+	n.role = leader
+	n.leader = n.id
+	next := n.lastEntry().height() + 1
+	for _, p := range n.members {
+		n.nextHeight[p] = next
+		n.matchHeight[p] = 0
+	}
+	n.notify()
+	n.broadcast()
+}
+
+func (n *node) stepDown(term uint64) {
+	if term > n.term {
+		n.term = term
+		n.votedFor = ""
+	}
+	if n.role != follower {
+		n.role = follower
+		n.notify()
+	}
+}
+
+// broadcast replicates the blocks to every follower which has no request in flight.
+func (n *node) broadcast() {
+	for _, p := range n.members {
+		if p == n.id || n.replicating[p] {
+			continue
+		}
+		n.replicating[p] = true
+		go n.replicate(p)
+	}
+}
+
+func (n *node) replicate(p peer.ID) {
+	n.Lock()
+	req, err := n.appendRequest(p)
+	n.Unlock()
+	if err != nil {
+		logrus.Errorf("make append request for %s error: %v", p, err)
+		n.Lock()
+		n.replicating[p] = false
+		n.Unlock()
+		return
+	}
+
+	resp := new(AppendResponse)
+	err = n.request(p, AppendEntriesCode, req, resp)
+
+	n.Lock()
+	defer n.Unlock()
+	n.replicating[p] = false
+	if err != nil {
+		logrus.Debugf("append blocks to %s error: %v", p, err)
+		return
+	}
+	if resp.Term > n.term {
+		n.stepDown(resp.Term)
+		return
+	}
+	if n.role != leader || n.term != req.Term {
+		return
+	}
+	if resp.Success {
+		match := req.PrevHeight + BlockNum(len(req.Blocks))
+		if match > n.matchHeight[p] {
+			n.matchHeight[p] = match
+		}
+		n.nextHeight[p] = match + 1
+		n.advanceCommit()
+		if n.nextHeight[p] > n.lastEntry().height() {
+			return
+		}
+	} else {
+		next := n.nextHeight[p] - 1
+		if resp.LastHeight+1 < next {
+			next = resp.LastHeight + 1
+		}
+		if next < 1 {
+			next = 1
+		}
+		n.nextHeight[p] = next
+	}
+	n.replicating[p] = true
+	go n.replicate(p)
+}
+
+func (n *node) appendRequest(p peer.ID) (*AppendRequest, error) {
+	next := n.nextHeight[p]
+	if next < 1 {
+		next = 1
+	}
+	prev, err := n.entryAt(next - 1)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := n.blocksFrom(next, maxAppendBlocks)
+	if err != nil {
+		return nil, err
+	}
+	req := &AppendRequest{
+		Term:       n.term,
+		Leader:     n.id,
+		PrevHeight: prev.height(),
+		PrevHash:   prev.block.Hash,
+		Blocks:     make([][]byte, 0, len(blocks)),
+		Commit:     n.commit,
+	}
+	for _, block := range blocks {
+		byt, err := block.Encode()
+		if err != nil {
+			return nil, err
+		}
+		req.Blocks = append(req.Blocks, byt)
+	}
+	return req, nil
+}
+
+// advanceCommit commits the highest block of current term which the majority have.
+func (n *node) advanceCommit() {
+	for i := len(n.entries) - 1; i >= 0; i-- {
+		e := n.entries[i]
+		if e.height() <= n.commit || e.term != n.term {
+			return
+		}
+		count := 0
+		for _, p := range n.members {
+			if p == n.id || n.matchHeight[p] >= e.height() {
+				count++
+			}
+		}
+		if count >= n.quorum() {
+			n.commit = e.height()
+			n.notify()
+			return
+		}
+	}
+}
+
+func (n *node) handleVote(data []byte) ([]byte, error) {
+	var req VoteRequest
+	err := decodeMsg(data, &req)
+	if err != nil {
+		return nil, err
+	}
+	n.Lock()
+	defer n.Unlock()
+	if n.base == nil {
+		return nil, RaftNotStarted
+	}
+
+	resp := &VoteResponse{Term: n.term}
+	oldTerm := n.term
+	// the removed members or the partitioned nodes must not disturb a living leader.
+	if !n.isMember(req.Candidate) || n.role == leader ||
+		(n.leader != "" && time.Since(n.lastHeard) < n.electionTimeout) {
+		return encodeMsg(resp)
+	}
+	if req.Term < n.term {
+		return encodeMsg(resp)
+	}
+	if req.Term > n.term {
+		n.stepDown(req.Term)
+		n.leader = ""
+	}
+	resp.Term = n.term
+
+	last := n.lastEntry()
+	upToDate := req.LastTerm > last.term || (req.LastTerm == last.term && req.LastHeight >= last.height())
+	if upToDate && (n.votedFor == "" || n.votedFor == req.Candidate) {
+		n.votedFor = req.Candidate
+		n.lastHeard = time.Now()
+		resp.Granted = true
+	}
+	if resp.Granted || n.term != oldTerm {
+		err = n.persist()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encodeMsg(resp)
+}
+
+func (n *node) handleAppend(data []byte) ([]byte, error) {
+	var req AppendRequest
+	err := decodeMsg(data, &req)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := req.decodeBlocks()
+	if err != nil {
+		return nil, err
+	}
+	n.Lock()
+	defer n.Unlock()
+	if n.base == nil {
+		return nil, RaftNotStarted
+	}
+
+	resp := &AppendResponse{Term: n.term}
+	if req.Term < n.term {
+		resp.LastHeight = n.lastEntry().height()
+		return encodeMsg(resp)
+	}
+	oldTerm := n.term
+	n.stepDown(req.Term)
+	n.leader = req.Leader
+	n.lastHeard = time.Now()
+	resp.Term = n.term
+	if n.term != oldTerm {
+		err = n.persist()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.PrevHeight > n.lastEntry().height() {
+		resp.LastHeight = n.lastEntry().height()
+		return encodeMsg(resp)
+	}
+	// the blocks up to base are committed, they must be the same as leader's.
+	if req.PrevHeight >= n.base.height() {
+		prev, err := n.entryAt(req.PrevHeight)
+		if err != nil {
+			return nil, err
+		}
+		if prev.block.Hash != req.PrevHash {
+			err = n.truncate(req.PrevHeight)
+			if err != nil {
+				return nil, err
+			}
+			err = n.persist()
+			if err != nil {
+				return nil, err
+			}
+			resp.LastHeight = n.lastEntry().height()
+			return encodeMsg(resp)
+		}
+	}
+
+	appended := false
+	for _, block := range blocks {
+		if block.Height <= n.base.height() {
+			continue
+		}
+		if block.Height <= n.lastEntry().height() {
+			existing, err := n.entryAt(block.Height)
+			if err != nil {
+				return nil, err
+			}
+			if existing.block.Hash == block.Hash {
+				continue
+			}
+			err = n.truncate(block.Height)
+			if err != nil {
+				return nil, err
+			}
+		}
+		n.entries = append(n.entries, newEntry(block))
+		appended = true
+	}
+	if appended {
+		err = n.persist()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lastNew := req.PrevHeight + BlockNum(len(blocks))
+	commit := req.Commit
+	if commit > lastNew {
+		commit = lastNew
+	}
+	if commit > n.commit {
+		n.commit = commit
+		n.notify()
+	}
+	resp.Success = true
+	resp.LastHeight = n.lastEntry().height()
+	return encodeMsg(resp)
+}
+
+// truncate removes the uncommitted blocks from height.
+func (n *node) truncate(height BlockNum) error {
+	if height <= n.commit {
+		logrus.Errorf("raft node(%s) truncates the committed height(%d)", n.id, height)
+		return RaftLogConflict
+	}
+	n.entries = n.entries[:height-n.base.height()-1]
+	return nil
+}
+
+// propose appends the block produced by leader into the log, then replicates it.
+func (n *node) propose(block *Block) error {
+	n.Lock()
+	defer n.Unlock()
+	if n.role != leader || blockTerm(block.Header) != n.term {
+		return NotRaftLeader
+	}
+	if block.Height != n.lastEntry().height()+1 {
+		return BlockIllegal(block.Hash)
+	}
+	n.entries = append(n.entries, newEntry(block))
+	err := n.persist()
+	if err != nil {
+		n.entries = n.entries[:len(n.entries)-1]
+		return err
+	}
+	n.advanceCommit()
+	n.broadcast()
+	return nil
+}
+
+// committed returns a copy of the committed block at height, nil if it is not committed yet.
+func (n *node) committed(height BlockNum) *Block {
+	n.Lock()
+	defer n.Unlock()
+	if height > n.commit || height <= n.base.height() {
+		return nil
+	}
+	e, err := n.entryAt(height)
+	if err != nil {
+		return nil
+	}
+	header := *e.block.Header
+	return &Block{Header: &header, Txns: e.block.Txns}
+}
+
+// applied moves base forward after the committed block is appended into chain.
+func (n *node) applied(block *Block) {
+	n.Lock()
+	defer n.Unlock()
+	for len(n.entries) > 0 && n.entries[0].height() <= block.Height {
+		n.entries = n.entries[1:]
+	}
+	n.base = newEntry(block)
+	if n.commit < block.Height {
+		n.commit = block.Height
+	}
+}
+
+func (n *node) setMembers(members []peer.ID) {
+	n.Lock()
+	defer n.Unlock()
+	n.members = members
+	if n.role != leader {
+		return
+	}
+	if !n.isMember(n.id) {
+		logrus.Infof("raft node(%s) is removed, steps down", n.id)
+		n.role = follower
+		n.leader = ""
+		n.notify()
+		return
+	}
+	for _, p := range members {
+		if _, ok := n.nextHeight[p]; !ok {
+			n.nextHeight[p] = n.lastEntry().height() + 1
+		}
+	}
+	n.advanceCommit()
+	n.broadcast()
+}
+
+// proposing returns the term and the last block if the node is leader and should propose a block after it.
+// The leader proposes when it has no block at height, or when its last block is left by the previous leaders,
+// because these blocks are committed only after a block of current term is committed.
+func (n *node) proposing(height BlockNum) (uint64, *Block, bool) {
+	n.Lock()
+	defer n.Unlock()
+	last := n.lastEntry()
+	if n.role != leader || (last.height() >= height && last.term == n.term) {
+		return 0, nil, false
+	}
+	return n.term, last.block, true
+}
+
+func (n *node) leaderInfo() (peer.ID, uint64) {
+	n.Lock()
+	defer n.Unlock()
+	return n.leader, n.term
+}
+
+// waitChan returns a channel closed when the commit or role changes next time.
+func (n *node) waitChan() <-chan struct{} {
+	n.Lock()
+	defer n.Unlock()
+	return n.changed
+}
+
+func (n *node) notify() {
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+func (n *node) lastEntry() *entry {
+	if len(n.entries) > 0 {
+		return n.entries[len(n.entries)-1]
+	}
+	return n.base
+}
+
+func (n *node) entryAt(height BlockNum) (*entry, error) {
+	if height > n.base.height() {
+		return n.entries[height-n.base.height()-1], nil
+	}
+	if height == n.base.height() {
+		return n.base, nil
+	}
+	blocks, err := n.loadBlocks(height, height)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, NoBlockInHeight(height)
+	}
+	return newEntry(blocks[0]), nil
+}
+
+func (n *node) blocksFrom(start BlockNum, limit int) ([]*Block, error) {
+	end := start + BlockNum(limit) - 1
+	if last := n.lastEntry().height(); end > last {
+		end = last
+	}
+	if start > end {
+		return nil, nil
+	}
+	blocks := make([]*Block, 0, end-start+1)
+	if start <= n.base.height() {
+		appended, err := n.loadBlocks(start, n.base.height())
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, appended...)
+		start = n.base.height() + 1
+	}
+	for h := start; h <= end; h++ {
+		blocks = append(blocks, n.entries[h-n.base.height()-1].block)
+	}
+	if len(blocks) > limit {
+		blocks = blocks[:limit]
+	}
+	return blocks, nil
+}
+
+func (n *node) isMember(id peer.ID) bool {
+	for _, p := range n.members {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) quorum() int {
+	return len(n.members)/2 + 1
+}
+
+func (n *node) request(p peer.ID, code int, req, resp any) error {
+	byt, err := encodeMsg(req)
+	if err != nil {
+		return err
+	}
+	respByt, err := n.net.RequestPeer(p, code, byt)
+	if err != nil {
+		return err
+	}
+	return decodeMsg(respByt, resp)
+}
@@ -0,0 +1,282 @@
+package raft
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/tripod/dev"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testPeer runs the block cycle of Raft tripod on a chain in memory.
+type testPeer struct {
+	*node
+	chainLock sync.RWMutex
+	chain     []*Block
+}
+
+var genesis = &Block{Header: &Header{Hash: HexToHash("genesis")}}
+
+func newTestStore(t *testing.T) *logStore {
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(t.TempDir(), "raft.db")})
+	assert.NoError(t, err)
+	return newLogStore(kvdb.New("raft"))
+}
+
+func newTestPeer(t *testing.T, net *p2p.SimNode, members []peer.ID) *testPeer {
+	tp := &testPeer{
+		node:  newNode(newTestStore(t), 150*time.Millisecond, 20*time.Millisecond),
+		chain: []*Block{genesis},
+	}
+	net.SetHandlers(map[int]dev.P2pHandler{
+		RequestVoteCode:   tp.handleVote,
+		AppendEntriesCode: tp.handleAppend,
+	})
+	assert.NoError(t, tp.start(net.LocalID(), net, members, genesis, tp.loadBlocks))
+	go tp.runCycle()
+	t.Cleanup(tp.close)
+	return tp
+}
+
+func (tp *testPeer) runCycle() {
+	for {
+		tp.chainLock.RLock()
+		height := BlockNum(len(tp.chain))
+		tp.chainLock.RUnlock()
+
+		changed := tp.waitChan()
+		if block := tp.committed(height); block != nil {
+			tp.chainLock.Lock()
+			tp.chain = append(tp.chain, block)
+			tp.chainLock.Unlock()
+			tp.applied(block)
+			continue
+		}
+		if term, last, ok := tp.proposing(height); ok {
+			block := &Block{Header: &Header{
+				Height:   last.Height + 1,
+				PrevHash: last.Hash,
+				Extra:    termBytes(term),
+			}}
+			block.Hash, _ = blockHash(block.Header)
+			_ = tp.propose(block)
+			time.Sleep(5 * time.Millisecond)
+		}
+		select {
+		case <-changed:
+		case <-time.After(tp.heartbeat):
+		case <-tp.stop:
+			return
+		}
+	}
+}
+
+func (tp *testPeer) loadBlocks(start, end BlockNum) ([]*Block, error) {
+	tp.chainLock.RLock()
+	defer tp.chainLock.RUnlock()
+	return tp.chain[start : end+1], nil
+}
+
+func (tp *testPeer) height() BlockNum {
+	tp.chainLock.RLock()
+	defer tp.chainLock.RUnlock()
+	return BlockNum(len(tp.chain) - 1)
+}
+
+func (tp *testPeer) hashAt(height BlockNum) Hash {
+	tp.chainLock.RLock()
+	defer tp.chainLock.RUnlock()
+	return tp.chain[height].Hash
+}
+
+func (tp *testPeer) isLeader() bool {
+	leader, _ := tp.leaderInfo()
+	return leader == tp.id
+}
+
+func newCluster(t *testing.T, num, members int) (*p2p.SimNetwork, []*p2p.SimNode, []peer.ID) {
+	net := p2p.NewSimNetwork(1)
+	net.SetLatency(time.Millisecond, 3*time.Millisecond)
+	nodes := make([]*p2p.SimNode, 0, num)
+	ids := make([]peer.ID, 0, num)
+	for i := 0; i < num; i++ {
+		node := net.NewNode()
+		nodes = append(nodes, node)
+		ids = append(ids, node.LocalID())
+	}
+	return net, nodes, ids[:members]
+}
+
+func startPeers(t *testing.T, nodes []*p2p.SimNode, members []peer.ID) []*testPeer {
+	peers := make([]*testPeer, 0, len(nodes))
+	for _, node := range nodes {
+		peers = append(peers, newTestPeer(t, node, members))
+	}
+	return peers
+}
+
+func waitLeader(t *testing.T, peers []*testPeer) *testPeer {
+	var found *testPeer
+	assert.Eventually(t, func() bool {
+		for _, tp := range peers {
+			if tp.isLeader() {
+				found = tp
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	return found
+}
+
+func waitHeight(t *testing.T, peers []*testPeer, height BlockNum) {
+	assert.Eventually(t, func() bool {
+		for _, tp := range peers {
+			if tp.height() < height {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// assertSameChain checks the blocks which all the peers have.
+func assertSameChain(t *testing.T, peers []*testPeer) {
+	height := peers[0].height()
+	for _, tp := range peers[1:] {
+		if tp.height() < height {
+			height = tp.height()
+		}
+	}
+	for h := BlockNum(1); h <= height; h++ {
+		for _, tp := range peers[1:] {
+			assert.Equal(t, peers[0].hashAt(h), tp.hashAt(h), "height %d", h)
+		}
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	net, nodes, members := newCluster(t, 3, 3)
+	// the lost requests are retried by the heartbeats.
+	net.SetDropRate(0.05)
+	peers := startPeers(t, nodes, members)
+	waitLeader(t, peers)
+	waitHeight(t, peers, 10)
+	assertSameChain(t, peers)
+}
+
+func TestLeaderCrash(t *testing.T) {
+	net, nodes, members := newCluster(t, 3, 3)
+	peers := startPeers(t, nodes, members)
+	oldLeader := waitLeader(t, peers)
+	waitHeight(t, peers, 5)
+	_, oldTerm := oldLeader.leaderInfo()
+
+	net.Partition([]peer.ID{oldLeader.id})
+	alive := make([]*testPeer, 0)
+	for _, tp := range peers {
+		if tp != oldLeader {
+			alive = append(alive, tp)
+		}
+	}
+	newLeader := waitLeader(t, alive)
+	_, newTerm := newLeader.leaderInfo()
+	assert.Greater(t, newTerm, oldTerm)
+	waitHeight(t, alive, oldLeader.height()+5)
+
+	// the old leader steps down and catches up after recovery.
+	net.Heal()
+	waitHeight(t, peers, newLeader.height()+5)
+	assert.False(t, oldLeader.isLeader())
+	assertSameChain(t, peers)
+}
+
+func TestChangeMembers(t *testing.T) {
+	_, nodes, members := newCluster(t, 4, 3)
+	peers := startPeers(t, nodes[:3], members)
+	leader := waitLeader(t, peers)
+	waitHeight(t, peers, 5)
+
+	// the new node receives nothing before it is added.
+	joined := newTestPeer(t, nodes[3], members)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, BlockNum(0), joined.height())
+
+	peers = append(peers, joined)
+	members = append(members, joined.id)
+	for _, tp := range peers {
+		tp.setMembers(members)
+	}
+	waitHeight(t, peers, leader.height()+3)
+	assertSameChain(t, peers)
+
+	// the removed leader steps down, the rest elect a new one.
+	remain := make([]peer.ID, 0)
+	alive := make([]*testPeer, 0)
+	for _, tp := range peers {
+		if tp != leader {
+			remain = append(remain, tp.id)
+			alive = append(alive, tp)
+		}
+	}
+	for _, tp := range peers {
+		tp.setMembers(remain)
+	}
+	assert.False(t, leader.isLeader())
+	newLeader := waitLeader(t, alive)
+	assert.NotEqual(t, leader.id, newLeader.id)
+	waitHeight(t, alive, newLeader.height()+3)
+}
+
+func TestRestartInTerm(t *testing.T) {
+	_, nodes, members := newCluster(t, 3, 3)
+	store := newTestStore(t)
+	// the node never campaigns by itself in the test.
+	n := newNode(store, time.Minute, time.Minute)
+	assert.NoError(t, n.start(members[0], nodes[0], members, genesis, nil))
+
+	vote := func(n *node, candidate peer.ID) bool {
+		byt, err := encodeMsg(&VoteRequest{Term: 5, Candidate: candidate})
+		assert.NoError(t, err)
+		respByt, err := n.handleVote(byt)
+		assert.NoError(t, err)
+		resp := new(VoteResponse)
+		assert.NoError(t, decodeMsg(respByt, resp))
+		return resp.Granted
+	}
+	assert.True(t, vote(n, members[1]))
+
+	// the leader of term 5 replicates 2 blocks which are not committed yet.
+	blocks := make([][]byte, 0)
+	prev := genesis
+	for h := BlockNum(1); h <= 2; h++ {
+		block := &Block{Header: &Header{Height: h, PrevHash: prev.Hash, Extra: termBytes(5)}}
+		block.Hash, _ = blockHash(block.Header)
+		byt, err := block.Encode()
+		assert.NoError(t, err)
+		blocks = append(blocks, byt)
+		prev = block
+	}
+	byt, err := encodeMsg(&AppendRequest{Term: 5, Leader: members[1], PrevHash: genesis.Hash, Blocks: blocks})
+	assert.NoError(t, err)
+	_, err = n.handleAppend(byt)
+	assert.NoError(t, err)
+	n.close()
+
+	// the restarted node keeps its vote and the acknowledged blocks of term 5.
+	restarted := newNode(store, time.Minute, time.Minute)
+	assert.NoError(t, restarted.start(members[0], nodes[0], members, genesis, nil))
+	defer restarted.close()
+	_, term := restarted.leaderInfo()
+	assert.Equal(t, uint64(5), term)
+	assert.False(t, vote(restarted, members[2]))
+	assert.Equal(t, BlockNum(2), restarted.lastEntry().height())
+	assert.Equal(t, prev.Hash, restarted.lastEntry().block.Hash)
+}
@@ -0,0 +1,80 @@
+package raft
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/types"
+)
+
+const (
+	RequestVoteCode   int = 200
+	AppendEntriesCode     = 201
+)
+
+type VoteRequest struct {
+	Term      uint64
+	Candidate peer.ID
+	// the last block in the log of candidate
+	LastHeight BlockNum
+	LastTerm   uint64
+}
+
+type VoteResponse struct {
+	Term    uint64
+	Granted bool
+}
+
+// AppendRequest replicates the blocks after PrevHeight to the follower,
+// it carries no blocks if it is a heartbeat.
+type AppendRequest struct {
+	Term       uint64
+	Leader     peer.ID
+	PrevHeight BlockNum
+	PrevHash   Hash
+	Blocks     [][]byte
+	// the committed height of leader
+	Commit BlockNum
+}
+
+type AppendResponse struct {
+	Term    uint64
+	Success bool
+	// the last block height in the log of follower, the leader retries from it if failed.
+	LastHeight BlockNum
+}
+
+func (r *AppendRequest) decodeBlocks() ([]*Block, error) {
+	blocks := make([]*Block, 0, len(r.Blocks))
+	for _, byt := range r.Blocks {
+		block, err := DecodeBlock(byt)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func encodeMsg(msg any) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func decodeMsg(data []byte, msg any) error {
+	return json.Unmarshal(data, msg)
+}
+
+// the raft term of a block is kept in its Extra.
+func blockTerm(header *Header) uint64 {
+	if len(header.Extra) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(header.Extra)
+}
+
+func termBytes(term uint64) []byte {
+	byt := make([]byte, 8)
+	binary.BigEndian.PutUint64(byt, term)
+	return byt
+}
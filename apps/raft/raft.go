@@ -0,0 +1,228 @@
+package raft
+
+import (
+	"fmt"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"github.com/yu-org/yu/utils/log"
+	"sync"
+	"time"
+)
+
+// Raft orders the blocks by the leader elected among the members,
+// a block is committed, executed and finalized once the majority of members have it.
+// It tolerates crash faults only, so the members must trust each other.
+type Raft struct {
+	*Tripod
+
+	node *node
+
+	myPubkey  PubKey
+	myPrivKey PrivKey
+
+	// members from config, used until the members are changed in state.
+	initMembers []*MemberConf
+	members     []*member
+	membersLock sync.RWMutex
+
+	blockInterval time.Duration
+	packNum       uint64
+	lastPropose   time.Time
+}
+
+func NewRaft(cfg *RaftConfig) *Raft {
+	pub, priv, err := GenKeyPairWithSecret(cfg.KeyType, []byte(cfg.MySecret))
+	if err != nil {
+		logrus.Fatal("generate raft keypair error: ", err)
+	}
+	members, err := resolveMembers(cfg.Members)
+	if err != nil {
+		logrus.Fatal("resolve raft members error: ", err)
+	}
+	db, err := kv.NewKvdb(&cfg.DB)
+	if err != nil {
+		logrus.Fatal("init raft db error: ", err)
+	}
+
+	r := &Raft{
+		Tripod:        NewTripod(),
+		node:          newNode(newLogStore(db.New("raft")), time.Duration(cfg.ElectionTimeout)*time.Millisecond, time.Duration(cfg.HeartbeatInterval)*time.Millisecond),
+		myPubkey:      pub,
+		myPrivKey:     priv,
+		initMembers:   cfg.Members,
+		members:       members,
+		blockInterval: time.Duration(cfg.BlockInterval) * time.Millisecond,
+		packNum:       cfg.PackNum,
+	}
+	r.SetInit(r)
+	r.SetBlockCycle(r)
+	r.SetBlockVerifier(r)
+	r.SetWritings(r.AddMember, r.RemoveMember)
+	r.SetReadings(r.GetMembers, r.GetLeader)
+	r.SetP2pHandler(RequestVoteCode, r.node.handleVote).SetP2pHandler(AppendEntriesCode, r.node.handleAppend)
+	return r
+}
+
+func (r *Raft) LocalAddress() Address {
+	return r.myPubkey.Address()
+}
+
+func (r *Raft) VerifyBlock(block *Block) bool {
+	minerPubkey, err := PubKeyFromBytes(block.MinerPubkey)
+	if err != nil {
+		logrus.Warnf("parse pubkey(%s) error: %v", block.MinerPubkey, err)
+		return false
+	}
+	if r.memberByAddress(minerPubkey.Address()) == nil {
+		logrus.Warn("illegal miner: ", minerPubkey.StringWithType())
+		return false
+	}
+	hash, err := blockHash(block.Header)
+	if err != nil || hash != block.Hash {
+		return false
+	}
+	return minerPubkey.VerifySignature(block.Hash.Bytes(), block.MinerSignature)
+}
+
+func (r *Raft) InitChain() {
+	r.loadMembers()
+	end, err := r.Chain.GetEndBlock()
+	if err != nil {
+		logrus.Fatal("get end block error: ", err)
+	}
+	err = r.node.start(r.P2pNetwork.LocalID(), r.P2pNetwork, r.memberIDs(), &Block{Header: end.Header}, r.Chain.GetRangeBlocks)
+	if err != nil {
+		logrus.Fatal("start raft node error: ", err)
+	}
+}
+
+// StartBlock waits for the block committed at the height.
+// The leader proposes the block if no one is proposed for the height in its term.
+func (r *Raft) StartBlock(block *Block) {
+	log.StarConsole.Info(fmt.Sprintf("start a new block, height=%d", block.Height))
+
+	for {
+		changed := r.node.waitChan()
+		if committed := r.node.committed(block.Height); committed != nil {
+			block.CopyFrom(committed)
+			r.State.StartBlock(block.Hash)
+			return
+		}
+		if term, last, ok := r.node.proposing(block.Height); ok {
+			err := r.propose(block, term, last)
+			if err != nil {
+				logrus.Warnf("propose block at height(%d) failed: %v", block.Height, err)
+			}
+		}
+		select {
+		case <-changed:
+		case <-time.After(r.node.heartbeat):
+		}
+	}
+}
+
+func (r *Raft) propose(block *Block, term uint64, last *Block) error {
+	header := *block.Header
+	proposal := &Block{Header: &header}
+	if last.Height+1 == block.Height {
+		time.Sleep(time.Until(r.lastPropose.Add(r.blockInterval)))
+		r.lastPropose = time.Now()
+
+		txns, err := r.Pool.Pack(r.packNum)
+		if err != nil {
+			return err
+		}
+		proposal.SetTxns(txns)
+	} else {
+		// an empty block commits the blocks left by the previous leaders.
+		proposal.Height = last.Height + 1
+		proposal.PrevHash = last.Hash
+	}
+
+	txnRoot, err := MakeTxnRoot(proposal.Txns)
+	if err != nil {
+		return err
+	}
+	proposal.TxnRoot = txnRoot
+	proposal.Extra = termBytes(term)
+	proposal.MinerPubkey = r.myPubkey.BytesWithType()
+	proposal.Hash, err = blockHash(proposal.Header)
+	if err != nil {
+		return err
+	}
+	proposal.MinerSignature, err = r.myPrivKey.SignData(proposal.Hash.Bytes())
+	if err != nil {
+		return err
+	}
+	logrus.Infof(" I am Leader! I propose the block for height (%d) in term (%d)! ", proposal.Height, term)
+	return r.node.propose(proposal)
+}
+
+func (r *Raft) EndBlock(block *Block) {
+	err := r.Execute(block)
+	if err != nil {
+		logrus.Panic("execute block failed: ", err)
+	}
+
+	err = r.Chain.AppendBlock(block)
+	if err != nil {
+		logrus.Panic("append block failed: ", err)
+	}
+
+	err = r.Pool.Reset(block.Txns)
+	if err != nil {
+		logrus.Panic("reset pool failed: ", err)
+	}
+
+	r.State.FinalizeBlock(block.Hash)
+
+	// the member changes take effect once the block is applied.
+	r.loadMembers()
+	r.node.applied(block)
+	r.node.setMembers(r.memberIDs())
+}
+
+// FinalizeBlock finalizes every block, since it is executed only after being committed.
+func (r *Raft) FinalizeBlock(block *Block) {
+	log.DoubleLineConsole.Info(fmt.Sprintf("finalize block, height=%d, hash=%s", block.Height, block.Hash.String()))
+	err := r.Chain.Finalize(block.Hash)
+	if err != nil {
+		logrus.Error("finalize block error: ", err)
+	}
+}
+
+func (r *Raft) memberIDs() []peer.ID {
+	r.membersLock.RLock()
+	defer r.membersLock.RUnlock()
+	return memberIDs(r.members)
+}
+
+func (r *Raft) memberByAddress(addr Address) *member {
+	r.membersLock.RLock()
+	defer r.membersLock.RUnlock()
+	for _, m := range r.members {
+		if m.pubkey.Address() == addr {
+			return m
+		}
+	}
+	return nil
+}
+
+func blockHash(header *Header) (Hash, error) {
+	unsigned := *header
+	unsigned.Hash = NullHash
+	unsigned.StateRoot = NullHash
+	unsigned.ReceiptRoot = NullHash
+	unsigned.LeiUsed = 0
+	unsigned.MinerSignature = nil
+	byt, err := (&Block{Header: &unsigned}).Encode()
+	if err != nil {
+		return NullHash, err
+	}
+	return BytesToHash(Sha256(byt)), nil
+}
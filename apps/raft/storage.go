@@ -0,0 +1,66 @@
+package raft
+
+import (
+	"encoding/json"
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/storage/kv"
+)
+
+var raftStateKey = []byte("raft-state")
+
+// persistentState is the raft state which must survive restarts:
+// a node never votes twice in a term or forgets the blocks it has acknowledged to the leader.
+type persistentState struct {
+	Term     uint64  `json:"term"`
+	VotedFor peer.ID `json:"voted_for"`
+	// the blocks not appended into chain yet
+	Blocks [][]byte `json:"blocks"`
+}
+
+// logStore saves the persistent state in one key, so that it is written atomically.
+type logStore struct {
+	db kv.KV
+}
+
+func newLogStore(db kv.KV) *logStore {
+	return &logStore{db: db}
+}
+
+func (s *logStore) save(term uint64, votedFor peer.ID, entries []*entry) error {
+	st := &persistentState{Term: term, VotedFor: votedFor, Blocks: make([][]byte, 0, len(entries))}
+	for _, e := range entries {
+		byt, err := e.block.Encode()
+		if err != nil {
+			return err
+		}
+		st.Blocks = append(st.Blocks, byt)
+	}
+	byt, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.db.Set(raftStateKey, byt)
+}
+
+// load returns the saved term, vote and blocks, the term is 0 if nothing has been saved.
+func (s *logStore) load() (uint64, peer.ID, []*Block, error) {
+	byt, err := s.db.Get(raftStateKey)
+	if err != nil || byt == nil {
+		return 0, "", nil, err
+	}
+	st := new(persistentState)
+	err = json.Unmarshal(byt, st)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	blocks := make([]*Block, 0, len(st.Blocks))
+	for _, b := range st.Blocks {
+		block, err := DecodeBlock(b)
+		if err != nil {
+			return 0, "", nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return st.Term, st.VotedFor, blocks, nil
+}
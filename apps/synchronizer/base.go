@@ -7,59 +7,81 @@ import (
 	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/core/tripod"
 	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
 )
 
+// syncFullHistory fetches the missing blocks from all the boot nodes in batches and executes them,
+// it repeats until no peer is higher than the local chain.
 func (b *Synchronizer) syncFullHistory() error {
-	logrus.Info("start to sync history from other node")
+	logrus.Info("start to sync history from other nodes")
 
-	resp, err := b.requestBlocks(nil)
-	if err != nil {
-		return err
-	}
-	if resp.Err != nil {
-		return resp.Err
-	}
-
-	for resp.MissingRange != nil {
-		// todo: the missing range maybe very huge and we need fetch them multiple times
-		// the remote node will return new Missing blocks-range in this response.
-		resp, err = b.requestBlocks(resp.MissingRange)
+	for {
+		peers, target, err := b.handshakePeers()
 		if err != nil {
 			return err
 		}
-
-		if resp.Err != nil {
-			return resp.Err
+		if len(peers) == 0 {
+			logrus.Info("history is synced")
+			return nil
 		}
 
-		if resp.BlocksByt != nil {
-			blocks, err := DecodeBlocks(resp.BlocksByt)
-			if err != nil {
-				return err
-			}
-
-			err = b.syncHistoryBlocks(blocks)
+		end, err := b.Chain.GetEndBlock()
+		if err != nil {
+			return err
+		}
+		b.progress.start(end.Height, target)
+		err = b.downloader.download(peers, end.Height+1, target, end.Hash, func(blocks []*Block) error {
+			err := b.syncHistoryBlocks(blocks)
 			if err != nil {
 				return err
 			}
-
-			resp.MissingRange = nil
+			b.progress.update(blocks[len(blocks)-1].Height)
+			return nil
+		})
+		b.progress.stop()
+		if err != nil {
+			return err
 		}
 	}
+}
 
-	return nil
+// handshakePeers returns the boot nodes higher than the local chain, and the highest end height of them.
+func (b *Synchronizer) handshakePeers() ([]*syncPeer, BlockNum, error) {
+	var (
+		peers  []*syncPeer
+		target BlockNum
+		errs   int
+	)
+	bootNodes := b.P2pNetwork.GetBootNodes()
+	for _, bootNode := range bootNodes {
+		resp, err := b.requestBlocks(bootNode, nil)
+		if err == nil && resp.Err != nil {
+			err = resp.Err
+		}
+		if err != nil {
+			logrus.Warnf("handshake with peer(%s) failed: %v", bootNode, err)
+			errs++
+			continue
+		}
+		if resp.MissingRange == nil {
+			continue
+		}
+		peers = append(peers, &syncPeer{ID: bootNode, EndHeight: resp.MissingRange.EndHeight})
+		if resp.MissingRange.EndHeight > target {
+			target = resp.MissingRange.EndHeight
+		}
+	}
+	if errs == len(bootNodes) {
+		return nil, 0, PeerUnreachable
+	}
+	return peers, target, nil
 }
 
 func (b *Synchronizer) syncHistoryBlocks(blocks []*Block) error {
 	for _, block := range blocks {
 		logrus.Trace("sync history block is ", block.Hash.String())
 
-		err := b.RangeList(func(tri *Tripod) error {
-			if !tri.VerifyBlock(block) {
-				return BlockIllegal(block.Hash)
-			}
-			return nil
-		})
+		err := b.verifyBlock(block)
 		if err != nil {
 			return err
 		}
@@ -69,14 +91,30 @@ func (b *Synchronizer) syncHistoryBlocks(blocks []*Block) error {
 			return err
 		}
 
+		b.State.StartBlock(block.Hash)
 		err = b.Execute(block)
 		if err != nil {
 			return err
 		}
+
+		// the txns may be received before catching up while the node is running.
+		err = b.Pool.Reset(block.Txns)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func (b *Synchronizer) verifyBlock(block *Block) error {
+	return b.RangeList(func(tri *Tripod) error {
+		if !tri.VerifyBlock(block) {
+			return BlockIllegal(block.Hash)
+		}
+		return nil
+	})
+}
+
 func (b *Synchronizer) handleHsReq(byt []byte) ([]byte, error) {
 	remoteReq, err := DecodeHsRequest(byt)
 	if err != nil {
@@ -107,7 +145,7 @@ func (b *Synchronizer) handleHsReq(byt []byte) ([]byte, error) {
 	return hsResp.Encode()
 }
 
-func (b *Synchronizer) requestBlocks(fetchRange *BlocksRange) (*HandShakeResp, error) {
+func (b *Synchronizer) requestBlocks(peerID peerstore.ID, fetchRange *BlocksRange) (*HandShakeResp, error) {
 	hs, err := b.NewHsReq(fetchRange)
 	if err != nil {
 		return nil, err
@@ -122,7 +160,7 @@ func (b *Synchronizer) requestBlocks(fetchRange *BlocksRange) (*HandShakeResp, e
 		return nil, err
 	}
 
-	respByt, err := b.P2pNetwork.RequestPeer(b.P2pNetwork.GetBootNodes()[0], HandshakeCode, byt)
+	respByt, err := b.P2pNetwork.RequestPeer(peerID, HandshakeCode, byt)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +176,7 @@ func (b *Synchronizer) compareMissingRange(remoteInfo *HandShakeInfo) (*BlocksRa
 }
 
 func (b *Synchronizer) getMissingBlocks(remoteReq *HandShakeRequest) ([]byte, error) {
-	fetchRange := remoteReq.FetchRange
+	fetchRange := remoteReq.FetchRange.capped()
 	blocks, err := b.Chain.GetRangeBlocks(fetchRange.StartHeight, fetchRange.EndHeight)
 	if err != nil {
 		return nil, err
@@ -146,6 +184,20 @@ func (b *Synchronizer) getMissingBlocks(remoteReq *HandShakeRequest) ([]byte, er
 	return EncodeBlocks(blocks)
 }
 
+// handleBlocksReq serves at most MaxSyncBatch blocks in the range.
+func (b *Synchronizer) handleBlocksReq(byt []byte) ([]byte, error) {
+	br, err := DecodeBlocksRange(byt)
+	if err != nil {
+		return nil, err
+	}
+	br = br.capped()
+	blocks, err := b.Chain.GetRangeBlocks(br.StartHeight, br.EndHeight)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeBlocks(blocks)
+}
+
 func (b *Synchronizer) handleSyncTxnsReq(byt []byte) ([]byte, error) {
 	txnsReq, err := DecodeTxnsRequest(byt)
 	if err != nil {
@@ -160,6 +212,13 @@ func (b *Synchronizer) handleSyncTxnsReq(byt []byte) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		// the txn maybe has been packed into a block.
+		if stxn == nil && b.TxDB.ExistTxn(hash) {
+			stxn, err = b.TxDB.GetTxn(hash)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		if stxn != nil {
 			txns = append(txns, stxn)
@@ -169,7 +228,7 @@ func (b *Synchronizer) handleSyncTxnsReq(byt []byte) ([]byte, error) {
 	}
 
 	// request the node of block-producer for missingTxnHashes
-	if txnsReq.BlockProducer != b.P2pNetwork.LocalID() {
+	if len(missingTxnHashes) > 0 && txnsReq.BlockProducer != b.P2pNetwork.LocalID() {
 		stxns, err := b.requestTxns(txnsReq.BlockProducer, txnsReq.BlockProducer, missingTxnHashes)
 		if err != nil {
 			return nil, err
@@ -190,6 +249,12 @@ func (b *Synchronizer) handleSyncTxnsReq(byt []byte) ([]byte, error) {
 }
 
 func (b *Synchronizer) requestTxns(connectPeer, blockProducer peerstore.ID, txnHashes []Hash) (SignedTxns, error) {
+	return RequestTxns(b.P2pNetwork, connectPeer, blockProducer, txnHashes)
+}
+
+// RequestTxns fetches txns from the peer by SyncTxnsCode,
+// the peer requests the block producer for the txns which it does not have.
+func RequestTxns(p2pNetwork p2p.P2pNetwork, connectPeer, blockProducer peerstore.ID, txnHashes []Hash) (SignedTxns, error) {
 	txnsRequest := TxnsRequest{
 		Hashes:        txnHashes,
 		BlockProducer: blockProducer,
@@ -199,9 +264,12 @@ func (b *Synchronizer) requestTxns(connectPeer, blockProducer peerstore.ID, txnH
 		return nil, err
 	}
 
-	respByt, err := b.P2pNetwork.RequestPeer(connectPeer, SyncTxnsCode, reqByt)
+	respByt, err := p2pNetwork.RequestPeer(connectPeer, SyncTxnsCode, reqByt)
 	if err != nil {
 		return nil, err
 	}
+	if len(respByt) == 0 {
+		return nil, nil
+	}
 	return DecodeSignedTxns(respByt)
 }
@@ -0,0 +1,69 @@
+package synchronizer
+
+import (
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	"time"
+)
+
+const (
+	defaultCatchUpInterval = 5 * time.Second
+	defaultCatchUpLag      = 3
+)
+
+// catchUp compares the local chain with peers periodically or when the consensus finds the node behind,
+// and syncs the missing blocks with the block production paused.
+func (b *Synchronizer) catchUp() {
+	interval := defaultCatchUpInterval
+	lag := BlockNum(defaultCatchUpLag)
+	if b.syncCfg != nil {
+		if b.syncCfg.CatchUpInterval > 0 {
+			interval = time.Duration(b.syncCfg.CatchUpInterval) * time.Millisecond
+		}
+		if b.syncCfg.CatchUpLag > 0 {
+			lag = BlockNum(b.syncCfg.CatchUpLag)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var asked bool
+		select {
+		case <-ticker.C:
+		case <-b.Gate.Behind():
+			asked = true
+		}
+		err := b.catchUpOnce(lag, asked)
+		if err != nil {
+			logrus.Error("catch up blocks from peers failed: ", err)
+		}
+	}
+}
+
+// catchUpOnce syncs the missing blocks if the node falls behind peers by more than lag blocks,
+// or the consensus asks for it.
+func (b *Synchronizer) catchUpOnce(lag BlockNum, asked bool) error {
+	if !asked {
+		behind, err := b.isBehind(lag)
+		if err != nil || !behind {
+			return err
+		}
+	}
+
+	b.Gate.Pause()
+	defer b.Gate.Resume()
+	return b.syncFullHistory()
+}
+
+func (b *Synchronizer) isBehind(lag BlockNum) (bool, error) {
+	peers, target, err := b.handshakePeers()
+	if err != nil || len(peers) == 0 {
+		return false, err
+	}
+	end, err := b.Chain.GetEndBlock()
+	if err != nil {
+		return false, err
+	}
+	return target > end.Height+lag, nil
+}
@@ -0,0 +1,228 @@
+package synchronizer
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	. "github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"time"
+)
+
+const (
+	defaultBatchSize      = 128
+	defaultParallel       = 4
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// syncPeer is a peer having the blocks up to EndHeight.
+type syncPeer struct {
+	ID        peer.ID
+	EndHeight BlockNum
+}
+
+// downloader fetches the blocks in batches from several peers in parallel,
+// and hands them over in order.
+type downloader struct {
+	net p2p.P2pNetwork
+	// SyncBlocksCode, or SyncHeadersCode for the light client.
+	code      int
+	batchSize BlockNum
+	parallel  int
+	retries   int
+	timeout   time.Duration
+}
+
+func newDownloader(net p2p.P2pNetwork, cfg *config.SyncConf) *downloader {
+	d := &downloader{
+		net:       net,
+		code:      SyncBlocksCode,
+		batchSize: defaultBatchSize,
+		parallel:  defaultParallel,
+		timeout:   defaultRequestTimeout,
+	}
+	if cfg == nil {
+		return d
+	}
+	if cfg.BatchSize > 0 {
+		d.batchSize = BlockNum(cfg.BatchSize)
+	}
+	if d.batchSize > MaxSyncBatch {
+		d.batchSize = MaxSyncBatch
+	}
+	if cfg.Parallel > 0 {
+		d.parallel = cfg.Parallel
+	}
+	if cfg.RequestTimeout > 0 {
+		d.timeout = time.Duration(cfg.RequestTimeout) * time.Millisecond
+	}
+	d.retries = cfg.Retries
+	return d
+}
+
+type fetchedBatch struct {
+	index  int
+	blocks []*Block
+	err    error
+}
+
+// download fetches the blocks from start to end whose first parent is prevHash,
+// apply is called with every batch in the order of heights.
+func (d *downloader) download(peers []*syncPeer, start, end BlockNum, prevHash Hash, apply func([]*Block) error) error {
+	ranges := d.split(start, end)
+	// the batches in flight never block even if the download fails.
+	results := make(chan *fetchedBatch, d.parallel)
+	fetched := make(map[int][]*Block)
+	var (
+		next     int
+		applied  int
+		inflight int
+	)
+	for applied < len(ranges) {
+		// fetch at most 2*parallel batches ahead of the applied one to bound the memory.
+		for inflight < d.parallel && next < len(ranges) && next < applied+2*d.parallel {
+			go func(index int) {
+				blocks, err := d.fetch(peers, ranges[index], index)
+				results <- &fetchedBatch{index: index, blocks: blocks, err: err}
+			}(next)
+			next++
+			inflight++
+		}
+
+		result := <-results
+		inflight--
+		if result.err != nil {
+			return result.err
+		}
+		fetched[result.index] = result.blocks
+
+		for {
+			blocks, ok := fetched[applied]
+			if !ok {
+				break
+			}
+			if blocks[0].PrevHash != prevHash {
+				return BlockIllegal(blocks[0].Hash)
+			}
+			err := apply(blocks)
+			if err != nil {
+				return err
+			}
+			prevHash = blocks[len(blocks)-1].Hash
+			delete(fetched, applied)
+			applied++
+		}
+	}
+	return nil
+}
+
+func (d *downloader) split(start, end BlockNum) []*BlocksRange {
+	ranges := make([]*BlocksRange, 0)
+	for s := start; s <= end; s += d.batchSize {
+		e := s + d.batchSize - 1
+		if e > end {
+			e = end
+		}
+		ranges = append(ranges, &BlocksRange{StartHeight: s, EndHeight: e})
+	}
+	return ranges
+}
+
+// fetch requests the batch from the peers having it in turn until success or running out of retries.
+func (d *downloader) fetch(peers []*syncPeer, br *BlocksRange, index int) ([]*Block, error) {
+	candidates := make([]peer.ID, 0, len(peers))
+	for _, p := range peers {
+		if p.EndHeight >= br.EndHeight {
+			candidates = append(candidates, p.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, NoPeerToSync
+	}
+
+	var err error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		// spread the batches over the peers.
+		p := candidates[(index+attempt)%len(candidates)]
+		var blocks []*Block
+		blocks, err = d.request(p, br)
+		if err == nil {
+			return blocks, nil
+		}
+		logrus.Warnf("fetch blocks from (%d) to (%d) from peer(%s) failed: %v", br.StartHeight, br.EndHeight, p, err)
+	}
+	return nil, err
+}
+
+func (d *downloader) request(p peer.ID, br *BlocksRange) ([]*Block, error) {
+	reqByt, err := br.Encode()
+	if err != nil {
+		return nil, err
+	}
+	respByt, err := d.call(p, d.code, reqByt)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := DecodeBlocks(respByt)
+	if err != nil {
+		return nil, err
+	}
+	return blocks, checkBatch(blocks, br)
+}
+
+// fetchChunk requests the snapshot chunk from the peers in turn until success or running out of retries.
+func (d *downloader) fetchChunk(peers []peer.ID, req *SnapshotChunkRequest) (*SnapshotChunk, error) {
+	reqByt, err := req.Encode()
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		p := peers[(req.Index+attempt)%len(peers)]
+		var respByt []byte
+		respByt, err = d.call(p, SnapshotChunkCode, reqByt)
+		if err == nil {
+			return DecodeSnapshotChunk(respByt)
+		}
+		logrus.Warnf("fetch snapshot chunk(%d) from peer(%s) failed: %v", req.Index, p, err)
+	}
+	return nil, err
+}
+
+// call requests the peer and gives up after the timeout.
+func (d *downloader) call(p peer.ID, code int, reqByt []byte) ([]byte, error) {
+	type response struct {
+		byt []byte
+		err error
+	}
+	respChan := make(chan response, 1)
+	go func() {
+		byt, err := d.net.RequestPeer(p, code, reqByt)
+		respChan <- response{byt: byt, err: err}
+	}()
+
+	select {
+	case resp := <-respChan:
+		return resp.byt, resp.err
+	case <-time.After(d.timeout):
+		return nil, RequestPeerTimeout
+	}
+}
+
+// checkBatch checks the blocks are exactly the range and linked one by one.
+func checkBatch(blocks []*Block, br *BlocksRange) error {
+	if BlockNum(len(blocks)) != br.EndHeight-br.StartHeight+1 {
+		return NoBlockInHeight(br.StartHeight + BlockNum(len(blocks)))
+	}
+	for i, block := range blocks {
+		if block.Height != br.StartHeight+BlockNum(i) {
+			return BlockIllegal(block.Hash)
+		}
+		if i > 0 && block.PrevHash != blocks[i-1].Hash {
+			return BlockIllegal(block.Hash)
+		}
+	}
+	return nil
+}
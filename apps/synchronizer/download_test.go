@@ -0,0 +1,235 @@
+package synchronizer
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/state"
+	"github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/tripod/dev"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func makeChain(end BlockNum) []*Block {
+	chain := []*Block{{Header: &Header{Hash: HexToHash("genesis")}}}
+	for h := BlockNum(1); h <= end; h++ {
+		chain = append(chain, &Block{Header: &Header{
+			Height:   h,
+			PrevHash: chain[h-1].Hash,
+			Hash:     BytesToHash(Sha256([]byte(strconv.Itoa(int(h))))),
+		}})
+	}
+	return chain
+}
+
+func serveBlocks(node *p2p.SimNode, serve func(br *BlocksRange) ([]*Block, error)) {
+	node.SetHandlers(map[int]dev.P2pHandler{
+		SyncBlocksCode: func(byt []byte) ([]byte, error) {
+			br, err := DecodeBlocksRange(byt)
+			if err != nil {
+				return nil, err
+			}
+			blocks, err := serve(br.capped())
+			if err != nil {
+				return nil, err
+			}
+			return EncodeBlocks(blocks)
+		},
+	})
+}
+
+func TestDownload(t *testing.T) {
+	chain := makeChain(100)
+	net := p2p.NewSimNetwork(1)
+	net.SetLatency(0, time.Millisecond)
+
+	honest := net.NewNode()
+	serveBlocks(honest, func(br *BlocksRange) ([]*Block, error) {
+		return chain[br.StartHeight : br.EndHeight+1], nil
+	})
+	broken := net.NewNode()
+	serveBlocks(broken, func(*BlocksRange) ([]*Block, error) {
+		return nil, errors.New("broken")
+	})
+	slow := net.NewNode()
+	serveBlocks(slow, func(br *BlocksRange) ([]*Block, error) {
+		time.Sleep(time.Second)
+		return chain[br.StartHeight : br.EndHeight+1], nil
+	})
+	forked := net.NewNode()
+	serveBlocks(forked, func(br *BlocksRange) ([]*Block, error) {
+		blocks := makeChain(br.EndHeight)[br.StartHeight : br.EndHeight+1]
+		blocks[len(blocks)-1].PrevHash = NullHash
+		return blocks, nil
+	})
+
+	client := net.NewNode()
+	d := newDownloader(client, &config.SyncConf{BatchSize: 7, Parallel: 3, Retries: 3, RequestTimeout: 100})
+	peers := []*syncPeer{
+		{ID: honest.LocalID(), EndHeight: 100},
+		{ID: broken.LocalID(), EndHeight: 100},
+		{ID: slow.LocalID(), EndHeight: 100},
+		{ID: forked.LocalID(), EndHeight: 100},
+	}
+
+	synced := []*Block{chain[0]}
+	err := d.download(peers, 1, 100, chain[0].Hash, func(blocks []*Block) error {
+		synced = append(synced, blocks...)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, synced, len(chain))
+	for i, block := range synced {
+		assert.Equal(t, chain[i].Hash, block.Hash)
+	}
+
+	// the only peer is broken.
+	err = d.download(peers[1:2], 1, 100, chain[0].Hash, func([]*Block) error {
+		return nil
+	})
+	assert.Error(t, err)
+	// no peer has the blocks.
+	err = d.download(peers[:1], 1, 101, chain[0].Hash, func([]*Block) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, yerror.NoPeerToSync)
+}
+
+type testTripod struct{}
+
+func (testTripod) Name() string {
+	return "test"
+}
+
+func newTestState(t *testing.T, path string) state.IState {
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: path})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(path)
+	})
+	return state.NewSpmtKV(kvdb)
+}
+
+func TestFetchSnapshot(t *testing.T) {
+	src := newTestState(t, "./test-snapshot-src.db")
+	blockHash := HexToHash("0a")
+	src.StartBlock(blockHash)
+	for i := 0; i < 20; i++ {
+		src.Set(testTripod{}, []byte(strconv.Itoa(i)), []byte("value"+strconv.Itoa(i)))
+	}
+	_, err := src.Commit()
+	assert.NoError(t, err)
+	meta, err := src.TakeSnapshot(blockHash, 1, 8)
+	assert.NoError(t, err)
+
+	net := p2p.NewSimNetwork(1)
+	net.SetLatency(0, time.Millisecond)
+	serveChunks := func(node *p2p.SimNode, serve func(index int) (*state.SnapshotChunk, error)) {
+		node.SetHandlers(map[int]dev.P2pHandler{
+			SnapshotChunkCode: func(byt []byte) ([]byte, error) {
+				req, err := DecodeSnapshotChunkRequest(byt)
+				if err != nil {
+					return nil, err
+				}
+				chunk, err := serve(req.Index)
+				if err != nil {
+					return nil, err
+				}
+				return chunk.Encode()
+			},
+		})
+	}
+	honest := net.NewNode()
+	serveChunks(honest, src.GetSnapshotChunk)
+	broken := net.NewNode()
+	serveChunks(broken, func(int) (*state.SnapshotChunk, error) {
+		return nil, errors.New("broken")
+	})
+	evil := net.NewNode()
+	serveChunks(evil, func(index int) (*state.SnapshotChunk, error) {
+		chunk, err := src.GetSnapshotChunk(index)
+		for _, entry := range chunk.Values {
+			entry.Value = []byte("evil")
+		}
+		return chunk, err
+	})
+
+	client := net.NewNode()
+	d := newDownloader(client, &config.SyncConf{Retries: 1, RequestTimeout: 100})
+	fetch := func(servers ...peer.ID) func(int) (*state.SnapshotChunk, error) {
+		return func(index int) (*state.SnapshotChunk, error) {
+			return d.fetchChunk(servers, &SnapshotChunkRequest{StateRoot: meta.StateRoot, Index: index})
+		}
+	}
+
+	dst := newTestState(t, "./test-snapshot-dst.db")
+	assert.NoError(t, dst.RestoreSnapshot(meta, fetch(honest.LocalID(), broken.LocalID())))
+	value, err := dst.Get(testTripod{}, []byte("7"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value7"), value)
+
+	bad := newTestState(t, "./test-snapshot-bad.db")
+	assert.ErrorIs(t, bad.RestoreSnapshot(meta, fetch(evil.LocalID())), yerror.SnapshotMismatched)
+}
+
+type rootVerifierTripod struct {
+	*tripod.Tripod
+}
+
+func (rootVerifierTripod) VerifyStateRoot(*Header) error {
+	return nil
+}
+
+func TestLatestSnapshot(t *testing.T) {
+	net := p2p.NewSimNetwork(1)
+	net.SetLatency(0, time.Millisecond)
+	serveMeta := func(meta *state.SnapshotMeta) *p2p.SimNode {
+		node := net.NewNode()
+		node.SetHandlers(map[int]dev.P2pHandler{
+			SnapshotMetaCode: func([]byte) ([]byte, error) {
+				return json.Marshal(meta)
+			},
+		})
+		return node
+	}
+	agreed := &state.SnapshotMeta{BlockHash: HexToHash("0a"), Height: 10, StateRoot: HexToHash("1a"), Chunks: 2}
+	honest1, honest2 := serveMeta(agreed), serveMeta(agreed)
+	evil := serveMeta(&state.SnapshotMeta{BlockHash: HexToHash("0b"), Height: 20, StateRoot: HexToHash("1b"), Chunks: 1})
+	peers := []*syncPeer{{ID: evil.LocalID()}, {ID: honest1.LocalID()}, {ID: honest2.LocalID()}}
+
+	b := NewSynchronizer(FastSync, &config.SyncConf{Retries: 1, RequestTimeout: 100})
+	b.downloader = newDownloader(net.NewNode(), b.syncCfg)
+	assert.Equal(t, defaultSnapshotPeers, b.snapshotPeers())
+	// the higher snapshot served by a single peer is not trusted.
+	meta, servers := b.latestSnapshot(peers, b.snapshotPeers())
+	assert.Equal(t, agreed, meta)
+	assert.Equal(t, []peer.ID{honest1.LocalID(), honest2.LocalID()}, servers)
+	meta, servers = b.latestSnapshot(peers, 1)
+	assert.Equal(t, BlockNum(20), meta.Height)
+	assert.Equal(t, []peer.ID{evil.LocalID()}, servers)
+	meta, _ = b.latestSnapshot(peers, 3)
+	assert.Nil(t, meta)
+
+	// the snapshot is only restored when a tripod verifies the StateRoot.
+	land := tripod.NewLand()
+	verifier := rootVerifierTripod{tripod.NewTripodWithName("verifier")}
+	for _, tri := range []*tripod.Tripod{b.Tripod, verifier.Tripod} {
+		tri.SetLand(land)
+	}
+	b.SetInstance(b)
+	land.SetTripods(b.Tripod)
+	assert.Empty(t, b.stateRootVerifiers())
+	verifier.SetInstance(verifier)
+	land.SetTripods(verifier.Tripod)
+	assert.Len(t, b.stateRootVerifiers(), 1)
+}
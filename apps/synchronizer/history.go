@@ -3,9 +3,11 @@ package synchronizer
 import (
 	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
 	. "github.com/yu-org/yu/core/keypair"
 	. "github.com/yu-org/yu/core/tripod"
 	. "github.com/yu-org/yu/core/types"
+	"sync"
 )
 
 const (
@@ -17,22 +19,57 @@ const (
 type Synchronizer struct {
 	*Tripod
 	syncMode int
+	syncCfg  *config.SyncConf
+
+	downloader *downloader
+	progress   *progress
+
+	snapshotLock sync.RWMutex
 }
 
-func NewSynchronizer(syncMode int) *Synchronizer {
+func NewSynchronizer(syncMode int, syncCfg *config.SyncConf) *Synchronizer {
 	tri := NewTripodWithName("synchronizer")
-	fh := &Synchronizer{Tripod: tri, syncMode: syncMode}
+	fh := &Synchronizer{Tripod: tri, syncMode: syncMode, syncCfg: syncCfg, progress: new(progress)}
 	tri.SetInit(fh)
-	tri.SetP2pHandler(HandshakeCode, fh.handleHsReq).SetP2pHandler(SyncTxnsCode, fh.handleSyncTxnsReq)
+	tri.SetBlockCycle(fh)
+	tri.SetP2pHandler(HandshakeCode, fh.handleHsReq).
+		SetP2pHandler(SyncTxnsCode, fh.handleSyncTxnsReq).
+		SetP2pHandler(SyncBlocksCode, fh.handleBlocksReq).
+		SetP2pHandler(SnapshotMetaCode, fh.handleSnapshotMetaReq).
+		SetP2pHandler(SnapshotChunkCode, fh.handleSnapshotChunkReq).
+		SetP2pHandler(SyncHeadersCode, fh.handleHeadersReq).
+		SetP2pHandler(StateProofCode, fh.handleStateProofReq)
 	return fh
 }
 
+// Progress returns the progress of syncing history.
+func (b *Synchronizer) Progress() SyncProgress {
+	return b.progress.get()
+}
+
 func (b *Synchronizer) InitChain() {
+	b.downloader = newDownloader(b.P2pNetwork, b.syncCfg)
 	b.defineGenesis()
 	b.syncHistory()
+	if len(b.P2pNetwork.GetBootNodes()) > 0 {
+		go b.catchUp()
+	}
 }
 
 func (b *Synchronizer) defineGenesis() {
+	gensisBlock := genesisBlock()
+	err := b.Chain.SetGenesis(gensisBlock)
+	if err != nil {
+		logrus.Panic("set genesis block failed: ", err)
+	}
+	err = b.Chain.Finalize(gensisBlock.Hash)
+	if err != nil {
+		logrus.Panic("finalize genesis block failed: ", err)
+	}
+}
+
+// genesisBlock is the same on all the nodes, the light clients start from it as well.
+func genesisBlock() *Block {
 	// FIXME: must NOT generate private key onchain.
 	rootPubkey, rootPrivkey := GenSrKeyWithSecret([]byte("root"))
 	genesisHash := HexToHash("genesis")
@@ -41,22 +78,13 @@ func (b *Synchronizer) defineGenesis() {
 		logrus.Panic("sign genesis block failed: ", err)
 	}
 
-	gensisBlock := &Block{
+	return &Block{
 		Header: &Header{
 			Hash:           genesisHash,
 			MinerPubkey:    rootPubkey.BytesWithType(),
 			MinerSignature: signer,
 		},
 	}
-
-	err = b.Chain.SetGenesis(gensisBlock)
-	if err != nil {
-		logrus.Panic("set genesis block failed: ", err)
-	}
-	err = b.Chain.Finalize(genesisHash)
-	if err != nil {
-		logrus.Panic("finalize genesis block failed: ", err)
-	}
 }
 
 func (b *Synchronizer) syncHistory() {
@@ -70,8 +98,11 @@ func (b *Synchronizer) syncHistory() {
 			logrus.Panic("sync full history failed, err: ", err)
 		}
 	case FastSync:
-
+		err := b.syncFastHistory()
+		if err != nil {
+			logrus.Panic("sync fast history failed, err: ", err)
+		}
 	case LightSync:
-
+		// the light nodes only keep the headers, see LightClient.
 	}
 }
@@ -0,0 +1,444 @@
+package synchronizer
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/env"
+	. "github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"sync"
+	"time"
+)
+
+// the number of latest headers kept by the light client.
+const lightHeadersWindow = 1024
+
+// HeaderVerifier verifies the headers synced by the light client in the order of heights,
+// it is provided by the consensus, such as poa.NewLightVerifier.
+type HeaderVerifier interface {
+	VerifyHeader(header *Header) error
+	// VerifyStateRoot checks the StateRoot of a verified header is committed by the consensus,
+	// StateRoot is filled after the miner signs the block, so VerifyHeader does not cover it.
+	VerifyStateRoot(header *Header) error
+}
+
+// LightClient syncs only the headers from the boot nodes and verifies them by the consensus,
+// the Readings of its tripods read the state from full nodes with the proofs against the committed StateRoot in headers.
+type LightClient struct {
+	net        p2p.P2pNetwork
+	verifier   HeaderVerifier
+	downloader *downloader
+	land       *Land
+	interval   time.Duration
+
+	genesis *Header
+	// the latest verified headers in order of heights.
+	headers     []*Header
+	headersLock sync.RWMutex
+
+	syncLock sync.Mutex
+	stop     chan struct{}
+}
+
+// NewLightClient makes a light client over the network whose boot nodes are full nodes,
+// the tripods only serve their Readings.
+func NewLightClient(net p2p.P2pNetwork, verifier HeaderVerifier, syncCfg *config.SyncConf, tripodInstances ...interface{}) (*LightClient, error) {
+	d := newDownloader(net, syncCfg)
+	d.code = SyncHeadersCode
+	lc := &LightClient{
+		net:        net,
+		verifier:   verifier,
+		downloader: d,
+		land:       NewLand(),
+		interval:   defaultCatchUpInterval,
+		genesis:    genesisBlock().Header,
+		stop:       make(chan struct{}),
+	}
+	if syncCfg != nil && syncCfg.CatchUpInterval > 0 {
+		lc.interval = time.Duration(syncCfg.CatchUpInterval) * time.Millisecond
+	}
+
+	chainEnv := &env.ChainEnv{
+		State:      &lightState{lc: lc},
+		P2pNetwork: net,
+	}
+	tripods := make([]*Tripod, 0, len(tripodInstances))
+	for i, v := range tripodInstances {
+		tri := ResolveTripod(v)
+		tri.SetChainEnv(chainEnv)
+		tri.SetLand(lc.land)
+		tri.SetInstance(tripodInstances[i])
+		tripods = append(tripods, tri)
+	}
+	lc.land.SetTripods(tripods...)
+	for _, v := range tripodInstances {
+		err := Inject(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lc, nil
+}
+
+// Start syncs the headers periodically until Stop.
+func (lc *LightClient) Start() {
+	go func() {
+		ticker := time.NewTicker(lc.interval)
+		defer ticker.Stop()
+		for {
+			err := lc.Sync()
+			if err != nil {
+				logrus.Error("light client syncs headers failed: ", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-lc.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (lc *LightClient) Stop() {
+	close(lc.stop)
+}
+
+// Sync fetches and verifies the headers until no boot node is higher.
+func (lc *LightClient) Sync() error {
+	lc.syncLock.Lock()
+	defer lc.syncLock.Unlock()
+	for {
+		peers, target, err := lc.handshakePeers()
+		if err != nil {
+			return err
+		}
+		if len(peers) == 0 {
+			return nil
+		}
+		end := lc.EndHeader()
+		err = lc.downloader.download(peers, end.Height+1, target, end.Hash, func(blocks []*Block) error {
+			for _, block := range blocks {
+				err := lc.verifier.VerifyHeader(block.Header)
+				if err != nil {
+					return err
+				}
+				lc.appendHeader(block.Header)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handshakePeers returns the boot nodes higher than the local headers, and the highest end height of them.
+func (lc *LightClient) handshakePeers() ([]*syncPeer, BlockNum, error) {
+	end := lc.EndHeader()
+	hs := &HandShakeRequest{Info: &HandShakeInfo{
+		GenesisBlockHash: lc.genesis.Hash,
+		EndHeight:        end.Height,
+		EndBlockHash:     end.Hash,
+	}}
+	byt, err := hs.Encode()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		peers  []*syncPeer
+		target BlockNum
+		errs   int
+	)
+	bootNodes := lc.net.GetBootNodes()
+	for _, bootNode := range bootNodes {
+		resp, err := lc.handshake(bootNode, byt)
+		if err != nil {
+			logrus.Warnf("handshake with peer(%s) failed: %v", bootNode, err)
+			errs++
+			continue
+		}
+		if resp.MissingRange == nil {
+			continue
+		}
+		peers = append(peers, &syncPeer{ID: bootNode, EndHeight: resp.MissingRange.EndHeight})
+		if resp.MissingRange.EndHeight > target {
+			target = resp.MissingRange.EndHeight
+		}
+	}
+	if errs == len(bootNodes) {
+		return nil, 0, PeerUnreachable
+	}
+	return peers, target, nil
+}
+
+func (lc *LightClient) handshake(p peer.ID, reqByt []byte) (*HandShakeResp, error) {
+	respByt, err := lc.downloader.call(p, HandshakeCode, reqByt)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := DecodeHsResp(respByt)
+	if err != nil {
+		return nil, err
+	}
+	return resp, resp.Err
+}
+
+// EndHeader returns the latest verified header.
+func (lc *LightClient) EndHeader() *Header {
+	lc.headersLock.RLock()
+	defer lc.headersLock.RUnlock()
+	if len(lc.headers) == 0 {
+		return lc.genesis
+	}
+	return lc.headers[len(lc.headers)-1]
+}
+
+// GetHeader returns the verified header at the height, nil if it is not synced or too old.
+func (lc *LightClient) GetHeader(height BlockNum) *Header {
+	if height == 0 {
+		return lc.genesis
+	}
+	lc.headersLock.RLock()
+	defer lc.headersLock.RUnlock()
+	if len(lc.headers) == 0 || height < lc.headers[0].Height {
+		return nil
+	}
+	idx := int(height - lc.headers[0].Height)
+	if idx >= len(lc.headers) {
+		return nil
+	}
+	return lc.headers[idx]
+}
+
+func (lc *LightClient) appendHeader(header *Header) {
+	lc.headersLock.Lock()
+	defer lc.headersLock.Unlock()
+	lc.headers = append(lc.headers, header)
+	if len(lc.headers) > lightHeadersWindow {
+		lc.headers = lc.headers[1:]
+	}
+}
+
+// replaceHeader replaces the verified header of the same hash, such as the one with its commit.
+func (lc *LightClient) replaceHeader(header *Header) {
+	lc.headersLock.Lock()
+	defer lc.headersLock.Unlock()
+	for i := len(lc.headers) - 1; i >= 0; i-- {
+		if lc.headers[i].Hash == header.Hash {
+			lc.headers[i] = header
+			return
+		}
+	}
+}
+
+// Read runs the Reading of tripod, whose state is read from full nodes and verified.
+func (lc *LightClient) Read(rdCall *RdCall) (*context.ResponseData, error) {
+	ctx, err := context.NewReadContext(rdCall)
+	if err != nil {
+		return nil, err
+	}
+
+	rd, err := lc.land.GetReading(rdCall.TripodName, rdCall.FuncName)
+	if err != nil {
+		return nil, err
+	}
+	rd(ctx)
+	return ctx.Response(), nil
+}
+
+// GetWithProof fetches the latest value of key from the boot nodes in turn,
+// and verifies it against the state root of the header it is proved with.
+func (lc *LightClient) GetWithProof(triName NameString, key []byte) (*StateProof, error) {
+	req := &StateProofRequest{Tripod: triName.Name(), Key: key}
+	reqByt, err := req.Encode()
+	if err != nil {
+		return nil, err
+	}
+	err = PeerUnreachable
+	for _, bootNode := range lc.net.GetBootNodes() {
+		var proof *StateProof
+		proof, err = lc.requestProof(bootNode, triName, key, reqByt)
+		if err == nil {
+			return proof, nil
+		}
+		logrus.Warnf("get state proof from peer(%s) failed: %v", bootNode, err)
+	}
+	return nil, err
+}
+
+func (lc *LightClient) requestProof(p peer.ID, triName NameString, key, reqByt []byte) (*StateProof, error) {
+	respByt, err := lc.downloader.call(p, StateProofCode, reqByt)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := DecodeStateProofResp(respByt)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Proof == nil || !resp.Proof.Matches(triName, key) {
+		return nil, StateProofIllegal
+	}
+
+	header := lc.GetHeader(resp.Height)
+	if header == nil && resp.Height > lc.EndHeader().Height {
+		err = lc.Sync()
+		if err != nil {
+			return nil, err
+		}
+		header = lc.GetHeader(resp.Height)
+	}
+	if header == nil {
+		return nil, NoBlockInHeight(resp.Height)
+	}
+	if header.Hash != resp.Proof.BlockHash {
+		return nil, StateProofIllegal
+	}
+	header, err = lc.committedHeader(p, header)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Proof.Verify(header.StateRoot) {
+		return nil, StateProofIllegal
+	}
+	return resp.Proof, nil
+}
+
+// committedHeader returns the header whose StateRoot is committed, the header may be synced before
+// its commit is saved by the peers, then it is fetched from the peer again.
+func (lc *LightClient) committedHeader(p peer.ID, header *Header) (*Header, error) {
+	if lc.verifier.VerifyStateRoot(header) == nil {
+		return header, nil
+	}
+	blocks, err := lc.downloader.request(p, &BlocksRange{StartHeight: header.Height, EndHeight: header.Height})
+	if err != nil {
+		return nil, err
+	}
+	fetched := blocks[0].Header
+	if fetched.Hash != header.Hash {
+		return nil, StateProofIllegal
+	}
+	err = lc.verifier.VerifyStateRoot(fetched)
+	if err != nil {
+		return nil, err
+	}
+	lc.replaceHeader(fetched)
+	return fetched, nil
+}
+
+// handleHeadersReq serves the headers of at most MaxSyncBatch blocks in the range to the light clients.
+func (b *Synchronizer) handleHeadersReq(byt []byte) ([]byte, error) {
+	br, err := DecodeBlocksRange(byt)
+	if err != nil {
+		return nil, err
+	}
+	br = br.capped()
+	blocks, err := b.Chain.GetRangeBlocks(br.StartHeight, br.EndHeight)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*Block, 0, len(blocks))
+	for _, block := range blocks {
+		headers = append(headers, &Block{Header: block.Header})
+	}
+	return EncodeBlocks(headers)
+}
+
+func (b *Synchronizer) handleStateProofReq(byt []byte) ([]byte, error) {
+	req, err := DecodeStateProofRequest(byt)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := b.State.GetWithProof(tripodName(req.Tripod), req.Key)
+	if err != nil {
+		return nil, err
+	}
+	// the block may be committed but not appended into chain yet.
+	block, err := b.Chain.GetBlock(proof.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	resp := &StateProofResp{Height: block.Height, Proof: proof}
+	return resp.Encode()
+}
+
+type tripodName string
+
+func (n tripodName) Name() string {
+	return string(n)
+}
+
+// lightState reads the latest state of full nodes through the light client, it is read-only.
+type lightState struct {
+	lc *LightClient
+}
+
+func (s *lightState) Get(triName NameString, key []byte) ([]byte, error) {
+	proof, err := s.lc.GetWithProof(triName, key)
+	if err != nil {
+		return nil, err
+	}
+	return proof.Value, nil
+}
+
+// GetFinalized returns the latest value, since the light client only follows the latest state.
+func (s *lightState) GetFinalized(triName NameString, key []byte) ([]byte, error) {
+	return s.Get(triName, key)
+}
+
+func (s *lightState) Exist(triName NameString, key []byte) bool {
+	value, _ := s.Get(triName, key)
+	return value != nil
+}
+
+func (s *lightState) GetByBlockHash(NameString, []byte, Hash) ([]byte, error) {
+	return nil, LightUnsupported
+}
+
+func (s *lightState) GetWithProof(triName NameString, key []byte) (*StateProof, error) {
+	return s.lc.GetWithProof(triName, key)
+}
+
+func (s *lightState) Set(NameString, []byte, []byte) {}
+
+func (s *lightState) Delete(NameString, []byte) {}
+
+func (s *lightState) Commit() ([]byte, error) {
+	return nil, LightUnsupported
+}
+
+func (s *lightState) NextTxn() {}
+
+func (s *lightState) Discard() {}
+
+func (s *lightState) DiscardAll() {}
+
+func (s *lightState) StartBlock(Hash) {}
+
+func (s *lightState) FinalizeBlock(Hash) {}
+
+func (s *lightState) RevertTo(Hash) error {
+	return LightUnsupported
+}
+
+func (s *lightState) TakeSnapshot(Hash, BlockNum, int) (*SnapshotMeta, error) {
+	return nil, LightUnsupported
+}
+
+func (s *lightState) GetSnapshot() (*SnapshotMeta, error) {
+	return nil, LightUnsupported
+}
+
+func (s *lightState) GetSnapshotChunk(int) (*SnapshotChunk, error) {
+	return nil, LightUnsupported
+}
+
+func (s *lightState) RestoreSnapshot(*SnapshotMeta, func(int) (*SnapshotChunk, error)) error {
+	return LightUnsupported
+}
@@ -0,0 +1,203 @@
+package synchronizer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/tripod/dev"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lightTripod struct {
+	*Tripod
+}
+
+func newLightTripod() *lightTripod {
+	lt := &lightTripod{Tripod: NewTripodWithName("test")}
+	lt.SetReadings(lt.GetValue)
+	return lt
+}
+
+func (lt *lightTripod) GetValue(ctx *context.ReadContext) {
+	value, err := lt.Get([]byte(ctx.ParamsStr()))
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	ctx.DataOk("text/plain", value)
+}
+
+// lightServer serves the headers and state proofs like a full node.
+type lightServer struct {
+	lock    sync.Mutex
+	st      state.IState
+	headers []*Header
+	// the headers are served before they are committed.
+	uncommitted bool
+}
+
+func (s *lightServer) commit(t *testing.T, kvs map[string]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	prev := s.headers[len(s.headers)-1]
+	height := prev.Height + 1
+	header := &Header{
+		Height:   height,
+		PrevHash: prev.Hash,
+		Hash:     BytesToHash(Sha256([]byte(strconv.Itoa(int(height))))),
+	}
+	s.st.StartBlock(header.Hash)
+	for k, v := range kvs {
+		s.st.Set(testTripod{}, []byte(k), []byte(v))
+	}
+	root, err := s.st.Commit()
+	assert.NoError(t, err)
+	header.StateRoot = BytesToHash(root)
+	if !s.uncommitted {
+		header.Proof = fakeCommit(header)
+	}
+	s.headers = append(s.headers, header)
+}
+
+func (s *lightServer) commitAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, header := range s.headers[1:] {
+		header.Proof = fakeCommit(header)
+	}
+}
+
+// fakeCommit stands for the commit of validators on the StateRoot.
+func fakeCommit(header *Header) []byte {
+	return Sha256(append(header.Hash.Bytes(), header.StateRoot.Bytes()...))
+}
+
+func (s *lightServer) handlers(forge bool) map[int]dev.P2pHandler {
+	return map[int]dev.P2pHandler{
+		HandshakeCode: func(byt []byte) ([]byte, error) {
+			req, err := DecodeHsRequest(byt)
+			if err != nil {
+				return nil, err
+			}
+			s.lock.Lock()
+			end := s.headers[len(s.headers)-1]
+			s.lock.Unlock()
+			info := &HandShakeInfo{GenesisBlockHash: s.headers[0].Hash, EndHeight: end.Height, EndBlockHash: end.Hash}
+			missing, err := info.Compare(req.Info)
+			if err != nil {
+				return nil, err
+			}
+			return (&HandShakeResp{MissingRange: missing}).Encode()
+		},
+		SyncHeadersCode: func(byt []byte) ([]byte, error) {
+			br, err := DecodeBlocksRange(byt)
+			if err != nil {
+				return nil, err
+			}
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			blocks := make([]*Block, 0)
+			for _, header := range s.headers[br.StartHeight : br.EndHeight+1] {
+				blocks = append(blocks, &Block{Header: header})
+			}
+			return EncodeBlocks(blocks)
+		},
+		StateProofCode: func(byt []byte) ([]byte, error) {
+			req, err := DecodeStateProofRequest(byt)
+			if err != nil {
+				return nil, err
+			}
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			proof, err := s.st.GetWithProof(tripodName(req.Tripod), req.Key)
+			if err != nil {
+				return nil, err
+			}
+			if forge {
+				proof.Value = []byte("evil")
+			}
+			end := s.headers[len(s.headers)-1]
+			return (&StateProofResp{Height: end.Height, Proof: proof}).Encode()
+		},
+	}
+}
+
+type countVerifier struct {
+	heights []BlockNum
+}
+
+func (v *countVerifier) VerifyHeader(header *Header) error {
+	v.heights = append(v.heights, header.Height)
+	return nil
+}
+
+func (v *countVerifier) VerifyStateRoot(header *Header) error {
+	if !bytes.Equal(header.Proof, fakeCommit(header)) {
+		return yerror.StateRootUncommitted
+	}
+	return nil
+}
+
+func TestLightClient(t *testing.T) {
+	server := &lightServer{
+		st:      newTestState(t, "./test-light.db"),
+		headers: []*Header{genesisBlock().Header},
+	}
+	server.commit(t, map[string]string{"a": "1", "b": "2"})
+	server.commit(t, map[string]string{"a": "3"})
+
+	net := p2p.NewSimNetwork(1)
+	net.SetLatency(0, time.Millisecond)
+	honest := net.NewNode()
+	honest.SetHandlers(server.handlers(false))
+	evil := net.NewNode()
+	evil.SetHandlers(server.handlers(true))
+	client := net.NewNode()
+	client.SetBootNodes(evil.LocalID(), honest.LocalID())
+
+	verifier := new(countVerifier)
+	lc, err := NewLightClient(client, verifier, &config.SyncConf{Retries: 1, RequestTimeout: 100}, newLightTripod())
+	assert.NoError(t, err)
+	assert.NoError(t, lc.Sync())
+	assert.Equal(t, BlockNum(2), lc.EndHeader().Height)
+	assert.Equal(t, []BlockNum{1, 2}, verifier.heights)
+
+	// the proof is against a header not synced yet.
+	server.commit(t, map[string]string{"b": "4"})
+	resp, err := lc.Read(&RdCall{TripodName: "test", FuncName: "GetValue", Params: "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("4"), resp.DataBytes)
+	assert.Equal(t, BlockNum(3), lc.EndHeader().Height)
+	assert.Equal(t, []BlockNum{1, 2, 3}, verifier.heights)
+
+	proof, err := lc.GetWithProof(testTripod{}, []byte("c"))
+	assert.NoError(t, err)
+	assert.Nil(t, proof.Value)
+
+	// the proofs are not trusted until the StateRoot is committed.
+	server.uncommitted = true
+	server.commit(t, map[string]string{"b": "5"})
+	_, err = lc.GetWithProof(testTripod{}, []byte("b"))
+	assert.ErrorIs(t, err, yerror.StateRootUncommitted)
+	// the header synced before its commit is fetched again.
+	server.commitAll()
+	proof, err = lc.GetWithProof(testTripod{}, []byte("b"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("5"), proof.Value)
+	assert.Equal(t, fakeCommit(lc.EndHeader()), lc.EndHeader().Proof)
+
+	// only the forged proof is available.
+	client.SetBootNodes(evil.LocalID())
+	_, err = lc.GetWithProof(testTripod{}, []byte("a"))
+	assert.ErrorIs(t, err, yerror.StateProofIllegal)
+}
@@ -0,0 +1,56 @@
+package synchronizer
+
+import (
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	"sync"
+)
+
+// SyncProgress is the progress of syncing history blocks from peers.
+type SyncProgress struct {
+	Syncing       bool     `json:"syncing"`
+	StartHeight   BlockNum `json:"start_height"`
+	CurrentHeight BlockNum `json:"current_height"`
+	TargetHeight  BlockNum `json:"target_height"`
+}
+
+type progress struct {
+	sync.RWMutex
+	SyncProgress
+}
+
+func (p *progress) start(current, target BlockNum) {
+	p.Lock()
+	defer p.Unlock()
+	p.SyncProgress = SyncProgress{
+		Syncing:       true,
+		StartHeight:   current,
+		CurrentHeight: current,
+		TargetHeight:  target,
+	}
+	logrus.Infof("sync history blocks from height(%d) to height(%d)", current+1, target)
+}
+
+func (p *progress) update(current BlockNum) {
+	p.Lock()
+	defer p.Unlock()
+	p.CurrentHeight = current
+	total := p.TargetHeight - p.StartHeight
+	if total == 0 {
+		return
+	}
+	logrus.Infof("synced history blocks to height(%d), target height(%d), %.2f%%",
+		current, p.TargetHeight, float64(current-p.StartHeight)*100/float64(total))
+}
+
+func (p *progress) stop() {
+	p.Lock()
+	defer p.Unlock()
+	p.Syncing = false
+}
+
+func (p *progress) get() SyncProgress {
+	p.RLock()
+	defer p.RUnlock()
+	return p.SyncProgress
+}
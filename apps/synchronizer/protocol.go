@@ -5,13 +5,22 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 	. "github.com/yu-org/yu/common"
 	"github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/state"
 )
 
 const (
-	HandshakeCode int = 100
-	SyncTxnsCode      = 101
+	HandshakeCode     int = 100
+	SyncTxnsCode          = 101
+	SyncBlocksCode        = 102
+	SnapshotMetaCode      = 103
+	SnapshotChunkCode     = 104
+	SyncHeadersCode       = 105
+	StateProofCode        = 106
 )
 
+// MaxSyncBatch is the max number of blocks served in a request.
+const MaxSyncBatch BlockNum = 1024
+
 type HandShakeRequest struct {
 	FetchRange *BlocksRange
 	Info       *HandShakeInfo
@@ -106,6 +115,25 @@ type BlocksRange struct {
 	EndHeight   BlockNum
 }
 
+// capped limits the range to MaxSyncBatch blocks.
+func (br *BlocksRange) capped() *BlocksRange {
+	end := br.EndHeight
+	if end >= br.StartHeight+MaxSyncBatch {
+		end = br.StartHeight + MaxSyncBatch - 1
+	}
+	return &BlocksRange{StartHeight: br.StartHeight, EndHeight: end}
+}
+
+func (br *BlocksRange) Encode() ([]byte, error) {
+	return json.Marshal(br)
+}
+
+func DecodeBlocksRange(data []byte) (*BlocksRange, error) {
+	var br BlocksRange
+	err := json.Unmarshal(data, &br)
+	return &br, err
+}
+
 type TxnsRequest struct {
 	Hashes        []Hash
 	BlockProducer peer.ID
@@ -119,3 +147,50 @@ func DecodeTxnsRequest(data []byte) (tr TxnsRequest, err error) {
 	err = json.Unmarshal(data, &tr)
 	return
 }
+
+type SnapshotChunkRequest struct {
+	StateRoot Hash
+	Index     int
+}
+
+func (sr *SnapshotChunkRequest) Encode() ([]byte, error) {
+	return json.Marshal(sr)
+}
+
+func DecodeSnapshotChunkRequest(data []byte) (*SnapshotChunkRequest, error) {
+	var sr SnapshotChunkRequest
+	err := json.Unmarshal(data, &sr)
+	return &sr, err
+}
+
+type StateProofRequest struct {
+	Tripod string
+	Key    []byte
+}
+
+func (sr *StateProofRequest) Encode() ([]byte, error) {
+	return json.Marshal(sr)
+}
+
+func DecodeStateProofRequest(data []byte) (*StateProofRequest, error) {
+	var sr StateProofRequest
+	err := json.Unmarshal(data, &sr)
+	return &sr, err
+}
+
+// StateProofResp carries the height of the block which the proof is against,
+// so that the light client knows which header to check it with.
+type StateProofResp struct {
+	Height BlockNum
+	Proof  *StateProof
+}
+
+func (sr *StateProofResp) Encode() ([]byte, error) {
+	return json.Marshal(sr)
+}
+
+func DecodeStateProofResp(data []byte) (*StateProofResp, error) {
+	var sr StateProofResp
+	err := json.Unmarshal(data, &sr)
+	return &sr, err
+}
@@ -0,0 +1,228 @@
+package synchronizer
+
+import (
+	"encoding/json"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+)
+
+// StartBlock takes a snapshot of the state at the end block if it is finalized and at the snapshot interval,
+// the state has not been changed by the new block yet.
+func (b *Synchronizer) StartBlock(*Block) {
+	if b.syncCfg == nil || b.syncCfg.SnapshotInterval == 0 {
+		return
+	}
+	end, err := b.Chain.GetEndBlock()
+	if err != nil {
+		logrus.Error("get end block error: ", err)
+		return
+	}
+	if end.Height == 0 || uint64(end.Height)%b.syncCfg.SnapshotInterval != 0 {
+		return
+	}
+	finalized, err := b.Chain.LastFinalized()
+	if err != nil || finalized.Hash != end.Hash {
+		return
+	}
+
+	b.snapshotLock.Lock()
+	defer b.snapshotLock.Unlock()
+	meta, err := b.State.TakeSnapshot(end.Hash, end.Height, b.syncCfg.SnapshotChunkSize)
+	if err != nil {
+		logrus.Errorf("take snapshot at height(%d) error: %v", end.Height, err)
+		return
+	}
+	logrus.Infof("take snapshot at height(%d), state root(%s), %d chunks", meta.Height, meta.StateRoot.String(), meta.Chunks)
+}
+
+func (b *Synchronizer) EndBlock(*Block) {}
+
+func (b *Synchronizer) FinalizeBlock(*Block) {}
+
+// syncFastHistory restores the latest snapshot of peers instead of executing all the blocks before it,
+// then syncs the blocks after the snapshot in full.
+func (b *Synchronizer) syncFastHistory() error {
+	end, err := b.Chain.GetEndBlock()
+	if err != nil {
+		return err
+	}
+	// the snapshot is only restored into an empty state.
+	if end.Height == 0 {
+		err = b.syncSnapshot()
+		if err != nil {
+			return err
+		}
+	}
+	return b.syncFullHistory()
+}
+
+// the min number of peers serving the same snapshot by default.
+const defaultSnapshotPeers = 2
+
+// StateRootVerifier checks the StateRoot of a header is committed by the validators,
+// it is implemented by the consensus tripod, such as poa with BFT finality.
+type StateRootVerifier interface {
+	VerifyStateRoot(header *Header) error
+}
+
+func (b *Synchronizer) syncSnapshot() error {
+	verifiers := b.stateRootVerifiers()
+	if len(verifiers) == 0 {
+		logrus.Info("no consensus commits the state root, sync history in full")
+		return nil
+	}
+	peers, _, err := b.handshakePeers()
+	if err != nil || len(peers) == 0 {
+		return err
+	}
+	meta, servers := b.latestSnapshot(peers, b.snapshotPeers())
+	if meta == nil {
+		logrus.Info("no snapshot agreed by peers, sync history in full")
+		return nil
+	}
+	logrus.Infof("sync snapshot at height(%d) from %d peers", meta.Height, len(servers))
+
+	// the blocks before the snapshot are only verified and stored, not executed.
+	genesis, err := b.Chain.GetGenesis()
+	if err != nil {
+		return err
+	}
+	b.progress.start(0, meta.Height)
+	err = b.downloader.download(peers, 1, meta.Height, genesis.Hash, func(blocks []*Block) error {
+		for _, block := range blocks {
+			err := b.verifyBlock(block)
+			if err != nil {
+				return err
+			}
+			err = b.Chain.AppendBlock(block)
+			if err != nil {
+				return err
+			}
+		}
+		b.progress.update(blocks[len(blocks)-1].Height)
+		return nil
+	})
+	b.progress.stop()
+	if err != nil {
+		return err
+	}
+
+	header, err := b.Chain.GetBlockByHeight(meta.Height)
+	if err != nil {
+		return err
+	}
+	if header.Hash != meta.BlockHash || header.StateRoot != meta.StateRoot {
+		return SnapshotMismatched
+	}
+	// the snapshot is trusted only if its StateRoot is committed by the validators.
+	for _, verifier := range verifiers {
+		err = verifier.VerifyStateRoot(header.Header)
+		if err != nil {
+			return err
+		}
+	}
+	err = b.State.RestoreSnapshot(meta, func(index int) (*SnapshotChunk, error) {
+		return b.downloader.fetchChunk(servers, &SnapshotChunkRequest{StateRoot: meta.StateRoot, Index: index})
+	})
+	if err != nil {
+		return err
+	}
+	return b.Chain.Finalize(meta.BlockHash)
+}
+
+func (b *Synchronizer) stateRootVerifiers() []StateRootVerifier {
+	verifiers := make([]StateRootVerifier, 0)
+	_ = b.RangeList(func(tri *Tripod) error {
+		if verifier, ok := tri.Instance.(StateRootVerifier); ok {
+			verifiers = append(verifiers, verifier)
+		}
+		return nil
+	})
+	return verifiers
+}
+
+func (b *Synchronizer) snapshotPeers() int {
+	if b.syncCfg == nil || b.syncCfg.SnapshotPeers == 0 {
+		return defaultSnapshotPeers
+	}
+	return b.syncCfg.SnapshotPeers
+}
+
+// latestSnapshot asks the peers for their snapshots, returns the highest one served by at least minPeers peers
+// and the peers having it, so that a single peer cannot make the node restore its snapshot.
+func (b *Synchronizer) latestSnapshot(peers []*syncPeer, minPeers int) (*SnapshotMeta, []peer.ID) {
+	var (
+		metas   []SnapshotMeta
+		servers = make(map[SnapshotMeta][]peer.ID)
+	)
+	for _, p := range peers {
+		byt, err := b.downloader.call(p.ID, SnapshotMetaCode, nil)
+		if err != nil {
+			logrus.Warnf("get snapshot from peer(%s) failed: %v", p.ID, err)
+			continue
+		}
+		if len(byt) == 0 {
+			continue
+		}
+		meta := new(SnapshotMeta)
+		err = json.Unmarshal(byt, meta)
+		if err != nil {
+			logrus.Warnf("decode snapshot from peer(%s) failed: %v", p.ID, err)
+			continue
+		}
+		if _, ok := servers[*meta]; !ok {
+			metas = append(metas, *meta)
+		}
+		servers[*meta] = append(servers[*meta], p.ID)
+	}
+
+	var latest *SnapshotMeta
+	for i, meta := range metas {
+		if len(servers[meta]) < minPeers {
+			continue
+		}
+		if latest == nil || meta.Height > latest.Height {
+			latest = &metas[i]
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	return latest, servers[*latest]
+}
+
+func (b *Synchronizer) handleSnapshotMetaReq([]byte) ([]byte, error) {
+	b.snapshotLock.RLock()
+	defer b.snapshotLock.RUnlock()
+	meta, err := b.State.GetSnapshot()
+	if err != nil || meta == nil {
+		return nil, err
+	}
+	return json.Marshal(meta)
+}
+
+func (b *Synchronizer) handleSnapshotChunkReq(byt []byte) ([]byte, error) {
+	req, err := DecodeSnapshotChunkRequest(byt)
+	if err != nil {
+		return nil, err
+	}
+	b.snapshotLock.RLock()
+	defer b.snapshotLock.RUnlock()
+	meta, err := b.State.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	// the snapshot has been replaced by a newer one.
+	if meta == nil || meta.StateRoot != req.StateRoot {
+		return nil, NoSnapshot
+	}
+	chunk, err := b.State.GetSnapshotChunk(req.Index)
+	if err != nil {
+		return nil, err
+	}
+	return chunk.Encode()
+}
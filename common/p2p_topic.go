@@ -5,4 +5,5 @@ const (
 	EndBlockTopic      = "end-block"
 	FinalizeBlockTopic = "finalize-block"
 	UnpackedTxnsTopic  = "unpacked-txns"
+	ConsensusVoteTopic = "consensus-vote"
 )
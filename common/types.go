@@ -6,7 +6,6 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"strconv"
-	"strings"
 	"unsafe"
 )
 
@@ -44,6 +43,13 @@ type (
 		// TODO: make LeiPrice and Tips as a sortable interface.
 		LeiPrice uint64 `json:"lei_price,omitempty"`
 		Tips     uint64 `json:"tips,omitempty"`
+		// Nonce of the caller, it must be the next nonce of the caller account when executing.
+		Nonce uint64 `json:"nonce,omitempty"`
+		// TTL is the lifetime of the txn in txpool in seconds, 0 means using the default TTL of txpool.
+		TTL uint64 `json:"ttl,omitempty"`
+		// Timestamp is the unix nano time when the caller sends the txn, it is signed with the call,
+		// so signed calls must set it by themselves.
+		Timestamp uint64 `json:"timestamp,omitempty"`
 	}
 
 	// RdCall from clients, it is an instance of an 'Read'.
@@ -152,9 +158,11 @@ func HashesToHex(hs []Hash) string {
 }
 
 func HexToHashes(s string) (hs []Hash) {
-	arr := strings.SplitN(s, "", HashLen)
-	for _, hx := range arr {
-		hs = append(hs, HexToHash(hx))
+	// every hash is encoded as "0x" and 2*HashLen hex digits by HashesToHex.
+	hexLen := 2 + 2*HashLen
+	for len(s) >= hexLen {
+		hs = append(hs, HexToHash(s[:hexLen]))
+		s = s[hexLen:]
 	}
 	return
 }
@@ -9,20 +9,37 @@ var TypeErr = errors.New("the type of params error")
 var IntegerOverflow = errors.New("integer overflow")
 
 var NoP2PTopic = errors.New("no p2p topic")
+var PeerUnreachable = errors.New("peer unreachable")
+var RequestPeerTimeout = errors.New("request peer timeout")
+var NoPeerToSync = errors.New("no peer has the blocks to sync")
 
 var NoRunMode = errors.New("no run mode")
 var NoKeyType = errors.New("no key type")
 var NoConvergeType = errors.New("no converge type")
+var NoOrderPolicy = errors.New("no order policy")
 
 var GenesisBlockIllegal = errors.New("genesis block is illegal")
 
+var GrpcUnsupported = errors.New("unsupported by the grpc service")
+
+var ReceiptRootMismatched = errors.New("receipt root mismatched")
+var SnapshotMismatched = errors.New("snapshot mismatches the state root")
+var NoSnapshot = errors.New("no state snapshot")
+var StateProofIllegal = errors.New("state proof mismatches the state root")
+var StateRootUncommitted = errors.New("state root is not committed by validators")
+var LightUnsupported = errors.New("unsupported by the light client")
+
 var NoKvdbType = errors.New("no kvdb type")
 var NoSqlDbType = errors.New("no sqlDB type")
 
 var (
-	PoolOverflow  error = errors.New("pool size is full")
-	TxnTimeoutErr error = errors.New("Txn time out")
-	TxnTooLarge   error = errors.New("the size of txn is too large")
+	PoolOverflow   error = errors.New("txpool is full and the tips of txn are not higher than the lowest txn")
+	TxnTimeoutErr  error = errors.New("txn expired")
+	TxnTooLarge    error = errors.New("the size of txn is too large")
+	TxnUnderpriced error = errors.New("replacement txn underpriced")
+	TxnUnsigned    error = errors.New("txn is not signed")
+	TxnHashIllegal error = errors.New("txn hash mismatches the txn")
+	SigMismatched  error = errors.New("signature does not match the pubkey")
 )
 
 var OutOfLei = errors.New("Lei out")
@@ -35,8 +52,11 @@ func TxnSignatureIllegal(err error) ErrTxnSignatureIllegal {
 	return ErrTxnSignatureIllegal{err: err}
 }
 
-func (ErrTxnSignatureIllegal) Error() string {
-	return errors.Errorf("txn signature illegal").Error()
+func (e ErrTxnSignatureIllegal) Error() string {
+	if e.err == nil {
+		return errors.Errorf("txn signature illegal").Error()
+	}
+	return errors.Errorf("txn signature illegal: %v", e.err).Error()
 }
 
 type ErrBlockIllegal struct {
@@ -51,6 +71,30 @@ func (b ErrBlockIllegal) Error() string {
 	return errors.Errorf("block(%s) illegal", b.BlockHash).Error()
 }
 
+type ErrNoBlockInHeight struct {
+	Height BlockNum
+}
+
+func NoBlockInHeight(height BlockNum) ErrNoBlockInHeight {
+	return ErrNoBlockInHeight{Height: height}
+}
+
+func (b ErrNoBlockInHeight) Error() string {
+	return errors.Errorf("no block in height(%d)", b.Height).Error()
+}
+
+type ErrStateUnreachable struct {
+	BlockHash string
+}
+
+func StateUnreachable(blockHash Hash) ErrStateUnreachable {
+	return ErrStateUnreachable{BlockHash: blockHash.String()}
+}
+
+func (s ErrStateUnreachable) Error() string {
+	return errors.Errorf("state cannot be reverted to block(%s)", s.BlockHash).Error()
+}
+
 type ErrNoTxnInP2P struct {
 	TxnHash string
 }
@@ -63,6 +107,18 @@ func (t ErrNoTxnInP2P) Error() string {
 	return errors.Errorf("no txn(%s) in P2P network", t.TxnHash).Error()
 }
 
+type ErrReceiptNotFound struct {
+	TxnHash string
+}
+
+func ReceiptNotFound(txnHash Hash) ErrReceiptNotFound {
+	return ErrReceiptNotFound{TxnHash: txnHash.String()}
+}
+
+func (r ErrReceiptNotFound) Error() string {
+	return errors.Errorf("receipt of txn(%s) NOT Found", r.TxnHash).Error()
+}
+
 type ErrTripodNotFound struct {
 	TripodName string
 }
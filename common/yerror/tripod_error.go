@@ -35,6 +35,101 @@ func AmountNeg(amount *big.Int) ErrAmountNeg {
 	return ErrAmountNeg{amount: amount}
 }
 
+type ErrLeiPriceTooLow struct {
+	leiPrice     uint64
+	baseLeiPrice uint64
+}
+
+func (lp ErrLeiPriceTooLow) Error() string {
+	return errors.Errorf("lei price(%d) is lower than base lei price(%d)", lp.leiPrice, lp.baseLeiPrice).Error()
+}
+
+func LeiPriceTooLow(leiPrice, baseLeiPrice uint64) ErrLeiPriceTooLow {
+	return ErrLeiPriceTooLow{leiPrice: leiPrice, baseLeiPrice: baseLeiPrice}
+}
+
+type ErrNonceTooLow struct {
+	nonce uint64
+	next  uint64
+}
+
+func (nt ErrNonceTooLow) Error() string {
+	return errors.Errorf("nonce(%d) is lower than the next nonce(%d) of account", nt.nonce, nt.next).Error()
+}
+
+func NonceTooLow(nonce, next uint64) ErrNonceTooLow {
+	return ErrNonceTooLow{nonce: nonce, next: next}
+}
+
+type ErrNonceMismatch struct {
+	nonce uint64
+	next  uint64
+}
+
+func (nm ErrNonceMismatch) Error() string {
+	return errors.Errorf("nonce(%d) is not the next nonce(%d) of account", nm.nonce, nm.next).Error()
+}
+
+func NonceMismatch(nonce, next uint64) ErrNonceMismatch {
+	return ErrNonceMismatch{nonce: nonce, next: next}
+}
+
+// poa errors
+var (
+	ValidatorExists   = errors.New("validator already exists")
+	ValidatorNotFound = errors.New("validator not found")
+	LastValidator     = errors.New("cannot remove the last validator")
+	ProposalNotFound  = errors.New("validator proposal not found")
+	ProposalApproved  = errors.New("validator proposal has been approved")
+	AlreadyVoted      = errors.New("caller has voted for the proposal")
+	ValidatorJailed   = errors.New("validator has been jailed")
+	EvidenceNotFound  = errors.New("double-sign evidence not found")
+	// the light client follows the validator set changes only by the commits of validators.
+	ValidatorsUncommitted = errors.New("validator set change is not committed")
+)
+
+type ErrValidatorOpIllegal struct {
+	op string
+}
+
+func (vo ErrValidatorOpIllegal) Error() string {
+	return errors.Errorf("validator operation(%s) illegal", vo.op).Error()
+}
+
+func ValidatorOpIllegal(op string) ErrValidatorOpIllegal {
+	return ErrValidatorOpIllegal{op: op}
+}
+
+type ErrEvidenceIllegal struct {
+	reason string
+}
+
+func (ei ErrEvidenceIllegal) Error() string {
+	return errors.Errorf("double-sign evidence illegal: %s", ei.reason).Error()
+}
+
+func EvidenceIllegal(reason string) ErrEvidenceIllegal {
+	return ErrEvidenceIllegal{reason: reason}
+}
+
+// dpos errors
+var (
+	CandidateNotFound = errors.New("candidate not found")
+	StakeNotEnough    = errors.New("stake is not enough to unbond")
+	AmountZero        = errors.New("amount must be positive")
+)
+
+// raft errors
+var (
+	MemberExists    = errors.New("raft member already exists")
+	MemberNotFound  = errors.New("raft member not found")
+	LastMember      = errors.New("cannot remove the last raft member")
+	MemberChanging  = errors.New("only one raft member change is allowed in a block")
+	NotRaftLeader   = errors.New("node is not the raft leader")
+	RaftNotStarted  = errors.New("raft node has not started")
+	RaftLogConflict = errors.New("raft log conflicts with the committed blocks")
+)
+
 // hotstuff errors
 var (
 	NoValidQC       = errors.New("Target QC is empty.")
@@ -33,12 +33,31 @@ type KernelConf struct {
 	LogOutput string `toml:"log_output"`
 
 	LeiLimit uint64 `toml:"lei_limit"`
+	// the minimum base price of per lei, 0 means no fee.
+	MinLeiPrice uint64 `toml:"min_lei_price"`
+	// execute txns of a block in parallel speculatively,
+	// the results are the same as executing in order.
+	ParallelExecute bool `toml:"parallel_execute"`
 
 	KVDB KVconf `toml:"kvdb"`
 	//---------component config---------
 	BlockChain BlockchainConf `toml:"block_chain"`
 	Txpool     TxpoolConf     `toml:"txpool"`
 	P2P        P2pConf        `toml:"p2p"`
+	Sync       SyncConf       `toml:"sync"`
+}
+
+// WorkerConf is only used by workers in master-worker mode.
+type WorkerConf struct {
+	// grpc endpoint of master
+	MasterEndpoint string `toml:"master_endpoint"`
+	// worker serves its tripods on the grpc port
+	GrpcPort string `toml:"grpc_port"`
+	// grpc endpoint of this worker, master dials it to call tripods.
+	Endpoint string `toml:"endpoint"`
+	// log out level:
+	// panic, fatal, error, warn, info, debug, trace
+	LogLevel string `toml:"log_level"`
 }
 
 type P2pConf struct {
@@ -62,15 +81,64 @@ type P2pConf struct {
 	NodeKeyBits int `toml:"node_key_bits"`
 	// When use param 'NodeKey', 'NodeKeyFile' will not work.
 	NodeKeyFile string `toml:"node_key_file"`
+
+	// Unpacked txns are gossiped in batch when the batch reaches TxnsBatchSize bytes
+	// or every TxnsBatchInterval milliseconds. 0 means gossiping every txn at once.
+	TxnsBatchSize     int    `toml:"txns_batch_size"`
+	TxnsBatchInterval uint64 `toml:"txns_batch_interval"`
+	// capacity of the LRU cache of seen txns from P2P network, 0 means no cache.
+	SeenTxnsCacheSize int `toml:"seen_txns_cache_size"`
 }
 
 type BlockchainConf struct {
 	ChainDB SqlDbConf `toml:"chain_db"`
+	// how the forks converge into the canonical chain:
+	// "longest": the highest fork wins.
+	// "heaviest": the fork with the most total difficulty wins.
+	// "finalize": the canonical chain only switches to the finalized fork.
+	ConvergeType string `toml:"converge_type"`
 }
 
 type TxpoolConf struct {
 	PoolSize   uint64 `toml:"pool_size"`
 	TxnMaxSize int    `toml:"txn_max_size"`
+	// "ordered": order txns by tips.
+	// "nonced": order txns of every caller by nonce.
+	UnpackedTxnsType string `toml:"unpacked_txns_type"`
+	// default lifetime of txns in seconds if the client does not set TTL, 0 means never expire.
+	TxnTTL uint64 `toml:"txn_ttl"`
+	// max lifetime of txns in seconds, the larger TTL set by client is cut down to it, 0 means no limit.
+	MaxTxnTTL uint64 `toml:"max_txn_ttl"`
+	// interval of sweeping the expired txns in seconds.
+	SweepInterval uint64 `toml:"sweep_interval"`
+	// if PoolDB is set, unpacked txns are also stored into it and reloaded after restarting.
+	PoolDB *SqlDbConf `toml:"pool_db"`
+	// policy of ordering txns when packing:
+	// "fifo", "tips", "fair_share"
+	OrderPolicy string `toml:"order_policy"`
+	// max count of txns of one sender in a block, 0 means no limit.
+	SenderQuota uint64 `toml:"sender_quota"`
+}
+
+type SyncConf struct {
+	// the max number of blocks fetched in a request, 0 means 128.
+	BatchSize uint64 `toml:"batch_size"`
+	// the number of batches fetched from peers at the same time, 0 means 4.
+	Parallel int `toml:"parallel"`
+	// times of retrying a failed batch from other peers.
+	Retries int `toml:"retries"`
+	// timeout of fetching a batch in milliseconds, 0 means 10000.
+	RequestTimeout uint64 `toml:"request_timeout"`
+	// interval of comparing the local chain with peers while running in milliseconds, 0 means 5000.
+	CatchUpInterval uint64 `toml:"catch_up_interval"`
+	// the node pauses producing blocks to catch up when it falls behind peers by more than CatchUpLag blocks.
+	CatchUpLag uint64 `toml:"catch_up_lag"`
+	// take a snapshot of the state every SnapshotInterval finalized blocks for FastSync, 0 means never.
+	SnapshotInterval uint64 `toml:"snapshot_interval"`
+	// the max number of entries in a snapshot chunk, 0 means 4096.
+	SnapshotChunkSize int `toml:"snapshot_chunk_size"`
+	// the min number of peers serving the same snapshot to restore it for FastSync, 0 means 2.
+	SnapshotPeers int `toml:"snapshot_peers"`
 }
 
 func LoadTomlConf(fpath string, cfg interface{}) {
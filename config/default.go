@@ -20,6 +20,10 @@ func InitDefaultCfg() *KernelConf {
 		NodeKey:         "",
 		NodeKeyBits:     0,
 		NodeKeyFile:     "",
+
+		TxnsBatchSize:     512 * 1024,
+		TxnsBatchInterval: 100,
+		SeenTxnsCacheSize: 100000,
 	}
 	cfg.KVDB = KVconf{
 		KvType: "pebble",
@@ -31,10 +35,27 @@ func InitDefaultCfg() *KernelConf {
 			SqlDbType: "sqlite",
 			Dsn:       "chain.db",
 		},
+		ConvergeType: "longest",
 	}
 	cfg.Txpool = TxpoolConf{
 		PoolSize:   2048,
 		TxnMaxSize: 1024000,
+
+		UnpackedTxnsType: "ordered",
+		TxnTTL:           3600,
+		MaxTxnTTL:        86400,
+		SweepInterval:    60,
+		OrderPolicy:      "tips",
+	}
+	cfg.Sync = SyncConf{
+		BatchSize:         128,
+		Parallel:          4,
+		Retries:           3,
+		RequestTimeout:    10000,
+		CatchUpInterval:   5000,
+		CatchUpLag:        3,
+		SnapshotInterval:  1000,
+		SnapshotChunkSize: 4096,
 	}
 	return cfg
 }
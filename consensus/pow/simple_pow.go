@@ -1,90 +1,113 @@
 package pow
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"github.com/sirupsen/logrus"
 	"github.com/yu-org/yu/common"
-	"github.com/yu-org/yu/core/types"
 	"math"
 	"math/big"
 )
 
-func Run(block *types.CompactBlock, target *big.Int, targetBits int64) (nonce int64, hash common.Hash, err error) {
-	var hashInt big.Int
-	nonce = 0
+// the max change of difficulty in one retarget.
+const maxRetargetFactor = 4
 
-	logrus.Info("[[[Mining a new Block!!!]]]")
-	for nonce < math.MaxInt64 {
-		var data []byte
-		data, err = prepareData(block, nonce, targetBits)
-		if err != nil {
-			return
-		}
-		hash = sha256.Sum256(data)
-		if math.Remainder(float64(nonce), 100000) == 0 {
-			logrus.Infof("Hash is \r%x", hash.Bytes())
-		}
-		hashInt.SetBytes(hash.Bytes())
+var maxTarget = new(big.Int).Lsh(big.NewInt(1), 256)
 
-		if hashInt.Cmp(target) == -1 {
-			break
-		} else {
-			nonce++
-		}
+// Target returns the upper bound of the block hash with the difficulty, which is 2^256 / difficulty.
+// So the difficulty is the expected number of hashes for mining a block.
+func Target(difficulty uint64) *big.Int {
+	if difficulty == 0 {
+		difficulty = 1
 	}
-	return
+	return new(big.Int).Div(maxTarget, new(big.Int).SetUint64(difficulty))
 }
 
-func Validate(block *types.CompactBlock, target *big.Int, targetBits int64) bool {
-	var hashInt big.Int
+// Hash returns the block hash by the hash of sealed header and the nonce.
+func Hash(sealHash common.Hash, nonce uint64) common.Hash {
+	data := make([]byte, 0, common.HashLen+8)
+	data = append(data, sealHash.Bytes()...)
+	data = binary.BigEndian.AppendUint64(data, nonce)
+	return sha256.Sum256(data)
+}
 
-	var nonce uint64 = block.Nonce
-	data, err := prepareData(block, int64(nonce), targetBits)
-	if err != nil {
+func Validate(sealHash common.Hash, nonce uint64, hash common.Hash, difficulty uint64) bool {
+	if Hash(sealHash, nonce) != hash {
 		return false
 	}
-	hash := sha256.Sum256(data)
-	hashInt.SetBytes(hash[:])
-
-	return hashInt.Cmp(target) == -1
+	return new(big.Int).SetBytes(hash.Bytes()).Cmp(Target(difficulty)) < 0
 }
 
-func prepareData(block *types.CompactBlock, nonce, targetBits int64) ([]byte, error) {
-	num := block.Timestamp
-	hex1, err := intToHex(int64(num))
-	if err != nil {
-		return nil, err
+// Run searches the nonce in `threads` goroutines until one of them finds it or ctx is done.
+func Run(ctx context.Context, sealHash common.Hash, difficulty uint64, threads int) (nonce uint64, hash common.Hash, err error) {
+	if threads < 1 {
+		threads = 1
 	}
-	hex2, err := intToHex(targetBits)
-	if err != nil {
-		return nil, err
+	target := Target(difficulty)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		nonce uint64
+		hash  common.Hash
 	}
-	hex3, err := intToHex(nonce)
-	if err != nil {
-		return nil, err
+	found := make(chan result, threads)
+
+	logrus.Infof("[[[Mining a new Block!!!]]] difficulty(%d) threads(%d)", difficulty, threads)
+	for i := 0; i < threads; i++ {
+		go func(start uint64) {
+			var hashInt big.Int
+			for i, n := 0, start; n <= math.MaxUint64-uint64(threads); i, n = i+1, n+uint64(threads) {
+				// check the cancellation every 1024 hashes.
+				if i%1024 == 0 {
+					select {
+					case <-ctx.Done():
+						return
+					default:
+					}
+				}
+				h := Hash(sealHash, n)
+				hashInt.SetBytes(h.Bytes())
+				if hashInt.Cmp(target) < 0 {
+					found <- result{nonce: n, hash: h}
+					return
+				}
+			}
+		}(uint64(i))
 	}
-	data := bytes.Join(
-		[][]byte{
-			block.PrevHash.Bytes(),
-			block.TxnRoot.Bytes(),
-			hex1,
-			hex2,
-			hex3,
-		},
-		[]byte{},
-	)
 
-	return data, nil
+	select {
+	case r := <-found:
+		return r.nonce, r.hash, nil
+	case <-ctx.Done():
+		return 0, common.NullHash, ctx.Err()
+	}
 }
 
-func intToHex(num int64) ([]byte, error) {
-	buff := new(bytes.Buffer)
-	err := binary.Write(buff, binary.BigEndian, num)
-	if err != nil {
-		return nil, err
+// Retarget adjusts the difficulty by the ratio of the expected span to the actual span of recent blocks,
+// the difficulty changes at most maxRetargetFactor times in one retarget.
+func Retarget(difficulty, actualSpan, expectedSpan uint64) uint64 {
+	if expectedSpan == 0 {
+		return difficulty
 	}
-
-	return buff.Bytes(), nil
+	if actualSpan < expectedSpan/maxRetargetFactor {
+		actualSpan = expectedSpan / maxRetargetFactor
+	}
+	if actualSpan > expectedSpan*maxRetargetFactor {
+		actualSpan = expectedSpan * maxRetargetFactor
+	}
+	if actualSpan == 0 {
+		actualSpan = 1
+	}
+	next := new(big.Int).SetUint64(difficulty)
+	next.Mul(next, new(big.Int).SetUint64(expectedSpan))
+	next.Div(next, new(big.Int).SetUint64(actualSpan))
+	if !next.IsUint64() {
+		return math.MaxUint64
+	}
+	if next.Uint64() == 0 {
+		return 1
+	}
+	return next.Uint64()
 }
@@ -0,0 +1,31 @@
+package pow
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/yu-org/yu/common"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	seal := common.HexToHash("abcd")
+	nonce, hash, err := Run(context.Background(), seal, 1000, 4)
+	assert.NoError(t, err)
+	assert.True(t, Validate(seal, nonce, hash, 1000))
+	assert.False(t, Validate(seal, nonce+1, hash, 1000))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = Run(ctx, seal, 1<<62, 4)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRetarget(t *testing.T) {
+	// blocks are twice as slow as expected
+	assert.Equal(t, uint64(500), Retarget(1000, 200, 100))
+	// blocks are twice as fast as expected
+	assert.Equal(t, uint64(2000), Retarget(1000, 50, 100))
+	// the change is limited
+	assert.Equal(t, uint64(4000), Retarget(1000, 0, 100))
+	assert.Equal(t, uint64(250), Retarget(1000, 1000, 100))
+}
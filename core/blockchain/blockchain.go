@@ -1,6 +1,8 @@
 package blockchain
 
 import (
+	"sync"
+
 	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
 	"github.com/yu-org/yu/config"
@@ -9,9 +11,14 @@ import (
 )
 
 type BlockChain struct {
-	nodeType int
-	chain    ysql.SqlDB
+	nodeType     int
+	chain        ysql.SqlDB
+	convergeType ConvergeType
 	ItxDB
+
+	// guards the canonical chain index
+	headLock     sync.Mutex
+	reorgHandler func(reorg *Reorg) error
 }
 
 func NewBlockChain(nodeType int, cfg *config.BlockchainConf, txdb ItxDB) *BlockChain {
@@ -24,16 +31,30 @@ func NewBlockChain(nodeType int, cfg *config.BlockchainConf, txdb ItxDB) *BlockC
 	if err != nil {
 		logrus.Fatal("create blockchain scheme: ", err)
 	}
+	err = chain.CreateIfNotExist(&CanonicalScheme{})
+	if err != nil {
+		logrus.Fatal("create canonical scheme: ", err)
+	}
+
+	convergeType, err := parseConvergeType(cfg.ConvergeType)
+	if err != nil {
+		logrus.Fatal(err)
+	}
 
 	return &BlockChain{
-		nodeType: nodeType,
-		chain:    chain,
-		ItxDB:    txdb,
+		nodeType:     nodeType,
+		chain:        chain,
+		convergeType: convergeType,
+		ItxDB:        txdb,
 	}
 }
 
 func (bc *BlockChain) ConvergeType() ConvergeType {
-	return Longest
+	return bc.convergeType
+}
+
+func (bc *BlockChain) SetReorgHandler(fn func(reorg *Reorg) error) {
+	bc.reorgHandler = fn
 }
 
 func (bc *BlockChain) NewEmptyBlock() *Block {
@@ -85,7 +106,11 @@ func (bc *BlockChain) AppendBlock(b *Block) error {
 	if err != nil {
 		return err
 	}
-	return bc.ItxDB.SetTxns(b.Txns)
+	err = bc.ItxDB.SetTxns(b.Txns)
+	if err != nil {
+		return err
+	}
+	return bc.chooseHead(cb)
 }
 
 func (bc *BlockChain) appendCompactBlock(b *CompactBlock) error {
@@ -94,6 +119,16 @@ func (bc *BlockChain) appendCompactBlock(b *CompactBlock) error {
 		return err
 	}
 
+	var parents []BlocksScheme
+	err = bc.chain.Db().Where(&BlocksScheme{Hash: b.PrevHash.String()}).Limit(1).Find(&parents).Error
+	if err != nil {
+		return err
+	}
+	bs.TotalDifficulty = b.Difficulty
+	if len(parents) > 0 {
+		bs.TotalDifficulty += parents[0].TotalDifficulty
+	}
+
 	return bc.chain.Db().Create(bs).Error
 }
 
@@ -120,16 +155,14 @@ func (bc *BlockChain) GetBlock(blockHash Hash) (*CompactBlock, error) {
 	return bs.toBlock()
 }
 
+// GetBlockByHeight returns the block on the canonical chain.
 func (bc *BlockChain) GetBlockByHeight(height BlockNum) (*CompactBlock, error) {
-	var bs BlocksScheme
-	err := bc.chain.Db().Where(&BlocksScheme{
-		Height:   height,
-		Finalize: true,
-	}).First(&bs).Error
+	var cs CanonicalScheme
+	err := bc.chain.Db().Where("height = ?", height).First(&cs).Error
 	if err != nil {
 		return nil, err
 	}
-	return bs.toBlock()
+	return bc.GetBlock(HexToHash(cs.Hash))
 }
 
 func (bc *BlockChain) GetAllBlocksByHeight(height BlockNum) ([]*CompactBlock, error) {
@@ -178,26 +211,37 @@ func (bc *BlockChain) Children(prevBlockHash Hash) ([]*CompactBlock, error) {
 }
 
 func (bc *BlockChain) Finalize(blockHash Hash) error {
-	return bc.chain.Db().Model(&BlocksScheme{}).Where(&BlocksScheme{
+	err := bc.chain.Db().Model(&BlocksScheme{}).Where(&BlocksScheme{
 		Hash: blockHash.String(),
 	}).Updates(BlocksScheme{Finalize: true}).Error
+	if err != nil {
+		return err
+	}
+	return bc.followFinalized(blockHash)
 }
 
 func (bc *BlockChain) LastFinalized() (*CompactBlock, error) {
-	var bss []BlocksScheme
+	var bs BlocksScheme
 	err := bc.chain.Db().Model(&BlocksScheme{}).Where(&BlocksScheme{
 		Finalize: true,
-	}).Order("height").Find(&bss).Error
+	}).Order("height desc").First(&bs).Error
 	if err != nil {
 		return nil, err
 	}
-	bs := bss[len(bss)-1]
 	return bs.toBlock()
 }
 
+// GetEndBlock returns the end block of canonical chain.
 func (bc *BlockChain) GetEndBlock() (*CompactBlock, error) {
+	head, err := bc.canonicalHead()
+	if err != nil {
+		return nil, err
+	}
+	if head != nil {
+		return head, nil
+	}
 	var bs BlocksScheme
-	err := bc.chain.Db().Raw("select * from blockchain where height = (select max(height) from blockchain)").First(&bs).Error
+	err = bc.chain.Db().Raw("select * from blockchain where height = (select max(height) from blockchain)").First(&bs).Error
 	if err != nil {
 		return nil, err
 	}
@@ -229,7 +273,8 @@ func (bc *BlockChain) GetAllBlocks() ([]*CompactBlock, error) {
 
 func (bc *BlockChain) GetRangeBlocks(startHeight, endHeight BlockNum) (blocks []*Block, err error) {
 	var bss []BlocksScheme
-	err = bc.chain.Db().Where("height BETWEEN ? AND ?", startHeight, endHeight).Find(&bss).Error
+	canonical := bc.chain.Db().Model(&CanonicalScheme{}).Select("hash").Where("height BETWEEN ? AND ?", startHeight, endHeight)
+	err = bc.chain.Db().Where("hash IN (?)", canonical).Order("height").Find(&bss).Error
 	if err != nil {
 		return
 	}
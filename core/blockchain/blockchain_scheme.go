@@ -22,8 +22,9 @@ type BlocksScheme struct {
 	TxnsHashes string
 	PeerID     string
 
-	LeiLimit uint64
-	LeiUsed  uint64
+	LeiLimit     uint64
+	LeiUsed      uint64
+	BaseLeiPrice uint64
 
 	Finalize bool
 
@@ -34,6 +35,8 @@ type BlocksScheme struct {
 
 	Nonce      uint64
 	Difficulty uint64
+	// sum of the difficulties from genesis block to this block
+	TotalDifficulty uint64
 
 	ProofBlock  string
 	ProofHeight BlockNum
@@ -46,6 +49,16 @@ func (BlocksScheme) TableName() string {
 	return "blockchain"
 }
 
+// CanonicalScheme indexes the blocks of canonical chain by height.
+type CanonicalScheme struct {
+	Height BlockNum `gorm:"primaryKey;autoIncrement:false"`
+	Hash   string
+}
+
+func (CanonicalScheme) TableName() string {
+	return "canonical"
+}
+
 func toBlocksScheme(b *CompactBlock) (BlocksScheme, error) {
 	validators, err := proto.Marshal(ValidatorsToPb(b.Validators))
 	if err != nil {
@@ -66,6 +79,8 @@ func toBlocksScheme(b *CompactBlock) (BlocksScheme, error) {
 		LeiLimit:    b.LeiLimit,
 		LeiUsed:     b.LeiUsed,
 
+		BaseLeiPrice: b.BaseLeiPrice,
+
 		MinerPubkey:    ToHex(b.MinerPubkey),
 		MinerSignature: ToHex(b.MinerSignature),
 
@@ -113,8 +128,9 @@ func (b *BlocksScheme) toBlock() (*CompactBlock, error) {
 		Timestamp:   b.Timestamp,
 		PeerID:      PeerID,
 
-		LeiLimit: b.LeiLimit,
-		LeiUsed:  b.LeiUsed,
+		LeiLimit:     b.LeiLimit,
+		LeiUsed:      b.LeiUsed,
+		BaseLeiPrice: b.BaseLeiPrice,
 
 		MinerPubkey:    FromHex(b.MinerPubkey),
 		MinerSignature: FromHex(b.MinerSignature),
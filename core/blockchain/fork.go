@@ -0,0 +1,297 @@
+package blockchain
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/types"
+	"gorm.io/gorm"
+)
+
+func parseConvergeType(name string) (ConvergeType, error) {
+	switch name {
+	case "", "longest":
+		return Longest, nil
+	case "heaviest":
+		return Heaviest, nil
+	case "finalize":
+		return Finalize, nil
+	default:
+		return 0, NoConvergeType
+	}
+}
+
+// chooseHead makes the new appended block the end of canonical chain if it wins the fork choice.
+func (bc *BlockChain) chooseHead(block *CompactBlock) error {
+	bc.headLock.Lock()
+	head, err := bc.canonicalHead()
+	if err != nil {
+		bc.headLock.Unlock()
+		return err
+	}
+	var reorg *Reorg
+	if head == nil || block.PrevHash == head.Hash {
+		reorg, err = bc.switchHead(block)
+	} else {
+		var better bool
+		better, err = bc.betterThan(block, head)
+		if err == nil && better {
+			reorg, err = bc.switchHead(block)
+		}
+	}
+	bc.headLock.Unlock()
+	if err != nil {
+		return err
+	}
+	return bc.handleReorg(reorg)
+}
+
+// followFinalized switches the canonical chain to the fork of finalized block
+// if it is not on the canonical chain.
+func (bc *BlockChain) followFinalized(blockHash Hash) error {
+	bc.headLock.Lock()
+	canonical, err := bc.isCanonical(blockHash)
+	if err != nil || canonical {
+		bc.headLock.Unlock()
+		return err
+	}
+	block, err := bc.GetBlock(blockHash)
+	if err != nil {
+		bc.headLock.Unlock()
+		return err
+	}
+	newHead, err := bc.bestDescendant(block)
+	if err != nil {
+		bc.headLock.Unlock()
+		return err
+	}
+	reorg, err := bc.switchHead(newHead)
+	bc.headLock.Unlock()
+	if err != nil {
+		return err
+	}
+	return bc.handleReorg(reorg)
+}
+
+func (bc *BlockChain) handleReorg(reorg *Reorg) error {
+	if reorg == nil {
+		return nil
+	}
+	logrus.Warnf("chain reorgs from ancestor block(%s) height(%d), revert %d blocks, apply %d blocks",
+		reorg.Ancestor.Hash.String(), reorg.Ancestor.Height, len(reorg.Reverted), len(reorg.Applied))
+	if bc.reorgHandler == nil {
+		return nil
+	}
+	return bc.reorgHandler(reorg)
+}
+
+// betterThan reports whether the block on another fork should replace the current end block.
+// The finalized blocks are never reverted.
+func (bc *BlockChain) betterThan(block, head *CompactBlock) (bool, error) {
+	finalized, err := bc.lastFinalized()
+	if err != nil {
+		return false, err
+	}
+	if finalized != nil {
+		descends, err := bc.descends(block, finalized)
+		if err != nil || !descends {
+			return false, err
+		}
+	}
+
+	switch bc.convergeType {
+	case Heaviest:
+		blockTd, err := bc.totalDifficulty(block.Hash)
+		if err != nil {
+			return false, err
+		}
+		headTd, err := bc.totalDifficulty(head.Hash)
+		if err != nil {
+			return false, err
+		}
+		return blockTd > headTd, nil
+	case Finalize:
+		// forks only converge by finalization.
+		return false, nil
+	default:
+		return block.Height > head.Height, nil
+	}
+}
+
+// bestDescendant returns the end block of the best fork after the block.
+func (bc *BlockChain) bestDescendant(block *CompactBlock) (*CompactBlock, error) {
+	best := block
+	var bestTd uint64
+	if bc.convergeType == Heaviest {
+		td, err := bc.totalDifficulty(block.Hash)
+		if err != nil {
+			return nil, err
+		}
+		bestTd = td
+	}
+
+	children, err := bc.Children(block.Hash)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		end, err := bc.bestDescendant(child)
+		if err != nil {
+			return nil, err
+		}
+		if bc.convergeType == Heaviest {
+			td, err := bc.totalDifficulty(end.Hash)
+			if err != nil {
+				return nil, err
+			}
+			if td > bestTd {
+				best, bestTd = end, td
+			}
+		} else if end.Height > best.Height {
+			best = end
+		}
+	}
+	return best, nil
+}
+
+// switchHead makes the block the end of canonical chain,
+// returns the reorg if some blocks are dropped from the canonical chain.
+func (bc *BlockChain) switchHead(newHead *CompactBlock) (*Reorg, error) {
+	applied := make([]*CompactBlock, 0)
+	var ancestor *CompactBlock
+	for block := newHead; ; {
+		canonical, err := bc.isCanonical(block.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if canonical {
+			ancestor = block
+			break
+		}
+		applied = append([]*CompactBlock{block}, applied...)
+		if block.Height == 0 {
+			break
+		}
+		prevHash := block.PrevHash
+		block, err = bc.GetBlock(prevHash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// the fork is not connected to the chain yet.
+			logrus.Warnf("parent block(%s) of fork not found", prevHash.String())
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var reverted []*CompactBlock
+	if ancestor != nil {
+		var err error
+		reverted, err = bc.canonicalAbove(ancestor.Height)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var count int64
+		err := bc.chain.Db().Model(&CanonicalScheme{}).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			// never switch to a fork with another genesis block.
+			return nil, GenesisBlockIllegal
+		}
+	}
+
+	err := bc.chain.Db().Transaction(func(tx *gorm.DB) error {
+		if ancestor != nil {
+			err := tx.Where("height > ?", ancestor.Height).Delete(&CanonicalScheme{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, block := range applied {
+			err := tx.Create(&CanonicalScheme{Height: block.Height, Hash: block.Hash.String()}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reverted) == 0 {
+		return nil, nil
+	}
+	return &Reorg{
+		Ancestor: ancestor,
+		Reverted: reverted,
+		Applied:  applied,
+	}, nil
+}
+
+// descends reports whether the block is the ancestor itself or one of its descendants.
+func (bc *BlockChain) descends(block, ancestor *CompactBlock) (bool, error) {
+	var err error
+	for block.Height > ancestor.Height {
+		block, err = bc.GetBlock(block.PrevHash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return block.Hash == ancestor.Hash, nil
+}
+
+func (bc *BlockChain) canonicalHead() (*CompactBlock, error) {
+	var css []CanonicalScheme
+	err := bc.chain.Db().Order("height desc").Limit(1).Find(&css).Error
+	if err != nil || len(css) == 0 {
+		return nil, err
+	}
+	return bc.GetBlock(HexToHash(css[0].Hash))
+}
+
+// canonicalAbove returns the canonical blocks higher than the height, from the highest one.
+func (bc *BlockChain) canonicalAbove(height BlockNum) ([]*CompactBlock, error) {
+	var css []CanonicalScheme
+	err := bc.chain.Db().Where("height > ?", height).Order("height desc").Find(&css).Error
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]*CompactBlock, 0, len(css))
+	for _, cs := range css {
+		block, err := bc.GetBlock(HexToHash(cs.Hash))
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (bc *BlockChain) isCanonical(blockHash Hash) (bool, error) {
+	var count int64
+	err := bc.chain.Db().Model(&CanonicalScheme{}).Where(&CanonicalScheme{Hash: blockHash.String()}).Count(&count).Error
+	return count > 0, err
+}
+
+func (bc *BlockChain) lastFinalized() (*CompactBlock, error) {
+	var bss []BlocksScheme
+	err := bc.chain.Db().Where(&BlocksScheme{Finalize: true}).Order("height desc").Limit(1).Find(&bss).Error
+	if err != nil || len(bss) == 0 {
+		return nil, err
+	}
+	return bss[0].toBlock()
+}
+
+func (bc *BlockChain) totalDifficulty(blockHash Hash) (uint64, error) {
+	var bs BlocksScheme
+	err := bc.chain.Db().Select("total_difficulty").Where(&BlocksScheme{Hash: blockHash.String()}).First(&bs).Error
+	return bs.TotalDifficulty, err
+}
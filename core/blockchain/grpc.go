@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type GrpcBlockChain struct {
+	chain IBlockChain
+}
+
+func NewGrpcBlockChain(chain IBlockChain) *GrpcBlockChain {
+	return &GrpcBlockChain{chain: chain}
+}
+
+func (g *GrpcBlockChain) GetGenesis(context.Context, *emptypb.Empty) (*goproto.BlockResponse, error) {
+	block, err := g.chain.GetGenesis()
+	if err != nil {
+		return &goproto.BlockResponse{Error: err.Error()}, nil
+	}
+	return &goproto.BlockResponse{Block: block.Compact().ToPb()}, nil
+}
+
+func (g *GrpcBlockChain) SetGenesis(_ context.Context, cb *goproto.CompactBlock) (*goproto.Err, error) {
+	block, err := g.compactToBlock(cb)
+	if err != nil {
+		return makeErr(err), nil
+	}
+	err = g.chain.SetGenesis(block)
+	return makeErr(err), nil
+}
+
+func (g *GrpcBlockChain) AppendBlock(_ context.Context, cb *goproto.CompactBlock) (*goproto.Err, error) {
+	block, err := g.compactToBlock(cb)
+	if err != nil {
+		return makeErr(err), nil
+	}
+	err = g.chain.AppendBlock(block)
+	return makeErr(err), nil
+}
+
+func (g *GrpcBlockChain) GetBlock(_ context.Context, hash *goproto.BlockHash) (*goproto.BlockResponse, error) {
+	block, err := g.chain.GetBlock(common.BytesToHash(hash.Hash))
+	if err != nil {
+		return &goproto.BlockResponse{Error: err.Error()}, nil
+	}
+	return &goproto.BlockResponse{Block: block.ToPb()}, nil
+}
+
+func (g *GrpcBlockChain) ExistsBlock(_ context.Context, hash *goproto.BlockHash) (*goproto.Bool, error) {
+	ok, err := g.chain.ExistsBlock(common.BytesToHash(hash.Hash))
+	if err != nil {
+		return nil, err
+	}
+	return &goproto.Bool{Ok: ok}, nil
+}
+
+func (g *GrpcBlockChain) UpdateBlock(_ context.Context, cb *goproto.CompactBlock) (*goproto.Err, error) {
+	err := g.chain.UpdateBlock(CompactBlockFromPb(cb))
+	return makeErr(err), nil
+}
+
+func (g *GrpcBlockChain) Children(_ context.Context, hash *goproto.BlockHash) (*goproto.BlocksResponse, error) {
+	blocks, err := g.chain.Children(common.BytesToHash(hash.Hash))
+	if err != nil {
+		return &goproto.BlocksResponse{Error: err.Error()}, nil
+	}
+	return &goproto.BlocksResponse{Blocks: compactBlocksToPb(blocks)}, nil
+}
+
+func (g *GrpcBlockChain) Finalize(_ context.Context, hash *goproto.BlockHash) (*goproto.Err, error) {
+	err := g.chain.Finalize(common.BytesToHash(hash.Hash))
+	return makeErr(err), nil
+}
+
+func (g *GrpcBlockChain) GetFinalizedBlock(context.Context, *emptypb.Empty) (*goproto.BlockResponse, error) {
+	block, err := g.chain.LastFinalized()
+	if err != nil {
+		return &goproto.BlockResponse{Error: err.Error()}, nil
+	}
+	return &goproto.BlockResponse{Block: block.ToPb()}, nil
+}
+
+func (g *GrpcBlockChain) GetEndBlock(context.Context, *emptypb.Empty) (*goproto.BlockResponse, error) {
+	block, err := g.chain.GetEndBlock()
+	if err != nil {
+		return &goproto.BlockResponse{Error: err.Error()}, nil
+	}
+	return &goproto.BlockResponse{Block: block.ToPb()}, nil
+}
+
+func (g *GrpcBlockChain) GetRangeBlocks(_ context.Context, req *goproto.RangeRequest) (*goproto.BlocksResponse, error) {
+	blocks, err := g.chain.GetRangeBlocks(common.BlockNum(req.StartHeight), common.BlockNum(req.EndHeight))
+	if err != nil {
+		return &goproto.BlocksResponse{Error: err.Error()}, nil
+	}
+	cbs := make([]*CompactBlock, 0)
+	for _, block := range blocks {
+		cbs = append(cbs, block.Compact())
+	}
+	return &goproto.BlocksResponse{Blocks: compactBlocksToPb(cbs)}, nil
+}
+
+// compactToBlock fills the txns of the compact block from TxDB.
+func (g *GrpcBlockChain) compactToBlock(pb *goproto.CompactBlock) (*Block, error) {
+	cb := CompactBlockFromPb(pb)
+	block := &Block{Header: cb.Header}
+	for _, hash := range cb.TxnsHashes {
+		txn, err := g.chain.GetTxn(hash)
+		if err != nil {
+			return nil, err
+		}
+		block.Txns = append(block.Txns, txn)
+	}
+	return block, nil
+}
+
+func compactBlocksToPb(blocks []*CompactBlock) []*goproto.CompactBlock {
+	pbs := make([]*goproto.CompactBlock, 0)
+	for _, block := range blocks {
+		pbs = append(pbs, block.ToPb())
+	}
+	return pbs
+}
+
+func makeErr(err error) *goproto.Err {
+	if err == nil {
+		return &goproto.Err{}
+	}
+	return &goproto.Err{Msg: err.Error()}
+}
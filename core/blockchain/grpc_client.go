@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// GrpcBlockChainClient is used by the workers in MasterWorker mode,
+// it reaches the BlockChain of master by grpc.
+type GrpcBlockChainClient struct {
+	cli goproto.BlockChainClient
+	ItxDB
+}
+
+func NewGrpcBlockChainClient(conn *grpc.ClientConn, txdb ItxDB) *GrpcBlockChainClient {
+	return &GrpcBlockChainClient{
+		cli:   goproto.NewBlockChainClient(conn),
+		ItxDB: txdb,
+	}
+}
+
+func (g *GrpcBlockChainClient) ConvergeType() ConvergeType {
+	return Longest
+}
+
+func (g *GrpcBlockChainClient) NewEmptyBlock() *Block {
+	return &Block{Header: &Header{}, Txns: nil}
+}
+
+func (g *GrpcBlockChainClient) GetGenesis() (*Block, error) {
+	resp, err := g.cli.GetGenesis(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	cb, err := blockFromResp(resp)
+	if err != nil {
+		return nil, err
+	}
+	return g.fillTxns(cb)
+}
+
+func (g *GrpcBlockChainClient) SetGenesis(b *Block) error {
+	resp, err := g.cli.SetGenesis(context.Background(), b.Compact().ToPb())
+	return errFromPb(resp, err)
+}
+
+// AppendBlock stores the txns by TxDB first, because master only receives the compact block.
+func (g *GrpcBlockChainClient) AppendBlock(b *Block) error {
+	err := g.SetTxns(b.Txns)
+	if err != nil {
+		return err
+	}
+	resp, err := g.cli.AppendBlock(context.Background(), b.Compact().ToPb())
+	return errFromPb(resp, err)
+}
+
+func (g *GrpcBlockChainClient) GetBlock(blockHash Hash) (*CompactBlock, error) {
+	resp, err := g.cli.GetBlock(context.Background(), &goproto.BlockHash{Hash: blockHash.Bytes()})
+	if err != nil {
+		return nil, err
+	}
+	return blockFromResp(resp)
+}
+
+// GetBlockByHeight walks back from the last finalized block, because there is no such method in grpc.
+func (g *GrpcBlockChainClient) GetBlockByHeight(height BlockNum) (*CompactBlock, error) {
+	block, err := g.LastFinalized()
+	if err != nil {
+		return nil, err
+	}
+	if height > block.Height {
+		return nil, errors.Errorf("block on height(%d) is not finalized", height)
+	}
+	for block.Height > height {
+		block, err = g.GetBlock(block.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return block, nil
+}
+
+func (g *GrpcBlockChainClient) GetAllBlocksByHeight(height BlockNum) ([]*CompactBlock, error) {
+	resp, err := g.cli.GetRangeBlocks(context.Background(), &goproto.RangeRequest{
+		StartHeight: uint64(height),
+		EndHeight:   uint64(height),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocksFromResp(resp)
+}
+
+func (g *GrpcBlockChainClient) ExistsBlock(blockHash Hash) (bool, error) {
+	resp, err := g.cli.ExistsBlock(context.Background(), &goproto.BlockHash{Hash: blockHash.Bytes()})
+	if err != nil {
+		return false, err
+	}
+	return resp.Ok, nil
+}
+
+func (g *GrpcBlockChainClient) UpdateBlock(b *CompactBlock) error {
+	resp, err := g.cli.UpdateBlock(context.Background(), b.ToPb())
+	return errFromPb(resp, err)
+}
+
+func (g *GrpcBlockChainClient) Children(prevBlockHash Hash) ([]*CompactBlock, error) {
+	resp, err := g.cli.Children(context.Background(), &goproto.BlockHash{Hash: prevBlockHash.Bytes()})
+	if err != nil {
+		return nil, err
+	}
+	return blocksFromResp(resp)
+}
+
+func (g *GrpcBlockChainClient) Finalize(blockHash Hash) error {
+	resp, err := g.cli.Finalize(context.Background(), &goproto.BlockHash{Hash: blockHash.Bytes()})
+	return errFromPb(resp, err)
+}
+
+func (g *GrpcBlockChainClient) LastFinalized() (*CompactBlock, error) {
+	resp, err := g.cli.GetFinalizedBlock(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return blockFromResp(resp)
+}
+
+func (g *GrpcBlockChainClient) GetEndBlock() (*CompactBlock, error) {
+	resp, err := g.cli.GetEndBlock(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return blockFromResp(resp)
+}
+
+func (g *GrpcBlockChainClient) GetAllBlocks() ([]*CompactBlock, error) {
+	return nil, GrpcUnsupported
+}
+
+func (g *GrpcBlockChainClient) GetRangeBlocks(startHeight, endHeight BlockNum) ([]*Block, error) {
+	resp, err := g.cli.GetRangeBlocks(context.Background(), &goproto.RangeRequest{
+		StartHeight: uint64(startHeight),
+		EndHeight:   uint64(endHeight),
+	})
+	if err != nil {
+		return nil, err
+	}
+	cbs, err := blocksFromResp(resp)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]*Block, 0)
+	for _, cb := range cbs {
+		block, err := g.fillTxns(cb)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (g *GrpcBlockChainClient) fillTxns(cb *CompactBlock) (*Block, error) {
+	block := &Block{Header: cb.Header}
+	for _, hash := range cb.TxnsHashes {
+		txn, err := g.GetTxn(hash)
+		if err != nil {
+			return nil, err
+		}
+		block.Txns = append(block.Txns, txn)
+	}
+	return block, nil
+}
+
+func blockFromResp(resp *goproto.BlockResponse) (*CompactBlock, error) {
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return CompactBlockFromPb(resp.Block), nil
+}
+
+func blocksFromResp(resp *goproto.BlocksResponse) ([]*CompactBlock, error) {
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	blocks := make([]*CompactBlock, 0)
+	for _, pb := range resp.Blocks {
+		blocks = append(blocks, CompactBlockFromPb(pb))
+	}
+	return blocks, nil
+}
+
+func errFromPb(pbErr *goproto.Err, err error) error {
+	if err != nil {
+		return err
+	}
+	if pbErr.Msg != "" {
+		return errors.New(pbErr.Msg)
+	}
+	return nil
+}
+
+// SetReorgHandler does nothing, only master reorgs the chain.
+func (g *GrpcBlockChainClient) SetReorgHandler(func(reorg *Reorg) error) {}
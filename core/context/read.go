@@ -42,6 +42,10 @@ func (rc *ReadContext) BindJson(v any) error {
 	return common.BindJsonParams(rc.rdCall.Params, v)
 }
 
+func (rc *ReadContext) ParamsStr() string {
+	return rc.rdCall.Params
+}
+
 func (rc *ReadContext) GetBlockHash() *common.Hash {
 	return rc.BlockHash
 }
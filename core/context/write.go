@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/core/state"
 	. "github.com/yu-org/yu/core/types"
 )
 
@@ -18,6 +19,10 @@ type WriteContext struct {
 	Extra  []byte
 
 	LeiCost uint64
+
+	// State is the chain state seen by the txn, such as its TxnView when txns are executed in parallel.
+	// Nil means the State of ChainEnv.
+	State state.StateKV
 }
 
 func NewWriteContext(stxn *SignedTxn, block *Block) (*WriteContext, error) {
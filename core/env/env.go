@@ -19,6 +19,10 @@ type ChainEnv struct {
 	Sub *Subscription
 
 	Execute ExecuteFn
+	// MinLeiPrice is the lowest base lei price of blocks, 0 means the fee market is disabled.
+	MinLeiPrice uint64
 
 	P2pNetwork p2p.P2pNetwork
+
+	Gate *SyncGate
 }
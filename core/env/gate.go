@@ -0,0 +1,85 @@
+package env
+
+import "sync"
+
+// SyncGate stops the kernel from producing blocks while the node is catching up the blocks from peers.
+type SyncGate struct {
+	lock sync.Mutex
+	cond *sync.Cond
+
+	// a block cycle is running
+	cycling bool
+	// the running block cycle waits for catching up
+	waiting bool
+	// catching up blocks from peers
+	catching bool
+	// increased after every catching up
+	round uint64
+
+	behind chan struct{}
+}
+
+func NewSyncGate() *SyncGate {
+	g := &SyncGate{behind: make(chan struct{}, 1)}
+	g.cond = sync.NewCond(&g.lock)
+	return g
+}
+
+// EnterCycle blocks until the node is not catching up, kernel calls it before every block cycle.
+func (g *SyncGate) EnterCycle() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for g.catching {
+		g.cond.Wait()
+	}
+	g.cycling = true
+}
+
+func (g *SyncGate) ExitCycle() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.cycling = false
+	g.cond.Broadcast()
+}
+
+// Pause stops new block cycles and waits for the running one to end or to wait for catching up.
+func (g *SyncGate) Pause() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.catching = true
+	for g.cycling && !g.waiting {
+		g.cond.Wait()
+	}
+}
+
+// Resume lets the block cycles go on after catching up.
+func (g *SyncGate) Resume() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.catching = false
+	g.round++
+	g.cond.Broadcast()
+}
+
+// WaitCatchUp is called in a block cycle when the consensus finds the node behind its peers,
+// it asks for catching up and blocks until the next catching up ends.
+func (g *SyncGate) WaitCatchUp() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	round := g.round
+	g.waiting = true
+	g.cond.Broadcast()
+	select {
+	case g.behind <- struct{}{}:
+	default:
+	}
+	for g.round == round {
+		g.cond.Wait()
+	}
+	g.waiting = false
+}
+
+// Behind is notified when the consensus asks for catching up.
+func (g *SyncGate) Behind() <-chan struct{} {
+	return g.behind
+}
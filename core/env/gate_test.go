@@ -0,0 +1,71 @@
+package env
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSyncGatePause(t *testing.T) {
+	g := NewSyncGate()
+	g.EnterCycle()
+
+	paused := make(chan struct{})
+	go func() {
+		g.Pause()
+		close(paused)
+	}()
+	select {
+	case <-paused:
+		t.Fatal("pause before the block cycle ends")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.ExitCycle()
+	<-paused
+
+	entered := make(chan struct{})
+	go func() {
+		g.EnterCycle()
+		close(entered)
+	}()
+	select {
+	case <-entered:
+		t.Fatal("enter a block cycle while catching up")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.Resume()
+	<-entered
+	g.ExitCycle()
+}
+
+func TestSyncGateWaitCatchUp(t *testing.T) {
+	g := NewSyncGate()
+	g.EnterCycle()
+
+	caughtUp := make(chan struct{})
+	go func() {
+		g.WaitCatchUp()
+		close(caughtUp)
+	}()
+
+	<-g.Behind()
+	// the block cycle is waiting, so it does not block pausing.
+	g.Pause()
+	select {
+	case <-caughtUp:
+		t.Fatal("stop waiting before catching up")
+	case <-time.After(50 * time.Millisecond):
+	}
+	g.Resume()
+	<-caughtUp
+	g.ExitCycle()
+
+	select {
+	case <-g.Behind():
+		t.Fatal("behind is notified without asking")
+	default:
+	}
+	assert.Equal(t, uint64(1), g.round)
+}
@@ -0,0 +1,146 @@
+package kernel
+
+import (
+	"crypto/sha256"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/config"
+	. "github.com/yu-org/yu/core/types"
+	"go.uber.org/atomic"
+	"sync"
+	"time"
+)
+
+// txnsGossip batches the outgoing unpacked txns by size and time window,
+// and remembers the recently seen txns to drop the duplicated ones from P2P network.
+type txnsGossip struct {
+	sync.Mutex
+	batch      SignedTxns
+	batchBytes int
+
+	maxBytes int
+	interval time.Duration
+	flushCh  chan struct{}
+
+	// hashes of txns and the hashes of raw messages
+	seen *lru.Cache[Hash, struct{}]
+
+	metrics GossipMetrics
+}
+
+// GossipMetrics counts the unpacked txns gossiped in P2P network.
+type GossipMetrics struct {
+	PublishedBatches atomic.Uint64 `json:"published_batches"`
+	PublishedTxns    atomic.Uint64 `json:"published_txns"`
+	ReceivedBatches  atomic.Uint64 `json:"received_batches"`
+	ReceivedTxns     atomic.Uint64 `json:"received_txns"`
+	// the duplicated batches are dropped before decoding.
+	DuplicatedBatches atomic.Uint64 `json:"duplicated_batches"`
+	DuplicatedTxns    atomic.Uint64 `json:"duplicated_txns"`
+}
+
+func newTxnsGossip(cfg *P2pConf) *txnsGossip {
+	g := &txnsGossip{
+		maxBytes: cfg.TxnsBatchSize,
+		interval: time.Duration(cfg.TxnsBatchInterval) * time.Millisecond,
+		flushCh:  make(chan struct{}, 1),
+	}
+	if cfg.SeenTxnsCacheSize > 0 {
+		seen, err := lru.New[Hash, struct{}](cfg.SeenTxnsCacheSize)
+		if err != nil {
+			logrus.Fatal("init seen txns cache error: ", err)
+		}
+		g.seen = seen
+	}
+	return g
+}
+
+// add puts the txn into the outgoing batch, the batch is flushed once it is full.
+func (g *txnsGossip) add(stxn *SignedTxn) {
+	g.markSeen(stxn.TxnHash)
+
+	g.Lock()
+	g.batch = append(g.batch, stxn)
+	g.batchBytes += stxn.Size()
+	full := g.interval == 0 || g.batchBytes >= g.maxBytes
+	g.Unlock()
+
+	if full {
+		select {
+		case g.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (g *txnsGossip) take() SignedTxns {
+	g.Lock()
+	defer g.Unlock()
+	batch := g.batch
+	g.batch = nil
+	g.batchBytes = 0
+	return batch
+}
+
+// seenMsg reports whether the raw message has been received, and remembers it.
+func (g *txnsGossip) seenMsg(byt []byte) bool {
+	if g.seen == nil {
+		return false
+	}
+	contains, _ := g.seen.ContainsOrAdd(sha256.Sum256(byt), struct{}{})
+	return contains
+}
+
+// seenTxn reports whether the txn has been received or sent,
+// the txns from P2P are remembered by markSeen only after they are checked.
+func (g *txnsGossip) seenTxn(hash Hash) bool {
+	if g.seen == nil {
+		return false
+	}
+	return g.seen.Contains(hash)
+}
+
+func (g *txnsGossip) markSeen(hash Hash) {
+	if g.seen != nil {
+		g.seen.Add(hash, struct{}{})
+	}
+}
+
+// forget drops the txn from the seen cache, such as the txn replaced by fee.
+func (g *txnsGossip) forget(hash Hash) {
+	if g.seen != nil {
+		g.seen.Remove(hash)
+	}
+}
+
+// GossipTxns publishes the batched unpacked txns into P2P network.
+func (k *Kernel) GossipTxns() {
+	var tick <-chan time.Time
+	if k.gossip.interval > 0 {
+		ticker := time.NewTicker(k.gossip.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+		case <-k.gossip.flushCh:
+		}
+		txns := k.gossip.take()
+		if len(txns) == 0 {
+			continue
+		}
+		err := k.pubUnpackedTxns(txns)
+		if err != nil {
+			logrus.Error("publish unpacked txns error: ", err)
+			continue
+		}
+		k.gossip.metrics.PublishedBatches.Inc()
+		k.gossip.metrics.PublishedTxns.Add(uint64(len(txns)))
+	}
+}
+
+func (k *Kernel) GossipMetrics() *GossipMetrics {
+	return &k.gossip.metrics
+}
@@ -0,0 +1,148 @@
+package kernel
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/keypair"
+	"github.com/yu-org/yu/core/txpool"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	ytime "github.com/yu-org/yu/utils/time"
+	"testing"
+)
+
+var gossipPubkey, gossipPrivkey = keypair.GenSrKeyWithSecret([]byte("gossip"))
+
+func newGossipTxn(t *testing.T, nonce uint64) *SignedTxn {
+	call := incr(false)
+	call.Nonce = nonce
+	call.Timestamp = ytime.NowNanoTsU64()
+	hash, err := call.Hash()
+	assert.NoError(t, err)
+	sig, err := gossipPrivkey.SignData(hash)
+	assert.NoError(t, err)
+	stxn, err := NewSignedTxn(call, gossipPubkey.BytesWithType(), sig)
+	assert.NoError(t, err)
+	return stxn
+}
+
+func newGossipKernel(t *testing.T, path string, p2pCfg *config.P2pConf) *Kernel {
+	k := newTestKernel(t, path)
+	net := p2p.NewMockP2p(1)
+	net.AddTopic(UnpackedTxnsTopic)
+	k.P2pNetwork = net
+	cfg := config.InitDefaultCfg()
+	k.Pool = txpool.WithDefaultChecks(FullNode, &cfg.Txpool, k.TxDB)
+	k.gossip = newTxnsGossip(p2pCfg)
+	return k
+}
+
+// receive publishes the txns as a message from P2P network and accepts it.
+func receive(t *testing.T, k *Kernel, txns ...*SignedTxn) {
+	byt, err := SignedTxns(txns).Encode()
+	assert.NoError(t, err)
+	assert.NoError(t, k.P2pNetwork.PubP2P(UnpackedTxnsTopic, byt))
+	assert.NoError(t, k.AcceptUnpkgTxns())
+}
+
+func pooled(t *testing.T, k *Kernel, stxn *SignedTxn) bool {
+	got, err := k.Pool.GetTxn(stxn.TxnHash)
+	assert.NoError(t, err)
+	return got != nil
+}
+
+func TestAcceptDedup(t *testing.T) {
+	k := newGossipKernel(t, "./test-gossip-dedup.db", &config.P2pConf{SeenTxnsCacheSize: 16})
+	tx0, tx1 := newGossipTxn(t, 0), newGossipTxn(t, 1)
+
+	// the forged copy has a wrong signature.
+	forged := *tx0
+	forged.Signature = append([]byte{}, tx1.Signature...)
+	forged.TxnHash, _ = forged.Hash()
+	receive(t, k, &forged)
+	assert.False(t, pooled(t, k, &forged))
+	assert.False(t, k.gossip.seenTxn(forged.TxnHash))
+
+	receive(t, k, tx0, tx0)
+	assert.True(t, pooled(t, k, tx0))
+	assert.Equal(t, uint64(1), k.gossip.metrics.DuplicatedTxns.Load())
+
+	// the same message is dropped before decoding.
+	receive(t, k, tx0, tx0)
+	assert.Equal(t, uint64(1), k.gossip.metrics.DuplicatedBatches.Load())
+
+	receive(t, k, tx1, tx0)
+	assert.True(t, pooled(t, k, tx1))
+	assert.Equal(t, uint64(2), k.gossip.metrics.DuplicatedTxns.Load())
+	assert.Equal(t, uint64(4), k.gossip.metrics.ReceivedBatches.Load())
+	assert.Equal(t, uint64(5), k.gossip.metrics.ReceivedTxns.Load())
+}
+
+func TestAcceptForgedTxns(t *testing.T) {
+	k := newGossipKernel(t, "./test-gossip-forged.db", &config.P2pConf{SeenTxnsCacheSize: 16})
+	tx0 := newGossipTxn(t, 0)
+
+	// a valid txn is relayed under a made-up hash.
+	forgedHash := *tx0
+	forgedHash.TxnHash = HexToHash("0a")
+	byt, err := SignedTxns{&forgedHash}.Encode()
+	assert.NoError(t, err)
+	assert.NoError(t, k.P2pNetwork.PubP2P(UnpackedTxnsTopic, byt))
+	assert.Equal(t, TxnHashIllegal, k.AcceptUnpkgTxns())
+	assert.False(t, pooled(t, k, &forgedHash))
+
+	// a valid txn is relayed under a made-up timestamp to get a new hash.
+	call := *tx0.Raw.WrCall
+	call.Timestamp++
+	forgedTs := *tx0
+	forgedTs.Raw = &UnsignedTxn{WrCall: &call}
+	forgedTs.TxnHash, err = forgedTs.Hash()
+	assert.NoError(t, err)
+	receive(t, k, &forgedTs)
+	assert.False(t, pooled(t, k, &forgedTs))
+
+	receive(t, k, tx0)
+	assert.True(t, pooled(t, k, tx0))
+}
+
+func TestGossipBatching(t *testing.T) {
+	tx0, tx1 := newGossipTxn(t, 0), newGossipTxn(t, 1)
+	k := newGossipKernel(t, "./test-gossip-batch.db", &config.P2pConf{
+		TxnsBatchSize: tx0.Size() + tx1.Size(),
+		// flushed only by size in this test.
+		TxnsBatchInterval: 1000 * 1000,
+		SeenTxnsCacheSize: 16,
+	})
+	go k.GossipTxns()
+
+	k.gossip.add(tx0)
+	select {
+	case <-k.gossip.flushCh:
+		t.Fatal("the batch is flushed before it is full")
+	default:
+	}
+	k.gossip.add(tx1)
+
+	byt, err := k.P2pNetwork.SubP2P(UnpackedTxnsTopic)
+	assert.NoError(t, err)
+	txns, err := DecodeSignedTxns(byt)
+	assert.NoError(t, err)
+	assert.Equal(t, SignedTxns{tx0, tx1}.Hashes(), txns.Hashes())
+
+	// the sent txns and the message itself are dropped when they come back.
+	assert.True(t, k.gossip.seenTxn(tx0.TxnHash))
+	assert.True(t, k.gossip.seenMsg(byt))
+}
+
+func TestGossipForgetReplaced(t *testing.T) {
+	g := newTxnsGossip(&config.P2pConf{SeenTxnsCacheSize: 16})
+	replaced := HexToHash("0a")
+	g.markSeen(replaced)
+	assert.True(t, g.seenTxn(replaced))
+
+	// the replaced txn is accepted again from P2P after its replacement is dropped.
+	g.forget(replaced)
+	assert.False(t, g.seenTxn(replaced))
+}
@@ -1,8 +1,8 @@
 package kernel
 
 import (
-	"github.com/sirupsen/logrus"
 	"github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/core"
 	"github.com/yu-org/yu/core/context"
 	. "github.com/yu-org/yu/core/types"
@@ -30,14 +30,118 @@ func (k *Kernel) HandleTxn(signedWrCall *core.SignedWrCall) error {
 		return err
 	}
 
-	go func() {
-		err = k.pubUnpackedTxns(FromArray(stxn))
+	// a replacement which pays not enough is rejected here and never gossiped.
+	err = k.Pool.Insert(stxn)
+	if err != nil {
+		return err
+	}
+
+	k.gossip.add(stxn)
+	return nil
+}
+
+// SimulateResult is the result of a dry-run txn.
+type SimulateResult struct {
+	Events  []*Event `json:"events"`
+	Extra   []byte   `json:"extra"`
+	LeiCost uint64   `json:"lei_cost"`
+	Fee     uint64   `json:"fee"`
+	Tips    uint64   `json:"tips"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// HandleSimulate runs the Writing on top of the latest state without committing anything,
+// the txn is not inserted into txpool either.
+func (k *Kernel) HandleSimulate(signedWrCall *core.SignedWrCall) (*SimulateResult, error) {
+	stxn, err := NewSignedTxn(signedWrCall.Call, signedWrCall.Pubkey, signedWrCall.Signature)
+	if err != nil {
+		return nil, err
+	}
+	wrCall := signedWrCall.Call
+	writing, err := k.land.GetWriting(wrCall.TripodName, wrCall.FuncName)
+	if err != nil {
+		return nil, err
+	}
+
+	k.Lock()
+	defer k.Unlock()
+
+	block, err := k.makeNewBasicBlock()
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := context.NewWriteContext(stxn, block)
+	if err != nil {
+		return nil, err
+	}
+
+	// write into a new txn stash and discard it at last.
+	k.State.NextTxn()
+	err = k.execWriting(writing, ctx)
+	if IfLeiOut(ctx.LeiCost, block) {
+		err = OutOfLei
+	}
+	receipt := NewReceipt(ctx.Events, err, ctx.Extra)
+	if !notStarted(err) {
+		k.endTxn(ctx, receipt)
+	}
+	k.State.Discard()
+
+	result := &SimulateResult{
+		Events:  ctx.Events,
+		Extra:   ctx.Extra,
+		LeiCost: ctx.LeiCost,
+		Fee:     receipt.Fee,
+		Tips:    receipt.Tips,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// HandleReceiptProof returns the Merkle proof of the txn receipt against the ReceiptRoot of its block.
+func (k *Kernel) HandleReceiptProof(txnHash common.Hash) (*ReceiptProof, error) {
+	receipt, err := k.TxDB.GetReceipt(txnHash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, ReceiptNotFound(txnHash)
+	}
+	block, err := k.Chain.GetBlock(receipt.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// txns after the block is out of lei have no receipts.
+	receipts := make([]*Receipt, 0)
+	index := -1
+	for _, hash := range block.TxnsHashes {
+		r, err := k.TxDB.GetReceipt(hash)
 		if err != nil {
-			logrus.Error("publish unpacked txns error: ", err)
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+		if hash == txnHash {
+			index = len(receipts)
 		}
-	}()
+		receipts = append(receipts, r)
+	}
+	if index < 0 {
+		return nil, ReceiptNotFound(txnHash)
+	}
 
-	return k.Pool.Insert(stxn)
+	proof, err := MakeReceiptProof(receipts, index)
+	if err != nil {
+		return nil, err
+	}
+	if proof.ReceiptRoot != block.ReceiptRoot {
+		return nil, ReceiptRootMismatched
+	}
+	return proof, nil
 }
 
 func (k *Kernel) HandleRead(rdCall *common.RdCall) (*context.ResponseData, error) {
@@ -0,0 +1,50 @@
+package kernel
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/core"
+	. "github.com/yu-org/yu/core/types"
+	"testing"
+)
+
+func TestHandleSimulate(t *testing.T) {
+	k := newTestKernel(t, "./test-simulate.db")
+	counter := k.land.TripodsMap["counter"]
+	getCount := func() uint64 {
+		byt, err := k.State.Get(counter, []byte("count"))
+		assert.NoError(t, err)
+		return binary.BigEndian.Uint64(byt)
+	}
+
+	block := &Block{Header: &Header{Height: 1, Hash: HexToHash("0a"), LeiLimit: 100}}
+	block.SetTxns(newTestTxns(t, incr(false)))
+	k.State.StartBlock(block.Hash)
+	assert.NoError(t, k.OrderedExecute(block))
+	assert.Equal(t, uint64(1), getCount())
+
+	result, err := k.HandleSimulate(&core.SignedWrCall{Call: incr(false)})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Event{{Value: []byte("count 2")}}, result.Events)
+	assert.Equal(t, uint64(1), result.LeiCost)
+	assert.Empty(t, result.Error)
+
+	result, err = k.HandleSimulate(&core.SignedWrCall{Call: incr(true)})
+	assert.NoError(t, err)
+	assert.Equal(t, errOdd.Error(), result.Error)
+
+	// nothing is written by the simulations.
+	assert.Equal(t, uint64(1), getCount())
+	end, err := k.Chain.GetEndBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, BlockNum(0), end.Height)
+	block = &Block{Header: &Header{Height: 2, Hash: HexToHash("0b"), PrevHash: block.Hash, LeiLimit: 100}}
+	stxns := newTestTxns(t, incr(false))
+	block.SetTxns(stxns)
+	k.State.StartBlock(block.Hash)
+	assert.NoError(t, k.OrderedExecute(block))
+	receipt, err := k.TxDB.GetReceipt(stxns[0].TxnHash)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Event{{Value: []byte("count 2")}}, receipt.Events)
+}
@@ -3,6 +3,7 @@ package kernel
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
 	. "github.com/yu-org/yu/core"
 	"net/http"
 )
@@ -18,6 +19,19 @@ func (k *Kernel) HandleHttp() {
 	r.POST(RdApiPath, func(c *gin.Context) {
 		k.handleHttpRd(c)
 	})
+	// POST request
+	r.POST(SimApiPath, func(c *gin.Context) {
+		k.handleHttpSim(c)
+	})
+
+	// GET request
+	r.GET(ReceiptProofPath, func(c *gin.Context) {
+		k.handleHttpReceiptProof(c)
+	})
+	// GET request
+	r.GET(GossipMetricsPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, k.GossipMetrics())
+	})
 
 	err := r.Run(k.httpPort)
 	if err != nil {
@@ -33,9 +47,35 @@ func (k *Kernel) handleHttpWr(c *gin.Context) {
 	}
 
 	err = k.HandleTxn(signedWrCall)
+	if err != nil {
+		// tell the client why the txn is rejected, such as expired or txpool is full.
+		c.String(http.StatusBadRequest, err.Error())
+	}
+}
+
+func (k *Kernel) handleHttpSim(c *gin.Context) {
+	signedWrCall, err := GetSignedWrCall(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := k.HandleSimulate(signedWrCall)
 	if err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (k *Kernel) handleHttpReceiptProof(c *gin.Context) {
+	txnHash := HexToHash(c.Query(TxnHashKey))
+	proof, err := k.HandleReceiptProof(txnHash)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
 	}
+	c.JSON(http.StatusOK, proof)
 }
 
 func (k *Kernel) handleHttpRd(c *gin.Context) {
@@ -23,6 +23,11 @@ type Kernel struct {
 	wsPort   string
 	leiLimit uint64
 
+	gossip *txnsGossip
+
+	// the last block executed on the state, null if no block is executed since startup.
+	stateHead Hash
+
 	*ChainEnv
 
 	land *Land
@@ -41,9 +46,24 @@ func NewKernel(
 		wsPort:   MakePort(cfg.WsPort),
 		ChainEnv: env,
 		land:     land,
+		gossip:   newTxnsGossip(&cfg.P2P),
+	}
+	env.MinLeiPrice = cfg.MinLeiPrice
+
+	if cfg.ParallelExecute {
+		k.WithExecuteFn(k.ParallelExecute)
+	} else {
+		k.WithExecuteFn(k.OrderedExecute)
 	}
 
-	env.Execute = k.OrderedExecute
+	k.Chain.SetReorgHandler(k.HandleReorg)
+	k.Pool.OnReplaced(func(replaced *SignedTxn) {
+		k.gossip.forget(replaced.TxnHash)
+	})
+
+	if k.Gate == nil {
+		k.Gate = NewSyncGate()
+	}
 
 	// Configure the handlers in P2P network
 
@@ -67,7 +87,21 @@ func NewKernel(
 }
 
 func (k *Kernel) WithExecuteFn(fn ExecuteFn) {
-	k.Execute = fn
+	// executing block and simulating txn must not write the state at the same time.
+	k.Execute = func(block *Block) error {
+		k.Lock()
+		defer k.Unlock()
+		err := k.baseStateOn(block, fn)
+		if err != nil {
+			return err
+		}
+		err = fn(block)
+		if err != nil {
+			return err
+		}
+		k.stateHead = block.Hash
+		return nil
+	}
 }
 
 func (k *Kernel) Startup() {
@@ -91,12 +125,29 @@ func (k *Kernel) InitChain() {
 }
 
 func (k *Kernel) AcceptUnpkgTxns() error {
-	txns, err := k.subUnpackedTxns()
+	byt, err := k.P2pNetwork.SubP2P(UnpackedTxnsTopic)
 	if err != nil {
 		return err
 	}
+	k.gossip.metrics.ReceivedBatches.Inc()
+	if k.gossip.seenMsg(byt) {
+		k.gossip.metrics.DuplicatedBatches.Inc()
+		return nil
+	}
+	txns, err := DecodeSignedTxns(byt)
+	if err != nil {
+		return err
+	}
+	k.gossip.metrics.ReceivedTxns.Add(uint64(len(txns)))
 
+	newTxns := make([]*SignedTxn, 0, len(txns))
+	batchSeen := make(map[Hash]struct{}, len(txns))
 	for _, txn := range txns {
+		if _, ok := batchSeen[txn.TxnHash]; ok || k.gossip.seenTxn(txn.TxnHash) {
+			k.gossip.metrics.DuplicatedTxns.Inc()
+			continue
+		}
+		batchSeen[txn.TxnHash] = struct{}{}
 		if k.Pool.Exist(txn) {
 			continue
 		}
@@ -104,12 +155,18 @@ func (k *Kernel) AcceptUnpkgTxns() error {
 
 		logrus.WithField("p2p", "accept-txn").
 			Tracef("txn(%s) from network, content: %v", txn.TxnHash.String(), txn.Raw.WrCall)
+		newTxns = append(newTxns, txn)
+	}
 
-		err = k.Pool.CheckTxn(txn)
-		if err != nil {
-			logrus.Error("check txn from P2P into txpool error: ", err)
+	// verify the signatures of txns from P2P in batch.
+	errs := k.Pool.BatchCheckTxns(newTxns)
+	for i, txn := range newTxns {
+		if errs[i] != nil {
+			logrus.Error("check txn from P2P into txpool error: ", errs[i])
 			continue
 		}
+		// a forged txn with the same hash must not shadow the valid one.
+		k.gossip.markSeen(txn.TxnHash)
 		err = k.Pool.Insert(txn)
 		if err != nil {
 			logrus.Error("insert txn from P2P into txpool error: ", err)
@@ -119,18 +176,12 @@ func (k *Kernel) AcceptUnpkgTxns() error {
 	return nil
 }
 
-func (k *Kernel) subUnpackedTxns() (SignedTxns, error) {
-	byt, err := k.P2pNetwork.SubP2P(UnpackedTxnsTopic)
-	if err != nil {
-		return nil, err
-	}
-	return DecodeSignedTxns(byt)
-}
-
 func (k *Kernel) pubUnpackedTxns(txns SignedTxns) error {
 	byt, err := txns.Encode()
 	if err != nil {
 		return err
 	}
+	// the message comes back from P2P network, drop it.
+	k.gossip.seenMsg(byt)
 	return k.P2pNetwork.PubP2P(UnpackedTxnsTopic, byt)
 }
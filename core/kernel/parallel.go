@@ -0,0 +1,139 @@
+package kernel
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/types"
+	"runtime"
+	"sync"
+)
+
+// speculation is the result of executing a txn speculatively.
+type speculation struct {
+	view *state.TxnView
+	ctx  *context.WriteContext
+	// error of NewWriteContext
+	ctxErr error
+	// error of the writing
+	err error
+	// the writing panics, maybe it read dirty values.
+	panicked bool
+	// the writing runs on a worker and writes the state of master directly,
+	// it is not executed speculatively.
+	remote bool
+	// some txns write the state out of their views, the speculation is invalid.
+	bypassed bool
+}
+
+// ParallelExecute executes all txns of the block speculatively in parallel and records their read-write sets,
+// then applies them one by one in the order of block. A txn whose read-set has been changed by the txns
+// applied before it will be re-executed, so the StateRoot and receipts are the same as OrderedExecute.
+// Writings and TxnCycles must only touch the chain state through Tripod.TxnState to be executed in parallel,
+// if any of them writes the state directly, the whole block is re-executed in order.
+func (k *Kernel) ParallelExecute(block *Block) error {
+	ps := k.parallelState()
+	stxns := block.Txns
+
+	// the txns are speculated on the state changed by the block transitions.
+	err := k.applyBlock(block)
+	if err != nil {
+		return err
+	}
+
+	specs := make([]*speculation, len(stxns))
+	ps.StartSpeculation()
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, runtime.NumCPU())
+	for i, stxn := range stxns {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, stxn *SignedTxn) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			specs[i] = k.speculate(ps, block, stxn)
+		}(i, stxn)
+	}
+	wg.Wait()
+	if !ps.EndSpeculation() {
+		logrus.Debugf("block(%s) writes the state out of txn views, execute it in order", block.Hash.String())
+		for _, spec := range specs {
+			spec.bypassed = true
+		}
+	}
+
+	receipts := make([]*Receipt, 0)
+
+	// the state changes before executing txns must not be discarded by a failed txn.
+	k.State.NextTxn()
+
+	for i, stxn := range stxns {
+		spec := specs[i]
+		if spec.ctxErr != nil {
+			receipt := k.handleError(spec.ctxErr, spec.ctx, block, stxn)
+			receipts = append(receipts, receipt)
+			continue
+		}
+
+		ctx, err := spec.ctx, spec.err
+		if spec.remote || spec.bypassed || spec.panicked || !ps.Apply(spec.view) {
+			logrus.Debugf("txn(%s) conflicts, re-execute it", stxn.TxnHash.String())
+			ctx, err = context.NewWriteContext(stxn, block)
+			if err != nil {
+				receipt := k.handleError(err, ctx, block, stxn)
+				receipts = append(receipts, receipt)
+				continue
+			}
+			wrCall := stxn.Raw.WrCall
+			writing, _ := k.land.GetWriting(wrCall.TripodName, wrCall.FuncName)
+			err = k.execWriting(writing, ctx)
+		}
+		// EndTxn runs in order on the chain state.
+		ctx.State = nil
+
+		receipt, leiOut := k.settleTxn(err, ctx, block, stxn)
+		receipts = append(receipts, receipt)
+		if leiOut {
+			break
+		}
+	}
+
+	return k.commitState(block, receipts)
+}
+
+func (k *Kernel) speculate(ps *state.ParallelState, block *Block, stxn *SignedTxn) (spec *speculation) {
+	spec = &speculation{view: ps.NewTxnView()}
+	spec.ctx, spec.ctxErr = context.NewWriteContext(stxn, block)
+	if spec.ctxErr != nil {
+		return
+	}
+	spec.ctx.State = spec.view
+
+	wrCall := stxn.Raw.WrCall
+	if k.land.IsRemote(wrCall.TripodName) {
+		spec.remote = true
+		return
+	}
+	writing, _ := k.land.GetWriting(wrCall.TripodName, wrCall.FuncName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			spec.panicked = true
+		}
+	}()
+	spec.err = k.execWriting(writing, spec.ctx)
+	return
+}
+
+// parallelState wraps the State of chain into ParallelState,
+// tripods share the same ChainEnv, so all of them read and write it.
+func (k *Kernel) parallelState() *state.ParallelState {
+	if ps, ok := k.State.(*state.ParallelState); ok {
+		return ps
+	}
+	ps := state.NewParallelState(k.State)
+	k.State = ps
+	return ps
+}
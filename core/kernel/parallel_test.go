@@ -0,0 +1,184 @@
+package kernel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/blockchain"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/env"
+	"github.com/yu-org/yu/core/state"
+	. "github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/txdb"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/p2p"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"os"
+	"testing"
+)
+
+var errOdd = errors.New("odd counter")
+
+// counter increases one shared key in every Incr, so the Incr txns conflict with each other.
+type counter struct {
+	*Tripod
+}
+
+func newCounter() *counter {
+	c := &counter{Tripod: NewTripodWithName("counter")}
+	c.SetWritings(c.Incr, c.Put)
+	return c
+}
+
+func (c *counter) Incr(ctx *WriteContext) error {
+	ctx.SetLei(1)
+	st := c.TxnState(ctx)
+	byt, err := st.Get([]byte("count"))
+	if err != nil {
+		return err
+	}
+	var count uint64
+	if len(byt) == 8 {
+		count = binary.BigEndian.Uint64(byt)
+	}
+	count++
+	byt = make([]byte, 8)
+	binary.BigEndian.PutUint64(byt, count)
+	st.Set([]byte("count"), byt)
+	ctx.EmitStringEvent("count %d", count)
+	if ctx.GetBoolean("fail") {
+		return errOdd
+	}
+	return nil
+}
+
+func (c *counter) Put(ctx *WriteContext) error {
+	ctx.SetLei(1)
+	c.TxnState(ctx).Set([]byte(ctx.GetString("key")), []byte(ctx.GetString("value")))
+	return nil
+}
+
+// direct writes the state without TxnState.
+type direct struct {
+	*Tripod
+}
+
+func newDirect() *direct {
+	d := &direct{Tripod: NewTripodWithName("direct")}
+	d.SetWritings(d.Touch)
+	return d
+}
+
+func (d *direct) Touch(ctx *WriteContext) error {
+	ctx.SetLei(1)
+	byt, _ := d.Get([]byte("touched"))
+	d.Set([]byte("touched"), append(byt, 1))
+	return nil
+}
+
+func newTestKernel(t *testing.T, path string) *Kernel {
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: path})
+	assert.NoError(t, err)
+	chainPath := path + "-chain"
+	t.Cleanup(func() {
+		os.RemoveAll(path)
+		os.RemoveAll(chainPath)
+	})
+	base := txdb.NewTxDB(FullNode, kvdb)
+	chain := blockchain.NewBlockChain(FullNode, &config.BlockchainConf{
+		ChainDB:      config.SqlDbConf{SqlDbType: "sqlite", Dsn: chainPath},
+		ConvergeType: "longest",
+	}, base)
+	assert.NoError(t, chain.SetGenesis(&Block{Header: &Header{Hash: HexToHash("0f")}}))
+	env := &ChainEnv{
+		State:      state.NewStateDB(kvdb),
+		Chain:      chain,
+		TxDB:       base,
+		P2pNetwork: p2p.NewMockP2p(1),
+	}
+	land := NewLand()
+	for _, v := range []interface{}{newCounter(), newDirect(), newGate()} {
+		tri := ResolveTripod(v)
+		tri.SetChainEnv(env)
+		tri.SetLand(land)
+		tri.SetInstance(v)
+		land.SetTripods(tri)
+	}
+	return &Kernel{ChainEnv: env, land: land, leiLimit: 100}
+}
+
+func newTestTxns(t *testing.T, calls ...*WrCall) SignedTxns {
+	stxns := make(SignedTxns, 0, len(calls))
+	for _, call := range calls {
+		stxn, err := NewSignedTxn(call, nil, nil)
+		assert.NoError(t, err)
+		stxns = append(stxns, stxn)
+	}
+	return stxns
+}
+
+func incr(fail bool) *WrCall {
+	return &WrCall{TripodName: "counter", FuncName: "Incr", Params: fmt.Sprintf(`{"fail":%v}`, fail)}
+}
+
+func put(key, value string) *WrCall {
+	return &WrCall{TripodName: "counter", FuncName: "Put", Params: fmt.Sprintf(`{"key":"%s","value":"%s"}`, key, value)}
+}
+
+// execute runs the txns in a block by both OrderedExecute and ParallelExecute,
+// and checks they produce the same state and receipts.
+func execute(t *testing.T, stxns SignedTxns) *Kernel {
+	ordered := newTestKernel(t, "./test-ordered.db")
+	parallel := newTestKernel(t, "./test-parallel.db")
+
+	newBlock := func() *Block {
+		block := &Block{Header: &Header{Height: 1, Hash: HexToHash("0a"), LeiLimit: 100}}
+		block.SetTxns(stxns)
+		return block
+	}
+	orderedBlock, parallelBlock := newBlock(), newBlock()
+	ordered.State.StartBlock(orderedBlock.Hash)
+	assert.NoError(t, ordered.OrderedExecute(orderedBlock))
+	parallel.State.StartBlock(parallelBlock.Hash)
+	assert.NoError(t, parallel.ParallelExecute(parallelBlock))
+
+	assert.Equal(t, orderedBlock.StateRoot, parallelBlock.StateRoot)
+	assert.Equal(t, orderedBlock.ReceiptRoot, parallelBlock.ReceiptRoot)
+	assert.Equal(t, orderedBlock.LeiUsed, parallelBlock.LeiUsed)
+	for _, stxn := range stxns {
+		want, err := ordered.TxDB.GetReceipt(stxn.TxnHash)
+		assert.NoError(t, err)
+		got, err := parallel.TxDB.GetReceipt(stxn.TxnHash)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	return parallel
+}
+
+func TestParallelExecuteConflicts(t *testing.T) {
+	k := execute(t, newTestTxns(t,
+		incr(false),
+		put("a", "1"),
+		incr(true),
+		incr(false),
+		put("a", "2"),
+		put("b", "3"),
+		incr(false),
+	))
+	// the failed Incr is discarded.
+	count, err := k.State.Get(k.land.TripodsMap["counter"], []byte("count"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), binary.BigEndian.Uint64(count))
+}
+
+func TestParallelExecuteBypassed(t *testing.T) {
+	execute(t, newTestTxns(t,
+		incr(false),
+		&WrCall{TripodName: "direct", FuncName: "Touch", Params: "{}"},
+		incr(false),
+		&WrCall{TripodName: "direct", FuncName: "Touch", Params: "{}"},
+	))
+}
@@ -0,0 +1,148 @@
+package kernel
+
+import (
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/env"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+)
+
+// HandleReorg reverts the state to the common ancestor and notifies tripods,
+// then executes the blocks of new fork, and puts the txns only in the reverted blocks back into txpool.
+func (k *Kernel) HandleReorg(reorg *Reorg) error {
+	k.Lock()
+	err := k.State.RevertTo(reorg.Ancestor.Hash)
+	if err == nil {
+		k.stateHead = reorg.Ancestor.Hash
+	}
+	k.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = k.land.RangeList(func(tri *Tripod) error {
+		tri.HandleReorg(reorg)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[Hash]struct{})
+	for _, cb := range reorg.Applied {
+		block, err := k.getFullBlock(cb)
+		if err != nil {
+			return err
+		}
+		k.State.StartBlock(block.Hash)
+		err = k.Execute(block)
+		if err != nil {
+			return err
+		}
+		for _, hash := range cb.TxnsHashes {
+			applied[hash] = struct{}{}
+		}
+	}
+
+	for _, cb := range reorg.Reverted {
+		for _, hash := range cb.TxnsHashes {
+			if _, ok := applied[hash]; ok {
+				continue
+			}
+			stxn, err := k.TxDB.GetTxn(hash)
+			if err != nil || stxn == nil {
+				continue
+			}
+			err = k.Pool.Insert(stxn)
+			if err != nil {
+				logrus.Warnf("put txn(%s) of reverted block back into txpool error: %v", hash.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// baseStateOn makes the block executed on the state of its previous block.
+// If the block is on another fork from the last executed one, the state reverts to their common ancestor
+// and the blocks from the ancestor to the previous block are executed again.
+func (k *Kernel) baseStateOn(block *Block, fn ExecuteFn) error {
+	if k.stateHead == NullHash || k.stateHead == block.PrevHash {
+		return nil
+	}
+	// the last executed block may be dropped before it is appended, such as the block not committed by validators.
+	exists, err := k.Chain.ExistsBlock(k.stateHead)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err = k.State.RevertTo(block.PrevHash)
+		if err != nil {
+			return err
+		}
+		k.stateHead = block.PrevHash
+		k.State.StartBlock(block.Hash)
+		return nil
+	}
+	ancestor, forkBlocks, err := k.forkFrom(k.stateHead, block.PrevHash)
+	if err != nil {
+		return err
+	}
+	err = k.State.RevertTo(ancestor)
+	if err != nil {
+		return err
+	}
+	k.stateHead = ancestor
+	for _, cb := range forkBlocks {
+		forkBlock, err := k.getFullBlock(cb)
+		if err != nil {
+			return err
+		}
+		k.State.StartBlock(forkBlock.Hash)
+		err = fn(forkBlock)
+		if err != nil {
+			return err
+		}
+		k.stateHead = forkBlock.Hash
+	}
+	k.State.StartBlock(block.Hash)
+	return nil
+}
+
+// forkFrom returns the common ancestor of the two blocks,
+// and the blocks after the ancestor until the second one in ascending order.
+func (k *Kernel) forkFrom(from, to Hash) (Hash, []*CompactBlock, error) {
+	fromBlock, err := k.Chain.GetBlock(from)
+	if err != nil {
+		return NullHash, nil, err
+	}
+	toBlock, err := k.Chain.GetBlock(to)
+	if err != nil {
+		return NullHash, nil, err
+	}
+	forkBlocks := make([]*CompactBlock, 0)
+	for fromBlock.Hash != toBlock.Hash {
+		if fromBlock.Height > toBlock.Height {
+			fromBlock, err = k.Chain.GetBlock(fromBlock.PrevHash)
+		} else {
+			forkBlocks = append([]*CompactBlock{toBlock}, forkBlocks...)
+			toBlock, err = k.Chain.GetBlock(toBlock.PrevHash)
+		}
+		if err != nil {
+			return NullHash, nil, err
+		}
+	}
+	return fromBlock.Hash, forkBlocks, nil
+}
+
+func (k *Kernel) getFullBlock(cb *CompactBlock) (*Block, error) {
+	txns := make(SignedTxns, 0, len(cb.TxnsHashes))
+	for _, hash := range cb.TxnsHashes {
+		stxn, err := k.TxDB.GetTxn(hash)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, stxn)
+	}
+	return &Block{Header: cb.Header, Txns: txns}, nil
+}
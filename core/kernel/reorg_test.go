@@ -0,0 +1,48 @@
+package kernel
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/types"
+	"testing"
+)
+
+func newForkBlock(t *testing.T, height BlockNum, hash, prevHash Hash, calls ...*WrCall) *Block {
+	block := &Block{Header: &Header{Height: height, Hash: hash, PrevHash: prevHash, LeiLimit: 100}}
+	block.SetTxns(newTestTxns(t, calls...))
+	return block
+}
+
+func executeAndAppend(t *testing.T, k *Kernel, block *Block) {
+	k.State.StartBlock(block.Hash)
+	assert.NoError(t, k.Execute(block))
+	assert.NoError(t, k.Chain.AppendBlock(block))
+}
+
+func TestExecuteOnFork(t *testing.T) {
+	k := newTestKernel(t, "./test-fork.db")
+	k.WithExecuteFn(k.OrderedExecute)
+	genesis := HexToHash("0f")
+	blockA := newForkBlock(t, 1, HexToHash("0a"), genesis, put("a", "1"))
+	blockB := newForkBlock(t, 2, HexToHash("0b"), blockA.Hash, put("a", "2"))
+	executeAndAppend(t, k, blockA)
+	executeAndAppend(t, k, blockB)
+
+	// the fork block is executed on the state of genesis block, not the one of block B.
+	forkA := newForkBlock(t, 1, HexToHash("1a"), genesis, put("b", "3"))
+	executeAndAppend(t, k, forkA)
+	single := newTestKernel(t, "./test-fork-single.db")
+	single.WithExecuteFn(single.OrderedExecute)
+	want := newForkBlock(t, 1, forkA.Hash, genesis, put("b", "3"))
+	executeAndAppend(t, single, want)
+	assert.Equal(t, want.StateRoot, forkA.StateRoot)
+
+	// block C is executed on the state of block B again.
+	blockC := newForkBlock(t, 3, HexToHash("0c"), blockB.Hash, put("c", "4"))
+	executeAndAppend(t, k, blockC)
+	counter := k.land.TripodsMap["counter"]
+	value, err := k.State.GetByBlockHash(counter, []byte("a"), blockC.Hash)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("2"), value)
+	assert.False(t, k.State.Exist(counter, []byte("b")))
+}
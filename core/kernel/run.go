@@ -1,16 +1,19 @@
 package kernel
 
 import (
+	"errors"
 	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
 	. "github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/core/context"
 	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/tripod/dev"
 	. "github.com/yu-org/yu/core/types"
 	ytime "github.com/yu-org/yu/utils/time"
 )
 
 func (k *Kernel) Run() {
+	go k.GossipTxns()
 	go func() {
 		for {
 			err := k.AcceptUnpkgTxns()
@@ -38,8 +41,16 @@ func (k *Kernel) Run() {
 		}
 	case MasterWorker:
 		for {
-			err := k.MasterWokrerRun()
-			logrus.Errorf("master-worker-run blockchain error: %s", err.Error())
+			select {
+			case <-k.stopChan:
+				logrus.Info("Stop the Chain!")
+				return
+			default:
+				err := k.MasterWokrerRun()
+				if err != nil {
+					logrus.Errorf("master-worker-run blockchain error: %s", err.Error())
+				}
+			}
 		}
 
 	default:
@@ -48,7 +59,20 @@ func (k *Kernel) Run() {
 
 }
 
-func (k *Kernel) LocalRun() (err error) {
+func (k *Kernel) LocalRun() error {
+	return k.runBlockCycle()
+}
+
+// MasterWokrerRun runs the same block cycle as LocalRun,
+// the tripods registered by workers are called through grpc.
+func (k *Kernel) MasterWokrerRun() error {
+	return k.runBlockCycle()
+}
+
+func (k *Kernel) runBlockCycle() error {
+	k.Gate.EnterCycle()
+	defer k.Gate.ExitCycle()
+
 	newBlock, err := k.makeNewBasicBlock()
 	if err != nil {
 		return err
@@ -63,6 +87,16 @@ func (k *Kernel) LocalRun() (err error) {
 		return err
 	}
 
+	// the block at this height has been synced from peers while starting it.
+	endBlock, err := k.Chain.GetEndBlock()
+	if err != nil {
+		return err
+	}
+	if endBlock.Height >= newBlock.Height {
+		logrus.Infof("block height(%d) has been caught up from peers, drop the local one", newBlock.Height)
+		return nil
+	}
+
 	// end block and append to Chain
 	err = k.land.RangeList(func(tri *Tripod) error {
 		tri.EndBlock(newBlock)
@@ -91,52 +125,125 @@ func (k *Kernel) makeNewBasicBlock() (*Block, error) {
 	newBlock.PeerID = k.P2pNetwork.LocalID()
 	newBlock.Height = prevBlock.Height + 1
 	newBlock.LeiLimit = k.leiLimit
+	newBlock.BaseLeiPrice = NextBaseLeiPrice(prevBlock.Header, k.MinLeiPrice)
 	return newBlock, nil
 }
 
 func (k *Kernel) OrderedExecute(block *Block) error {
 	stxns := block.Txns
 
-	receipts := make(map[Hash]*Receipt)
+	receipts := make([]*Receipt, 0)
+
+	err := k.applyBlock(block)
+	if err != nil {
+		return err
+	}
+	// the state changes before executing txns must not be discarded by a failed txn.
+	k.State.NextTxn()
 
 	for _, stxn := range stxns {
 		wrCall := stxn.Raw.WrCall
 		ctx, err := context.NewWriteContext(stxn, block)
 		if err != nil {
 			receipt := k.handleError(err, ctx, block, stxn)
-			receipts[stxn.TxnHash] = receipt
+			receipts = append(receipts, receipt)
 			continue
 		}
 
 		writing, _ := k.land.GetWriting(wrCall.TripodName, wrCall.FuncName)
 
-		err = writing(ctx)
-		if IfLeiOut(ctx.LeiCost, block) {
-			k.State.Discard()
-			receipt := k.handleError(OutOfLei, ctx, block, stxn)
-			receipts[stxn.TxnHash] = receipt
+		err = k.execWriting(writing, ctx)
+		receipt, leiOut := k.settleTxn(err, ctx, block, stxn)
+		receipts = append(receipts, receipt)
+		if leiOut {
 			break
 		}
-		if err != nil {
-			k.State.Discard()
-			k.handleError(err, ctx, block, stxn)
-		} else {
-			k.State.NextTxn()
-		}
+	}
 
-		block.UseLei(ctx.LeiCost)
+	return k.commitState(block, receipts)
+}
 
-		// if no error and event, give a default event
-		//if ctx.Error == nil && len(ctx.Events) == 0 {
-		//	_ = ctx.EmitJsonEvent(DefaultJsonEvent)
-		//}
+// applyBlock calls ApplyBlock of all tripods before executing the txns of block.
+func (k *Kernel) applyBlock(block *Block) error {
+	return k.land.RangeList(func(tri *Tripod) error {
+		return tri.ApplyBlock(block)
+	})
+}
 
-		receipt := k.handleEvent(ctx, block, stxn)
-		receipts[stxn.TxnHash] = receipt
+// execWriting calls StartTxn of all tripods before the writing.
+func (k *Kernel) execWriting(writing Writing, ctx *context.WriteContext) error {
+	err := k.land.RangeList(func(tri *Tripod) error {
+		return tri.StartTxn(ctx)
+	})
+	if err != nil {
+		return &startTxnError{err}
 	}
+	return writing(ctx)
+}
 
+// startTxnError means the txn fails the checks of StartTxn, such as nonce mismatch,
+// the txn is not executed and the caller is not charged.
+type startTxnError struct {
+	error
+}
+
+func (e *startTxnError) Unwrap() error {
+	return e.error
+}
+
+func notStarted(err error) bool {
+	var startErr *startTxnError
+	return errors.As(err, &startErr)
+}
+
+// settleTxn keeps or discards the state changes of the executed txn and makes its receipt,
+// it returns true if the block is out of lei.
+func (k *Kernel) settleTxn(err error, ctx *context.WriteContext, block *Block, stxn *SignedTxn) (*Receipt, bool) {
+	if IfLeiOut(ctx.LeiCost, block) {
+		k.State.Discard()
+		return k.handleError(OutOfLei, ctx, block, stxn), true
+	}
+	if err != nil {
+		k.State.Discard()
+	} else {
+		k.State.NextTxn()
+	}
+
+	block.UseLei(ctx.LeiCost)
+
+	// if no error and event, give a default event
+	//if ctx.Error == nil && len(ctx.Events) == 0 {
+	//	_ = ctx.EmitJsonEvent(DefaultJsonEvent)
+	//}
+
+	receipt := NewReceipt(ctx.Events, err, ctx.Extra)
+	if !notStarted(err) {
+		k.endTxn(ctx, receipt)
+	}
+	// keep the state changes of EndTxn, such as fees, even if the next txn is discarded.
+	k.State.NextTxn()
+
+	if err != nil {
+		logrus.Error("push error: ", err.Error())
+	}
+	k.handleReceipt(ctx, receipt, block, stxn)
+	return receipt, false
+}
+
+func (k *Kernel) endTxn(ctx *context.WriteContext, receipt *Receipt) {
+	_ = k.land.RangeList(func(tri *Tripod) error {
+		tri.EndTxn(ctx, receipt)
+		return nil
+	})
+}
+
+func (k *Kernel) commitState(block *Block, receipts []*Receipt) error {
 	if len(receipts) > 0 {
-		err := k.TxDB.SetReceipts(receipts)
+		receiptsMap := make(map[Hash]*Receipt)
+		for _, receipt := range receipts {
+			receiptsMap[receipt.TxHash] = receipt
+		}
+		err := k.TxDB.SetReceipts(receiptsMap)
 		if err != nil {
 			return err
 		}
@@ -153,43 +260,6 @@ func (k *Kernel) OrderedExecute(block *Block) error {
 	return err
 }
 
-func (k *Kernel) MasterWokrerRun() error {
-	//workersIps, err := k.allWorkersIP()
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//newBlock := k.Chain.NewDefaultBlock()
-	//
-	//err = k.nortifyWorker(workersIps, StartBlockPath, newBlock)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//// todo: if need broadcast block,
-	//// k.readyBroadcastBlock(newBlock)
-	//
-	//err = k.SyncTxns(newBlock)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//err = k.nortifyWorker(workersIps, EndBlockPath, newBlock)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//go func() {
-	//	err := k.nortifyWorker(workersIps, ExecuteTxnsPath, newBlock)
-	//	if err != nil {
-	//		logrus.Errorf("nortify worker executing txns error: %s", err.Error())
-	//	}
-	//}()
-	//
-	//return k.nortifyWorker(workersIps, FinalizeBlockPath, newBlock)
-	return nil
-}
-
 func (k *Kernel) handleError(err error, ctx *context.WriteContext, block *Block, stxn *SignedTxn) *Receipt {
 	logrus.Error("push error: ", err.Error())
 	receipt := NewReceipt(ctx.Events, err, ctx.Extra)
@@ -0,0 +1,62 @@
+package kernel
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/tripod"
+	. "github.com/yu-org/yu/core/types"
+	"testing"
+)
+
+var errRejected = errors.New("rejected by gate")
+
+// gate rejects the txns with param "reject" before executing, and counts the charged txns.
+type gate struct {
+	*Tripod
+}
+
+func newGate() *gate {
+	g := &gate{Tripod: NewTripodWithName("gate")}
+	g.SetTxnCycle(g)
+	return g
+}
+
+func (g *gate) StartTxn(ctx *WriteContext) error {
+	if ctx.Get("reject") != nil {
+		return errRejected
+	}
+	return nil
+}
+
+func (g *gate) EndTxn(ctx *WriteContext, _ *Receipt) {
+	st := g.TxnState(ctx)
+	byt, _ := st.Get([]byte("charged"))
+	var charged uint64
+	if len(byt) == 8 {
+		charged = binary.BigEndian.Uint64(byt)
+	}
+	byt = make([]byte, 8)
+	binary.BigEndian.PutUint64(byt, charged+1)
+	st.Set([]byte("charged"), byt)
+}
+
+func TestNotChargeRejectedTxns(t *testing.T) {
+	stxns := newTestTxns(t,
+		incr(false),
+		&WrCall{TripodName: "counter", FuncName: "Incr", Params: `{"reject":true}`},
+		incr(true),
+	)
+	k := execute(t, stxns)
+
+	receipt, err := k.TxDB.GetReceipt(stxns[1].TxnHash)
+	assert.NoError(t, err)
+	assert.Equal(t, errRejected.Error(), receipt.Error)
+
+	// the failed writing is charged, but the rejected txn is not.
+	charged, err := k.State.Get(k.land.TripodsMap["gate"], []byte("charged"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), binary.BigEndian.Uint64(charged))
+}
@@ -1,6 +1,7 @@
 package kernel
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -19,6 +20,10 @@ func (k *Kernel) HandleWS() {
 		k.handleWS(ctx, reading)
 	})
 
+	r.GET(SimApiPath, func(ctx *gin.Context) {
+		k.handleWS(ctx, simulation)
+	})
+
 	r.GET(SubResultsPath, func(ctx *gin.Context) {
 		k.handleWS(ctx, subscription)
 	})
@@ -32,6 +37,7 @@ const (
 	reading = iota
 	writing
 	subscription
+	simulation
 )
 
 func (k *Kernel) handleWS(ctx *gin.Context, typ int) {
@@ -55,6 +61,8 @@ func (k *Kernel) handleWS(ctx *gin.Context, typ int) {
 	switch typ {
 	case writing:
 		k.handleWsWr(ctx, string(params))
+	case simulation:
+		k.handleWsSim(c, params)
 		//case reading:
 		//	k.handleWsRd(c, req, string(params))
 	}
@@ -74,6 +82,31 @@ func (k *Kernel) handleWsWr(ctx *gin.Context, params string) {
 	}
 }
 
+func (k *Kernel) handleWsSim(c *websocket.Conn, params []byte) {
+	wpb := new(WritingPostBody)
+	err := json.Unmarshal(params, wpb)
+	if err != nil {
+		k.errorAndClose(c, fmt.Sprintf("decode simulating call from websocket error: %v", err))
+		return
+	}
+	signedWrCall, err := wpb.ToSignedWrCall()
+	if err != nil {
+		k.errorAndClose(c, err.Error())
+		return
+	}
+
+	result, err := k.HandleSimulate(signedWrCall)
+	if err != nil {
+		k.errorAndClose(c, err.Error())
+		return
+	}
+	err = c.WriteJSON(result)
+	if err != nil {
+		logrus.Errorf("response simulating result error: %s", err.Error())
+	}
+	c.Close()
+}
+
 //func (k *Kernel) handleWsRd(c *websocket.Conn, req *http.Request, params string) {
 //	rdCall, err := getRdFromHttp(req, params)
 //	if err != nil {
@@ -0,0 +1,59 @@
+package keypair
+
+import (
+	"runtime"
+	"sync"
+)
+
+// BatchVerifier verifies a batch of signatures of any key types concurrently.
+type BatchVerifier struct {
+	items []*batchItem
+}
+
+type batchItem struct {
+	pubkey PubKey
+	msg    []byte
+	sig    []byte
+}
+
+func NewBatchVerifier() *BatchVerifier {
+	return &BatchVerifier{items: make([]*batchItem, 0)}
+}
+
+func (bv *BatchVerifier) Add(pubkey PubKey, msg, sig []byte) {
+	bv.items = append(bv.items, &batchItem{
+		pubkey: pubkey,
+		msg:    msg,
+		sig:    sig,
+	})
+}
+
+func (bv *BatchVerifier) Len() int {
+	return len(bv.items)
+}
+
+// Verify returns true if all signatures are valid,
+// and the result of every signature in the order of adding.
+func (bv *BatchVerifier) Verify() (bool, []bool) {
+	results := make([]bool, len(bv.items))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, runtime.NumCPU())
+	for i, item := range bv.items {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, item *batchItem) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			results[i] = item.pubkey != nil && item.pubkey.VerifySignature(item.msg, item.sig)
+		}(i, item)
+	}
+	wg.Wait()
+
+	allOk := true
+	for _, ok := range results {
+		allOk = allOk && ok
+	}
+	return allOk, results
+}
@@ -40,3 +40,23 @@ func testKey(t *testing.T, keyType string) {
 	ok := pubkey.VerifySignature(hash, signByt)
 	assert.True(t, ok)
 }
+
+func TestBatchVerifier(t *testing.T) {
+	msg := []byte("yu")
+	bv := NewBatchVerifier()
+	for _, keyType := range []string{Sr25519, Ed25519, Secp256k1} {
+		pubkey, privkey, err := GenKeyPair(keyType)
+		assert.NoError(t, err)
+		sig, err := privkey.SignData(msg)
+		assert.NoError(t, err)
+
+		// pubkey is sent with its key type.
+		pubkey, err = PubKeyFromBytes(pubkey.BytesWithType())
+		assert.NoError(t, err)
+		bv.Add(pubkey, msg, sig)
+		bv.Add(pubkey, []byte("boyi"), sig)
+	}
+	allOk, results := bv.Verify()
+	assert.False(t, allOk)
+	assert.Equal(t, []bool{true, false, true, false, true, false}, results)
+}
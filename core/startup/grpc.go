@@ -3,28 +3,37 @@ package startup
 import (
 	"github.com/sirupsen/logrus"
 	"github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/core/blockchain"
+	"github.com/yu-org/yu/core/env"
 	"github.com/yu-org/yu/core/state"
 	"github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/txdb"
+	"github.com/yu-org/yu/core/txpool"
 	"github.com/yu-org/yu/core/types/goproto"
+	"github.com/yu-org/yu/utils/ip"
 	"google.golang.org/grpc"
 	"net"
 )
 
-func StartGrpcServer() {
+func StartGrpcServer(chainEnv *env.ChainEnv) {
 	if KernelCfg.RunMode != common.MasterWorker {
 		return
 	}
-	lis, err := net.Listen("tcp", KernelCfg.GrpcPort)
+	lis, err := net.Listen("tcp", ip.MakePort(KernelCfg.GrpcPort))
 	if err != nil {
 		logrus.Fatal("listen for grpc failed: ", err)
 	}
 	grpcServer := grpc.NewServer()
-	goproto.RegisterStateDBServer(grpcServer, state.NewGrpcMptKV(StateDB))
-	goproto.RegisterLandServer(grpcServer, tripod.NewGrpcLand(Land))
-	// TODO: add chain server, pool server, txndb server.
+	goproto.RegisterStateDBServer(grpcServer, state.NewGrpcMptKV(chainEnv.State))
+	goproto.RegisterBlockChainServer(grpcServer, blockchain.NewGrpcBlockChain(chainEnv.Chain))
+	goproto.RegisterTxDBServer(grpcServer, txdb.NewGrpcTxDB(chainEnv.TxDB))
+	goproto.RegisterTxpoolServer(grpcServer, txpool.NewGrpcTxpool(chainEnv.Pool))
+	goproto.RegisterLandServer(grpcServer, tripod.NewGrpcLand(Land, chainEnv))
 
-	err = grpcServer.Serve(lis)
-	if err != nil {
-		logrus.Fatal("failed to serve grpc: ", err)
-	}
+	go func() {
+		err := grpcServer.Serve(lis)
+		if err != nil {
+			logrus.Fatal("failed to serve grpc: ", err)
+		}
+	}()
 }
@@ -42,7 +42,7 @@ func StartUp(tripodInstances ...interface{}) {
 }
 
 func InitDefaultKernel(tripodInstances ...interface{}) *kernel.Kernel {
-	tripodInstances = append([]interface{}{synchronizer.NewSynchronizer(KernelCfg.SyncMode)}, tripodInstances...)
+	tripodInstances = append([]interface{}{synchronizer.NewSynchronizer(KernelCfg.SyncMode, &KernelCfg.Sync)}, tripodInstances...)
 	return InitKernel(tripodInstances...)
 }
 
@@ -58,6 +58,9 @@ func InitKernel(tripodInstances ...interface{}) *kernel.Kernel {
 	// init database
 	KernelCfg.KVDB.Path = path.Join(KernelCfg.DataDir, KernelCfg.KVDB.Path)
 	KernelCfg.BlockChain.ChainDB.Dsn = path.Join(KernelCfg.DataDir, KernelCfg.BlockChain.ChainDB.Dsn)
+	if KernelCfg.Txpool.PoolDB != nil {
+		KernelCfg.Txpool.PoolDB.Dsn = path.Join(KernelCfg.DataDir, KernelCfg.Txpool.PoolDB.Dsn)
+	}
 	kvdb, err := kv.NewKvdb(&KernelCfg.KVDB)
 	if err != nil {
 		logrus.Fatal("init kvdb error: ", err)
@@ -76,8 +79,6 @@ func InitKernel(tripodInstances ...interface{}) *kernel.Kernel {
 		StateDB = state.NewStateDB(kvdb)
 	}
 
-	StartGrpcServer()
-
 	for _, tri := range tripods {
 		Pool.WithTripodCheck(tri)
 	}
@@ -89,6 +90,7 @@ func InitKernel(tripodInstances ...interface{}) *kernel.Kernel {
 		Pool:       Pool,
 		Sub:        subscribe.NewSubscription(),
 		P2pNetwork: p2p.NewP2P(&KernelCfg.P2P),
+		Gate:       env.NewSyncGate(),
 	}
 
 	for i, t := range tripods {
@@ -99,6 +101,8 @@ func InitKernel(tripodInstances ...interface{}) *kernel.Kernel {
 
 	Land.SetTripods(tripods...)
 
+	StartGrpcServer(chainEnv)
+
 	for _, tripodInterface := range tripodInstances {
 		err = tripod.Inject(tripodInterface)
 		if err != nil {
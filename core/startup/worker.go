@@ -0,0 +1,81 @@
+package startup
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/blockchain"
+	"github.com/yu-org/yu/core/env"
+	"github.com/yu-org/yu/core/state"
+	"github.com/yu-org/yu/core/tripod"
+	"github.com/yu-org/yu/core/txdb"
+	"github.com/yu-org/yu/core/txpool"
+	"github.com/yu-org/yu/core/types/goproto"
+	"github.com/yu-org/yu/utils/ip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"net"
+)
+
+// StartWorker runs tripods on a worker in master-worker mode.
+// The worker uses the chain, txdb, txpool and state of master through grpc,
+// and registers its tripods into the Land of master.
+func StartWorker(cfg *config.WorkerConf, tripodInstances ...interface{}) {
+	lvl, err := logrus.ParseLevel(cfg.LogLevel)
+	if err == nil {
+		logrus.SetLevel(lvl)
+	}
+
+	conn, err := grpc.Dial(cfg.MasterEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logrus.Fatal("dial master failed: ", err)
+	}
+
+	txnDB := txdb.NewGrpcTxDBClient(conn)
+	chainEnv := &env.ChainEnv{
+		State: state.NewGrpcStateClient(conn),
+		Chain: blockchain.NewGrpcBlockChainClient(conn, txnDB),
+		TxDB:  txnDB,
+		Pool:  txpool.NewGrpcTxpoolClient(conn),
+	}
+
+	land := tripod.NewLand()
+	tripods := make([]*tripod.Tripod, 0)
+	for _, v := range tripodInstances {
+		tri := tripod.ResolveTripod(v)
+		tri.SetChainEnv(chainEnv)
+		tri.SetLand(land)
+		tri.SetInstance(v)
+		tripods = append(tripods, tri)
+	}
+	land.SetTripods(tripods...)
+
+	for _, tripodInterface := range tripodInstances {
+		err = tripod.Inject(tripodInterface)
+		if err != nil {
+			logrus.Fatal("inject tripod failed: ", err)
+		}
+	}
+
+	lis, err := net.Listen("tcp", ip.MakePort(cfg.GrpcPort))
+	if err != nil {
+		logrus.Fatal("listen for grpc failed: ", err)
+	}
+	worker := tripod.NewGrpcWorker(land)
+	grpcServer := grpc.NewServer()
+	goproto.RegisterTripodServer(grpcServer, worker)
+	goproto.RegisterWritingServer(grpcServer, worker)
+	goproto.RegisterReadingServer(grpcServer, worker)
+
+	go func() {
+		_, err := goproto.NewLandClient(conn).SetTripods(context.Background(), worker.TripodsInfo(cfg.Endpoint))
+		if err != nil {
+			logrus.Fatal("register tripods into master failed: ", err)
+		}
+	}()
+
+	err = grpcServer.Serve(lis)
+	if err != nil {
+		logrus.Fatal("failed to serve grpc: ", err)
+	}
+}
@@ -25,12 +25,12 @@ func (g *GrpcMptKV) Get(_ context.Context, key *goproto.Key) (*goproto.ValueResp
 
 func (g *GrpcMptKV) Set(_ context.Context, keyValue *goproto.KeyValue) (*emptypb.Empty, error) {
 	g.kv.set(keyValue.GetTripodName(), keyValue.GetKey(), keyValue.GetValue())
-	return nil, nil
+	return &emptypb.Empty{}, nil
 }
 
 func (g *GrpcMptKV) Delete(_ context.Context, key *goproto.Key) (*emptypb.Empty, error) {
 	g.kv.delete(key.GetTripodName(), key.GetKey())
-	return nil, nil
+	return &emptypb.Empty{}, nil
 }
 
 func (g *GrpcMptKV) Exist(_ context.Context, key *goproto.Key) (*goproto.Bool, error) {
@@ -56,7 +56,7 @@ func (g *GrpcMptKV) GetFinalized(_ context.Context, key *goproto.Key) (*goproto.
 
 func (g *GrpcMptKV) StartBlock(_ context.Context, hash *goproto.TxnHash) (*emptypb.Empty, error) {
 	g.kv.StartBlock(common.BytesToHash(hash.Hash))
-	return nil, nil
+	return &emptypb.Empty{}, nil
 }
 
 func (g *GrpcMptKV) Commit(context.Context, *emptypb.Empty) (*goproto.TxnHashResponse, error) {
@@ -69,15 +69,15 @@ func (g *GrpcMptKV) Commit(context.Context, *emptypb.Empty) (*goproto.TxnHashRes
 
 func (g *GrpcMptKV) Discard(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	g.kv.Discard()
-	return nil, nil
+	return &emptypb.Empty{}, nil
 }
 
 func (g *GrpcMptKV) DiscardAll(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	g.kv.DiscardAll()
-	return nil, nil
+	return &emptypb.Empty{}, nil
 }
 
 func (g *GrpcMptKV) NextTxn(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	g.kv.NextTxn()
-	return nil, nil
+	return &emptypb.Empty{}, nil
 }
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// GrpcStateClient is used by the workers in MasterWorker mode,
+// it reaches the StateDB of master by grpc.
+// The writes go to the state of master directly, so master executes the txns of workers in order.
+type GrpcStateClient struct {
+	cli goproto.StateDBClient
+}
+
+func NewGrpcStateClient(conn *grpc.ClientConn) *GrpcStateClient {
+	return &GrpcStateClient{cli: goproto.NewStateDBClient(conn)}
+}
+
+func (g *GrpcStateClient) Set(triName NameString, key, value []byte) {
+	_, err := g.cli.Set(context.Background(), &goproto.KeyValue{
+		TripodName: triName.Name(),
+		Key:        key,
+		Value:      value,
+	})
+	if err != nil {
+		logrus.Error("grpc statedb set error: ", err)
+	}
+}
+
+func (g *GrpcStateClient) Delete(triName NameString, key []byte) {
+	_, err := g.cli.Delete(context.Background(), &goproto.Key{
+		TripodName: triName.Name(),
+		Key:        key,
+	})
+	if err != nil {
+		logrus.Error("grpc statedb delete error: ", err)
+	}
+}
+
+func (g *GrpcStateClient) Get(triName NameString, key []byte) ([]byte, error) {
+	resp, err := g.cli.Get(context.Background(), &goproto.Key{
+		TripodName: triName.Name(),
+		Key:        key,
+	})
+	return valueFromResp(resp, err)
+}
+
+func (g *GrpcStateClient) GetFinalized(triName NameString, key []byte) ([]byte, error) {
+	resp, err := g.cli.GetFinalized(context.Background(), &goproto.Key{
+		TripodName: triName.Name(),
+		Key:        key,
+	})
+	return valueFromResp(resp, err)
+}
+
+func (g *GrpcStateClient) Exist(triName NameString, key []byte) bool {
+	resp, err := g.cli.Exist(context.Background(), &goproto.Key{
+		TripodName: triName.Name(),
+		Key:        key,
+	})
+	if err != nil {
+		logrus.Error("grpc statedb exist error: ", err)
+		return false
+	}
+	return resp.Ok
+}
+
+func (g *GrpcStateClient) GetByBlockHash(triName NameString, key []byte, blockHash Hash) ([]byte, error) {
+	resp, err := g.cli.GetByBlockHash(context.Background(), &goproto.KeyByHash{
+		TripodName: triName.Name(),
+		Key:        key,
+		BlockHash:  blockHash.Bytes(),
+	})
+	return valueFromResp(resp, err)
+}
+
+func (g *GrpcStateClient) Commit() ([]byte, error) {
+	resp, err := g.cli.Commit(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.ErrMsg != "" {
+		return nil, errors.New(resp.ErrMsg)
+	}
+	return resp.Hash, nil
+}
+
+func (g *GrpcStateClient) NextTxn() {
+	_, err := g.cli.NextTxn(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		logrus.Error("grpc statedb next-txn error: ", err)
+	}
+}
+
+func (g *GrpcStateClient) Discard() {
+	_, err := g.cli.Discard(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		logrus.Error("grpc statedb discard error: ", err)
+	}
+}
+
+func (g *GrpcStateClient) DiscardAll() {
+	_, err := g.cli.DiscardAll(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		logrus.Error("grpc statedb discard-all error: ", err)
+	}
+}
+
+func (g *GrpcStateClient) StartBlock(blockHash Hash) {
+	_, err := g.cli.StartBlock(context.Background(), &goproto.TxnHash{Hash: blockHash.Bytes()})
+	if err != nil {
+		logrus.Error("grpc statedb start-block error: ", err)
+	}
+}
+
+// FinalizeBlock does nothing, only master finalizes the state.
+func (g *GrpcStateClient) FinalizeBlock(Hash) {}
+
+// RevertTo is unsupported, only master reverts the state.
+func (g *GrpcStateClient) RevertTo(Hash) error {
+	return GrpcUnsupported
+}
+
+// TakeSnapshot is unsupported, only master takes the snapshots.
+func (g *GrpcStateClient) TakeSnapshot(Hash, BlockNum, int) (*SnapshotMeta, error) {
+	return nil, GrpcUnsupported
+}
+
+func (g *GrpcStateClient) GetSnapshot() (*SnapshotMeta, error) {
+	return nil, GrpcUnsupported
+}
+
+func (g *GrpcStateClient) GetSnapshotChunk(int) (*SnapshotChunk, error) {
+	return nil, GrpcUnsupported
+}
+
+func (g *GrpcStateClient) RestoreSnapshot(*SnapshotMeta, func(int) (*SnapshotChunk, error)) error {
+	return GrpcUnsupported
+}
+
+func (g *GrpcStateClient) GetWithProof(NameString, []byte) (*StateProof, error) {
+	return nil, GrpcUnsupported
+}
+
+func valueFromResp(resp *goproto.ValueResponse, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp.ErrMsg != "" {
+		return nil, errors.New(resp.ErrMsg)
+	}
+	return resp.Value, nil
+}
@@ -0,0 +1,59 @@
+package state
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+func TestGrpcState(t *testing.T) {
+	master := newTestState(t, "./test-grpc.db")
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	goproto.RegisterStateDBServer(server, NewGrpcMptKV(master))
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	worker := NewGrpcStateClient(conn)
+
+	tri1 := new(TestTripod1)
+	blockA := HexToHash("0a")
+	worker.StartBlock(blockA)
+	worker.Set(tri1, key1, value1)
+	worker.Set(tri1, key2, value2)
+	worker.Delete(tri1, key2)
+
+	// the worker writes the state of master.
+	value, err := master.Get(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+	assert.True(t, worker.Exist(tri1, key1))
+	assert.False(t, worker.Exist(tri1, key2))
+
+	root, err := worker.Commit()
+	assert.NoError(t, err)
+	value, err = worker.GetByBlockHash(tri1, key1, blockA)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+
+	proof, err := master.GetWithProof(tri1, key1)
+	assert.NoError(t, err)
+	assert.True(t, proof.Verify(BytesToHash(root)))
+
+	assert.Equal(t, yerror.GrpcUnsupported, worker.RevertTo(blockA))
+}
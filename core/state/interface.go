@@ -5,20 +5,37 @@ import (
 	"github.com/yu-org/yu/infra/storage/kv"
 )
 
-// TODO: need to add prove() and verify()
-type IState interface {
+// StateKV is the part of IState which txns read and write.
+type StateKV interface {
 	Set(triName NameString, key, value []byte)
 	Delete(triName NameString, key []byte)
 	Get(triName NameString, key []byte) ([]byte, error)
 	GetFinalized(triName NameString, key []byte) ([]byte, error)
 	Exist(triName NameString, key []byte) bool
 	GetByBlockHash(triName NameString, key []byte, blockHash Hash) ([]byte, error)
+}
+
+type IState interface {
+	StateKV
 	Commit() ([]byte, error)
 	NextTxn()
 	Discard()
 	DiscardAll()
 	StartBlock(blockHash Hash)
 	FinalizeBlock(blockHash Hash)
+	// RevertTo reverts the state to the block, used when the chain reorgs.
+	RevertTo(blockHash Hash) error
+
+	// TakeSnapshot replaces the latest snapshot with the whole state of the block, split into chunks of chunkSize entries.
+	TakeSnapshot(blockHash Hash, height BlockNum, chunkSize int) (*SnapshotMeta, error)
+	GetSnapshot() (*SnapshotMeta, error)
+	GetSnapshotChunk(index int) (*SnapshotChunk, error)
+	// RestoreSnapshot rebuilds the state from the snapshot of another node, used by FastSync.
+	RestoreSnapshot(meta *SnapshotMeta, fetch func(index int) (*SnapshotChunk, error)) error
+
+	// GetWithProof returns the value with its proof against the state root of the latest committed block,
+	// used to serve the light clients.
+	GetWithProof(triName NameString, key []byte) (*StateProof, error)
 }
 
 func NewStateDB(kvdb kv.Kvdb) IState {
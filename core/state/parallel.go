@@ -0,0 +1,169 @@
+package state
+
+import (
+	"bytes"
+	. "github.com/yu-org/yu/common"
+	"sync/atomic"
+)
+
+// ParallelState executes the txns of one block speculatively in parallel,
+// every txn reads and writes the state through its own TxnView.
+// While speculating, the writes not through a TxnView are dropped and reported by EndSpeculation,
+// then the block must be executed in order.
+type ParallelState struct {
+	IState
+	speculating atomic.Bool
+	bypassed    atomic.Bool
+}
+
+func NewParallelState(st IState) *ParallelState {
+	return &ParallelState{IState: st}
+}
+
+// NewTxnView makes a view over the state for one txn.
+func (ps *ParallelState) NewTxnView() *TxnView {
+	return &TxnView{
+		ps:     ps,
+		reads:  make(map[readKey][]byte),
+		writes: newTxnStashes(),
+	}
+}
+
+func (ps *ParallelState) StartSpeculation() {
+	ps.bypassed.Store(false)
+	ps.speculating.Store(true)
+}
+
+// EndSpeculation returns false if the state was written without TxnView while speculating.
+func (ps *ParallelState) EndSpeculation() bool {
+	ps.speculating.Store(false)
+	return !ps.bypassed.Load()
+}
+
+func (ps *ParallelState) Set(triName NameString, key, value []byte) {
+	if ps.speculating.Load() {
+		ps.bypassed.Store(true)
+		return
+	}
+	ps.IState.Set(triName, key, value)
+}
+
+func (ps *ParallelState) Delete(triName NameString, key []byte) {
+	if ps.speculating.Load() {
+		ps.bypassed.Store(true)
+		return
+	}
+	ps.IState.Delete(triName, key)
+}
+
+// Apply checks whether all the values read by the view are still the same in the underlying IState.
+// If so, it replays the writes of the view into the underlying IState and returns true,
+// otherwise the view conflicts with the txns applied before it, and nothing is written.
+func (ps *ParallelState) Apply(view *TxnView) bool {
+	if view.broken {
+		return false
+	}
+	for key, value := range view.reads {
+		current, err := ps.read(key)
+		if err != nil || !bytes.Equal(current, value) {
+			return false
+		}
+	}
+	for element := view.writes.stashes.Front(); element != nil; element = element.Next() {
+		stash := element.Value.(*KvStash)
+		switch stash.ops {
+		case SetOp:
+			ps.IState.Set(noName{}, stash.Key, stash.Value)
+		case DeleteOp:
+			ps.IState.Delete(noName{}, stash.Key)
+		}
+	}
+	return true
+}
+
+func (ps *ParallelState) read(key readKey) ([]byte, error) {
+	switch key.kind {
+	case readFinalized:
+		return ps.IState.GetFinalized(noName{}, []byte(key.key))
+	case readByBlockHash:
+		return ps.IState.GetByBlockHash(noName{}, []byte(key.key), key.blockHash)
+	default:
+		return ps.IState.Get(noName{}, []byte(key.key))
+	}
+}
+
+const (
+	readLatest = iota
+	readFinalized
+	readByBlockHash
+)
+
+type readKey struct {
+	kind int
+	// string(tripodName + key)
+	key       string
+	blockHash Hash
+}
+
+// TxnView is the state seen by one txn executed speculatively,
+// it records the read-set and write-set of the txn.
+type TxnView struct {
+	ps     *ParallelState
+	reads  map[readKey][]byte
+	writes *TxnStashes
+	// reading from the underlying IState failed, the txn must be re-executed.
+	broken bool
+}
+
+func (v *TxnView) Set(triName NameString, key, value []byte) {
+	v.writes.append(SetOp, makeKey(triName.Name(), key), value)
+}
+
+func (v *TxnView) Delete(triName NameString, key []byte) {
+	v.writes.append(DeleteOp, makeKey(triName.Name(), key), nil)
+}
+
+func (v *TxnView) Get(triName NameString, key []byte) ([]byte, error) {
+	fullKey := makeKey(triName.Name(), key)
+	ops, value := v.writes.get(fullKey)
+	if ops != nil {
+		if *ops == DeleteOp {
+			return nil, nil
+		}
+		return value, nil
+	}
+	return v.read(readKey{kind: readLatest, key: string(fullKey)})
+}
+
+func (v *TxnView) GetFinalized(triName NameString, key []byte) ([]byte, error) {
+	return v.read(readKey{kind: readFinalized, key: string(makeKey(triName.Name(), key))})
+}
+
+func (v *TxnView) GetByBlockHash(triName NameString, key []byte, blockHash Hash) ([]byte, error) {
+	return v.read(readKey{kind: readByBlockHash, key: string(makeKey(triName.Name(), key)), blockHash: blockHash})
+}
+
+func (v *TxnView) Exist(triName NameString, key []byte) bool {
+	value, _ := v.Get(triName, key)
+	return value != nil
+}
+
+func (v *TxnView) read(key readKey) ([]byte, error) {
+	if value, ok := v.reads[key]; ok {
+		return value, nil
+	}
+	value, err := v.ps.read(key)
+	if err != nil {
+		v.broken = true
+		return nil, err
+	}
+	v.reads[key] = value
+	return value, nil
+}
+
+// the keys recorded in TxnView are already prefixed by tripod name.
+type noName struct{}
+
+func (noName) Name() string {
+	return ""
+}
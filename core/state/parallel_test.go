@@ -0,0 +1,83 @@
+package state
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"sync"
+	"testing"
+)
+
+func TestParallelStateConflict(t *testing.T) {
+	kvdb, err := kv.NewKvdb(kvcfg)
+	assert.NoError(t, err)
+	defer removeTestDB()
+	ps := NewParallelState(NewSpmtKV(kvdb))
+
+	tri1 := new(TestTripod1)
+	tri2 := new(TestTripod2)
+
+	// txn1 writes key1, txn2 reads key1, txn3 only writes key2
+	view1, view2, view3 := ps.NewTxnView(), ps.NewTxnView(), ps.NewTxnView()
+	ps.StartSpeculation()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		view1.Set(tri1, key1, value1)
+	}()
+	go func() {
+		defer wg.Done()
+		value, err := view2.Get(tri1, key1)
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+		view2.Set(tri2, key2, value)
+	}()
+	go func() {
+		defer wg.Done()
+		view3.Set(tri2, key2, value2)
+	}()
+	wg.Wait()
+	assert.True(t, ps.EndSpeculation())
+
+	// nothing is written into the underlying state before applying.
+	assert.False(t, ps.Exist(tri1, key1))
+
+	assert.True(t, ps.Apply(view1))
+	ps.NextTxn()
+	assert.False(t, ps.Apply(view2))
+	assert.True(t, ps.Apply(view3))
+	ps.NextTxn()
+
+	value, err := ps.Get(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+	value, err = ps.Get(tri2, key2)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+}
+
+func TestParallelStateBypassed(t *testing.T) {
+	kvdb, err := kv.NewKvdb(kvcfg)
+	assert.NoError(t, err)
+	defer removeTestDB()
+	ps := NewParallelState(NewSpmtKV(kvdb))
+	tri1 := new(TestTripod1)
+	blockA := HexToHash("0a")
+
+	ps.StartBlock(blockA)
+	ps.Set(tri1, key1, value1)
+	_, err = ps.Commit()
+	assert.NoError(t, err)
+
+	view := ps.NewTxnView()
+	ps.StartSpeculation()
+	value, err := view.GetByBlockHash(tri1, key1, blockA)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+	// the write out of views is dropped.
+	ps.Set(tri1, key2, value2)
+	assert.False(t, ps.EndSpeculation())
+	assert.False(t, ps.Exist(tri1, key2))
+	assert.True(t, ps.Apply(view))
+}
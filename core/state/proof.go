@@ -0,0 +1,74 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/celestiaorg/smt"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+)
+
+// StateProof proves the value of key in the state of a block,
+// a nil Value proves that the key does not exist.
+type StateProof struct {
+	BlockHash Hash                         `json:"block_hash"`
+	Key       []byte                       `json:"key"`
+	Value     []byte                       `json:"value"`
+	Proof     smt.SparseCompactMerkleProof `json:"proof"`
+}
+
+func (p *StateProof) Encode() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func DecodeStateProof(data []byte) (*StateProof, error) {
+	proof := new(StateProof)
+	err := json.Unmarshal(data, proof)
+	return proof, err
+}
+
+// Verify checks the value of key against the state root of the block.
+func (p *StateProof) Verify(stateRoot Hash) bool {
+	return smt.VerifyCompactProof(p.Proof, stateRoot.Bytes(), p.Key, p.Value, hasher())
+}
+
+// Matches checks the proof is for the key of tripod.
+func (p *StateProof) Matches(triName NameString, key []byte) bool {
+	return bytes.Equal(p.Key, makeKey(triName.Name(), key))
+}
+
+func (skv *SpmtKV) GetWithProof(triName NameString, key []byte) (*StateProof, error) {
+	// the current block has not been committed while it is executing.
+	blockHash := skv.currentBlock
+	stateRoot, err := skv.getIndexDB(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if stateRoot == nil {
+		blockHash = skv.prevBlock
+		stateRoot, err = skv.getIndexDB(blockHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stateRoot == nil {
+		return nil, StateUnreachable(blockHash)
+	}
+
+	fullKey := makeKey(triName.Name(), key)
+	spmt := skv.importTree(stateRoot)
+	proof, err := spmt.ProveCompact(fullKey)
+	if err != nil {
+		return nil, err
+	}
+	value, err := skv.getAtRoot(stateRoot, fullKey)
+	if err != nil {
+		return nil, err
+	}
+	return &StateProof{
+		BlockHash: blockHash,
+		Key:       fullKey,
+		Value:     value,
+		Proof:     proof,
+	}, nil
+}
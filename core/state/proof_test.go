@@ -0,0 +1,61 @@
+package state
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"testing"
+)
+
+func TestGetWithProof(t *testing.T) {
+	st := newTestState(t, "./test-proof.db")
+	tri1, tri2 := new(TestTripod1), TestTripod2{}
+	blockA, blockB := HexToHash("0a"), HexToHash("0b")
+
+	_, err := st.GetWithProof(tri1, key1)
+	assert.ErrorAs(t, err, new(yerror.ErrStateUnreachable))
+
+	st.StartBlock(blockA)
+	st.Set(tri1, key1, value1)
+	st.Set(tri1, key2, value2)
+	rootA, err := st.Commit()
+	assert.NoError(t, err)
+
+	st.StartBlock(blockB)
+	st.Set(tri1, key1, value2)
+	// blockB is not committed yet, so the proof is against blockA.
+	proof, err := st.GetWithProof(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, blockA, proof.BlockHash)
+	assert.Equal(t, value1, proof.Value)
+	assert.True(t, proof.Matches(tri1, key1))
+	assert.False(t, proof.Matches(tri2, key1))
+	assert.True(t, proof.Verify(BytesToHash(rootA)))
+
+	rootB, err := st.Commit()
+	assert.NoError(t, err)
+	proof, err = st.GetWithProof(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, blockB, proof.BlockHash)
+	assert.Equal(t, value2, proof.Value)
+	assert.True(t, proof.Verify(BytesToHash(rootB)))
+	assert.False(t, proof.Verify(BytesToHash(rootA)))
+
+	// the proof survives encoding.
+	byt, err := proof.Encode()
+	assert.NoError(t, err)
+	proof, err = DecodeStateProof(byt)
+	assert.NoError(t, err)
+	assert.True(t, proof.Verify(BytesToHash(rootB)))
+
+	proof.Value = value1
+	assert.False(t, proof.Verify(BytesToHash(rootB)))
+
+	// absent key
+	proof, err = st.GetWithProof(tri2, key1)
+	assert.NoError(t, err)
+	assert.Nil(t, proof.Value)
+	assert.True(t, proof.Verify(BytesToHash(rootB)))
+	proof.Value = value1
+	assert.False(t, proof.Verify(BytesToHash(rootB)))
+}
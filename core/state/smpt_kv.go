@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"container/list"
 	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"github.com/celestiaorg/smt"
 	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/infra/storage/kv"
 )
 
@@ -21,16 +24,23 @@ import (
 type SpmtKV struct {
 	// blockHash -> stateRoot
 	indexDB KV
+	// blockHash -> stateJournal
+	journalDB KV
 
 	// for spmt
-	nodesDB  KV
-	valuesDB KV
+	nodesDB  *prunedNodes
+	valuesDB *leafValues
+
+	// the latest snapshot of the whole state
+	snapshotDB KV
 
 	spmt *smt.SparseMerkleTree
 
 	prevBlock      Hash
 	currentBlock   Hash
 	finalizedBlock Hash
+	// the current block is read instead of the previous one after it is committed.
+	committed bool
 
 	// FIXME: use ArrayList
 	stashes *list.List // []*TxnStashes
@@ -40,6 +50,10 @@ const (
 	SpmtIndex = "spmt-index"
 	Nodes     = "spmt-nodes"
 	Values    = "spmt-values"
+	Journals  = "spmt-journals"
+	Snapshots = "spmt-snapshots"
+	Stales    = "spmt-stales"
+	Leaves    = "spmt-leaves"
 )
 
 var (
@@ -49,15 +63,20 @@ var (
 
 func NewSpmtKV(kvdb Kvdb) IState {
 	indexDB := kvdb.New(SpmtIndex)
-	nodesDB := kvdb.New(Nodes)
-	valuesDB := kvdb.New(Values)
+	leavesDB := kvdb.New(Leaves)
+	nodesDB := &prunedNodes{KV: kvdb.New(Nodes), staleDB: kvdb.New(Stales), leavesDB: leavesDB}
+	valuesDB := &leafValues{KV: kvdb.New(Values), leavesDB: leavesDB}
+	journalDB := kvdb.New(Journals)
+	snapshotDB := kvdb.New(Snapshots)
 
 	spmt := smt.NewSparseMerkleTree(nodesDB, valuesDB, hasher())
 
 	return &SpmtKV{
 		indexDB:      indexDB,
+		journalDB:    journalDB,
 		nodesDB:      nodesDB,
 		valuesDB:     valuesDB,
+		snapshotDB:   snapshotDB,
 		spmt:         spmt,
 		prevBlock:    NullHash,
 		currentBlock: NullHash,
@@ -109,6 +128,9 @@ func (skv *SpmtKV) get(triName string, key []byte) ([]byte, error) {
 			}
 		}
 	}
+	if skv.committed {
+		return skv.getByBlockHash(triName, key, skv.currentBlock)
+	}
 	return skv.getByBlockHash(triName, key, skv.prevBlock)
 }
 
@@ -133,23 +155,77 @@ func (skv *SpmtKV) GetByBlockHash(triName NameString, key []byte, blockHash Hash
 	return skv.getByBlockHash(triName.Name(), key, blockHash)
 }
 
+// getByBlockHash reads the value from the tree of the block, because smt reads the latest value of key
+// whatever the root is.
 func (skv *SpmtKV) getByBlockHash(triName string, key []byte, blockHash Hash) ([]byte, error) {
 	key = makeKey(triName, key)
 	stateRoot, err := skv.getIndexDB(blockHash)
 	if err != nil {
 		return nil, err
 	}
+	if stateRoot == nil {
+		// no block is committed since startup.
+		if blockHash == NullHash {
+			return skv.getLatest(key)
+		}
+		// the block never committed, such as genesis block, has the empty state.
+		return nil, nil
+	}
+	value, err := skv.getAtRoot(stateRoot, key)
+	if err == errNodePruned {
+		return nil, StateUnreachable(blockHash)
+	}
+	return value, err
+}
 
-	mpt := smt.ImportSparseMerkleTree(skv.nodesDB, skv.valuesDB, hasher(), stateRoot)
-	value, err := mpt.Get(key)
-	if bytes.Equal(value, []byte{}) {
+func (skv *SpmtKV) getLatest(key []byte) ([]byte, error) {
+	value, err := skv.valuesDB.Get(Sha256(key))
+	if len(value) == 0 {
 		// because of https://github.com/celestiaorg/smt/blob/master/smt.go#L14
 		value = nil
 	}
 	return value, err
 }
 
-// Commit returns StateRoot or error
+var errNodePruned = errors.New("spmt node is pruned")
+
+// getAtRoot walks down the tree from the state root to the leaf of key, and reads the value of the leaf.
+func (skv *SpmtKV) getAtRoot(stateRoot, key []byte) ([]byte, error) {
+	path := Sha256(key)
+	node := stateRoot
+	for depth := 0; depth <= 8*HashLen; depth++ {
+		if bytes.Equal(node, NullHash.Bytes()) {
+			return nil, nil
+		}
+		data, err := skv.nodesDB.Get(node)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != 1+2*HashLen {
+			return nil, errNodePruned
+		}
+		if isLeaf(data) {
+			if !bytes.Equal(leafPath(data), path) {
+				return nil, nil
+			}
+			value, err := skv.valuesDB.getLeaf(node)
+			if err == nil && len(value) == 0 {
+				return nil, errNodePruned
+			}
+			return value, err
+		}
+		if path[depth/8]&(1<<(7-depth%8)) != 0 {
+			node = data[1+HashLen:]
+		} else {
+			node = data[1 : 1+HashLen]
+		}
+	}
+	return nil, errNodePruned
+}
+
+// Commit returns StateRoot or error.
+// The StateRoot commits to the whole state, so a block without state changes keeps the StateRoot of its previous block,
+// and only the empty state has the root of empty spmt (NullHash).
 func (skv *SpmtKV) Commit() ([]byte, error) {
 	//lastStateRoot, err := skv.getIndexDB(skv.prevBlock)
 	//if err != nil {
@@ -158,7 +234,20 @@ func (skv *SpmtKV) Commit() ([]byte, error) {
 	//if lastStateRoot == nil {
 	//	lastStateRoot = EmptyRoot.Bytes()
 	//}
-	spmt := smt.NewSparseMerkleTree(skv.nodesDB, skv.valuesDB, hasher())
+	journal, err := skv.makeJournal()
+	if err != nil {
+		skv.DiscardAll()
+		return nil, err
+	}
+
+	lastStateRoot, err := skv.getIndexDB(skv.prevBlock)
+	if err != nil {
+		skv.DiscardAll()
+		return nil, err
+	}
+	// the state root commits to the whole state, so the tree grows on top of the previous block.
+	spmt := skv.importTree(lastStateRoot)
+	skv.nodesDB.startBlock(skv.currentBlock)
 
 	// todo: optimize combine all key-values stashes
 	for element := skv.stashes.Front(); element != nil; element = element.Next() {
@@ -176,14 +265,21 @@ func (skv *SpmtKV) Commit() ([]byte, error) {
 	//	return NullHash, err
 	//}
 	stateRoot := spmt.Root()
+	journal.StaleNodes = skv.nodesDB.stales
 
-	err := skv.setIndexDB(skv.currentBlock, stateRoot)
+	err = skv.setIndexDB(skv.currentBlock, stateRoot)
+	if err != nil {
+		skv.DiscardAll()
+		return nil, err
+	}
+	err = skv.setJournal(skv.currentBlock, journal)
 	if err != nil {
 		skv.DiscardAll()
 		return nil, err
 	}
 
 	skv.stashes.Init()
+	skv.committed = true
 	return stateRoot, nil
 }
 
@@ -205,15 +301,188 @@ func (skv *SpmtKV) DiscardAll() {
 	}
 
 	skv.stashes.Init()
+	skv.committed = true
 }
 
 func (skv *SpmtKV) StartBlock(blockHash Hash) {
 	skv.prevBlock = skv.currentBlock
 	skv.currentBlock = blockHash
+	skv.committed = false
 }
 
 func (skv *SpmtKV) FinalizeBlock(blockHash Hash) {
 	skv.finalizedBlock = blockHash
+	// the state never reverts to the blocks before finalized block,
+	// so their journals and the nodes replaced by them are useless.
+	for block := blockHash; block != NullHash; {
+		journal, err := skv.getJournal(block)
+		if err != nil || journal == nil {
+			return
+		}
+		err = skv.nodesDB.prune(block, journal.StaleNodes)
+		if err != nil {
+			logrus.Error("FinalizeBlock: prune spmt nodes error: ", err)
+			return
+		}
+		err = skv.journalDB.Delete(block.Bytes())
+		if err != nil {
+			logrus.Error("FinalizeBlock: delete state journal error: ", err)
+			return
+		}
+		block = journal.PrevBlock
+	}
+}
+
+// RevertTo reverts the state to the one after the block was committed,
+// by undoing the blocks committed after it one by one.
+func (skv *SpmtKV) RevertTo(blockHash Hash) error {
+	skv.stashes.Init()
+
+	journals := make([]*stateJournal, 0)
+	for block := skv.currentBlock; block != blockHash; {
+		journal, err := skv.getJournal(block)
+		if err != nil {
+			return err
+		}
+		if journal == nil {
+			// the current block may be not committed yet.
+			if block == skv.currentBlock && skv.prevBlock != block {
+				block = skv.prevBlock
+				continue
+			}
+			// the block never committed, such as genesis block, has the empty state before all the blocks.
+			if block == NullHash && !skv.indexDB.Exist(blockHash.Bytes()) {
+				break
+			}
+			return StateUnreachable(blockHash)
+		}
+		journals = append(journals, journal)
+		block = journal.PrevBlock
+	}
+
+	for _, journal := range journals {
+		// the nodes replaced by the reverted blocks are in the tree again.
+		err := skv.nodesDB.keep(journal.StaleNodes)
+		if err != nil {
+			return err
+		}
+		for i := len(journal.Changes) - 1; i >= 0; i-- {
+			change := journal.Changes[i]
+			var err error
+			if change.Value == nil {
+				err = skv.valuesDB.Delete(change.Path)
+			} else {
+				err = skv.valuesDB.Set(change.Path, change.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	skv.prevBlock = blockHash
+	skv.currentBlock = blockHash
+	return nil
+}
+
+func (skv *SpmtKV) importTree(stateRoot []byte) *smt.SparseMerkleTree {
+	if len(stateRoot) == 0 {
+		return smt.NewSparseMerkleTree(skv.nodesDB, skv.valuesDB, hasher())
+	}
+	return smt.ImportSparseMerkleTree(skv.nodesDB, skv.valuesDB, hasher(), stateRoot)
+}
+
+// leafValues keeps the values by the leaves of spmt besides by their paths,
+// so that the values are read from the tree of any block until its leaves are pruned.
+type leafValues struct {
+	// path -> the latest value
+	KV
+	// leaf hash -> value
+	leavesDB KV
+}
+
+func (v *leafValues) Set(path, value []byte) error {
+	// the data of leaf is 0 + path + hash(value).
+	leaf := Sha256(append(append([]byte{0}, path...), Sha256(value)...))
+	err := v.leavesDB.Set(leaf, value)
+	if err != nil {
+		return err
+	}
+	return v.KV.Set(path, value)
+}
+
+func (v *leafValues) getLeaf(leaf []byte) ([]byte, error) {
+	return v.leavesDB.Get(leaf)
+}
+
+// prunedNodes keeps the nodes replaced in spmt until the block replacing them is finalized,
+// so that the trees of earlier blocks are still complete when the state reverts to them.
+type prunedNodes struct {
+	KV
+	// node key -> the block replacing it
+	staleDB KV
+	// the values of leaves are pruned with the leaves.
+	leavesDB KV
+
+	block  Hash
+	stales [][]byte
+}
+
+func (n *prunedNodes) startBlock(blockHash Hash) {
+	n.block = blockHash
+	n.stales = make([][]byte, 0)
+}
+
+// Set keeps the node if it was replaced before, because it is in the tree again.
+func (n *prunedNodes) Set(key, value []byte) error {
+	err := n.staleDB.Delete(key)
+	if err != nil {
+		return err
+	}
+	return n.KV.Set(key, value)
+}
+
+// Delete only marks the node replaced by the current block.
+func (n *prunedNodes) Delete(key []byte) error {
+	n.stales = append(n.stales, bytes.Clone(key))
+	return n.staleDB.Set(key, n.block.Bytes())
+}
+
+// prune deletes the nodes replaced by the finalized block,
+// unless they are set again or replaced by another block later.
+func (n *prunedNodes) prune(blockHash Hash, keys [][]byte) error {
+	for _, key := range keys {
+		replacer, err := n.staleDB.Get(key)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(replacer, blockHash.Bytes()) {
+			continue
+		}
+		err = n.KV.Delete(key)
+		if err != nil {
+			return err
+		}
+		err = n.leavesDB.Delete(key)
+		if err != nil {
+			return err
+		}
+		err = n.staleDB.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *prunedNodes) keep(keys [][]byte) error {
+	for _, key := range keys {
+		err := n.staleDB.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (skv *SpmtKV) setIndexDB(blockHash Hash, stateRoot []byte) error {
@@ -228,6 +497,70 @@ func (skv *SpmtKV) getIndexDB(blockHash Hash) ([]byte, error) {
 	return stateRoot, nil
 }
 
+// stateJournal records the values before a block is committed,
+// so that the state could be reverted when the block is dropped by reorg.
+type stateJournal struct {
+	PrevBlock Hash           `json:"prev_block"`
+	Changes   []*valueChange `json:"changes"`
+	// the spmt nodes replaced by the block
+	StaleNodes [][]byte `json:"stale_nodes"`
+}
+
+type valueChange struct {
+	// the path of key in spmt
+	Path []byte `json:"path"`
+	// nil if the key did not exist
+	Value []byte `json:"value"`
+}
+
+func (skv *SpmtKV) makeJournal() (*stateJournal, error) {
+	journal := &stateJournal{
+		PrevBlock: skv.prevBlock,
+		Changes:   make([]*valueChange, 0),
+	}
+	touched := make(map[string]struct{})
+	for element := skv.stashes.Front(); element != nil; element = element.Next() {
+		stashes := element.Value.(*TxnStashes)
+		for kvElement := stashes.stashes.Front(); kvElement != nil; kvElement = kvElement.Next() {
+			path := Sha256(kvElement.Value.(*KvStash).Key)
+			if _, ok := touched[string(path)]; ok {
+				continue
+			}
+			touched[string(path)] = struct{}{}
+
+			value, err := skv.valuesDB.Get(path)
+			if err != nil {
+				return nil, err
+			}
+			if len(value) == 0 {
+				value = nil
+			} else {
+				value = bytes.Clone(value)
+			}
+			journal.Changes = append(journal.Changes, &valueChange{Path: path, Value: value})
+		}
+	}
+	return journal, nil
+}
+
+func (skv *SpmtKV) setJournal(blockHash Hash, journal *stateJournal) error {
+	byt, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return skv.journalDB.Set(blockHash.Bytes(), byt)
+}
+
+func (skv *SpmtKV) getJournal(blockHash Hash) (*stateJournal, error) {
+	byt, err := skv.journalDB.Get(blockHash.Bytes())
+	if err != nil || byt == nil {
+		return nil, err
+	}
+	journal := new(stateJournal)
+	err = json.Unmarshal(byt, journal)
+	return journal, err
+}
+
 func makeKey(triName string, key []byte) []byte {
 	tripodName := []byte(triName)
 	return append(tripodName, key...)
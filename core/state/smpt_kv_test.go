@@ -3,6 +3,7 @@ package state
 import (
 	"github.com/stretchr/testify/assert"
 	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/config"
 	"github.com/yu-org/yu/infra/storage/kv"
 	"os"
@@ -73,6 +74,170 @@ func TestKvCommit(t *testing.T) {
 	removeTestDB()
 }
 
+func TestKvRevert(t *testing.T) {
+	kvdb, err := kv.NewKvdb(kvcfg)
+	assert.NoError(t, err)
+	statekv := NewSpmtKV(kvdb)
+	defer removeTestDB()
+
+	tri1 := new(TestTripod1)
+	blockA, blockB, blockC := HexToHash("0a"), HexToHash("0b"), HexToHash("0c")
+
+	statekv.StartBlock(blockA)
+	statekv.Set(tri1, key1, value1)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+
+	// an empty block keeps the state of previous block
+	statekv.StartBlock(blockB)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+	value, err := statekv.Get(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+
+	statekv.StartBlock(blockC)
+	statekv.Set(tri1, key1, value2)
+	statekv.Set(tri1, key2, value2)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+
+	assert.NoError(t, statekv.RevertTo(blockB))
+	value, err = statekv.Get(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+	assert.False(t, statekv.Exist(tri1, key2))
+
+	statekv.FinalizeBlock(blockB)
+	assert.Error(t, statekv.RevertTo(blockA))
+}
+
 func removeTestDB() {
 	os.RemoveAll(kvcfg.Path)
 }
+
+func TestKvEmptyCommit(t *testing.T) {
+	kvdb, err := kv.NewKvdb(kvcfg)
+	assert.NoError(t, err)
+	statekv := NewSpmtKV(kvdb)
+	defer removeTestDB()
+
+	tri1 := new(TestTripod1)
+	statekv.StartBlock(HexToHash("0a"))
+	root, err := statekv.Commit()
+	assert.NoError(t, err)
+	assert.Equal(t, NullHash, BytesToHash(root))
+
+	statekv.StartBlock(HexToHash("0b"))
+	statekv.Set(tri1, key1, value1)
+	rootB, err := statekv.Commit()
+	assert.NoError(t, err)
+
+	statekv.StartBlock(HexToHash("0c"))
+	rootC, err := statekv.Commit()
+	assert.NoError(t, err)
+	assert.Equal(t, rootB, rootC)
+}
+
+func TestKvPrune(t *testing.T) {
+	kvdb, err := kv.NewKvdb(kvcfg)
+	assert.NoError(t, err)
+	statekv := NewSpmtKV(kvdb).(*SpmtKV)
+	defer removeTestDB()
+
+	tri1 := new(TestTripod1)
+	blockA, blockB, blockC := HexToHash("0a"), HexToHash("0b"), HexToHash("0c")
+
+	statekv.StartBlock(blockA)
+	statekv.Set(tri1, key1, value1)
+	statekv.Set(tri1, key2, value2)
+	rootA, err := statekv.Commit()
+	assert.NoError(t, err)
+
+	statekv.StartBlock(blockB)
+	statekv.Set(tri1, key1, value2)
+	rootB, err := statekv.Commit()
+	assert.NoError(t, err)
+
+	// the nodes replaced by an unfinalized block are kept for reverting.
+	statekv.FinalizeBlock(blockA)
+	assert.True(t, statekv.nodesDB.Exist(rootA))
+
+	statekv.StartBlock(blockC)
+	statekv.Set(tri1, key2, value1)
+	rootC, err := statekv.Commit()
+	assert.NoError(t, err)
+
+	statekv.FinalizeBlock(blockC)
+	assert.False(t, statekv.nodesDB.Exist(rootA))
+	assert.False(t, statekv.nodesDB.Exist(rootB))
+	assert.True(t, statekv.nodesDB.Exist(rootC))
+	value, err := statekv.GetByBlockHash(tri1, key1, blockC)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+	value, err = statekv.GetByBlockHash(tri1, key2, blockC)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+}
+
+func TestKvGetByOlderBlock(t *testing.T) {
+	kvdb, err := kv.NewKvdb(kvcfg)
+	assert.NoError(t, err)
+	statekv := NewSpmtKV(kvdb)
+	defer removeTestDB()
+
+	tri1 := new(TestTripod1)
+	blockA, blockB, forkB, blockC := HexToHash("0a"), HexToHash("0b"), HexToHash("1b"), HexToHash("0c")
+
+	statekv.StartBlock(blockA)
+	statekv.Set(tri1, key1, value1)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+
+	statekv.StartBlock(blockB)
+	statekv.Set(tri1, key1, value2)
+	statekv.Set(tri1, key2, value2)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+
+	// the committed block is read until the next block starts.
+	value, err := statekv.Get(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+
+	// the older block is read after the later write.
+	value, err = statekv.GetByBlockHash(tri1, key1, blockA)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+	value, err = statekv.GetByBlockHash(tri1, key2, blockA)
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = statekv.GetByBlockHash(tri1, key1, blockB)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+
+	// the fork of block B is read besides block B.
+	assert.NoError(t, statekv.RevertTo(blockA))
+	statekv.StartBlock(forkB)
+	statekv.Set(tri1, key2, value1)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+	value, err = statekv.GetByBlockHash(tri1, key2, forkB)
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+	value, err = statekv.GetByBlockHash(tri1, key2, blockB)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+
+	// the tree of the fork is pruned after the block replacing it is finalized.
+	statekv.StartBlock(blockC)
+	statekv.Set(tri1, key2, value2)
+	_, err = statekv.Commit()
+	assert.NoError(t, err)
+	statekv.FinalizeBlock(blockC)
+	_, err = statekv.GetByBlockHash(tri1, key1, forkB)
+	assert.ErrorAs(t, err, new(yerror.ErrStateUnreachable))
+	value, err = statekv.GetByBlockHash(tri1, key2, blockC)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+}
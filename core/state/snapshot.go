@@ -0,0 +1,282 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/infra/storage/kv"
+	"strconv"
+)
+
+const defaultSnapshotChunkSize = 4096
+
+var snapshotMetaKey = []byte("meta")
+
+// SnapshotMeta describes the snapshot of the whole state at a block.
+type SnapshotMeta struct {
+	BlockHash Hash     `json:"block_hash"`
+	Height    BlockNum `json:"height"`
+	StateRoot Hash     `json:"state_root"`
+	Chunks    int      `json:"chunks"`
+}
+
+// SnapshotChunk is a part of the snapshot, all the spmt-nodes come before the spmt-values.
+type SnapshotChunk struct {
+	Nodes  []*SnapshotEntry `json:"nodes"`
+	Values []*SnapshotEntry `json:"values"`
+}
+
+type SnapshotEntry struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+func (c *SnapshotChunk) Encode() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func DecodeSnapshotChunk(data []byte) (*SnapshotChunk, error) {
+	chunk := new(SnapshotChunk)
+	err := json.Unmarshal(data, chunk)
+	return chunk, err
+}
+
+// TakeSnapshot replaces the latest snapshot with the state of the block,
+// it must be called before any change of the next block.
+func (skv *SpmtKV) TakeSnapshot(blockHash Hash, height BlockNum, chunkSize int) (*SnapshotMeta, error) {
+	if skv.currentBlock != NullHash && skv.currentBlock != blockHash {
+		return nil, StateUnreachable(blockHash)
+	}
+	stateRoot, err := skv.getIndexDB(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if stateRoot == nil {
+		return nil, StateUnreachable(blockHash)
+	}
+	oldMeta, err := skv.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultSnapshotChunkSize
+	}
+
+	w := &chunkWriter{db: skv.snapshotDB, size: chunkSize, chunk: new(SnapshotChunk)}
+	// two passes keep the nodes before the values.
+	err = skv.walkTree(stateRoot, func(hash, data []byte) error {
+		return w.add(&SnapshotEntry{Key: hash, Value: data}, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = skv.walkTree(stateRoot, func(hash, data []byte) error {
+		if !isLeaf(data) {
+			return nil
+		}
+		value, err := skv.valuesDB.getLeaf(hash)
+		if err != nil {
+			return err
+		}
+		return w.add(&SnapshotEntry{Key: leafPath(data), Value: value}, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = w.flush()
+	if err != nil {
+		return nil, err
+	}
+
+	if oldMeta != nil {
+		for i := w.index; i < oldMeta.Chunks; i++ {
+			err = skv.snapshotDB.Delete(chunkKey(i))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	meta := &SnapshotMeta{
+		BlockHash: blockHash,
+		Height:    height,
+		StateRoot: BytesToHash(stateRoot),
+		Chunks:    w.index,
+	}
+	byt, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	return meta, skv.snapshotDB.Set(snapshotMetaKey, byt)
+}
+
+// GetSnapshot returns the latest snapshot, nil if no snapshot is taken.
+func (skv *SpmtKV) GetSnapshot() (*SnapshotMeta, error) {
+	byt, err := skv.snapshotDB.Get(snapshotMetaKey)
+	if err != nil || byt == nil {
+		return nil, err
+	}
+	meta := new(SnapshotMeta)
+	err = json.Unmarshal(byt, meta)
+	return meta, err
+}
+
+func (skv *SpmtKV) GetSnapshotChunk(index int) (*SnapshotChunk, error) {
+	byt, err := skv.snapshotDB.Get(chunkKey(index))
+	if err != nil {
+		return nil, err
+	}
+	if byt == nil {
+		return nil, NoSnapshot
+	}
+	return DecodeSnapshotChunk(byt)
+}
+
+// RestoreSnapshot rebuilds the state from the chunks fetched in order and checks them against the state root,
+// then the state is at the block of snapshot. It must be called on an empty state.
+func (skv *SpmtKV) RestoreSnapshot(meta *SnapshotMeta, fetch func(index int) (*SnapshotChunk, error)) error {
+	// path -> hash of value, collected from the restored nodes before restoring values.
+	var leaves map[string][]byte
+	for i := 0; i < meta.Chunks; i++ {
+		chunk, err := fetch(i)
+		if err != nil {
+			return err
+		}
+		for _, node := range chunk.Nodes {
+			if leaves != nil || !bytes.Equal(Sha256(node.Value), node.Key) {
+				return SnapshotMismatched
+			}
+			err = skv.nodesDB.Set(node.Key, node.Value)
+			if err != nil {
+				return err
+			}
+		}
+		if len(chunk.Values) > 0 && leaves == nil {
+			leaves, err = skv.collectLeaves(meta.StateRoot)
+			if err != nil {
+				return err
+			}
+		}
+		for _, value := range chunk.Values {
+			valueHash, ok := leaves[string(value.Key)]
+			if !ok || !bytes.Equal(Sha256(value.Value), valueHash) {
+				return SnapshotMismatched
+			}
+			err = skv.valuesDB.Set(value.Key, value.Value)
+			if err != nil {
+				return err
+			}
+			delete(leaves, string(value.Key))
+		}
+	}
+	if leaves == nil {
+		var err error
+		leaves, err = skv.collectLeaves(meta.StateRoot)
+		if err != nil {
+			return err
+		}
+	}
+	if len(leaves) > 0 {
+		return SnapshotMismatched
+	}
+
+	err := skv.setIndexDB(meta.BlockHash, meta.StateRoot.Bytes())
+	if err != nil {
+		return err
+	}
+	skv.stashes.Init()
+	skv.prevBlock = meta.BlockHash
+	skv.currentBlock = meta.BlockHash
+	skv.finalizedBlock = meta.BlockHash
+	return nil
+}
+
+func (skv *SpmtKV) collectLeaves(stateRoot Hash) (map[string][]byte, error) {
+	leaves := make(map[string][]byte)
+	err := skv.walkTree(stateRoot.Bytes(), func(hash, data []byte) error {
+		if !bytes.Equal(Sha256(data), hash) {
+			return SnapshotMismatched
+		}
+		if isLeaf(data) {
+			leaves[string(leafPath(data))] = data[1+HashLen:]
+		}
+		return nil
+	})
+	return leaves, err
+}
+
+// walkTree visits all the nodes of spmt from the root in depth-first order.
+func (skv *SpmtKV) walkTree(root []byte, visit func(hash, data []byte) error) error {
+	if bytes.Equal(root, NullHash.Bytes()) {
+		return nil
+	}
+	data, err := skv.nodesDB.Get(root)
+	if err != nil {
+		return err
+	}
+	if len(data) != 1+2*HashLen {
+		return SnapshotMismatched
+	}
+	err = visit(root, data)
+	if err != nil || isLeaf(data) {
+		return err
+	}
+	err = skv.walkTree(data[1:1+HashLen], visit)
+	if err != nil {
+		return err
+	}
+	return skv.walkTree(data[1+HashLen:], visit)
+}
+
+// the data of spmt leaf is 0 + path + hash(value), the data of inner node is 1 + left + right.
+func isLeaf(data []byte) bool {
+	return data[0] == 0
+}
+
+func leafPath(data []byte) []byte {
+	return data[1 : 1+HashLen]
+}
+
+func chunkKey(index int) []byte {
+	return []byte("chunk-" + strconv.Itoa(index))
+}
+
+type chunkWriter struct {
+	db    KV
+	size  int
+	index int
+	count int
+	chunk *SnapshotChunk
+}
+
+func (w *chunkWriter) add(entry *SnapshotEntry, isValue bool) error {
+	if isValue {
+		w.chunk.Values = append(w.chunk.Values, entry)
+	} else {
+		w.chunk.Nodes = append(w.chunk.Nodes, entry)
+	}
+	w.count++
+	if w.count < w.size {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *chunkWriter) flush() error {
+	if w.count == 0 {
+		return nil
+	}
+	byt, err := w.chunk.Encode()
+	if err != nil {
+		return err
+	}
+	err = w.db.Set(chunkKey(w.index), byt)
+	if err != nil {
+		return err
+	}
+	w.index++
+	w.count = 0
+	w.chunk = new(SnapshotChunk)
+	return nil
+}
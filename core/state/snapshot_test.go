@@ -0,0 +1,77 @@
+package state
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/infra/storage/kv"
+	"os"
+	"testing"
+)
+
+func newTestState(t *testing.T, path string) IState {
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: path})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(path)
+	})
+	return NewSpmtKV(kvdb)
+}
+
+func TestSnapshot(t *testing.T) {
+	src := newTestState(t, "./test-snapshot-src.db")
+	tri1 := new(TestTripod1)
+	blockA, blockB, blockC := HexToHash("0a"), HexToHash("0b"), HexToHash("0c")
+
+	src.StartBlock(blockA)
+	src.Set(tri1, key1, value1)
+	src.Set(tri1, key2, value2)
+	_, err := src.Commit()
+	assert.NoError(t, err)
+
+	src.StartBlock(blockB)
+	src.Set(tri1, key1, value2)
+	src.Delete(tri1, key2)
+	src.Set(tri1, []byte("key3"), value1)
+	rootB, err := src.Commit()
+	assert.NoError(t, err)
+
+	meta, err := src.TakeSnapshot(blockB, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, BytesToHash(rootB), meta.StateRoot)
+	assert.Greater(t, meta.Chunks, 1)
+
+	dst := newTestState(t, "./test-snapshot-dst.db")
+	assert.NoError(t, dst.RestoreSnapshot(meta, src.GetSnapshotChunk))
+
+	value, err := dst.Get(tri1, key1)
+	assert.NoError(t, err)
+	assert.Equal(t, value2, value)
+	assert.False(t, dst.Exist(tri1, key2))
+	value, err = dst.Get(tri1, []byte("key3"))
+	assert.NoError(t, err)
+	assert.Equal(t, value1, value)
+
+	// the restored state goes on with the same state root.
+	for _, st := range []IState{src, dst} {
+		st.StartBlock(blockC)
+		st.Set(tri1, key2, value1)
+	}
+	rootC1, err := src.Commit()
+	assert.NoError(t, err)
+	rootC2, err := dst.Commit()
+	assert.NoError(t, err)
+	assert.Equal(t, rootC1, rootC2)
+
+	// a tampered value is rejected.
+	bad := newTestState(t, "./test-snapshot-bad.db")
+	err = bad.RestoreSnapshot(meta, func(index int) (*SnapshotChunk, error) {
+		chunk, err := src.GetSnapshotChunk(index)
+		for _, entry := range chunk.Values {
+			entry.Value = []byte("evil")
+		}
+		return chunk, err
+	})
+	assert.ErrorIs(t, err, yerror.SnapshotMismatched)
+}
@@ -1,6 +1,7 @@
 package tripod
 
 import (
+	. "github.com/yu-org/yu/core/context"
 	. "github.com/yu-org/yu/core/types"
 )
 
@@ -25,3 +26,16 @@ type DefaultBlockCycle struct{}
 func (*DefaultBlockCycle) StartBlock(*Block)    {}
 func (*DefaultBlockCycle) EndBlock(*Block)      {}
 func (*DefaultBlockCycle) FinalizeBlock(*Block) {}
+
+type DefaultBlockTransition struct{}
+
+func (*DefaultBlockTransition) ApplyBlock(*Block) error { return nil }
+
+type DefaultReorgHandler struct{}
+
+func (*DefaultReorgHandler) HandleReorg(*Reorg) {}
+
+type DefaultTxnCycle struct{}
+
+func (*DefaultTxnCycle) StartTxn(*WriteContext) error   { return nil }
+func (*DefaultTxnCycle) EndTxn(*WriteContext, *Receipt) {}
@@ -9,28 +9,23 @@ import (
 	"google.golang.org/grpc"
 )
 
+// GrpcWrRd calls the Writing and Reading of tripods which run on the workers.
 type GrpcWrRd struct {
-	targetAddr string
+	conn       *grpc.ClientConn
 	tripodName string
 	funcName   string
 }
 
-func NewGrpcWrRd(targetAddr, tripodName, funcName string) *GrpcWrRd {
+func NewGrpcWrRd(conn *grpc.ClientConn, tripodName, funcName string) *GrpcWrRd {
 	return &GrpcWrRd{
-		targetAddr: targetAddr,
+		conn:       conn,
 		tripodName: tripodName,
 		funcName:   funcName,
 	}
 }
 
 func (rpc *GrpcWrRd) Write(ctx *WriteContext) error {
-	conn, err := grpc.Dial(rpc.targetAddr)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	cli := goproto.NewWritingClient(conn)
+	cli := goproto.NewWritingClient(rpc.conn)
 	res, err := cli.Write(context.Background(), &goproto.WriteContext{
 		ReadContext: &goproto.ReadContext{
 			ParamsStr:  ctx.ParamsStr,
@@ -45,39 +40,37 @@ func (rpc *GrpcWrRd) Write(ctx *WriteContext) error {
 	if err != nil {
 		return err
 	}
-	if res.Error != nil {
-		return errors.New(res.Error.GetMsg())
-	}
 
-	events := make([]*Event, 0)
-	for _, value := range res.Values {
-		events = append(events, &Event{Value: value})
+	events := make([]*Event, 0, len(res.Events))
+	for _, event := range res.Events {
+		events = append(events, EventFromPb(event))
 	}
 	ctx.Events = events
+	ctx.Extra = res.Extra
+	ctx.LeiCost = res.LeiCost
+
+	if res.Error != nil && res.Error.Msg != "" {
+		return errors.New(res.Error.GetMsg())
+	}
 	return nil
 }
 
-//func (rpc *GrpcWrRd) Read(ctx *ReadContext) error {
-//	conn, err := grpc.Dial(rpc.targetAddr)
-//	if err != nil {
-//		return err
-//	}
-//	defer conn.Close()
-//
-//	cli := goproto.NewReadingClient(conn)
-//	res, err := cli.Read(context.Background(), &goproto.ReadContext{
-//		ParamsStr:  ctx.ParamsStr,
-//		Response:   nil,
-//		TripodName: rpc.tripodName,
-//		FuncName:   rpc.funcName,
-//	})
-//	if err != nil {
-//		return err
-//	}
-//	if res.Error != nil {
-//		return errors.New(res.Error.GetMsg())
-//	}
-//
-//	ctx.Bytes(res.GetResponse())
-//	return nil
-//}
+func (rpc *GrpcWrRd) Read(ctx *ReadContext) {
+	cli := goproto.NewReadingClient(rpc.conn)
+	res, err := cli.Read(context.Background(), &goproto.ReadContext{
+		ParamsStr:  ctx.ParamsStr(),
+		Response:   nil,
+		TripodName: rpc.tripodName,
+		FuncName:   rpc.funcName,
+	})
+	if err != nil {
+		ctx.ErrOk(err)
+		return
+	}
+	if res.Error != nil && res.Error.Msg != "" {
+		ctx.ErrOk(errors.New(res.Error.GetMsg()))
+		return
+	}
+
+	ctx.DataOk(res.GetContentType(), res.GetResponse())
+}
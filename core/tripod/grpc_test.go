@@ -0,0 +1,109 @@
+package tripod
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/tripod/dev"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+type workerTripod struct {
+	*Tripod
+}
+
+func newWorkerTripod() *workerTripod {
+	wt := &workerTripod{Tripod: NewTripodWithName("worker")}
+	wt.SetWritings(wt.Echo)
+	wt.SetReadings(wt.Hello)
+	return wt
+}
+
+func (wt *workerTripod) Echo(ctx *WriteContext) error {
+	ctx.SetLei(ctx.LeiCost + 10)
+	ctx.Extra = []byte("extra")
+	ctx.EmitStringEvent("echo %s", ctx.ParamsStr)
+	ctx.EmitEvent([]byte{0, 1, 2})
+	if ctx.Get("fail") != nil {
+		return errors.New("echo failed")
+	}
+	return nil
+}
+
+func (wt *workerTripod) Hello(ctx *ReadContext) {
+	ctx.DataOk("text/plain", []byte("hello "+ctx.ParamsStr()))
+}
+
+// dialWorker serves the tripods of worker in memory, returns the connection from master.
+func dialWorker(t *testing.T, tripods ...interface{}) *grpc.ClientConn {
+	land := NewLand()
+	for _, v := range tripods {
+		tri := ResolveTripod(v)
+		tri.SetLand(land)
+		tri.SetInstance(v)
+		land.SetTripods(tri)
+	}
+	worker := NewGrpcWorker(land)
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	goproto.RegisterTripodServer(server, worker)
+	goproto.RegisterWritingServer(server, worker)
+	goproto.RegisterReadingServer(server, worker)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func TestGrpcWorker(t *testing.T) {
+	conn := dialWorker(t, newWorkerTripod())
+	block := &Block{Header: &Header{Height: 1, Hash: HexToHash("0a")}}
+
+	write := func(params string) (*WriteContext, error) {
+		stxn, err := NewSignedTxn(&WrCall{TripodName: "worker", FuncName: "Echo", Params: params}, nil, nil)
+		assert.NoError(t, err)
+		ctx, err := NewWriteContext(stxn, block)
+		assert.NoError(t, err)
+		ctx.LeiCost = 5
+		return ctx, dev.NewGrpcWrRd(conn, "worker", "Echo").Write(ctx)
+	}
+
+	ctx, err := write(`{"name":"yu"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(15), ctx.LeiCost)
+	assert.Equal(t, []byte("extra"), ctx.Extra)
+	assert.Equal(t, []*Event{{Value: []byte(`echo {"name":"yu"}`)}, {Value: []byte{0, 1, 2}}}, ctx.Events)
+
+	// the events and lei are kept even if the writing fails.
+	ctx, err = write(`{"fail":true}`)
+	assert.EqualError(t, err, "echo failed")
+	assert.Equal(t, uint64(15), ctx.LeiCost)
+	assert.Len(t, ctx.Events, 2)
+
+	err = dev.NewGrpcWrRd(conn, "worker", "Unknown").Write(ctx)
+	assert.Error(t, err)
+
+	rdCtx, err := NewReadContext(&RdCall{TripodName: "worker", FuncName: "Hello", Params: `{"name":"yu"}`})
+	assert.NoError(t, err)
+	dev.NewGrpcWrRd(conn, "worker", "Hello").Read(rdCtx)
+	assert.Equal(t, []byte(`hello {"name":"yu"}`), rdCtx.Response().DataBytes)
+}
@@ -1,6 +1,7 @@
 package tripod
 
 import (
+	. "github.com/yu-org/yu/core/context"
 	. "github.com/yu-org/yu/core/types"
 )
 
@@ -21,3 +22,24 @@ type BlockCycle interface {
 	EndBlock(block *Block)
 	FinalizeBlock(block *Block)
 }
+
+// BlockTransition changes the state of every block before its txns, such as paying the block rewards.
+// It is called in Execute, so that the nodes syncing, reorging or executing the block again make the same changes.
+type BlockTransition interface {
+	ApplyBlock(block *Block) error
+}
+
+// ReorgHandler is called after the canonical chain reorgs and the state is reverted to the ancestor block,
+// tripods could undo the side effects of the reverted blocks.
+type ReorgHandler interface {
+	HandleReorg(reorg *Reorg)
+}
+
+// TxnCycle is called around every txn executed in the block.
+type TxnCycle interface {
+	// StartTxn is called before the Writing, if it returns error, neither the Writing nor EndTxn will be called.
+	StartTxn(ctx *WriteContext) error
+	// EndTxn is called after the Writing no matter the Writing returns error or not,
+	// such as charging fees. The receipt has not been filled with metadata yet.
+	EndTxn(ctx *WriteContext, receipt *Receipt)
+}
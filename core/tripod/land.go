@@ -3,9 +3,13 @@ package tripod
 import (
 	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/core/tripod/dev"
+	"sync"
 )
 
 type Land struct {
+	// tripods of workers may be set while the chain is running in MasterWorker mode.
+	lock sync.RWMutex
+
 	OrderedTripods []*Tripod
 	// Key: the Name of Tripod
 	TripodsMap map[string]*Tripod
@@ -19,6 +23,8 @@ func NewLand() *Land {
 }
 
 func (l *Land) SetTripods(Tripods ...*Tripod) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
 	for _, tri := range Tripods {
 		triName := tri.Name()
 		l.TripodsMap[triName] = tri
@@ -28,14 +34,14 @@ func (l *Land) SetTripods(Tripods ...*Tripod) {
 }
 
 func (l *Land) GetTripodInstance(name string) interface{} {
-	if tri, ok := l.TripodsMap[name]; ok {
+	if tri, ok := l.getTripod(name); ok {
 		return tri.Instance
 	}
 	return nil
 }
 
 func (l *Land) GetWriting(tripodName, wrName string) (Writing, error) {
-	tripod, ok := l.TripodsMap[tripodName]
+	tripod, ok := l.getTripod(tripodName)
 	if !ok {
 		return nil, TripodNotFound(tripodName)
 	}
@@ -47,7 +53,7 @@ func (l *Land) GetWriting(tripodName, wrName string) (Writing, error) {
 }
 
 func (l *Land) GetReading(tripodName, rdName string) (Reading, error) {
-	tri, ok := l.TripodsMap[tripodName]
+	tri, ok := l.getTripod(tripodName)
 	if !ok {
 		return nil, TripodNotFound(tripodName)
 	}
@@ -58,8 +64,22 @@ func (l *Land) GetReading(tripodName, rdName string) (Reading, error) {
 	return rd, nil
 }
 
+func (l *Land) getTripod(name string) (*Tripod, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	tri, ok := l.TripodsMap[name]
+	return tri, ok
+}
+
 func (l *Land) RangeMap(fn func(string, *Tripod) error) error {
+	l.lock.RLock()
+	tripodsMap := make(map[string]*Tripod, len(l.TripodsMap))
 	for name, tri := range l.TripodsMap {
+		tripodsMap[name] = tri
+	}
+	l.lock.RUnlock()
+
+	for name, tri := range tripodsMap {
 		err := fn(name, tri)
 		if err != nil {
 			return err
@@ -69,7 +89,12 @@ func (l *Land) RangeMap(fn func(string, *Tripod) error) error {
 }
 
 func (l *Land) RangeList(fn func(*Tripod) error) error {
-	for _, tri := range l.OrderedTripods {
+	l.lock.RLock()
+	tripods := make([]*Tripod, len(l.OrderedTripods))
+	copy(tripods, l.OrderedTripods)
+	l.lock.RUnlock()
+
+	for _, tri := range tripods {
 		err := fn(tri)
 		if err != nil {
 			return err
@@ -2,35 +2,138 @@ package tripod
 
 import (
 	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/core/env"
 	"github.com/yu-org/yu/core/tripod/dev"
+	. "github.com/yu-org/yu/core/types"
 	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// GrpcLand runs on master, workers register their tripods into master's Land by it.
 type GrpcLand struct {
 	land *Land
+	env  *ChainEnv
 }
 
-func NewGrpcLand(land *Land) *GrpcLand {
-	return &GrpcLand{land}
+func NewGrpcLand(land *Land, env *ChainEnv) *GrpcLand {
+	return &GrpcLand{land: land, env: env}
 }
 
 func (g *GrpcLand) SetTripods(_ context.Context, info *goproto.TripodsInfo) (*emptypb.Empty, error) {
 	tripods := make([]*Tripod, 0)
 	for _, triInfo := range info.Tripods {
+		conn, err := grpc.Dial(triInfo.Endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, err
+		}
+
 		tripod := NewTripodWithName(triInfo.Name)
 		for _, wrName := range triInfo.Writings {
-			wrRd := dev.NewGrpcWrRd(triInfo.Endpoint, triInfo.Name, wrName)
+			wrRd := dev.NewGrpcWrRd(conn, triInfo.Name, wrName)
 			tripod.writings[wrName] = wrRd.Write
 		}
-		//for _, rdName := range triInfo.Readings {
-		//	wrRd := dev.NewGrpcWrRd(triInfo.Endpoint, triInfo.Name, rdName)
-		//	tripod.readings[rdName] = wrRd.Read
-		//}
+		for _, rdName := range triInfo.Readings {
+			wrRd := dev.NewGrpcWrRd(conn, triInfo.Name, rdName)
+			tripod.readings[rdName] = wrRd.Read
+		}
 		// todo: set p2pHandles
 
-		tripods = append(tripods)
+		remote := newRemoteTripod(conn, triInfo.Name)
+		tripod.SetTxnChecker(remote)
+		tripod.SetBlockVerifier(remote)
+		tripod.SetBlockCycle(remote)
+
+		tripod.SetChainEnv(g.env)
+		tripod.SetLand(g.land)
+		tripod.SetInstance(remote)
+
+		g.env.Pool.WithTripodCheck(tripod)
+
+		logrus.Infof("worker(%s) registers Tripod(%s)", triInfo.Endpoint, triInfo.Name)
+		tripods = append(tripods, tripod)
 	}
 	g.land.SetTripods(tripods...)
-	return nil, nil
+	return &emptypb.Empty{}, nil
+}
+
+// remoteTripod calls the tripod which runs on the worker.
+type remoteTripod struct {
+	cli  goproto.TripodClient
+	name string
+}
+
+func newRemoteTripod(conn *grpc.ClientConn, name string) *remoteTripod {
+	return &remoteTripod{
+		cli:  goproto.NewTripodClient(conn),
+		name: name,
+	}
+}
+
+func (r *remoteTripod) CheckTxn(txn *SignedTxn) error {
+	res, err := r.cli.CheckTxn(context.Background(), &goproto.TripodTxnRequest{
+		TripodName: r.name,
+		Txn:        txn.ToPb(),
+	})
+	if err != nil {
+		return err
+	}
+	if res.Msg != "" {
+		return errors.New(res.Msg)
+	}
+	return nil
+}
+
+func (r *remoteTripod) VerifyBlock(block *Block) bool {
+	res, err := r.cli.VerifyBlock(context.Background(), r.blockRequest(block))
+	if err != nil {
+		logrus.Errorf("verify block on remote Tripod(%s) error: %v", r.name, err)
+		return false
+	}
+	return res.Ok
+}
+
+func (r *remoteTripod) StartBlock(block *Block) {
+	res, err := r.cli.StartBlock(context.Background(), r.blockRequest(block))
+	r.handleErr("start block", res, err)
+}
+
+func (r *remoteTripod) EndBlock(block *Block) {
+	res, err := r.cli.EndBlock(context.Background(), r.blockRequest(block))
+	r.handleErr("end block", res, err)
+}
+
+func (r *remoteTripod) FinalizeBlock(block *Block) {
+	res, err := r.cli.FinalizeBlock(context.Background(), r.blockRequest(block))
+	r.handleErr("finalize block", res, err)
+}
+
+func (r *remoteTripod) blockRequest(block *Block) *goproto.TripodBlockRequest {
+	return &goproto.TripodBlockRequest{
+		TripodName: r.name,
+		Block:      block.Compact().ToPb(),
+		Txns:       block.Txns.ToPb(),
+	}
+}
+
+func (r *remoteTripod) handleErr(stage string, res *goproto.Err, err error) {
+	if err == nil && res.Msg != "" {
+		err = errors.New(res.Msg)
+	}
+	if err != nil {
+		logrus.Errorf("%s on remote Tripod(%s) error: %v", stage, r.name, err)
+	}
+}
+
+// IsRemote reports whether the tripod runs on a worker.
+func (l *Land) IsRemote(tripodName string) bool {
+	tri, ok := l.getTripod(tripodName)
+	if !ok {
+		return false
+	}
+	_, ok = tri.Instance.(*remoteTripod)
+	return ok
 }
@@ -1,6 +1,10 @@
 package tripod
 
-import . "github.com/yu-org/yu/common"
+import (
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/core/context"
+	"github.com/yu-org/yu/core/state"
+)
 
 func (t *Tripod) Set(key, value []byte) {
 	t.State.Set(t, key, value)
@@ -45,3 +49,51 @@ func (t *Tripod) Discard() {
 func (t *Tripod) DiscardAll() {
 	t.State.DiscardAll()
 }
+
+// TxnState is the state of tripod seen by the executing txn.
+// Writings and TxnCycles must read and write the state through it to be executed in parallel.
+type TxnState struct {
+	tri *Tripod
+	kv  state.StateKV
+}
+
+func (t *Tripod) TxnState(ctx *context.WriteContext) *TxnState {
+	if ctx.State == nil {
+		return &TxnState{tri: t, kv: t.State}
+	}
+	return &TxnState{tri: t, kv: ctx.State}
+}
+
+func (s *TxnState) Set(key, value []byte) {
+	s.kv.Set(s.tri, key, value)
+}
+
+func (s *TxnState) Get(key []byte) ([]byte, error) {
+	return s.kv.Get(s.tri, key)
+}
+
+func (s *TxnState) Delete(key []byte) {
+	s.kv.Delete(s.tri, key)
+}
+
+func (s *TxnState) GetFinalized(key []byte) ([]byte, error) {
+	return s.kv.GetFinalized(s.tri, key)
+}
+
+func (s *TxnState) Exist(key []byte) bool {
+	return s.kv.Exist(s.tri, key)
+}
+
+func (s *TxnState) GetByBlockHash(key []byte, blockHash Hash) ([]byte, error) {
+	return s.kv.GetByBlockHash(s.tri, key, blockHash)
+}
+
+// TripodKV reads and writes the state of tripod, both Tripod and TxnState implement it.
+type TripodKV interface {
+	Set(key, value []byte)
+	Get(key []byte) ([]byte, error)
+	Delete(key []byte)
+	GetFinalized(key []byte) ([]byte, error)
+	Exist(key []byte) bool
+	GetByBlockHash(key []byte, blockHash Hash) ([]byte, error)
+}
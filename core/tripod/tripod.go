@@ -20,6 +20,9 @@ type Tripod struct {
 
 	Init
 	BlockCycle
+	BlockTransition
+	TxnCycle
+	ReorgHandler
 
 	Instance interface{}
 
@@ -43,10 +46,13 @@ func NewTripodWithName(name string) *Tripod {
 		readings:    make(map[string]Reading),
 		P2pHandlers: make(map[int]P2pHandler),
 
-		BlockVerifier: &DefaultBlockVerifier{},
-		TxnChecker:    &DefaultTxnChecker{},
-		Init:          &DefaultInit{},
-		BlockCycle:    &DefaultBlockCycle{},
+		BlockVerifier:   &DefaultBlockVerifier{},
+		TxnChecker:      &DefaultTxnChecker{},
+		Init:            &DefaultInit{},
+		BlockCycle:      &DefaultBlockCycle{},
+		BlockTransition: &DefaultBlockTransition{},
+		TxnCycle:        &DefaultTxnCycle{},
+		ReorgHandler:    &DefaultReorgHandler{},
 	}
 }
 
@@ -93,6 +99,18 @@ func (t *Tripod) SetBlockCycle(bc BlockCycle) {
 	t.BlockCycle = bc
 }
 
+func (t *Tripod) SetBlockTransition(bt BlockTransition) {
+	t.BlockTransition = bt
+}
+
+func (t *Tripod) SetTxnCycle(tc TxnCycle) {
+	t.TxnCycle = tc
+}
+
+func (t *Tripod) SetReorgHandler(rh ReorgHandler) {
+	t.ReorgHandler = rh
+}
+
 func (t *Tripod) SetBlockVerifier(bv BlockVerifier) {
 	t.BlockVerifier = bv
 }
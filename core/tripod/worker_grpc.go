@@ -0,0 +1,171 @@
+package tripod
+
+import (
+	"context"
+	"encoding/json"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/context"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+)
+
+const jsonContentType = "application/json"
+
+// GrpcWorker runs on the worker, it serves the tripods of worker for master.
+type GrpcWorker struct {
+	land *Land
+}
+
+func NewGrpcWorker(land *Land) *GrpcWorker {
+	return &GrpcWorker{land: land}
+}
+
+func (g *GrpcWorker) TripodsInfo(endpoint string) *goproto.TripodsInfo {
+	infos := make([]*goproto.TripodInfo, 0)
+	_ = g.land.RangeList(func(tri *Tripod) error {
+		infos = append(infos, &goproto.TripodInfo{
+			Name:     tri.Name(),
+			Endpoint: endpoint,
+			Readings: tri.AllReadingNames(),
+			Writings: tri.AllWritingNames(),
+		})
+		return nil
+	})
+	return &goproto.TripodsInfo{Tripods: infos}
+}
+
+func (g *GrpcWorker) CheckTxn(_ context.Context, req *goproto.TripodTxnRequest) (*goproto.Err, error) {
+	tri, ok := g.land.getTripod(req.TripodName)
+	if !ok {
+		return makeErr(TripodNotFound(req.TripodName)), nil
+	}
+	txn, err := SignedTxnFromPb(req.Txn)
+	if err != nil {
+		return makeErr(err), nil
+	}
+	return makeErr(tri.CheckTxn(txn)), nil
+}
+
+func (g *GrpcWorker) VerifyBlock(_ context.Context, req *goproto.TripodBlockRequest) (*goproto.Bool, error) {
+	tri, block, err := g.resolveBlockRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return &goproto.Bool{Ok: tri.VerifyBlock(block)}, nil
+}
+
+func (g *GrpcWorker) StartBlock(_ context.Context, req *goproto.TripodBlockRequest) (*goproto.Err, error) {
+	tri, block, err := g.resolveBlockRequest(req)
+	if err != nil {
+		return makeErr(err), nil
+	}
+	tri.StartBlock(block)
+	return makeErr(nil), nil
+}
+
+func (g *GrpcWorker) EndBlock(_ context.Context, req *goproto.TripodBlockRequest) (*goproto.Err, error) {
+	tri, block, err := g.resolveBlockRequest(req)
+	if err != nil {
+		return makeErr(err), nil
+	}
+	tri.EndBlock(block)
+	return makeErr(nil), nil
+}
+
+func (g *GrpcWorker) FinalizeBlock(_ context.Context, req *goproto.TripodBlockRequest) (*goproto.Err, error) {
+	tri, block, err := g.resolveBlockRequest(req)
+	if err != nil {
+		return makeErr(err), nil
+	}
+	tri.FinalizeBlock(block)
+	return makeErr(nil), nil
+}
+
+func (g *GrpcWorker) Write(_ context.Context, req *goproto.WriteContext) (*goproto.WriteResult, error) {
+	rdCtx := req.ReadContext
+	wr, err := g.land.GetWriting(rdCtx.TripodName, rdCtx.FuncName)
+	if err != nil {
+		return &goproto.WriteResult{Error: makeErr(err)}, nil
+	}
+	block, err := BlockFromPb(req.Block)
+	if err != nil {
+		return &goproto.WriteResult{Error: makeErr(err)}, nil
+	}
+	txn, err := SignedTxnFromPb(req.Txn)
+	if err != nil {
+		return &goproto.WriteResult{Error: makeErr(err)}, nil
+	}
+	ctx, err := NewWriteContext(txn, block)
+	if err != nil {
+		return &goproto.WriteResult{Error: makeErr(err)}, nil
+	}
+	ctx.LeiCost = req.LeiCost
+
+	err = wr(ctx)
+
+	events := make([]*goproto.Event, 0, len(ctx.Events))
+	for _, event := range ctx.Events {
+		events = append(events, event.ToPb())
+	}
+	return &goproto.WriteResult{
+		Events:  events,
+		Error:   makeErr(err),
+		LeiCost: ctx.LeiCost,
+		Extra:   ctx.Extra,
+	}, nil
+}
+
+func (g *GrpcWorker) Read(_ context.Context, req *goproto.ReadContext) (*goproto.ReadResult, error) {
+	rd, err := g.land.GetReading(req.TripodName, req.FuncName)
+	if err != nil {
+		return &goproto.ReadResult{Error: makeErr(err)}, nil
+	}
+	ctx, err := NewReadContext(&RdCall{
+		TripodName: req.TripodName,
+		FuncName:   req.FuncName,
+		Params:     req.ParamsStr,
+	})
+	if err != nil {
+		return &goproto.ReadResult{Error: makeErr(err)}, nil
+	}
+
+	rd(ctx)
+
+	resp := ctx.Response()
+	if resp == nil {
+		return &goproto.ReadResult{}, nil
+	}
+	if resp.IsJson {
+		byt, err := json.Marshal(resp.DataInterface)
+		if err != nil {
+			return &goproto.ReadResult{Error: makeErr(err)}, nil
+		}
+		return &goproto.ReadResult{Response: byt, ContentType: jsonContentType}, nil
+	}
+	return &goproto.ReadResult{Response: resp.DataBytes, ContentType: resp.ContentType}, nil
+}
+
+func (g *GrpcWorker) resolveBlockRequest(req *goproto.TripodBlockRequest) (*Tripod, *Block, error) {
+	tri, ok := g.land.getTripod(req.TripodName)
+	if !ok {
+		return nil, nil, TripodNotFound(req.TripodName)
+	}
+	cb := CompactBlockFromPb(req.Block)
+	block := &Block{Header: cb.Header}
+	if req.Txns != nil {
+		txns, err := SignedTxnsFromPb(req.Txns)
+		if err != nil {
+			return nil, nil, err
+		}
+		block.SetTxns(txns)
+	}
+	return tri, block, nil
+}
+
+func makeErr(err error) *goproto.Err {
+	if err == nil {
+		return &goproto.Err{}
+	}
+	return &goproto.Err{Msg: err.Error()}
+}
@@ -0,0 +1,52 @@
+package txdb
+
+import (
+	"context"
+	"github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+)
+
+type GrpcTxDB struct {
+	goproto.UnimplementedTxDBServer
+	txdb ItxDB
+}
+
+func NewGrpcTxDB(txdb ItxDB) *GrpcTxDB {
+	return &GrpcTxDB{txdb: txdb}
+}
+
+func (g *GrpcTxDB) GetTxn(_ context.Context, hash *goproto.TxnHash) (*goproto.TxnResponse, error) {
+	txn, err := g.txdb.GetTxn(common.BytesToHash(hash.Hash))
+	if err != nil {
+		return &goproto.TxnResponse{Error: err.Error()}, nil
+	}
+	if txn == nil {
+		return &goproto.TxnResponse{}, nil
+	}
+	return &goproto.TxnResponse{Txn: txn.ToPb()}, nil
+}
+
+func (g *GrpcTxDB) SetTxn(_ context.Context, pb *goproto.SignedTxn) (*goproto.Err, error) {
+	txn, err := SignedTxnFromPb(pb)
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}, nil
+	}
+	err = g.txdb.SetTxns([]*SignedTxn{txn})
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}, nil
+	}
+	return &goproto.Err{}, nil
+}
+
+func (g *GrpcTxDB) SetTxns(_ context.Context, req *goproto.TxnsRequest) (*goproto.Err, error) {
+	txns, err := SignedTxnsFromPb(&goproto.SignedTxns{Txns: req.Txns})
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}, nil
+	}
+	err = g.txdb.SetTxns(txns)
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}, nil
+	}
+	return &goproto.Err{}, nil
+}
@@ -0,0 +1,65 @@
+package txdb
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+)
+
+// GrpcTxDBClient is used by the workers in MasterWorker mode,
+// it reaches the TxDB of master by grpc.
+type GrpcTxDBClient struct {
+	cli goproto.TxDBClient
+}
+
+func NewGrpcTxDBClient(conn *grpc.ClientConn) *GrpcTxDBClient {
+	return &GrpcTxDBClient{cli: goproto.NewTxDBClient(conn)}
+}
+
+func (g *GrpcTxDBClient) GetTxn(txnHash Hash) (*SignedTxn, error) {
+	resp, err := g.cli.GetTxn(context.Background(), &goproto.TxnHash{Hash: txnHash.Bytes()})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	if resp.Txn == nil {
+		return nil, nil
+	}
+	return SignedTxnFromPb(resp.Txn)
+}
+
+func (g *GrpcTxDBClient) ExistTxn(txnHash Hash) bool {
+	txn, err := g.GetTxn(txnHash)
+	return err == nil && txn != nil
+}
+
+func (g *GrpcTxDBClient) SetTxns(txns []*SignedTxn) error {
+	resp, err := g.cli.SetTxns(context.Background(), &goproto.TxnsRequest{
+		Txns: SignedTxns(txns).ToPb().Txns,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Msg != "" {
+		return errors.New(resp.Msg)
+	}
+	return nil
+}
+
+func (g *GrpcTxDBClient) SetReceipts(map[Hash]*Receipt) error {
+	return GrpcUnsupported
+}
+
+func (g *GrpcTxDBClient) GetReceipt(Hash) (*Receipt, error) {
+	return nil, GrpcUnsupported
+}
+
+func (g *GrpcTxDBClient) SetReceipt(Hash, *Receipt) error {
+	return GrpcUnsupported
+}
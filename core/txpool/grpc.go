@@ -0,0 +1,80 @@
+package txpool
+
+import (
+	"context"
+	"github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type GrpcTxpool struct {
+	goproto.UnimplementedTxpoolServer
+	pool ItxPool
+}
+
+func NewGrpcTxpool(pool ItxPool) *GrpcTxpool {
+	return &GrpcTxpool{pool: pool}
+}
+
+func (g *GrpcTxpool) PoolSize(context.Context, *emptypb.Empty) (*goproto.U64, error) {
+	return &goproto.U64{U64: g.pool.PoolSize()}, nil
+}
+
+func (g *GrpcTxpool) BaseCheck(_ context.Context, pb *goproto.SignedTxn) (*goproto.Err, error) {
+	return g.check(pb, g.pool.BaseCheck), nil
+}
+
+func (g *GrpcTxpool) TripodsCheck(_ context.Context, pb *goproto.SignedTxn) (*goproto.Err, error) {
+	return g.check(pb, g.pool.TripodsCheck), nil
+}
+
+func (g *GrpcTxpool) NecessaryCheck(_ context.Context, pb *goproto.SignedTxn) (*goproto.Err, error) {
+	return g.check(pb, g.pool.NecessaryCheck), nil
+}
+
+func (g *GrpcTxpool) Insert(_ context.Context, pb *goproto.SignedTxn) (*goproto.Err, error) {
+	return g.check(pb, g.pool.Insert), nil
+}
+
+func (g *GrpcTxpool) BatchInsert(_ context.Context, pb *goproto.BatchSignedTxns) (*goproto.Err, error) {
+	for _, txn := range pb.Txns {
+		pbErr := g.check(txn, g.pool.Insert)
+		if pbErr.Msg != "" {
+			return pbErr, nil
+		}
+	}
+	return &goproto.Err{}, nil
+}
+
+func (g *GrpcTxpool) RemovesTxns(_ context.Context, pb *goproto.TxnsHashes) (*goproto.Err, error) {
+	var txns SignedTxns
+	for _, hash := range pb.Hashes {
+		txns = append(txns, &SignedTxn{TxnHash: common.BytesToHash(hash)})
+	}
+	err := g.pool.Reset(txns)
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}, nil
+	}
+	return &goproto.Err{}, nil
+}
+
+func (g *GrpcTxpool) Pack(_ context.Context, numLimit *goproto.U64) (*goproto.TxnsResponse, error) {
+	txns, err := g.pool.Pack(numLimit.U64)
+	if err != nil {
+		return &goproto.TxnsResponse{Error: err.Error()}, nil
+	}
+	return &goproto.TxnsResponse{Txns: SignedTxns(txns).ToPb().Txns}, nil
+}
+
+func (g *GrpcTxpool) check(pb *goproto.SignedTxn, fn TxnCheckFn) *goproto.Err {
+	txn, err := SignedTxnFromPb(pb)
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}
+	}
+	err = fn(txn)
+	if err != nil {
+		return &goproto.Err{Msg: err.Error()}
+	}
+	return &goproto.Err{}
+}
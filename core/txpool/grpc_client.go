@@ -0,0 +1,131 @@
+package txpool
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/core/types/goproto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// GrpcTxpoolClient is used by the workers in MasterWorker mode,
+// it reaches the Txpool of master by grpc.
+// The checks of txns are all registered on the master, so WithBaseCheck and WithTripodCheck do nothing here.
+type GrpcTxpoolClient struct {
+	cli goproto.TxpoolClient
+}
+
+func NewGrpcTxpoolClient(conn *grpc.ClientConn) *GrpcTxpoolClient {
+	return &GrpcTxpoolClient{cli: goproto.NewTxpoolClient(conn)}
+}
+
+func (g *GrpcTxpoolClient) PoolSize() uint64 {
+	resp, err := g.cli.PoolSize(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return 0
+	}
+	return resp.U64
+}
+
+func (g *GrpcTxpoolClient) WithBaseCheck(TxnChecker) ItxPool {
+	return g
+}
+
+func (g *GrpcTxpoolClient) WithTripodCheck(TxnChecker) ItxPool {
+	return g
+}
+
+func (g *GrpcTxpoolClient) WithNonceReader(NonceReader) ItxPool {
+	return g
+}
+
+func (g *GrpcTxpoolClient) OnReplaced(func(*SignedTxn)) ItxPool {
+	return g
+}
+
+func (g *GrpcTxpoolClient) BaseCheck(stxn *SignedTxn) error {
+	return errFromPb(g.cli.BaseCheck(context.Background(), stxn.ToPb()))
+}
+
+func (g *GrpcTxpoolClient) TripodsCheck(stxn *SignedTxn) error {
+	return errFromPb(g.cli.TripodsCheck(context.Background(), stxn.ToPb()))
+}
+
+func (g *GrpcTxpoolClient) NecessaryCheck(stxn *SignedTxn) error {
+	return errFromPb(g.cli.NecessaryCheck(context.Background(), stxn.ToPb()))
+}
+
+// Exist is not served by master, master will drop the duplicated txns when inserting.
+func (g *GrpcTxpoolClient) Exist(*SignedTxn) bool {
+	return false
+}
+
+func (g *GrpcTxpoolClient) CheckTxn(stxn *SignedTxn) error {
+	err := g.BaseCheck(stxn)
+	if err != nil {
+		return err
+	}
+	return g.TripodsCheck(stxn)
+}
+
+// BatchCheckTxns checks txns one by one on master.
+func (g *GrpcTxpoolClient) BatchCheckTxns(stxns []*SignedTxn) []error {
+	errs := make([]error, len(stxns))
+	for i, stxn := range stxns {
+		errs[i] = g.CheckTxn(stxn)
+	}
+	return errs
+}
+
+func (g *GrpcTxpoolClient) Insert(stxn *SignedTxn) error {
+	return errFromPb(g.cli.Insert(context.Background(), stxn.ToPb()))
+}
+
+func (g *GrpcTxpoolClient) Pack(numLimit uint64) ([]*SignedTxn, error) {
+	resp, err := g.cli.Pack(context.Background(), &goproto.U64{U64: numLimit})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return SignedTxnsFromPb(&goproto.SignedTxns{Txns: resp.Txns})
+}
+
+// PackFor packs txns from master and filters them locally.
+func (g *GrpcTxpoolClient) PackFor(numLimit uint64, filter func(txn *SignedTxn) bool) ([]*SignedTxn, error) {
+	txns, err := g.Pack(numLimit)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]*SignedTxn, 0)
+	for _, txn := range txns {
+		if filter(txn) {
+			filtered = append(filtered, txn)
+		}
+	}
+	return filtered, nil
+}
+
+func (g *GrpcTxpoolClient) GetTxn(Hash) (*SignedTxn, error) {
+	return nil, GrpcUnsupported
+}
+
+func (g *GrpcTxpoolClient) Reset(txns SignedTxns) error {
+	return errFromPb(g.cli.RemovesTxns(context.Background(), &goproto.TxnsHashes{
+		Hashes: HashesToTwoBytes(txns.Hashes()),
+	}))
+}
+
+func errFromPb(pbErr *goproto.Err, err error) error {
+	if err != nil {
+		return err
+	}
+	if pbErr.Msg != "" {
+		return errors.New(pbErr.Msg)
+	}
+	return nil
+}
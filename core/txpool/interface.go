@@ -11,6 +11,8 @@ type ItxPool interface {
 
 	WithBaseCheck(checkFn TxnChecker) ItxPool
 	WithTripodCheck(tripod TxnChecker) ItxPool
+	// WithNonceReader seeds the next nonces of callers, and makes the pending txns replaceable by fee.
+	WithNonceReader(reader NonceReader) ItxPool
 
 	BaseCheck(*SignedTxn) error
 	TripodsCheck(stxn *SignedTxn) error
@@ -20,8 +22,12 @@ type ItxPool interface {
 
 	Exist(stxn *SignedTxn) bool
 	CheckTxn(stxn *SignedTxn) error
+	// BatchCheckTxns checks txns from P2P, returns the error of every txn.
+	BatchCheckTxns(stxns []*SignedTxn) []error
 
 	Insert(txn *SignedTxn) error
+	// OnReplaced handles the pending txns replaced by fee.
+	OnReplaced(fn func(replaced *SignedTxn)) ItxPool
 
 	// Pack packs some txns to send to tripods
 	Pack(numLimit uint64) ([]*SignedTxn, error)
@@ -34,11 +40,23 @@ type ItxPool interface {
 	Reset(txns SignedTxns) error
 }
 
+// NonceReader reads the next nonce of caller from the chain state, such as the nonce tripod.
+type NonceReader interface {
+	NextNonce(caller Address) uint64
+}
+
 type IunpackedTxns interface {
-	Insert(input *SignedTxn)
-	Deletes(txnHashes []Hash)
+	// Insert returns the pending txn replaced by input, nil if no txn is replaced.
+	Insert(input *SignedTxn) (*SignedTxn, error)
+	// Deletes removes the packed txns, and returns the other txns dropped with them.
+	Deletes(txnHashes []Hash) []Hash
+	// Removes drops the unpacked txns, such as expired or evicted txns.
+	Removes(txnHashes []Hash)
 	Exist(txnHash Hash) bool
 	Get(txnHash Hash) *SignedTxn
 	Gets(numLimit uint64, filter func(txn *SignedTxn) bool) []*SignedTxn
 	Size() int
+	// Lowest returns the txn to be evicted first when txpool is full.
+	Lowest() *SignedTxn
+	Range(fn func(txn *SignedTxn) bool)
 }
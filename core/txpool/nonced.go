@@ -2,48 +2,275 @@ package txpool
 
 import (
 	"container/list"
+	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/core/types"
 )
 
+// the number of callers whose learned nonces are kept without pending txns.
+const maxLearnedNonces = 10000
+
+// noncedTxns keeps txns of every caller in the order of nonce,
+// it packs txns of one caller only if their nonces are continuous.
 type noncedTxns struct {
+	// caller -> txns sorted by nonce
 	txns map[Address]*list.List
 	idx  map[Hash]*list.Element
+	// caller -> the next nonce, learned from the packed txns.
+	nonces map[Address]uint64
+	// reader seeds the next nonces from the chain state, nil if no one provides it.
+	reader NonceReader
+	size   int
 }
 
 func newNoncedTxns() *noncedTxns {
 	return &noncedTxns{
-		txns: make(map[Address]*list.List),
-		idx:  make(map[Hash]*list.Element),
+		txns:   make(map[Address]*list.List),
+		idx:    make(map[Hash]*list.Element),
+		nonces: make(map[Address]uint64),
+	}
+}
+
+func callerOf(stxn *SignedTxn) Address {
+	caller := stxn.GetCallerAddr()
+	if caller == nil {
+		return NullAddress
+	}
+	return *caller
+}
+
+// Insert keeps txns of the caller sorted by nonce, and returns the pending txn replaced by input.
+// A txn with the same nonce as a pending one replaces it only if it pays more (see canReplace).
+func (n *noncedTxns) Insert(input *SignedTxn) (*SignedTxn, error) {
+	logrus.WithField("txpool", "nonced-txns").
+		Tracef("Insert txn(%s) to Txpool, txn content: %v", input.TxnHash.String(), input.Raw.WrCall)
+	caller := callerOf(input)
+	if next, ok := n.nextNonce(caller); ok && input.GetNonce() < next {
+		return nil, NonceTooLow(input.GetNonce(), next)
+	}
+
+	txns, ok := n.txns[caller]
+	if !ok {
+		txns = list.New()
+		n.txns[caller] = txns
+	}
+	for element := txns.Front(); element != nil; element = element.Next() {
+		tx := element.Value.(*SignedTxn)
+		if input.GetNonce() == tx.GetNonce() {
+			if !canReplace(tx, input) {
+				return nil, TxnUnderpriced
+			}
+			logrus.WithField("txpool", "nonced-txns").
+				Debugf("txn(%s) is replaced by txn(%s)", tx.TxnHash.String(), input.TxnHash.String())
+			delete(n.idx, tx.TxnHash)
+			element.Value = input
+			n.idx[input.TxnHash] = element
+			return tx, nil
+		}
+		if input.GetNonce() < tx.GetNonce() {
+			n.idx[input.TxnHash] = txns.InsertBefore(input, element)
+			n.size++
+			return nil, nil
+		}
 	}
+	n.idx[input.TxnHash] = txns.PushBack(input)
+	n.size++
+	return nil, nil
 }
 
-func (n *noncedTxns) Insert(input *SignedTxn) {
-	//TODO implement me
-	panic("implement me")
+// nextNonce returns the next nonce of caller, the greater one of the learned and the one read from chain.
+// It returns false if the next nonce is unknown.
+func (n *noncedTxns) nextNonce(caller Address) (uint64, bool) {
+	next, ok := n.nonces[caller]
+	if n.reader != nil {
+		if read := n.reader.NextNonce(caller); !ok || read > next {
+			return read, true
+		}
+	}
+	return next, ok
+}
+
+// setNonceReader seeds the next nonces from reader, and returns the pending txns whose nonces are stale,
+// such as the ones reloaded after restart.
+func (n *noncedTxns) setNonceReader(reader NonceReader) []Hash {
+	n.reader = reader
+	stale := make([]Hash, 0)
+	for caller, txns := range n.txns {
+		next, _ := n.nextNonce(caller)
+		for element := txns.Front(); element != nil; element = element.Next() {
+			stxn := element.Value.(*SignedTxn)
+			if stxn.GetNonce() >= next {
+				break
+			}
+			stale = append(stale, stxn.TxnHash)
+		}
+	}
+	return stale
+}
+
+// canReplace reports whether the new txn pays no less than the pending one on both Tips and LeiPrice,
+// and strictly more on at least one of them.
+func canReplace(pending, input *SignedTxn) bool {
+	old, cur := pending.Raw.WrCall, input.Raw.WrCall
+	if cur.Tips < old.Tips || cur.LeiPrice < old.LeiPrice {
+		return false
+	}
+	return cur.Tips > old.Tips || cur.LeiPrice > old.LeiPrice
 }
 
-func (n *noncedTxns) Deletes(txnHashes []Hash) {
-	//TODO implement me
-	panic("implement me")
+func (n *noncedTxns) delete(txnHash Hash) *SignedTxn {
+	e, ok := n.idx[txnHash]
+	if !ok {
+		return nil
+	}
+	stxn := e.Value.(*SignedTxn)
+	logrus.WithField("txpool", "nonced-txns").
+		Tracef("DELETE txn(%s) from txpool, txn content: %v", stxn.TxnHash.String(), stxn.Raw.WrCall)
+	caller := callerOf(stxn)
+	txns := n.txns[caller]
+	txns.Remove(e)
+	if txns.Len() == 0 {
+		delete(n.txns, caller)
+	}
+	delete(n.idx, txnHash)
+	n.size--
+	return stxn
+}
+
+// Deletes removes the packed txns, and drops and returns the txns whose nonces are stale.
+// A packed txn may be rejected by chain without consuming its nonce, so the next nonces are read
+// from chain if a NonceReader is set, and learned from the packed txns only if not.
+// The learned nonces of callers without pending txns are forgotten once there are too many of them.
+func (n *noncedTxns) Deletes(txnHashes []Hash) []Hash {
+	for _, hash := range txnHashes {
+		stxn := n.delete(hash)
+		if stxn == nil || n.reader != nil {
+			continue
+		}
+		caller := callerOf(stxn)
+		if next, ok := n.nonces[caller]; !ok || stxn.GetNonce()+1 > next {
+			n.nonces[caller] = stxn.GetNonce() + 1
+		}
+	}
+	stale := make([]Hash, 0)
+	for caller, txns := range n.txns {
+		next, ok := n.nextNonce(caller)
+		if !ok {
+			continue
+		}
+		for element := txns.Front(); element != nil; {
+			stxn := element.Value.(*SignedTxn)
+			element = element.Next()
+			if stxn.GetNonce() >= next {
+				break
+			}
+			n.delete(stxn.TxnHash)
+			stale = append(stale, stxn.TxnHash)
+		}
+	}
+	if len(n.nonces) > maxLearnedNonces {
+		for caller := range n.nonces {
+			if _, ok := n.txns[caller]; !ok {
+				delete(n.nonces, caller)
+			}
+		}
+	}
+	return stale
+}
+
+// Removes drops the txns without learning nonces from them,
+// the txns after a removed nonce are held back until the nonce is filled again.
+func (n *noncedTxns) Removes(txnHashes []Hash) {
+	for _, hash := range txnHashes {
+		n.delete(hash)
+	}
 }
 
 func (n *noncedTxns) Exist(txnHash Hash) bool {
-	//TODO implement me
-	panic("implement me")
+	_, ok := n.idx[txnHash]
+	return ok
 }
 
 func (n *noncedTxns) Get(txnHash Hash) *SignedTxn {
-	//TODO implement me
-	panic("implement me")
+	if e, ok := n.idx[txnHash]; ok {
+		return e.Value.(*SignedTxn)
+	}
+	return nil
 }
 
+// Gets packs the txns with continuous nonces of every caller from the next nonce,
+// the txns after a nonce gap are held back.
+// Among callers, the txn with more tips is packed first.
 func (n *noncedTxns) Gets(numLimit uint64, filter func(txn *SignedTxn) bool) []*SignedTxn {
-	//TODO implement me
-	panic("implement me")
+	// the next txn to pack of every caller
+	heads := make(map[Address]*list.Element)
+	for caller, txns := range n.txns {
+		front := txns.Front()
+		if next, ok := n.nextNonce(caller); ok && front.Value.(*SignedTxn).GetNonce() != next {
+			continue
+		}
+		heads[caller] = front
+	}
+
+	txns := make([]*SignedTxn, 0)
+	for uint64(len(txns)) < numLimit && len(heads) > 0 {
+		var (
+			bestCaller Address
+			best       *SignedTxn
+		)
+		for caller, element := range heads {
+			txn := element.Value.(*SignedTxn)
+			if best == nil || txn.Raw.WrCall.Tips > best.Raw.WrCall.Tips ||
+				(txn.Raw.WrCall.Tips == best.Raw.WrCall.Tips && txn.Raw.Timestamp() < best.Raw.Timestamp()) {
+				bestCaller, best = caller, txn
+			}
+		}
+
+		element := heads[bestCaller]
+		if !filter(best) {
+			// the txns after it cannot be packed either.
+			delete(heads, bestCaller)
+			continue
+		}
+		logrus.WithField("txpool", "nonced-txns").
+			Tracef("Pack txn(%s) from Txpool, txn content: %v", best.TxnHash.String(), best.Raw.WrCall)
+		txns = append(txns, best)
+
+		nextElement := element.Next()
+		if nextElement == nil || nextElement.Value.(*SignedTxn).GetNonce() != best.GetNonce()+1 {
+			delete(heads, bestCaller)
+		} else {
+			heads[bestCaller] = nextElement
+		}
+	}
+	return txns
 }
 
 func (n *noncedTxns) Size() int {
-	//TODO implement me
-	panic("implement me")
+	return n.size
+}
+
+// Lowest returns the txn with the least tips among the last txns of callers,
+// the later one if tips are the same. Evicting the last txn does not make a nonce gap.
+func (n *noncedTxns) Lowest() *SignedTxn {
+	var lowest *SignedTxn
+	for _, txns := range n.txns {
+		txn := txns.Back().Value.(*SignedTxn)
+		if lowest == nil || txn.Raw.WrCall.Tips < lowest.Raw.WrCall.Tips ||
+			(txn.Raw.WrCall.Tips == lowest.Raw.WrCall.Tips && txn.Raw.Timestamp() > lowest.Raw.Timestamp()) {
+			lowest = txn
+		}
+	}
+	return lowest
+}
+
+func (n *noncedTxns) Range(fn func(txn *SignedTxn) bool) {
+	for _, txns := range n.txns {
+		for element := txns.Front(); element != nil; element = element.Next() {
+			if !fn(element.Value.(*SignedTxn)) {
+				return
+			}
+		}
+	}
 }
@@ -0,0 +1,161 @@
+package txpool
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/types"
+	ytime "github.com/yu-org/yu/utils/time"
+	"testing"
+)
+
+var noncedPubkey, noncedPrivkey = keypair.GenSrKeyWithSecret([]byte("nonced"))
+
+func newNoncedTxn(t *testing.T, nonce, tips uint64) *SignedTxn {
+	return signNoncedCall(t, &WrCall{Nonce: nonce, Tips: tips, Timestamp: ytime.NowNanoTsU64()})
+}
+
+func signNoncedCall(t *testing.T, ecall *WrCall) *SignedTxn {
+	hash, err := ecall.Hash()
+	assert.NoError(t, err)
+	sig, err := noncedPrivkey.SignData(hash)
+	assert.NoError(t, err)
+	stxn, err := NewSignedTxn(ecall, noncedPubkey.BytesWithType(), sig)
+	assert.NoError(t, err)
+	return stxn
+}
+
+// nonceMap reads the next nonces like the nonce tripod.
+type nonceMap map[Address]uint64
+
+func (m nonceMap) NextNonce(caller Address) uint64 {
+	return m[caller]
+}
+
+func insertTxn(txns IunpackedTxns, stxn *SignedTxn) error {
+	_, err := txns.Insert(stxn)
+	return err
+}
+
+func packAll(ntxns IunpackedTxns) []*SignedTxn {
+	return ntxns.Gets(10, func(*SignedTxn) bool {
+		return true
+	})
+}
+
+func TestNoncedOrder(t *testing.T) {
+	tx0, tx1, tx2 := newNoncedTxn(t, 0, 1), newNoncedTxn(t, 1, 3), newNoncedTxn(t, 2, 2)
+	ntxns := newNoncedTxns()
+	assert.NoError(t, insertTxn(ntxns, tx2))
+	assert.NoError(t, insertTxn(ntxns, tx0))
+	assert.NoError(t, insertTxn(ntxns, tx1))
+	assert.Equal(t, []*SignedTxn{tx0, tx1, tx2}, packAll(ntxns))
+
+	ntxns.Deletes([]Hash{tx0.TxnHash})
+	assert.Equal(t, []*SignedTxn{tx1, tx2}, packAll(ntxns))
+	assert.ErrorAs(t, insertTxn(ntxns, tx0), new(yerror.ErrNonceTooLow))
+}
+
+func TestNoncedRejected(t *testing.T) {
+	tx0, tx1 := newNoncedTxn(t, 0, 0), newNoncedTxn(t, 1, 0)
+	caller := *tx0.GetCallerAddr()
+	nonces := nonceMap{}
+	ntxns := newNoncedTxns()
+	ntxns.setNonceReader(nonces)
+	assert.NoError(t, insertTxn(ntxns, tx0))
+	assert.NoError(t, insertTxn(ntxns, tx1))
+
+	// tx0 is packed but rejected by chain, its nonce is not consumed.
+	assert.Empty(t, ntxns.Deletes([]Hash{tx0.TxnHash}))
+	assert.True(t, ntxns.Exist(tx1.TxnHash))
+	assert.NoError(t, insertTxn(ntxns, tx0))
+	assert.Equal(t, []*SignedTxn{tx0, tx1}, packAll(ntxns))
+
+	// tx0 is executed at last.
+	nonces[caller] = 1
+	assert.Equal(t, []Hash{tx0.TxnHash}, ntxns.Deletes(nil))
+	assert.Equal(t, []*SignedTxn{tx1}, packAll(ntxns))
+}
+
+func TestNoncedGap(t *testing.T) {
+	caller := *newNoncedTxn(t, 0, 0).GetCallerAddr()
+	ntxns := newNoncedTxns()
+	ntxns.setNonceReader(nonceMap{caller: 1})
+
+	assert.ErrorAs(t, insertTxn(ntxns, newNoncedTxn(t, 0, 0)), new(yerror.ErrNonceTooLow))
+	tx2, tx3 := newNoncedTxn(t, 2, 0), newNoncedTxn(t, 3, 0)
+	assert.NoError(t, insertTxn(ntxns, tx2))
+	assert.NoError(t, insertTxn(ntxns, tx3))
+	// nonce 1 is missing even if the caller is never seen by txpool.
+	assert.Empty(t, packAll(ntxns))
+
+	tx1 := newNoncedTxn(t, 1, 0)
+	assert.NoError(t, insertTxn(ntxns, tx1))
+	assert.Equal(t, []*SignedTxn{tx1, tx2, tx3}, packAll(ntxns))
+
+	// the txns after a removed nonce are held back.
+	ntxns.Removes([]Hash{tx1.TxnHash})
+	assert.Empty(t, packAll(ntxns))
+}
+
+func TestWithNonceReader(t *testing.T) {
+	cfg := config.InitDefaultCfg()
+	cfg.Txpool.UnpackedTxnsType = NoncedTxns
+	pool := NewTxPool(FullNode, &cfg.Txpool, nil)
+	tx0, tx1, tx2 := newNoncedTxn(t, 0, 0), newNoncedTxn(t, 1, 0), newNoncedTxn(t, 2, 0)
+	for _, stxn := range []*SignedTxn{tx0, tx1, tx2} {
+		assert.NoError(t, pool.Insert(stxn))
+	}
+
+	// the txns with stale nonces are dropped, such as the ones reloaded after restart.
+	pool.WithNonceReader(nonceMap{*tx0.GetCallerAddr(): 2})
+	txns, err := pool.Pack(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*SignedTxn{tx2}, txns)
+	for _, stxn := range []*SignedTxn{tx0, tx1} {
+		got, err := pool.GetTxn(stxn.TxnHash)
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	}
+}
+
+func TestNoncedReplace(t *testing.T) {
+	pending := newNoncedTxn(t, 0, 10)
+	ntxns := newNoncedTxns()
+	assert.NoError(t, insertTxn(ntxns, pending))
+
+	assert.Equal(t, yerror.TxnUnderpriced, insertTxn(ntxns, newNoncedTxn(t, 0, 9)))
+	assert.Equal(t, yerror.TxnUnderpriced, insertTxn(ntxns, newNoncedTxn(t, 0, 10)))
+	assert.Equal(t, []*SignedTxn{pending}, packAll(ntxns))
+
+	bumped := newNoncedTxn(t, 0, 11)
+	replaced, err := ntxns.Insert(bumped)
+	assert.NoError(t, err)
+	assert.Equal(t, pending, replaced)
+	assert.Equal(t, 1, ntxns.Size())
+	assert.False(t, ntxns.Exist(pending.TxnHash))
+	assert.Equal(t, []*SignedTxn{bumped}, packAll(ntxns))
+}
+
+func TestOnReplaced(t *testing.T) {
+	cfg := config.InitDefaultCfg()
+	pool := NewTxPool(FullNode, &cfg.Txpool, nil)
+	var replaced []*SignedTxn
+	pool.OnReplaced(func(stxn *SignedTxn) {
+		replaced = append(replaced, stxn)
+	})
+	pending, bumped := newNoncedTxn(t, 0, 10), newNoncedTxn(t, 0, 11)
+
+	// the txns are not replaceable until the nonces are read from chain.
+	assert.NoError(t, pool.Insert(pending))
+	assert.NoError(t, pool.Insert(bumped))
+	assert.Empty(t, replaced)
+	pool.Reset(SignedTxns{bumped})
+
+	pool.WithNonceReader(nonceMap{})
+	assert.Equal(t, yerror.TxnUnderpriced, pool.Insert(newNoncedTxn(t, 0, 9)))
+	assert.NoError(t, pool.Insert(newNoncedTxn(t, 0, 12)))
+	assert.Equal(t, []*SignedTxn{pending}, replaced)
+}
@@ -4,12 +4,25 @@ import (
 	"container/list"
 	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/core/types"
 )
 
 type orderedTxns struct {
 	txns *list.List
 	idx  map[Hash]*list.Element
+	// (caller, nonce) -> txn, it is kept only after the nonces are read from chain,
+	// so that a txn can be replaced by the one with the same nonce.
+	nonced map[nonceKey]*list.Element
+}
+
+type nonceKey struct {
+	caller Address
+	nonce  uint64
+}
+
+func nonceKeyOf(stxn *SignedTxn) nonceKey {
+	return nonceKey{caller: callerOf(stxn), nonce: stxn.GetNonce()}
 }
 
 func newOrderedTxns() *orderedTxns {
@@ -19,20 +32,50 @@ func newOrderedTxns() *orderedTxns {
 	}
 }
 
-func (ot *orderedTxns) Insert(input *SignedTxn) {
+// Insert keeps txns sorted by tips. After the nonces are read from chain,
+// a txn with the same caller and nonce as a pending one replaces it only if it pays more (see canReplace).
+func (ot *orderedTxns) Insert(input *SignedTxn) (*SignedTxn, error) {
 	logrus.WithField("txpool", "ordered-txns").
 		Tracef("Insert txn(%s) to Txpool, txn content: %v", input.TxnHash.String(), input.Raw.WrCall)
-	for element := ot.txns.Front(); element != nil; element = element.Next() {
+	var replaced *SignedTxn
+	if ot.nonced != nil {
+		if e, ok := ot.nonced[nonceKeyOf(input)]; ok {
+			replaced = e.Value.(*SignedTxn)
+			if !canReplace(replaced, input) {
+				return nil, TxnUnderpriced
+			}
+			logrus.WithField("txpool", "ordered-txns").
+				Debugf("txn(%s) is replaced by txn(%s)", replaced.TxnHash.String(), input.TxnHash.String())
+			ot.delete(replaced.TxnHash)
+		}
+	}
+
+	e := ot.txns.PushBack(input)
+	for element := ot.txns.Front(); element != e; element = element.Next() {
 		tx := element.Value.(*SignedTxn)
 		// fixme: cannot only use tips to judge.
 		if input.Raw.WrCall.Tips > tx.Raw.WrCall.Tips {
-			e := ot.txns.InsertBefore(input, element)
-			ot.idx[input.TxnHash] = e
-			return
+			ot.txns.MoveBefore(e, element)
+			break
 		}
 	}
-	e := ot.txns.PushBack(input)
 	ot.idx[input.TxnHash] = e
+	if ot.nonced != nil {
+		ot.nonced[nonceKeyOf(input)] = e
+	}
+	return replaced, nil
+}
+
+// setNonceReader makes the txns replaceable by fee, the nonces are not checked here.
+func (ot *orderedTxns) setNonceReader(NonceReader) []Hash {
+	ot.nonced = make(map[nonceKey]*list.Element)
+	for element := ot.txns.Front(); element != nil; element = element.Next() {
+		key := nonceKeyOf(element.Value.(*SignedTxn))
+		if _, ok := ot.nonced[key]; !ok {
+			ot.nonced[key] = element
+		}
+	}
+	return nil
 }
 
 func (ot *orderedTxns) delete(txnHash Hash) {
@@ -42,10 +85,18 @@ func (ot *orderedTxns) delete(txnHash Hash) {
 			Tracef("DELETE txn(%s) from txpool, txn content: %v", stxn.TxnHash.String(), stxn.Raw.WrCall)
 		ot.txns.Remove(e)
 		delete(ot.idx, txnHash)
+		if key := nonceKeyOf(stxn); ot.nonced != nil && ot.nonced[key] == e {
+			delete(ot.nonced, key)
+		}
 	}
 }
 
-func (ot *orderedTxns) Deletes(txnHashes []Hash) {
+func (ot *orderedTxns) Deletes(txnHashes []Hash) []Hash {
+	ot.Removes(txnHashes)
+	return nil
+}
+
+func (ot *orderedTxns) Removes(txnHashes []Hash) {
 	for _, hash := range txnHashes {
 		ot.delete(hash)
 	}
@@ -84,3 +135,19 @@ func (ot *orderedTxns) Gets(numLimit uint64, filter func(txn *SignedTxn) bool) [
 func (ot *orderedTxns) Size() int {
 	return ot.txns.Len()
 }
+
+// Lowest returns the txn with the least tips, the later one if tips are the same.
+func (ot *orderedTxns) Lowest() *SignedTxn {
+	if e := ot.txns.Back(); e != nil {
+		return e.Value.(*SignedTxn)
+	}
+	return nil
+}
+
+func (ot *orderedTxns) Range(fn func(txn *SignedTxn) bool) {
+	for element := ot.txns.Front(); element != nil; element = element.Next() {
+		if !fn(element.Value.(*SignedTxn)) {
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package txpool
 import (
 	"github.com/stretchr/testify/assert"
 	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/core/keypair"
 	. "github.com/yu-org/yu/core/types"
 	"testing"
@@ -15,11 +16,9 @@ var (
 
 func init() {
 	pubkey1, privkey1 := keypair.GenSrKeyWithSecret([]byte("yu"))
-	caller1 = pubkey1.Address()
 	pubkey2, privkey2 := keypair.GenSrKeyWithSecret([]byte("boyi"))
-	caller2 = pubkey2.Address()
 
-	ecall1 := &WrCall{LeiPrice: 10}
+	ecall1 := &WrCall{Tips: 10}
 	hash1, err := ecall1.Hash()
 	if err != nil {
 		panic(err)
@@ -28,7 +27,7 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	ecall2 := &WrCall{LeiPrice: 30}
+	ecall2 := &WrCall{Tips: 30}
 	hash2, err := ecall2.Hash()
 	if err != nil {
 		panic(err)
@@ -37,7 +36,7 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	ecall3 := &WrCall{LeiPrice: 20}
+	ecall3 := &WrCall{Tips: 20}
 	hash3, err := ecall3.Hash()
 	if err != nil {
 		panic(err)
@@ -47,18 +46,19 @@ func init() {
 		panic(err)
 	}
 
-	tx1, err = NewSignedTxn(caller1, ecall1, pubkey1, sig1)
+	tx1, err = NewSignedTxn(ecall1, pubkey1.BytesWithType(), sig1)
 	if err != nil {
 		panic(err)
 	}
-	tx2, err = NewSignedTxn(caller1, ecall2, pubkey1, sig2)
+	tx2, err = NewSignedTxn(ecall2, pubkey1.BytesWithType(), sig2)
 	if err != nil {
 		panic(err)
 	}
-	tx3, err = NewSignedTxn(caller2, ecall3, pubkey1, sig3)
+	tx3, err = NewSignedTxn(ecall3, pubkey2.BytesWithType(), sig3)
 	if err != nil {
 		panic(err)
 	}
+	caller1, caller2 = *tx1.GetCallerAddr(), *tx3.GetCallerAddr()
 }
 
 func TestOrdered(t *testing.T) {
@@ -75,3 +75,24 @@ func TestOrdered(t *testing.T) {
 		assert.Equal(t, txn, correctOrder[i])
 	}
 }
+
+func TestOrderedReplace(t *testing.T) {
+	otxns := newOrderedTxns()
+	otxns.setNonceReader(nonceMap{})
+	pending, other := newNoncedTxn(t, 0, 10), newNoncedTxn(t, 1, 5)
+	for _, stxn := range []*SignedTxn{pending, other} {
+		assert.NoError(t, insertTxn(otxns, stxn))
+	}
+
+	assert.Equal(t, yerror.TxnUnderpriced, insertTxn(otxns, newNoncedTxn(t, 0, 10)))
+	assert.Equal(t, []*SignedTxn{pending, other}, packAll(otxns))
+
+	bumped := newNoncedTxn(t, 1, 20)
+	replaced, err := otxns.Insert(bumped)
+	assert.NoError(t, err)
+	assert.Equal(t, other, replaced)
+	assert.Equal(t, []*SignedTxn{bumped, pending}, packAll(otxns))
+
+	// the replaced txn can be inserted again only if it pays more than the replacement.
+	assert.Equal(t, yerror.TxnUnderpriced, insertTxn(otxns, other))
+}
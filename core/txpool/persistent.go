@@ -0,0 +1,111 @@
+package txpool
+
+import (
+	"github.com/sirupsen/logrus"
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/core/types"
+	ysql "github.com/yu-org/yu/infra/storage/sql"
+	ytime "github.com/yu-org/yu/utils/time"
+)
+
+// persistentTxns keeps the unpacked txns in memory as well as in SQL db,
+// so that the unpacked txns survive the restarts of node.
+type persistentTxns struct {
+	IunpackedTxns
+	db ysql.SqlDB
+}
+
+// newPersistentTxns reloads the unpacked txns from db into the txns of txpool,
+// the txns which have been packed into blocks, expired or fail the base checks are dropped.
+func newPersistentTxns(tp *TxPool, db ysql.SqlDB) *persistentTxns {
+	txns := tp.unpackedTxns
+	p := &persistentTxns{IunpackedTxns: txns, db: db}
+
+	stxns, err := getAllFromDB(db)
+	if err != nil {
+		logrus.Fatal("load txns from txpool db error: ", err)
+	}
+	now := ytime.NowNanoTsU64()
+	dropped := make([]Hash, 0)
+	for _, stxn := range stxns {
+		if tp.txdb != nil && tp.txdb.ExistTxn(stxn.TxnHash) {
+			dropped = append(dropped, stxn.TxnHash)
+			continue
+		}
+		if tp.isExpired(stxn, now) {
+			dropped = append(dropped, stxn.TxnHash)
+			continue
+		}
+		if err = tp.BaseCheck(stxn); err != nil {
+			logrus.Debugf("drop txn(%s) reloaded from txpool db: %v", stxn.TxnHash.String(), err)
+			dropped = append(dropped, stxn.TxnHash)
+			continue
+		}
+		replaced, err := txns.Insert(stxn)
+		if err != nil || !txns.Exist(stxn.TxnHash) {
+			dropped = append(dropped, stxn.TxnHash)
+			continue
+		}
+		if replaced != nil {
+			dropped = append(dropped, replaced.TxnHash)
+		}
+		for uint64(txns.Size()) > tp.poolSize {
+			lowest := txns.Lowest()
+			txns.Removes([]Hash{lowest.TxnHash})
+			dropped = append(dropped, lowest.TxnHash)
+		}
+	}
+	err = deleteFromDB(db, dropped)
+	if err != nil {
+		logrus.Error("delete dropped txns from txpool db error: ", err)
+	}
+	logrus.Infof("reload %d txns into txpool, drop %d txns", len(stxns)-len(dropped), len(dropped))
+	return p
+}
+
+func (p *persistentTxns) setNonceReader(reader NonceReader) []Hash {
+	if seeded, ok := p.IunpackedTxns.(nonceSeeded); ok {
+		return seeded.setNonceReader(reader)
+	}
+	return nil
+}
+
+func (p *persistentTxns) Insert(input *SignedTxn) (*SignedTxn, error) {
+	replaced, err := p.IunpackedTxns.Insert(input)
+	if err != nil {
+		return nil, err
+	}
+	if replaced != nil {
+		err = deleteFromDB(p.db, []Hash{replaced.TxnHash})
+		if err != nil {
+			logrus.Errorf("delete replaced txn(%s) from txpool db error: %v", replaced.TxnHash.String(), err)
+		}
+	}
+	if !p.IunpackedTxns.Exist(input.TxnHash) {
+		return replaced, nil
+	}
+	err = insertToDB(p.db, input)
+	if err != nil {
+		logrus.Errorf("insert txn(%s) into txpool db error: %v", input.TxnHash.String(), err)
+	}
+	return replaced, nil
+}
+
+// Deletes drops the packed txns from db, together with the txns dropped with them,
+// such as the txns with stale nonces.
+func (p *persistentTxns) Deletes(txnHashes []Hash) []Hash {
+	dropped := p.IunpackedTxns.Deletes(txnHashes)
+	err := deleteFromDB(p.db, append(append([]Hash{}, txnHashes...), dropped...))
+	if err != nil {
+		logrus.Error("delete txns from txpool db error: ", err)
+	}
+	return dropped
+}
+
+func (p *persistentTxns) Removes(txnHashes []Hash) {
+	p.IunpackedTxns.Removes(txnHashes)
+	err := deleteFromDB(p.db, txnHashes)
+	if err != nil {
+		logrus.Error("delete txns from txpool db error: ", err)
+	}
+}
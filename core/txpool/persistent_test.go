@@ -0,0 +1,73 @@
+package txpool
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/txdb"
+	. "github.com/yu-org/yu/core/types"
+	"github.com/yu-org/yu/infra/storage/kv"
+	ytime "github.com/yu-org/yu/utils/time"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newPersistentPool(t *testing.T, dir, txnsType string) *TxPool {
+	cfg := config.InitDefaultCfg()
+	cfg.Txpool.UnpackedTxnsType = txnsType
+	cfg.Txpool.TxnTTL = 60
+	cfg.Txpool.PoolDB = &config.SqlDbConf{SqlDbType: "sqlite", Dsn: filepath.Join(dir, "pool.db")}
+	kvdb, err := kv.NewKvdb(&config.KVconf{KvType: "bolt", Path: filepath.Join(t.TempDir(), "txdb.db")})
+	assert.NoError(t, err)
+	return WithDefaultChecks(common.FullNode, &cfg.Txpool, txdb.NewTxDB(common.FullNode, kvdb))
+}
+
+func dbHashes(t *testing.T, pool *TxPool) []common.Hash {
+	stxns, err := getAllFromDB(pool.unpackedTxns.(*persistentTxns).db)
+	assert.NoError(t, err)
+	hashes := make([]common.Hash, 0, len(stxns))
+	for _, stxn := range stxns {
+		hashes = append(hashes, stxn.TxnHash)
+	}
+	return hashes
+}
+
+func TestPersistentReload(t *testing.T) {
+	dir := t.TempDir()
+	pool := newPersistentPool(t, dir, OrderedTxns)
+	fresh := newNoncedTxn(t, 0, 0)
+	expired := signNoncedCall(t, &common.WrCall{Nonce: 1, Timestamp: ytime.NowNanoTsU64() - 61*uint64(time.Second)})
+	forged := newNoncedTxn(t, 2, 0)
+	forged.Raw.WrCall.Tips = 100
+	forged.TxnHash, _ = forged.Hash()
+	for _, stxn := range []*SignedTxn{fresh, expired, forged} {
+		assert.NoError(t, pool.Insert(stxn))
+	}
+	assert.Len(t, dbHashes(t, pool), 3)
+
+	// the expired txn and the txn failing the signature check are dropped when reloading.
+	pool = newPersistentPool(t, dir, OrderedTxns)
+	assert.Equal(t, 1, pool.unpackedTxns.Size())
+	assert.True(t, pool.unpackedTxns.Exist(fresh.TxnHash))
+	assert.Equal(t, []common.Hash{fresh.TxnHash}, dbHashes(t, pool))
+}
+
+func TestPersistentDeletes(t *testing.T) {
+	pool := newPersistentPool(t, t.TempDir(), NoncedTxns)
+	tx0, tx1, tx2 := newNoncedTxn(t, 0, 0), newNoncedTxn(t, 1, 0), newNoncedTxn(t, 2, 0)
+	for _, stxn := range []*SignedTxn{tx0, tx1, tx2} {
+		assert.NoError(t, pool.Insert(stxn))
+	}
+	// a row out of memory is not touched by Deletes.
+	other := newNoncedTxn(t, 3, 0)
+	assert.NoError(t, insertToDB(pool.unpackedTxns.(*persistentTxns).db, other))
+
+	// tx0 is dropped with tx1 since its nonce is stale.
+	assert.NoError(t, pool.Reset(SignedTxns{tx1}))
+	assert.ElementsMatch(t, []common.Hash{tx2.TxnHash, other.TxnHash}, dbHashes(t, pool))
+
+	bumped := newNoncedTxn(t, 2, 1)
+	assert.NoError(t, pool.Insert(bumped))
+	assert.ElementsMatch(t, []common.Hash{bumped.TxnHash, other.TxnHash}, dbHashes(t, pool))
+}
@@ -0,0 +1,147 @@
+package txpool
+
+import (
+	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
+	. "github.com/yu-org/yu/core/types"
+	"sort"
+)
+
+const (
+	FifoPolicy      = "fifo"
+	TipsPolicy      = "tips"
+	FairSharePolicy = "fair_share"
+)
+
+// OrderPolicy decides the order of txns packed into a block.
+type OrderPolicy interface {
+	// Order returns at most numLimit txns of candidates in the order to pack.
+	// Txns of the same sender are packed in the order of nonces.
+	Order(candidates []*SignedTxn, numLimit uint64) []*SignedTxn
+}
+
+// NewOrderPolicy returns the policy of name, TipsOrder if name is empty.
+func NewOrderPolicy(name string) (OrderPolicy, error) {
+	switch name {
+	case FifoPolicy:
+		return &FifoOrder{}, nil
+	case FairSharePolicy:
+		return &FairShareOrder{}, nil
+	case "", TipsPolicy:
+		return &TipsOrder{}, nil
+	default:
+		return nil, NoOrderPolicy
+	}
+}
+
+// FifoOrder packs the earliest txn first.
+type FifoOrder struct{}
+
+func (*FifoOrder) Order(candidates []*SignedTxn, numLimit uint64) []*SignedTxn {
+	return packBySenders(candidates, numLimit, func(heads []*SignedTxn) int {
+		best := 0
+		for i, txn := range heads {
+			if txn.Raw.Timestamp() < heads[best].Raw.Timestamp() {
+				best = i
+			}
+		}
+		return best
+	})
+}
+
+// TipsOrder packs the txn with the highest tips first, the earlier one if tips are the same.
+type TipsOrder struct{}
+
+func (*TipsOrder) Order(candidates []*SignedTxn, numLimit uint64) []*SignedTxn {
+	return packBySenders(candidates, numLimit, func(heads []*SignedTxn) int {
+		best := 0
+		for i, txn := range heads {
+			if higherTips(txn, heads[best]) {
+				best = i
+			}
+		}
+		return best
+	})
+}
+
+// FairShareOrder packs txns of tripods in turn, so that one tripod cannot fill the block
+// while txns of other tripods are waiting. Txns of the same tripod are packed by tips.
+type FairShareOrder struct{}
+
+func (*FairShareOrder) Order(candidates []*SignedTxn, numLimit uint64) []*SignedTxn {
+	// tripod name -> count of packed txns
+	shares := make(map[string]int)
+	return packBySenders(candidates, numLimit, func(heads []*SignedTxn) int {
+		best := 0
+		for i, txn := range heads {
+			share, bestShare := shares[txn.TripodName()], shares[heads[best].TripodName()]
+			if share < bestShare || (share == bestShare && higherTips(txn, heads[best])) {
+				best = i
+			}
+		}
+		shares[heads[best].TripodName()]++
+		return best
+	})
+}
+
+func higherTips(a, b *SignedTxn) bool {
+	return a.Raw.WrCall.Tips > b.Raw.WrCall.Tips ||
+		(a.Raw.WrCall.Tips == b.Raw.WrCall.Tips && a.Raw.Timestamp() < b.Raw.Timestamp())
+}
+
+// packBySenders queues candidates by sender and packs the txn picked from the first txns of senders every time.
+func packBySenders(candidates []*SignedTxn, numLimit uint64, pick func(heads []*SignedTxn) int) []*SignedTxn {
+	senders, queues := groupBySender(candidates)
+
+	txns := make([]*SignedTxn, 0)
+	for uint64(len(txns)) < numLimit && len(senders) > 0 {
+		heads := make([]*SignedTxn, len(senders))
+		for i, sender := range senders {
+			heads[i] = queues[sender][0]
+		}
+		i := pick(heads)
+		txns = append(txns, heads[i])
+
+		sender := senders[i]
+		queues[sender] = queues[sender][1:]
+		if len(queues[sender]) == 0 {
+			senders = append(senders[:i], senders[i+1:]...)
+		}
+	}
+	return txns
+}
+
+// limitSenders keeps at most quota txns of every sender.
+func limitSenders(candidates []*SignedTxn, quota uint64) []*SignedTxn {
+	counts := make(map[Address]uint64)
+	txns := make([]*SignedTxn, 0, len(candidates))
+	for _, txn := range candidates {
+		sender := callerOf(txn)
+		if counts[sender] >= quota {
+			continue
+		}
+		counts[sender]++
+		txns = append(txns, txn)
+	}
+	return txns
+}
+
+// groupBySender returns senders in the order of their first txns, and txns of every sender sorted by nonce,
+// the txns with the same nonce keep their order in candidates.
+func groupBySender(candidates []*SignedTxn) ([]Address, map[Address][]*SignedTxn) {
+	senders := make([]Address, 0)
+	queues := make(map[Address][]*SignedTxn)
+	for _, txn := range candidates {
+		sender := callerOf(txn)
+		if _, ok := queues[sender]; !ok {
+			senders = append(senders, sender)
+		}
+		queues[sender] = append(queues[sender], txn)
+	}
+	for _, queue := range queues {
+		sort.SliceStable(queue, func(i, j int) bool {
+			return queue[i].GetNonce() < queue[j].GetNonce()
+		})
+	}
+	return senders, queues
+}
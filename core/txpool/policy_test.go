@@ -0,0 +1,95 @@
+package txpool
+
+import (
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/keypair"
+	. "github.com/yu-org/yu/core/types"
+	ytime "github.com/yu-org/yu/utils/time"
+	"testing"
+)
+
+// newPolicyTxn makes a txn of the sender, the signature is not checked by policies.
+func newPolicyTxn(t *testing.T, sender, tripod string, tips, timestamp uint64) *SignedTxn {
+	pubkey, _ := keypair.GenSrKeyWithSecret([]byte(sender))
+	stxn, err := NewSignedTxn(&WrCall{TripodName: tripod, Tips: tips}, pubkey.BytesWithType(), nil)
+	assert.NoError(t, err)
+	stxn.Raw.WrCall.Timestamp = timestamp
+	return stxn
+}
+
+func TestFifoOrder(t *testing.T) {
+	// a2 is earlier than a1 but follows a1 in nonce.
+	a1, a2 := newPolicyTxn(t, "a", "x", 0, 5), newPolicyTxn(t, "a", "x", 0, 1)
+	b1 := newPolicyTxn(t, "b", "x", 0, 3)
+	txns := (&FifoOrder{}).Order([]*SignedTxn{a1, a2, b1}, 10)
+	assert.Equal(t, []*SignedTxn{b1, a1, a2}, txns)
+}
+
+func TestOrderByNonce(t *testing.T) {
+	newTxn := func(sender string, nonce, tips, timestamp uint64) *SignedTxn {
+		stxn := newPolicyTxn(t, sender, "x", tips, timestamp)
+		stxn.Raw.WrCall.Nonce = nonce
+		return stxn
+	}
+	// the txns of a sender arrive out of nonce order, a2 and a2r have the same nonce.
+	a3, a1, a2, a2r := newTxn("a", 3, 0, 1), newTxn("a", 1, 0, 2), newTxn("a", 2, 0, 3), newTxn("a", 2, 0, 4)
+	b1 := newTxn("b", 1, 0, 5)
+	candidates := []*SignedTxn{a3, a1, a2, b1, a2r}
+	assert.Equal(t, []*SignedTxn{a1, a2, a2r, a3, b1}, (&FifoOrder{}).Order(candidates, 10))
+	assert.Equal(t, []*SignedTxn{a3, a1, a2, b1, a2r}, candidates)
+}
+
+func TestNewOrderPolicy(t *testing.T) {
+	for _, name := range []string{"", FifoPolicy, TipsPolicy, FairSharePolicy} {
+		policy, err := NewOrderPolicy(name)
+		assert.NoError(t, err)
+		assert.NotNil(t, policy)
+	}
+	_, err := NewOrderPolicy("tip")
+	assert.Equal(t, yerror.NoOrderPolicy, err)
+}
+
+func TestTipsOrder(t *testing.T) {
+	a1, a2 := newPolicyTxn(t, "a", "x", 1, 1), newPolicyTxn(t, "a", "x", 100, 2)
+	b1, b2 := newPolicyTxn(t, "b", "x", 50, 3), newPolicyTxn(t, "b", "x", 50, 4)
+	candidates := []*SignedTxn{a1, a2, b1, b2}
+	assert.Equal(t, []*SignedTxn{b1, b2, a1, a2}, (&TipsOrder{}).Order(candidates, 10))
+	assert.Equal(t, []*SignedTxn{b1, b2}, (&TipsOrder{}).Order(candidates, 2))
+}
+
+func TestFairShareOrder(t *testing.T) {
+	x1 := newPolicyTxn(t, "a", "x", 100, 1)
+	x2 := newPolicyTxn(t, "b", "x", 90, 2)
+	x3 := newPolicyTxn(t, "c", "x", 80, 3)
+	y1 := newPolicyTxn(t, "d", "y", 1, 4)
+	candidates := []*SignedTxn{x1, x2, x3, y1}
+	// the spamming tripod cannot fill the block.
+	assert.Equal(t, []*SignedTxn{x1, y1}, (&FairShareOrder{}).Order(candidates, 2))
+	assert.Equal(t, []*SignedTxn{x1, y1, x2, x3}, (&FairShareOrder{}).Order(candidates, 10))
+}
+
+func TestLimitSenders(t *testing.T) {
+	a1, a2, a3 := newPolicyTxn(t, "a", "x", 3, 1), newPolicyTxn(t, "a", "x", 2, 2), newPolicyTxn(t, "a", "x", 1, 3)
+	b1 := newPolicyTxn(t, "b", "x", 0, 4)
+	txns := limitSenders([]*SignedTxn{a1, a2, b1, a3}, 2)
+	assert.Equal(t, []*SignedTxn{a1, a2, b1}, txns)
+}
+
+func TestPackWithSenderQuota(t *testing.T) {
+	cfg := config.InitDefaultCfg()
+	cfg.Txpool.OrderPolicy = FifoPolicy
+	cfg.Txpool.SenderQuota = 1
+	pool := NewTxPool(FullNode, &cfg.Txpool, nil)
+	now := ytime.NowNanoTsU64()
+	a1, a2 := newPolicyTxn(t, "a", "x", 0, now), newPolicyTxn(t, "a", "x", 0, now+1)
+	b1 := newPolicyTxn(t, "b", "x", 0, now+2)
+	for _, stxn := range []*SignedTxn{a1, a2, b1} {
+		assert.NoError(t, pool.Insert(stxn))
+	}
+	txns, err := pool.Pack(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*SignedTxn{a1, b1}, txns)
+}
@@ -1,11 +1,16 @@
 package txpool
 
 import (
+	"github.com/sirupsen/logrus"
 	. "github.com/yu-org/yu/common"
 	. "github.com/yu-org/yu/common/yerror"
 	. "github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/keypair"
 	. "github.com/yu-org/yu/core/types"
+	ysql "github.com/yu-org/yu/infra/storage/sql"
+	ytime "github.com/yu-org/yu/utils/time"
 	"sync"
+	"time"
 )
 
 type TxPool struct {
@@ -15,42 +20,108 @@ type TxPool struct {
 
 	poolSize   uint64
 	TxnMaxSize int
+	// default lifetime of txns in seconds
+	txnTTL        uint64
+	maxTxnTTL     uint64
+	sweepInterval time.Duration
 
 	unpackedTxns IunpackedTxns
 	txdb         ItxDB
 
+	orderPolicy OrderPolicy
+	senderQuota uint64
+
+	// handles the txns replaced by fee
+	replacedFn func(replaced *SignedTxn)
+
+	// verify signatures of txns before baseChecks
+	sigCheck     bool
 	baseChecks   []TxnCheckFn
 	tripodChecks []TxnCheckFn
 }
 
+const (
+	OrderedTxns = "ordered"
+	NoncedTxns  = "nonced"
+)
+
 func NewTxPool(nodeType int, cfg *TxpoolConf, base ItxDB) *TxPool {
-	ordered := newOrderedTxns()
+	return newTxPool(nodeType, cfg, base, false)
+}
+
+// WithDefaultChecks makes txpool with the default base checks,
+// the txns reloaded from PoolDB are checked by them as well.
+func WithDefaultChecks(nodeType int, cfg *TxpoolConf, base ItxDB) *TxPool {
+	return newTxPool(nodeType, cfg, base, true)
+}
+
+func newTxPool(nodeType int, cfg *TxpoolConf, base ItxDB, defaultChecks bool) *TxPool {
+	var unpackedTxns IunpackedTxns
+	switch cfg.UnpackedTxnsType {
+	case NoncedTxns:
+		unpackedTxns = newNoncedTxns()
+	default:
+		unpackedTxns = newOrderedTxns()
+	}
+
+	orderPolicy, err := NewOrderPolicy(cfg.OrderPolicy)
+	if err != nil {
+		logrus.Fatalf("txpool order policy(%s) error: %v", cfg.OrderPolicy, err)
+	}
 
 	tp := &TxPool{
-		nodeType:     nodeType,
-		poolSize:     cfg.PoolSize,
-		TxnMaxSize:   cfg.TxnMaxSize,
-		unpackedTxns: ordered,
-		txdb:         base,
-		baseChecks:   make([]TxnCheckFn, 0),
-		tripodChecks: make([]TxnCheckFn, 0),
+		nodeType:      nodeType,
+		poolSize:      cfg.PoolSize,
+		TxnMaxSize:    cfg.TxnMaxSize,
+		txnTTL:        cfg.TxnTTL,
+		maxTxnTTL:     cfg.MaxTxnTTL,
+		sweepInterval: time.Duration(cfg.SweepInterval) * time.Second,
+		unpackedTxns:  unpackedTxns,
+		txdb:          base,
+		orderPolicy:   orderPolicy,
+		senderQuota:   cfg.SenderQuota,
+		baseChecks:    make([]TxnCheckFn, 0),
+		tripodChecks:  make([]TxnCheckFn, 0),
+	}
+	if defaultChecks {
+		tp.withDefaultBaseChecks()
 	}
-	return tp
-}
 
-func WithDefaultChecks(nodeType int, cfg *TxpoolConf, base ItxDB) *TxPool {
-	tp := NewTxPool(nodeType, cfg, base)
-	return tp.withDefaultBaseChecks()
+	if cfg.PoolDB != nil && nodeType != LightNode {
+		db, err := ysql.NewSqlDB(cfg.PoolDB)
+		if err != nil {
+			logrus.Fatal("init txpool SQL db error: ", err)
+		}
+		err = db.CreateIfNotExist(&TxpoolScheme{})
+		if err != nil {
+			logrus.Fatal("create txpool scheme: ", err)
+		}
+		tp.unpackedTxns = newPersistentTxns(tp, db)
+	}
+
+	if tp.sweepInterval > 0 {
+		go tp.sweepExpiredTxns()
+	}
+	return tp
 }
 
 func (tp *TxPool) withDefaultBaseChecks() *TxPool {
+	tp.sigCheck = true
 	tp.baseChecks = []TxnCheckFn{
 		tp.checkPoolLimit,
 		tp.checkTxnSize,
+		tp.checkTxnExpired,
 	}
 	return tp
 }
 
+// SetOrderPolicy replaces the policy configured by OrderPolicy of TxpoolConf.
+func (tp *TxPool) SetOrderPolicy(policy OrderPolicy) {
+	tp.Lock()
+	defer tp.Unlock()
+	tp.orderPolicy = policy
+}
+
 func (tp *TxPool) PoolSize() uint64 {
 	return tp.poolSize
 }
@@ -65,6 +136,29 @@ func (tp *TxPool) WithTripodCheck(tc TxnChecker) ItxPool {
 	return tp
 }
 
+// WithNonceReader drops the pending txns with stale nonces if the txns are nonced,
+// and makes the pending txns replaceable by fee.
+func (tp *TxPool) WithNonceReader(reader NonceReader) ItxPool {
+	tp.Lock()
+	defer tp.Unlock()
+	if seeded, ok := tp.unpackedTxns.(nonceSeeded); ok {
+		tp.unpackedTxns.Removes(seeded.setNonceReader(reader))
+	}
+	return tp
+}
+
+func (tp *TxPool) OnReplaced(fn func(replaced *SignedTxn)) ItxPool {
+	tp.Lock()
+	defer tp.Unlock()
+	tp.replacedFn = fn
+	return tp
+}
+
+// nonceSeeded is implemented by the unpacked txns which replace txns by nonce.
+type nonceSeeded interface {
+	setNonceReader(reader NonceReader) []Hash
+}
+
 func (tp *TxPool) Exist(stxn *SignedTxn) bool {
 	tp.RLock()
 	defer tp.RUnlock()
@@ -89,7 +183,22 @@ func (tp *TxPool) Insert(stxn *SignedTxn) error {
 	if tp.nodeType == LightNode {
 		return nil
 	}
-	tp.unpackedTxns.Insert(stxn)
+	replaced, err := tp.unpackedTxns.Insert(stxn)
+	if err != nil {
+		return err
+	}
+	if replaced != nil && tp.replacedFn != nil {
+		tp.replacedFn(replaced)
+	}
+	// evict the lowest txns when txpool is full.
+	for uint64(tp.unpackedTxns.Size()) > tp.poolSize {
+		lowest := tp.unpackedTxns.Lowest()
+		tp.unpackedTxns.Removes([]Hash{lowest.TxnHash})
+		if lowest.TxnHash == stxn.TxnHash {
+			return PoolOverflow
+		}
+		logrus.Debugf("txpool is full, evict txn(%s)", lowest.TxnHash.String())
+	}
 	return nil
 }
 
@@ -108,8 +217,14 @@ func (tp *TxPool) Pack(numLimit uint64) ([]*SignedTxn, error) {
 func (tp *TxPool) PackFor(numLimit uint64, filter func(txn *SignedTxn) bool) ([]*SignedTxn, error) {
 	tp.RLock()
 	defer tp.RUnlock()
-	txns := tp.unpackedTxns.Gets(numLimit, filter)
-	return txns, nil
+	now := ytime.NowNanoTsU64()
+	candidates := tp.unpackedTxns.Gets(uint64(tp.unpackedTxns.Size()), func(txn *SignedTxn) bool {
+		return !tp.isExpired(txn, now) && filter(txn)
+	})
+	if tp.senderQuota > 0 {
+		candidates = limitSenders(candidates, tp.senderQuota)
+	}
+	return tp.orderPolicy.Order(candidates, numLimit), nil
 }
 
 func (tp *TxPool) Reset(txns SignedTxns) error {
@@ -122,9 +237,34 @@ func (tp *TxPool) Reset(txns SignedTxns) error {
 // ------------------- check txn rules ----------------------
 
 func (tp *TxPool) BaseCheck(stxn *SignedTxn) error {
+	if tp.sigCheck {
+		err := CheckSignature(stxn)
+		if err != nil {
+			return err
+		}
+	}
 	return Check(tp.baseChecks, stxn)
 }
 
+// BatchCheckTxns checks txns as CheckTxn, but verifies their signatures in batch.
+func (tp *TxPool) BatchCheckTxns(stxns []*SignedTxn) []error {
+	errs := make([]error, len(stxns))
+	if tp.sigCheck {
+		errs = BatchCheckSignatures(stxns)
+	}
+	for i, stxn := range stxns {
+		if errs[i] != nil {
+			continue
+		}
+		errs[i] = Check(tp.baseChecks, stxn)
+		if errs[i] != nil {
+			continue
+		}
+		errs[i] = tp.TripodsCheck(stxn)
+	}
+	return errs
+}
+
 func (tp *TxPool) TripodsCheck(stxn *SignedTxn) error {
 	return Check(tp.tripodChecks, stxn)
 }
@@ -138,8 +278,15 @@ func (tp *TxPool) NecessaryCheck(stxn *SignedTxn) (err error) {
 	return tp.TripodsCheck(stxn)
 }
 
-func (tp *TxPool) checkPoolLimit(*SignedTxn) error {
-	if uint64(tp.unpackedTxns.Size()) >= tp.poolSize {
+// checkPoolLimit rejects the txn when txpool is full, unless it pays more tips than the lowest txn in txpool.
+func (tp *TxPool) checkPoolLimit(stxn *SignedTxn) error {
+	tp.RLock()
+	defer tp.RUnlock()
+	if uint64(tp.unpackedTxns.Size()) < tp.poolSize {
+		return nil
+	}
+	lowest := tp.unpackedTxns.Lowest()
+	if lowest == nil || stxn.Raw.WrCall.Tips <= lowest.Raw.WrCall.Tips {
 		return PoolOverflow
 	}
 	return nil
@@ -152,6 +299,56 @@ func (tp *TxPool) checkTxnSize(stxn *SignedTxn) error {
 	return nil
 }
 
+func (tp *TxPool) checkTxnExpired(stxn *SignedTxn) error {
+	if tp.isExpired(stxn, ytime.NowNanoTsU64()) {
+		return TxnTimeoutErr
+	}
+	return nil
+}
+
+// isExpired reports whether the txn has lived longer than its TTL,
+// the TTL of txn is set by client and no more than the max TTL, or the default TTL of txpool.
+func (tp *TxPool) isExpired(stxn *SignedTxn, now uint64) bool {
+	ttl := stxn.Raw.WrCall.TTL
+	if ttl == 0 {
+		ttl = tp.txnTTL
+	}
+	if tp.maxTxnTTL > 0 && ttl > tp.maxTxnTTL {
+		ttl = tp.maxTxnTTL
+	}
+	if ttl == 0 {
+		return false
+	}
+	// compare in seconds, so that a large TTL does not overflow.
+	ts := stxn.Raw.Timestamp()
+	return now > ts && (now-ts)/uint64(time.Second) >= ttl
+}
+
+func (tp *TxPool) sweepExpiredTxns() {
+	ticker := time.NewTicker(tp.sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		tp.removeExpiredTxns()
+	}
+}
+
+func (tp *TxPool) removeExpiredTxns() {
+	tp.Lock()
+	defer tp.Unlock()
+	now := ytime.NowNanoTsU64()
+	expired := make([]Hash, 0)
+	tp.unpackedTxns.Range(func(txn *SignedTxn) bool {
+		if tp.isExpired(txn, now) {
+			expired = append(expired, txn.TxnHash)
+		}
+		return true
+	})
+	if len(expired) > 0 {
+		tp.unpackedTxns.Removes(expired)
+		logrus.Debugf("remove %d expired txns from txpool", len(expired))
+	}
+}
+
 type TxnCheckFn func(*SignedTxn) error
 
 func Check(checks []TxnCheckFn, stxn *SignedTxn) error {
@@ -164,15 +361,57 @@ func Check(checks []TxnCheckFn, stxn *SignedTxn) error {
 	return nil
 }
 
-//func CheckSignature(stxn *SignedTxn) error {
-//	sig := stxn.Signature
-//	wrCall := stxn.Raw.WrCall
-//	hash, err := wrCall.Hash()
-//	if err != nil {
-//		return err
-//	}
-//	if !stxn.Pubkey.VerifySignature(hash, sig) {
-//		return TxnSignatureErr
-//	}
-//	return nil
-//}
+// CheckSignature verifies the signature of txn over the hash of WrCall.
+func CheckSignature(stxn *SignedTxn) error {
+	pubkey, hash, err := signedContent(stxn)
+	if err != nil {
+		return err
+	}
+	if !pubkey.VerifySignature(hash, stxn.Signature) {
+		return TxnSignatureIllegal(SigMismatched)
+	}
+	return nil
+}
+
+// BatchCheckSignatures verifies the signatures of txns in batch and returns the error of every txn.
+func BatchCheckSignatures(stxns []*SignedTxn) []error {
+	errs := make([]error, len(stxns))
+	bv := keypair.NewBatchVerifier()
+	// index of txn in stxns for every signature in bv
+	idxs := make([]int, 0, len(stxns))
+	for i, stxn := range stxns {
+		pubkey, hash, err := signedContent(stxn)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		bv.Add(pubkey, hash, stxn.Signature)
+		idxs = append(idxs, i)
+	}
+	_, results := bv.Verify()
+	for j, ok := range results {
+		if !ok {
+			errs[idxs[j]] = TxnSignatureIllegal(SigMismatched)
+		}
+	}
+	return errs
+}
+
+func signedContent(stxn *SignedTxn) (keypair.PubKey, []byte, error) {
+	if len(stxn.Pubkey) == 0 || len(stxn.Signature) == 0 {
+		return nil, nil, TxnSignatureIllegal(TxnUnsigned)
+	}
+	pubkey, err := keypair.PubKeyFromBytes(stxn.Pubkey)
+	if err != nil {
+		return nil, nil, TxnSignatureIllegal(err)
+	}
+	// secret-free key
+	if pubkey == nil {
+		return nil, nil, TxnSignatureIllegal(TxnUnsigned)
+	}
+	hash, err := stxn.Raw.WrCall.Hash()
+	if err != nil {
+		return nil, nil, TxnSignatureIllegal(err)
+	}
+	return pubkey, hash, nil
+}
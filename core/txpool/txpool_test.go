@@ -5,17 +5,22 @@ import (
 	"github.com/yu-org/yu/common"
 	"github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/config"
+	"github.com/yu-org/yu/core/keypair"
 	"github.com/yu-org/yu/core/txdb"
 	"github.com/yu-org/yu/core/types"
 	"github.com/yu-org/yu/infra/storage/kv"
+	ytime "github.com/yu-org/yu/utils/time"
+	"math"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func initTxpool(t *testing.T) *TxPool {
 	cfg := config.InitDefaultCfg()
 	kvdb, err := kv.NewKvdb(&config.KVconf{
 		KvType: "bolt",
-		Path:   "./test-txpool.db",
+		Path:   filepath.Join(t.TempDir(), "txpool.db"),
 		Hosts:  nil,
 	})
 	if err != nil {
@@ -63,10 +68,102 @@ func TestPackFor(t *testing.T) {
 	}
 
 	txns, err := pool.PackFor(3, func(tx *types.SignedTxn) bool {
-		return tx.GetCallerAddr() == caller2
+		return *tx.GetCallerAddr() == caller2
 	})
 	if err != nil {
 		t.Fatalf("pack txns failed: %v", err)
 	}
 	assert.Equal(t, txns[0], tx3)
 }
+
+func TestRemoveExpiredTxns(t *testing.T) {
+	pool := initTxpool(t)
+	pool.txnTTL = 60
+	fresh := newNoncedTxn(t, 0, 0)
+	now := ytime.NowNanoTsU64()
+	stale := signNoncedCall(t, &common.WrCall{Nonce: 1, Timestamp: now - 61*uint64(time.Second)})
+	short := signNoncedCall(t, &common.WrCall{Nonce: 2, TTL: 1, Timestamp: now - 2*uint64(time.Second)})
+	// the TTL does not overflow, and it is cut down to the max TTL.
+	pool.maxTxnTTL = 120
+	long := signNoncedCall(t, &common.WrCall{Nonce: 3, TTL: math.MaxUint64, Timestamp: now - 121*uint64(time.Second)})
+
+	assert.Equal(t, yerror.TxnTimeoutErr, pool.BaseCheck(stale))
+	assert.Equal(t, yerror.TxnTimeoutErr, pool.BaseCheck(long))
+	for _, stxn := range []*types.SignedTxn{fresh, stale, short, long} {
+		assert.NoError(t, pool.Insert(stxn))
+	}
+	// the expired txns are never packed, even if they are not swept yet.
+	txns, err := pool.Pack(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.SignedTxn{fresh}, txns)
+
+	pool.removeExpiredTxns()
+	assert.Equal(t, 1, pool.unpackedTxns.Size())
+	assert.True(t, pool.unpackedTxns.Exist(fresh.TxnHash))
+}
+
+func TestEvictLowest(t *testing.T) {
+	pool := initTxpool(t)
+	pool.poolSize = 2
+	low, high := newNoncedTxn(t, 0, 10), newNoncedTxn(t, 1, 20)
+	assert.NoError(t, pool.Insert(low))
+	assert.NoError(t, pool.Insert(high))
+
+	lower := newNoncedTxn(t, 2, 10)
+	assert.Equal(t, yerror.PoolOverflow, pool.BaseCheck(lower))
+	assert.Equal(t, yerror.PoolOverflow, pool.Insert(lower))
+
+	higher := newNoncedTxn(t, 2, 15)
+	assert.NoError(t, pool.BaseCheck(higher))
+	assert.NoError(t, pool.Insert(higher))
+	assert.False(t, pool.unpackedTxns.Exist(low.TxnHash))
+	txns, err := pool.Pack(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.SignedTxn{high, higher}, txns)
+}
+
+func TestEvictNonced(t *testing.T) {
+	cfg := config.InitDefaultCfg()
+	cfg.Txpool.UnpackedTxnsType = NoncedTxns
+	cfg.Txpool.PoolSize = 2
+	pool := NewTxPool(common.FullNode, &cfg.Txpool, nil)
+	tx0, tx1 := newNoncedTxn(t, 0, 30), newNoncedTxn(t, 1, 1)
+	assert.NoError(t, pool.Insert(tx0))
+	assert.NoError(t, pool.Insert(tx1))
+
+	// the last txn of the caller is evicted, so that no nonce gap is made.
+	assert.Equal(t, yerror.PoolOverflow, pool.Insert(newNoncedTxn(t, 2, 20)))
+	txns, err := pool.Pack(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.SignedTxn{tx0, tx1}, txns)
+}
+
+func TestCheckForgedTxns(t *testing.T) {
+	pubkey, privkey := keypair.GenSrKeyWithSecret([]byte("forged"))
+	call := &common.WrCall{TripodName: "asset", Timestamp: 1}
+	hash, err := call.Hash()
+	assert.NoError(t, err)
+	sig, err := privkey.SignData(hash)
+	assert.NoError(t, err)
+	stxn, err := types.NewSignedTxn(call, pubkey.BytesWithType(), sig)
+	assert.NoError(t, err)
+	assert.NoError(t, CheckSignature(stxn))
+
+	// the hash is recomputed when decoding.
+	pb := stxn.ToPb()
+	pb.TxnHash = common.HexToHash("0a").Bytes()
+	_, err = types.SignedTxnFromPb(pb)
+	assert.Equal(t, yerror.TxnHashIllegal, err)
+
+	// the timestamp is signed with the call.
+	pb = stxn.ToPb()
+	pb.Raw.Timestamp++
+	forged := &types.SignedTxn{Raw: types.UnsignedTxnFromPb(pb.Raw), Pubkey: pb.Pubkey, Signature: pb.Signature}
+	forged.TxnHash, err = forged.Hash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, stxn.TxnHash, forged.TxnHash)
+	assert.Equal(t, yerror.TxnSignatureIllegal(yerror.SigMismatched), CheckSignature(forged))
+	errs := BatchCheckSignatures([]*types.SignedTxn{stxn, forged})
+	assert.NoError(t, errs[0])
+	assert.Error(t, errs[1])
+}
@@ -7,6 +7,8 @@ import (
 	. "github.com/yu-org/yu/common"
 	"github.com/yu-org/yu/core/types/goproto"
 	"github.com/yu-org/yu/infra/trie"
+	"math"
+	"math/big"
 )
 
 type Block struct {
@@ -157,6 +159,51 @@ func IfLeiOut(Lei uint64, block *Block) bool {
 	return Lei+block.LeiUsed > block.LeiLimit
 }
 
+// the base lei price changes at most 1/BaseLeiPriceChangeDenominator per block.
+const BaseLeiPriceChangeDenominator = 8
+
+// NextBaseLeiPrice calculates the base lei price of the block after parent.
+// If the parent block uses more than half of its LeiLimit, the price goes up, otherwise it goes down,
+// but never lower than minPrice. If minPrice is 0, the fee market is disabled.
+func NextBaseLeiPrice(parent *Header, minPrice uint64) uint64 {
+	price := parent.BaseLeiPrice
+	target := parent.LeiLimit / 2
+	if price == 0 || price < minPrice || target == 0 {
+		return minPrice
+	}
+
+	var diff uint64
+	if parent.LeiUsed > target {
+		diff = parent.LeiUsed - target
+	} else {
+		diff = target - parent.LeiUsed
+	}
+	// price * diff / target / BaseLeiPriceChangeDenominator
+	delta := new(big.Int).SetUint64(price)
+	delta.Mul(delta, new(big.Int).SetUint64(diff))
+	delta.Div(delta, new(big.Int).SetUint64(target*BaseLeiPriceChangeDenominator))
+
+	switch {
+	case parent.LeiUsed > target:
+		if delta.Sign() == 0 {
+			delta.SetUint64(1)
+		}
+		next := delta.Add(delta, new(big.Int).SetUint64(price))
+		if !next.IsUint64() {
+			return math.MaxUint64
+		}
+		return next.Uint64()
+	case parent.LeiUsed < target:
+		next := price - delta.Uint64()
+		if next < minPrice {
+			return minPrice
+		}
+		return next
+	default:
+		return price
+	}
+}
+
 func MakeTxnRoot(txns []*SignedTxn) (Hash, error) {
 	txnsBytes := make([]Hash, 0)
 	for _, tx := range txns {
@@ -184,6 +231,8 @@ type Header struct {
 
 	LeiLimit uint64
 	LeiUsed  uint64
+	// the price of per lei in this block, adjusted by the lei utilisation of the previous block.
+	BaseLeiPrice uint64
 
 	MinerPubkey    []byte
 	MinerSignature []byte
@@ -212,6 +261,8 @@ func (h *Header) ToPb() *goproto.Header {
 		LeiUsed:     h.LeiUsed,
 		Validators:  ValidatorsToPb(h.Validators),
 
+		BaseLeiPrice: h.BaseLeiPrice,
+
 		MinerPubkey:    h.MinerPubkey,
 		MinerSignature: h.MinerSignature,
 
@@ -256,6 +307,8 @@ func HeaderFromPb(pb *goproto.Header) *Header {
 		LeiUsed:    pb.LeiUsed,
 		Validators: ValidatorsFromPb(pb.Validators),
 
+		BaseLeiPrice: pb.BaseLeiPrice,
+
 		MinerPubkey:    pb.MinerPubkey,
 		MinerSignature: pb.MinerSignature,
 
@@ -1,5 +1,7 @@
 package types
 
+import "github.com/yu-org/yu/core/types/goproto"
+
 type Event struct {
 	//Caller      *Address `json:"caller"`
 	//BlockStage  string   `json:"block_stage"`
@@ -11,6 +13,14 @@ type Event struct {
 	Value []byte `json:"value"`
 }
 
+func (e *Event) ToPb() *goproto.Event {
+	return &goproto.Event{Value: e.Value}
+}
+
+func EventFromPb(pb *goproto.Event) *Event {
+	return &Event{Value: pb.Value}
+}
+
 //func (e *Event) DecodeJsonValue(v any) error {
 //	return json.Unmarshal(e.Value, v)
 //}
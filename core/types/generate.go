@@ -0,0 +1,3 @@
+package types
+
+//go:generate protoc -I proto --go_out=. --go-grpc_out=. proto/base_types.proto proto/block.proto proto/blockchain.proto proto/funcs.proto proto/p2p.proto proto/result.proto proto/statedb.proto proto/subscription.proto proto/tripod.proto proto/txdb.proto proto/txn.proto proto/txpool.proto
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
+// 	protoc-gen-go v1.32.0
 // 	protoc        v3.21.8
 // source: tripod.proto
 
@@ -209,6 +209,7 @@ type TripodBlockRequest struct {
 
 	TripodName string        `protobuf:"bytes,1,opt,name=tripod_name,json=tripodName,proto3" json:"tripod_name,omitempty"`
 	Block      *CompactBlock `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"`
+	Txns       *SignedTxns   `protobuf:"bytes,3,opt,name=txns,proto3" json:"txns,omitempty"`
 }
 
 func (x *TripodBlockRequest) Reset() {
@@ -257,6 +258,13 @@ func (x *TripodBlockRequest) GetBlock() *CompactBlock {
 	return nil
 }
 
+func (x *TripodBlockRequest) GetTxns() *SignedTxns {
+	if x != nil {
+		return x.Txns
+	}
+	return nil
+}
+
 var File_tripod_proto protoreflect.FileDescriptor
 
 var file_tripod_proto_rawDesc = []byte{
@@ -283,31 +291,33 @@ var file_tripod_proto_rawDesc = []byte{
 	0x70, 0x6f, 0x64, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
 	0x74, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x03, 0x74, 0x78,
 	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x53, 0x69, 0x67, 0x6e, 0x65, 0x64,
-	0x54, 0x78, 0x6e, 0x52, 0x03, 0x74, 0x78, 0x6e, 0x22, 0x5a, 0x0a, 0x12, 0x54, 0x72, 0x69, 0x70,
+	0x54, 0x78, 0x6e, 0x52, 0x03, 0x74, 0x78, 0x6e, 0x22, 0x7b, 0x0a, 0x12, 0x54, 0x72, 0x69, 0x70,
 	0x6f, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f,
 	0x0a, 0x0b, 0x74, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12,
 	0x23, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d,
 	0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x63, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x05, 0x62,
-	0x6c, 0x6f, 0x63, 0x6b, 0x32, 0xd4, 0x01, 0x0a, 0x06, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x12,
-	0x23, 0x0a, 0x08, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x54, 0x78, 0x6e, 0x12, 0x11, 0x2e, 0x54, 0x72,
-	0x69, 0x70, 0x6f, 0x64, 0x54, 0x78, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x04,
-	0x2e, 0x45, 0x72, 0x72, 0x12, 0x29, 0x0a, 0x0b, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x42, 0x6c,
-	0x6f, 0x63, 0x6b, 0x12, 0x13, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x05, 0x2e, 0x42, 0x6f, 0x6f, 0x6c, 0x12,
-	0x27, 0x0a, 0x0a, 0x53, 0x74, 0x61, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x13, 0x2e,
-	0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x04, 0x2e, 0x45, 0x72, 0x72, 0x12, 0x25, 0x0a, 0x08, 0x45, 0x6e, 0x64, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x1f, 0x0a, 0x04, 0x74, 0x78, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x53, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x54, 0x78, 0x6e, 0x73, 0x52,
+	0x04, 0x74, 0x78, 0x6e, 0x73, 0x32, 0xd4, 0x01, 0x0a, 0x06, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64,
+	0x12, 0x23, 0x0a, 0x08, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x54, 0x78, 0x6e, 0x12, 0x11, 0x2e, 0x54,
+	0x72, 0x69, 0x70, 0x6f, 0x64, 0x54, 0x78, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x04, 0x2e, 0x45, 0x72, 0x72, 0x12, 0x29, 0x0a, 0x0b, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x42,
 	0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x13, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c, 0x6f,
-	0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x04, 0x2e, 0x45, 0x72, 0x72, 0x12,
-	0x2a, 0x0a, 0x0d, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x12, 0x13, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x04, 0x2e, 0x45, 0x72, 0x72, 0x32, 0x3a, 0x0a, 0x04, 0x4c,
-	0x61, 0x6e, 0x64, 0x12, 0x32, 0x0a, 0x0a, 0x53, 0x65, 0x74, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64,
-	0x73, 0x12, 0x0c, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x1a,
-	0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x67, 0x6f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x05, 0x2e, 0x42, 0x6f, 0x6f, 0x6c,
+	0x12, 0x27, 0x0a, 0x0a, 0x53, 0x74, 0x61, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x13,
+	0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x04, 0x2e, 0x45, 0x72, 0x72, 0x12, 0x25, 0x0a, 0x08, 0x45, 0x6e, 0x64,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x13, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x04, 0x2e, 0x45, 0x72, 0x72,
+	0x12, 0x2a, 0x0a, 0x0d, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x12, 0x13, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x04, 0x2e, 0x45, 0x72, 0x72, 0x32, 0x3a, 0x0a, 0x04,
+	0x4c, 0x61, 0x6e, 0x64, 0x12, 0x32, 0x0a, 0x0a, 0x53, 0x65, 0x74, 0x54, 0x72, 0x69, 0x70, 0x6f,
+	0x64, 0x73, 0x12, 0x0c, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6f, 0x64, 0x73, 0x49, 0x6e, 0x66, 0x6f,
+	0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x67, 0x6f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -330,31 +340,33 @@ var file_tripod_proto_goTypes = []interface{}{
 	(*TripodBlockRequest)(nil), // 3: TripodBlockRequest
 	(*SignedTxn)(nil),          // 4: SignedTxn
 	(*CompactBlock)(nil),       // 5: CompactBlock
-	(*Err)(nil),                // 6: Err
-	(*Bool)(nil),               // 7: Bool
-	(*emptypb.Empty)(nil),      // 8: google.protobuf.Empty
+	(*SignedTxns)(nil),         // 6: SignedTxns
+	(*Err)(nil),                // 7: Err
+	(*Bool)(nil),               // 8: Bool
+	(*emptypb.Empty)(nil),      // 9: google.protobuf.Empty
 }
 var file_tripod_proto_depIdxs = []int32{
-	1, // 0: TripodsInfo.tripods:type_name -> TripodInfo
-	4, // 1: TripodTxnRequest.txn:type_name -> SignedTxn
-	5, // 2: TripodBlockRequest.block:type_name -> CompactBlock
-	2, // 3: Tripod.CheckTxn:input_type -> TripodTxnRequest
-	3, // 4: Tripod.VerifyBlock:input_type -> TripodBlockRequest
-	3, // 5: Tripod.StartBlock:input_type -> TripodBlockRequest
-	3, // 6: Tripod.EndBlock:input_type -> TripodBlockRequest
-	3, // 7: Tripod.FinalizeBlock:input_type -> TripodBlockRequest
-	0, // 8: Land.SetTripods:input_type -> TripodsInfo
-	6, // 9: Tripod.CheckTxn:output_type -> Err
-	7, // 10: Tripod.VerifyBlock:output_type -> Bool
-	6, // 11: Tripod.StartBlock:output_type -> Err
-	6, // 12: Tripod.EndBlock:output_type -> Err
-	6, // 13: Tripod.FinalizeBlock:output_type -> Err
-	8, // 14: Land.SetTripods:output_type -> google.protobuf.Empty
-	9, // [9:15] is the sub-list for method output_type
-	3, // [3:9] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	1,  // 0: TripodsInfo.tripods:type_name -> TripodInfo
+	4,  // 1: TripodTxnRequest.txn:type_name -> SignedTxn
+	5,  // 2: TripodBlockRequest.block:type_name -> CompactBlock
+	6,  // 3: TripodBlockRequest.txns:type_name -> SignedTxns
+	2,  // 4: Tripod.CheckTxn:input_type -> TripodTxnRequest
+	3,  // 5: Tripod.VerifyBlock:input_type -> TripodBlockRequest
+	3,  // 6: Tripod.StartBlock:input_type -> TripodBlockRequest
+	3,  // 7: Tripod.EndBlock:input_type -> TripodBlockRequest
+	3,  // 8: Tripod.FinalizeBlock:input_type -> TripodBlockRequest
+	0,  // 9: Land.SetTripods:input_type -> TripodsInfo
+	7,  // 10: Tripod.CheckTxn:output_type -> Err
+	8,  // 11: Tripod.VerifyBlock:output_type -> Bool
+	7,  // 12: Tripod.StartBlock:output_type -> Err
+	7,  // 13: Tripod.EndBlock:output_type -> Err
+	7,  // 14: Tripod.FinalizeBlock:output_type -> Err
+	9,  // 15: Land.SetTripods:output_type -> google.protobuf.Empty
+	10, // [10:16] is the sub-list for method output_type
+	4,  // [4:10] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_tripod_proto_init() }
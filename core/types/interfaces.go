@@ -55,6 +55,16 @@ const (
 	Finalize
 )
 
+// Reorg describes the switch of the canonical chain from one fork to another.
+type Reorg struct {
+	// the common ancestor of the old and new forks
+	Ancestor *CompactBlock
+	// blocks dropped from the canonical chain, from the old end block down to the ancestor.
+	Reverted []*CompactBlock
+	// blocks added into the canonical chain, from the ancestor up to the new end block.
+	Applied []*CompactBlock
+}
+
 type IBlockChain interface {
 	ItxDB
 	ConvergeType() ConvergeType
@@ -78,6 +88,9 @@ type IBlockChain interface {
 	GetAllBlocks() ([]*CompactBlock, error)
 
 	GetRangeBlocks(startHeight, endHeight BlockNum) ([]*Block, error)
+
+	// SetReorgHandler sets the function called after the canonical chain reorgs.
+	SetReorgHandler(fn func(reorg *Reorg) error)
 }
 
 type ItxDB interface {
@@ -1,7 +1,9 @@
 package types
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
 	. "github.com/yu-org/yu/common"
 	"github.com/yu-org/yu/infra/trie"
@@ -16,6 +18,10 @@ type Receipt struct {
 	TripodName  string   `json:"tripod_name"`
 	WritingName string   `json:"writing_name"`
 	LeiCost     uint64   `json:"lei_cost"`
+	// Fee is LeiCost * BaseLeiPrice which the caller paid.
+	Fee uint64 `json:"fee"`
+	// Tips are paid to the miner.
+	Tips uint64 `json:"tips"`
 
 	Events []*Event `json:"events,omitempty"`
 	Error  string   `json:"error,omitempty"`
@@ -34,6 +40,7 @@ func NewReceipt(events []*Event, err error, extra []byte) *Receipt {
 func (r *Receipt) FillMetadata(block *Block, stxn *SignedTxn, leiCost uint64) {
 	wrCall := stxn.Raw.WrCall
 
+	r.TxHash = stxn.TxnHash
 	r.Caller = stxn.GetCallerAddr()
 	r.TripodName = wrCall.TripodName
 	r.WritingName = wrCall.FuncName
@@ -50,24 +57,104 @@ func (r *Receipt) Decode(data []byte) error {
 	return json.Unmarshal(data, r)
 }
 
+// Hash hashes the canonical encoding of the receipt, it does not depend on the json encoding.
 func (r *Receipt) Hash() ([]byte, error) {
-	byt, err := r.Encode()
+	hash := sha256.Sum256(r.canonicalBytes())
+	return hash[:], nil
+}
+
+// canonicalBytes encodes all the fields in order,
+// the integers are big-endian and the variable-length fields are prefixed by their lengths.
+func (r *Receipt) canonicalBytes() []byte {
+	var buf bytes.Buffer
+	buf.Write(r.TxHash.Bytes())
+	if r.Caller == nil {
+		buf.WriteByte(0)
+	} else {
+		buf.WriteByte(1)
+		buf.Write(r.Caller.Bytes())
+	}
+	writeBytes(&buf, []byte(r.BlockStage))
+	buf.Write(r.BlockHash.Bytes())
+	writeUint64(&buf, uint64(r.Height))
+	writeBytes(&buf, []byte(r.TripodName))
+	writeBytes(&buf, []byte(r.WritingName))
+	writeUint64(&buf, r.LeiCost)
+	writeUint64(&buf, r.Fee)
+	writeUint64(&buf, r.Tips)
+	writeUint64(&buf, uint64(len(r.Events)))
+	for _, event := range r.Events {
+		var value []byte
+		if event != nil {
+			value = event.Value
+		}
+		writeBytes(&buf, value)
+	}
+	writeBytes(&buf, []byte(r.Error))
+	writeBytes(&buf, r.Extra)
+	return buf.Bytes()
+}
+
+func writeUint64(buf *bytes.Buffer, n uint64) {
+	var byt [8]byte
+	binary.BigEndian.PutUint64(byt[:], n)
+	buf.Write(byt[:])
+}
+
+func writeBytes(buf *bytes.Buffer, byt []byte) {
+	writeUint64(buf, uint64(len(byt)))
+	buf.Write(byt)
+}
+
+// CaculateReceiptRoot calculates the ReceiptRoot, receipts must be in the order of txns in the block.
+func CaculateReceiptRoot(receipts []*Receipt) (Hash, error) {
+	mTree, err := receiptsMerkleTree(receipts)
+	if err != nil {
+		return NullHash, err
+	}
+	return mTree.RootNode.Data, nil
+}
+
+// ReceiptProof proves the receipt is included in the block with the ReceiptRoot.
+type ReceiptProof struct {
+	Receipt     *Receipt         `json:"receipt"`
+	ReceiptRoot Hash             `json:"receipt_root"`
+	Proof       trie.MerkleProof `json:"proof"`
+}
+
+// MakeReceiptProof makes the proof of receipts[index], receipts must be in the order of txns in the block.
+func MakeReceiptProof(receipts []*Receipt, index int) (*ReceiptProof, error) {
+	mTree, err := receiptsMerkleTree(receipts)
 	if err != nil {
 		return nil, err
 	}
-	hash := sha256.Sum256(byt)
-	return hash[:], err
+	proof, err := mTree.Proof(index)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiptProof{
+		Receipt:     receipts[index],
+		ReceiptRoot: mTree.RootNode.Data,
+		Proof:       proof,
+	}, nil
+}
+
+func (rp *ReceiptProof) Verify() (bool, error) {
+	hash, err := rp.Receipt.Hash()
+	if err != nil {
+		return false, err
+	}
+	return trie.VerifyMerkleProof(rp.ReceiptRoot, BytesToHash(hash), rp.Proof), nil
 }
 
-func CaculateReceiptRoot(results map[Hash]*Receipt) (Hash, error) {
-	var receiptsByt []Hash
-	for _, result := range results {
-		receipt, err := result.Encode()
+func receiptsMerkleTree(receipts []*Receipt) (*trie.MerkleTree, error) {
+	hashes := make([]Hash, 0, len(receipts))
+	for _, receipt := range receipts {
+		hash, err := receipt.Hash()
 		if err != nil {
-			return NullHash, err
+			return nil, err
 		}
-		receiptsByt = append(receiptsByt, BytesToHash(receipt))
+		hashes = append(hashes, BytesToHash(hash))
 	}
-	mTree := trie.NewMerkleTree(receiptsByt)
-	return mTree.RootNode.Data, nil
+	return trie.NewMerkleTree(hashes), nil
 }
@@ -2,6 +2,8 @@ package types
 
 import (
 	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"strconv"
 	"testing"
 )
 
@@ -22,3 +24,41 @@ func TestCodecResult(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, ev, deEvReceipt.Events[0])
 }
+
+// The receipt root is consensus data, it must not change with the encoding of receipts.
+func TestReceiptRoot(t *testing.T) {
+	caller := HexToAddress("0x185590BE050F1c2622293Fa93d9BEcd02F8806B7")
+	receipts := make([]*Receipt, 0)
+	for i := 0; i < 3; i++ {
+		receipts = append(receipts, &Receipt{
+			TxHash:      HexToHash(strconv.Itoa(i + 1)),
+			Caller:      &caller,
+			BlockStage:  ExecuteTxnsStage,
+			BlockHash:   HexToHash("0a"),
+			Height:      1,
+			TripodName:  "asset",
+			WritingName: "Transfer",
+			LeiCost:     uint64(i),
+			Fee:         10,
+			Tips:        1,
+			Events:      []*Event{{Value: []byte("Transfer Completed!")}},
+			Extra:       []byte{byte(i)},
+		})
+	}
+	receipts[2].Caller = nil
+	receipts[2].Error = "insufficient funds"
+
+	hash, err := receipts[0].Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, HexToHash("0xb44ffd9daf1db7d031a81688546035827c499d6183d2f064df282442a8d49670"), BytesToHash(hash))
+
+	root, err := CaculateReceiptRoot(receipts)
+	assert.NoError(t, err)
+	assert.Equal(t, HexToHash("0xc117b47e39410e671cf6ba503114b352e91473aa8350bbff2dde71b50b79d5fb"), root)
+
+	proof, err := MakeReceiptProof(receipts, 2)
+	assert.NoError(t, err)
+	ok, err := proof.Verify()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
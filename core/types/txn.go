@@ -5,9 +5,9 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/golang/protobuf/proto"
 	. "github.com/yu-org/yu/common"
+	. "github.com/yu-org/yu/common/yerror"
 	"github.com/yu-org/yu/core/types/goproto"
 	ytime "github.com/yu-org/yu/utils/time"
-	"unsafe"
 )
 
 type SignedTxn struct {
@@ -57,6 +57,10 @@ func (st *SignedTxn) GetCallerAddr() *Address {
 	return &addr
 }
 
+func (st *SignedTxn) GetNonce() uint64 {
+	return st.Raw.WrCall.Nonce
+}
+
 func (st *SignedTxn) TripodName() string {
 	return st.Raw.WrCall.TripodName
 }
@@ -82,31 +86,42 @@ func (st *SignedTxn) ToPb() *goproto.SignedTxn {
 	}
 }
 
+// SignedTxnFromPb rebuilds the txn and rejects it if its hash is not the one computed from its content.
 func SignedTxnFromPb(pb *goproto.SignedTxn) (*SignedTxn, error) {
-	return &SignedTxn{
+	stxn := &SignedTxn{
 		Raw:       UnsignedTxnFromPb(pb.Raw),
 		TxnHash:   BytesToHash(pb.TxnHash),
 		Pubkey:    pb.Pubkey,
 		Signature: pb.Signature,
-	}, nil
+	}
+	hash, err := stxn.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != stxn.TxnHash {
+		return nil, TxnHashIllegal
+	}
+	return stxn, nil
 }
 
+// Hash is computed from the content of txn, excluding TxnHash itself.
 func (st *SignedTxn) Hash() (Hash, error) {
-	var hash Hash
-	byt, err := st.Encode()
+	pb := st.ToPb()
+	pb.TxnHash = nil
+	byt, err := proto.Marshal(pb)
 	if err != nil {
 		return NullHash, err
 	}
-	hash = sha256.Sum256(byt)
-	return hash, nil
+	return sha256.Sum256(byt), nil
 }
 
 func (st *SignedTxn) Encode() ([]byte, error) {
 	return proto.Marshal(st.ToPb())
 }
 
+// Size returns the length of the encoded txn.
 func (st *SignedTxn) Size() int {
-	return int(unsafe.Sizeof(st))
+	return proto.Size(st.ToPb())
 }
 
 func DecodeSignedTxn(data []byte) (st *SignedTxn, err error) {
@@ -190,17 +205,20 @@ func DecodeSignedTxns(data []byte) (SignedTxns, error) {
 }
 
 type UnsignedTxn struct {
-	WrCall    *WrCall
-	Timestamp uint64
-	// Nonce is unnecessary
-	Nonce uint64
+	WrCall *WrCall
 }
 
+// NewUnsignedTxn stamps the call with the current time if the caller does not.
 func NewUnsignedTxn(wrCall *WrCall) (*UnsignedTxn, error) {
-	return &UnsignedTxn{
-		WrCall:    wrCall,
-		Timestamp: ytime.NowNanoTsU64(),
-	}, nil
+	if wrCall.Timestamp == 0 {
+		wrCall.Timestamp = ytime.NowNanoTsU64()
+	}
+	return &UnsignedTxn{WrCall: wrCall}, nil
+}
+
+// Timestamp is the time when the caller sends the txn.
+func (ut *UnsignedTxn) Timestamp() uint64 {
+	return ut.WrCall.Timestamp
 }
 
 func (ut *UnsignedTxn) BindJsonParams(v interface{}) error {
@@ -215,8 +233,10 @@ func (ut *UnsignedTxn) ToPb() *goproto.UnsignedTxn {
 			Params:     ut.WrCall.Params,
 			LeiPrice:   ut.WrCall.LeiPrice,
 			Tips:       ut.WrCall.Tips,
+			Nonce:      ut.WrCall.Nonce,
+			Ttl:        ut.WrCall.TTL,
 		},
-		Timestamp: ut.Timestamp,
+		Timestamp: ut.WrCall.Timestamp,
 	}
 }
 
@@ -228,8 +248,10 @@ func UnsignedTxnFromPb(pb *goproto.UnsignedTxn) *UnsignedTxn {
 			Params:     pb.Ecall.Params,
 			LeiPrice:   pb.Ecall.LeiPrice,
 			Tips:       pb.Ecall.Tips,
+			Nonce:      pb.Ecall.Nonce,
+			TTL:        pb.Ecall.Ttl,
+			Timestamp:  pb.Timestamp,
 		},
-		Timestamp: pb.Timestamp,
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 
 // A complete writing-call url is POST /api/writing
 // A complete reading-call url is POST /api/reading
+// A complete simulating-call url is POST /api/simulate
 
 const (
 	// RootApiPath For developers, every customized Writing and Read of tripods
@@ -16,16 +17,23 @@ const (
 	RootApiPath = "/api"
 	WrCallType  = "writing"
 	RdCallType  = "reading"
+	SimCallType = "simulate"
 
 	TripodNameKey = "tripod_name"
 	FuncNameKey   = "func_name"
 	BlockHashKey  = "block_hash"
+	TxnHashKey    = "txn_hash"
 )
 
 var (
 	WrApiPath      = filepath.Join(RootApiPath, WrCallType)
 	RdApiPath      = filepath.Join(RootApiPath, RdCallType)
+	SimApiPath     = filepath.Join(RootApiPath, SimCallType)
 	SubResultsPath = "/subscribe/results"
+	// GET /api/receipt_proof?txn_hash=0x...
+	ReceiptProofPath = filepath.Join(RootApiPath, "receipt_proof")
+	// GET /api/gossip_metrics
+	GossipMetricsPath = filepath.Join(RootApiPath, "gossip_metrics")
 )
 
 type SignedWrCall struct {
@@ -48,8 +56,14 @@ func GetSignedWrCall(ctx *gin.Context) (*SignedWrCall, error) {
 	if err != nil {
 		return nil, err
 	}
+	return wpb.ToSignedWrCall()
+}
 
-	var pubkey []byte
+func (wpb *WritingPostBody) ToSignedWrCall() (*SignedWrCall, error) {
+	var (
+		pubkey []byte
+		err    error
+	)
 	if wpb.Pubkey != "" {
 		pubkey, err = hexutil.Decode(wpb.Pubkey)
 		if err != nil {
@@ -69,7 +83,7 @@ func GetSignedWrCall(ctx *gin.Context) (*SignedWrCall, error) {
 		Pubkey:    pubkey,
 		Signature: sig,
 		Call:      wpb.Call,
-	}, err
+	}, nil
 }
 
 func GetRdCall(ctx *gin.Context) (*RdCall, error) {
@@ -13,6 +13,7 @@ import (
 	. "github.com/yu-org/yu/common"
 	. "github.com/yu-org/yu/core"
 	. "github.com/yu-org/yu/core/types"
+	ytime "github.com/yu-org/yu/utils/time"
 	"go.uber.org/atomic"
 	"io"
 	"net/http"
@@ -57,6 +58,10 @@ func CallChainByWriting(wrCall *WrCall) error {
 }
 
 func CallChainByWritingWithSig(privKey *ecdsa.PrivateKey, wrCall *WrCall) error {
+	// the timestamp is signed with the call.
+	if wrCall.Timestamp == 0 {
+		wrCall.Timestamp = ytime.NowNanoTsU64()
+	}
 	msgByt, err := json.Marshal(wrCall)
 	if err != nil {
 		return err
@@ -4,8 +4,20 @@ import (
 	"github.com/yu-org/yu/apps/asset"
 	"github.com/yu-org/yu/apps/pow"
 	"github.com/yu-org/yu/core/startup"
+	"os"
 )
 
 func main() {
-	startup.StartUp(pow.NewPow(1024), asset.NewAsset("YuCoin"))
+	mySecret := "node1"
+	if len(os.Args) > 1 {
+		mySecret = os.Args[1]
+	}
+
+	startup.InitDefaultKernelConfig()
+	// the fork with the most total difficulty wins.
+	startup.KernelCfg.BlockChain.ConvergeType = "heaviest"
+	startup.DefaultStartup(
+		pow.NewPow(pow.DefaultCfg(mySecret)),
+		asset.NewAsset("YuCoin"),
+	)
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/yu-org/yu/apps/asset"
+	"github.com/yu-org/yu/apps/raft"
+	"github.com/yu-org/yu/core/startup"
+	"os"
+	"strconv"
+)
+
+func main() {
+	idx := 0
+	if len(os.Args) > 1 {
+		var err error
+		idx, err = strconv.Atoi(os.Args[1])
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	startup.InitDefaultKernelConfig()
+	startup.DefaultStartup(
+		raft.NewRaft(raft.DefaultCfg(idx)),
+		asset.NewAsset("YuCoin"),
+	)
+}
@@ -0,0 +1,352 @@
+package p2p
+
+import (
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/core/tripod/dev"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SimNetwork simulates a P2P network in process for testing several nodes.
+// The peer IDs, latency and drops are all decided by RNGs derived from the seed,
+// every link between two nodes has its own RNG, so the decisions on a link never depend on
+// how the goroutines sending on other links interleave.
+// Time is virtual: a message arrives at the virtual time of its sender plus the latency,
+// and the messages of a topic are received in the order of their arrival time,
+// so the same seed and the same sends give the same delivery trace without waiting on the wall clock.
+type SimNetwork struct {
+	sync.Mutex
+	seed int64
+	// only for generating node keys
+	rng   *rand.Rand
+	links map[simLink]*rand.Rand
+
+	nodes map[peer.ID]*SimNode
+	// in the order of creation
+	ids []peer.ID
+
+	minLatency time.Duration
+	maxLatency time.Duration
+	dropRate   float64
+	// peer -> partition group, the peers in different groups cannot reach each other.
+	groups map[peer.ID]int
+}
+
+// simLink is the direction from one node to another.
+type simLink struct {
+	from, to int
+}
+
+func NewSimNetwork(seed int64) *SimNetwork {
+	return &SimNetwork{
+		seed:   seed,
+		rng:    rand.New(rand.NewSource(seed)),
+		links:  make(map[simLink]*rand.Rand),
+		nodes:  make(map[peer.ID]*SimNode),
+		groups: make(map[peer.ID]int),
+	}
+}
+
+// NewNode joins a new node into network, its peer ID is generated from the RNG.
+func (s *SimNetwork) NewNode() *SimNode {
+	s.Lock()
+	defer s.Unlock()
+	_, pub, err := crypto.GenerateEd25519Key(s.rng)
+	if err != nil {
+		logrus.Panic("generate simulated node key error: ", err)
+	}
+	id, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		logrus.Panic("make simulated peer id error: ", err)
+	}
+	node := &SimNode{
+		id:       id,
+		index:    len(s.ids),
+		net:      s,
+		handlers: make(map[int]dev.P2pHandler),
+		topics:   make(map[string]*simTopic),
+	}
+	s.nodes[id] = node
+	s.ids = append(s.ids, id)
+	return node
+}
+
+// SetLatency makes every message delayed by a random duration in [min, max].
+func (s *SimNetwork) SetLatency(min, max time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.minLatency = min
+	s.maxLatency = max
+}
+
+// SetDropRate makes every message between different nodes dropped by the rate, from 0 to 1.
+func (s *SimNetwork) SetDropRate(rate float64) {
+	s.Lock()
+	defer s.Unlock()
+	s.dropRate = rate
+}
+
+// Partition splits the network into groups, the nodes not in any group stay together.
+func (s *SimNetwork) Partition(groups ...[]peer.ID) {
+	s.Lock()
+	defer s.Unlock()
+	s.groups = make(map[peer.ID]int)
+	for i, group := range groups {
+		for _, id := range group {
+			s.groups[id] = i + 1
+		}
+	}
+}
+
+// Heal removes all the partitions.
+func (s *SimNetwork) Heal() {
+	s.Partition()
+}
+
+func (s *SimNetwork) node(id peer.ID) *SimNode {
+	s.Lock()
+	defer s.Unlock()
+	return s.nodes[id]
+}
+
+// route decides whether a message from one node reaches another and how long it takes.
+func (s *SimNetwork) route(from, to *SimNode) (time.Duration, bool) {
+	s.Lock()
+	defer s.Unlock()
+	if s.groups[from.id] != s.groups[to.id] {
+		return 0, false
+	}
+	if from == to {
+		return 0, true
+	}
+	rng := s.linkRng(from.index, to.index)
+	if s.dropRate > 0 && rng.Float64() < s.dropRate {
+		return 0, false
+	}
+	latency := s.minLatency
+	if s.maxLatency > s.minLatency {
+		latency += time.Duration(rng.Int63n(int64(s.maxLatency - s.minLatency + 1)))
+	}
+	return latency, true
+}
+
+// linkRng returns the RNG of the link, it is seeded by the network seed and the indexes of the two nodes.
+func (s *SimNetwork) linkRng(from, to int) *rand.Rand {
+	link := simLink{from: from, to: to}
+	rng, ok := s.links[link]
+	if !ok {
+		rng = rand.New(rand.NewSource(s.seed ^ int64(from+1)<<32 ^ int64(to+1)))
+		s.links[link] = rng
+	}
+	return rng
+}
+
+// SimNode is a node in SimNetwork, it implements P2pNetwork.
+type SimNode struct {
+	id        peer.ID
+	index     int
+	net       *SimNetwork
+	bootNodes []peer.ID
+
+	clockLock sync.Mutex
+	// the virtual time of the node, it moves to the arrival time of every message the node receives.
+	clock time.Duration
+	// the number of messages published by the node
+	published uint64
+
+	handlersLock sync.RWMutex
+	handlers     map[int]dev.P2pHandler
+
+	topicsLock sync.RWMutex
+	topics     map[string]*simTopic
+}
+
+func (n *SimNode) LocalID() peer.ID {
+	return n.id
+}
+
+func (n *SimNode) LocalIdString() string {
+	return n.id.String()
+}
+
+// SetBootNodes sets the nodes which the synchronizer fetches history blocks from.
+func (n *SimNode) SetBootNodes(ids ...peer.ID) {
+	n.bootNodes = ids
+}
+
+func (n *SimNode) GetBootNodes() []peer.ID {
+	return n.bootNodes
+}
+
+func (n *SimNode) ConnectBootNodes() error {
+	return nil
+}
+
+func (n *SimNode) AddTopic(topicName string) {
+	n.topicsLock.Lock()
+	defer n.topicsLock.Unlock()
+	if _, ok := n.topics[topicName]; !ok {
+		n.topics[topicName] = newSimTopic()
+	}
+}
+
+func (n *SimNode) SetHandlers(handlers map[int]dev.P2pHandler) {
+	n.handlersLock.Lock()
+	defer n.handlersLock.Unlock()
+	n.handlers = handlers
+}
+
+// RequestPeer calls the handler of peer at once, the latencies of the request and response
+// are added to the virtual time of the node.
+func (n *SimNode) RequestPeer(peerID peer.ID, code int, request []byte) ([]byte, error) {
+	target := n.net.node(peerID)
+	if target == nil {
+		return nil, yerror.PeerUnreachable
+	}
+	latency, ok := n.net.route(n, target)
+	if !ok {
+		return nil, yerror.PeerUnreachable
+	}
+	n.advance(latency)
+
+	target.handlersLock.RLock()
+	handler, ok := target.handlers[code]
+	target.handlersLock.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("no p2p-handler for code(%d)", code)
+	}
+	response, err := handler(append([]byte(nil), request...))
+	if err != nil {
+		return nil, err
+	}
+
+	latency, ok = n.net.route(target, n)
+	if !ok {
+		return nil, yerror.PeerUnreachable
+	}
+	n.advance(latency)
+	return response, nil
+}
+
+// PubP2P delivers the message to every node subscribing the topic, including itself.
+func (n *SimNode) PubP2P(topic string, msg []byte) error {
+	if n.topic(topic) == nil {
+		return yerror.NoP2PTopic
+	}
+	n.net.Lock()
+	ids := append([]peer.ID(nil), n.net.ids...)
+	n.net.Unlock()
+
+	n.clockLock.Lock()
+	sentAt := n.clock
+	n.published++
+	seq := n.published
+	n.clockLock.Unlock()
+
+	for _, id := range ids {
+		target := n.net.node(id)
+		t := target.topic(topic)
+		if t == nil {
+			continue
+		}
+		latency, ok := n.net.route(n, target)
+		if !ok {
+			continue
+		}
+		t.push(&simMsg{
+			arrival: sentAt + latency,
+			from:    n.index,
+			seq:     seq,
+			data:    append([]byte(nil), msg...),
+		})
+	}
+	return nil
+}
+
+func (n *SimNode) SubP2P(topic string) ([]byte, error) {
+	t := n.topic(topic)
+	if t == nil {
+		return nil, yerror.NoP2PTopic
+	}
+	msg := t.pop()
+	n.clockLock.Lock()
+	if msg.arrival > n.clock {
+		n.clock = msg.arrival
+	}
+	n.clockLock.Unlock()
+	return msg.data, nil
+}
+
+func (n *SimNode) advance(d time.Duration) {
+	n.clockLock.Lock()
+	n.clock += d
+	n.clockLock.Unlock()
+}
+
+func (n *SimNode) topic(name string) *simTopic {
+	n.topicsLock.RLock()
+	defer n.topicsLock.RUnlock()
+	return n.topics[name]
+}
+
+// simMsg is a published message arriving at a node.
+type simMsg struct {
+	// the virtual time the message arrives
+	arrival time.Duration
+	// the index of the sender and the sequence of the message published by it
+	from int
+	seq  uint64
+	data []byte
+}
+
+func (m *simMsg) before(other *simMsg) bool {
+	if m.arrival != other.arrival {
+		return m.arrival < other.arrival
+	}
+	if m.from != other.from {
+		return m.from < other.from
+	}
+	return m.seq < other.seq
+}
+
+// simTopic queues the messages of a topic received by a node in the order of their arrival.
+type simTopic struct {
+	sync.Mutex
+	cond *sync.Cond
+	msgs []*simMsg
+}
+
+func newSimTopic() *simTopic {
+	t := &simTopic{}
+	t.cond = sync.NewCond(&t.Mutex)
+	return t
+}
+
+func (t *simTopic) push(msg *simMsg) {
+	t.Lock()
+	i := sort.Search(len(t.msgs), func(i int) bool {
+		return msg.before(t.msgs[i])
+	})
+	t.msgs = append(t.msgs, nil)
+	copy(t.msgs[i+1:], t.msgs[i:])
+	t.msgs[i] = msg
+	t.Unlock()
+	t.cond.Signal()
+}
+
+func (t *simTopic) pop() *simMsg {
+	t.Lock()
+	defer t.Unlock()
+	for len(t.msgs) == 0 {
+		t.cond.Wait()
+	}
+	msg := t.msgs[0]
+	t.msgs = t.msgs[1:]
+	return msg
+}
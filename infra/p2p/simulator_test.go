@@ -0,0 +1,139 @@
+package p2p
+
+import (
+	"fmt"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/yu-org/yu/common/yerror"
+	"github.com/yu-org/yu/core/tripod/dev"
+	"sync"
+	"testing"
+	"time"
+)
+
+const echoCode = 1
+
+func newEchoNodes(net *SimNetwork, num int) []*SimNode {
+	nodes := make([]*SimNode, 0, num)
+	for i := 0; i < num; i++ {
+		node := net.NewNode()
+		node.SetHandlers(map[int]dev.P2pHandler{
+			echoCode: func(req []byte) ([]byte, error) {
+				return append([]byte(node.LocalIdString()+":"), req...), nil
+			},
+		})
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func TestSimRequest(t *testing.T) {
+	net := NewSimNetwork(1)
+	net.SetLatency(time.Millisecond, 2*time.Millisecond)
+	nodes := newEchoNodes(net, 3)
+
+	resp, err := nodes[0].RequestPeer(nodes[1].LocalID(), echoCode, []byte("hi"))
+	assert.NoError(t, err)
+	assert.Equal(t, nodes[1].LocalIdString()+":hi", string(resp))
+
+	_, err = nodes[0].RequestPeer(nodes[1].LocalID(), 2, nil)
+	assert.Error(t, err)
+
+	net.Partition([]peer.ID{nodes[2].LocalID()})
+	_, err = nodes[0].RequestPeer(nodes[2].LocalID(), echoCode, nil)
+	assert.ErrorIs(t, err, yerror.PeerUnreachable)
+	_, err = nodes[0].RequestPeer(nodes[1].LocalID(), echoCode, nil)
+	assert.NoError(t, err)
+
+	net.Heal()
+	_, err = nodes[0].RequestPeer(nodes[2].LocalID(), echoCode, nil)
+	assert.NoError(t, err)
+}
+
+func TestSimSeed(t *testing.T) {
+	run := func(seed int64) ([]peer.ID, []bool) {
+		net := NewSimNetwork(seed)
+		net.SetDropRate(0.5)
+		nodes := newEchoNodes(net, 2)
+		delivered := make([]bool, 0)
+		for i := 0; i < 32; i++ {
+			_, err := nodes[0].RequestPeer(nodes[1].LocalID(), echoCode, nil)
+			delivered = append(delivered, err == nil)
+		}
+		return []peer.ID{nodes[0].LocalID(), nodes[1].LocalID()}, delivered
+	}
+	ids1, delivered1 := run(7)
+	ids2, delivered2 := run(7)
+	assert.Equal(t, ids1, ids2)
+	assert.Equal(t, delivered1, delivered2)
+	assert.Contains(t, delivered1, true)
+	assert.Contains(t, delivered1, false)
+
+	ids3, _ := run(8)
+	assert.NotEqual(t, ids1, ids3)
+}
+
+func TestSimPubSub(t *testing.T) {
+	net := NewSimNetwork(1)
+	net.SetLatency(0, time.Millisecond)
+	nodes := newEchoNodes(net, 3)
+	for _, node := range nodes {
+		node.AddTopic("blocks")
+	}
+	net.Partition([]peer.ID{nodes[2].LocalID()})
+
+	assert.NoError(t, nodes[0].PubP2P("blocks", []byte("block1")))
+	for _, node := range nodes[:2] {
+		msg, err := node.SubP2P("blocks")
+		assert.NoError(t, err)
+		assert.Equal(t, "block1", string(msg))
+	}
+
+	net.Heal()
+	assert.NoError(t, nodes[0].PubP2P("blocks", []byte("block2")))
+	msg, err := nodes[2].SubP2P("blocks")
+	assert.NoError(t, err)
+	assert.Equal(t, "block2", string(msg))
+
+	assert.ErrorIs(t, nodes[0].PubP2P("txns", nil), yerror.NoP2PTopic)
+}
+
+func TestSimTrace(t *testing.T) {
+	run := func(seed int64) [][]string {
+		net := NewSimNetwork(seed)
+		net.SetLatency(time.Millisecond, 10*time.Millisecond)
+		net.SetDropRate(0.2)
+		nodes := newEchoNodes(net, 4)
+		for _, node := range nodes {
+			node.AddTopic("blocks")
+		}
+
+		// the nodes publish concurrently, every node publishes in its own order.
+		var wg sync.WaitGroup
+		for i, node := range nodes {
+			wg.Add(1)
+			go func(i int, node *SimNode) {
+				defer wg.Done()
+				for j := 0; j < 8; j++ {
+					assert.NoError(t, node.PubP2P("blocks", []byte(fmt.Sprintf("%d-%d", i, j))))
+				}
+			}(i, node)
+		}
+		wg.Wait()
+
+		traces := make([][]string, 0, len(nodes))
+		for _, node := range nodes {
+			trace := make([]string, 0)
+			for len(node.topic("blocks").msgs) > 0 {
+				msg, err := node.SubP2P("blocks")
+				assert.NoError(t, err)
+				trace = append(trace, string(msg))
+			}
+			traces = append(traces, trace)
+		}
+		return traces
+	}
+	trace1 := run(7)
+	assert.Equal(t, trace1, run(7))
+	assert.NotEqual(t, trace1, run(8))
+}
@@ -15,6 +15,7 @@ func (p *LibP2P) AddDefaultTopics() {
 	p.AddTopic(EndBlockTopic)
 	p.AddTopic(FinalizeBlockTopic)
 	p.AddTopic(UnpackedTxnsTopic)
+	p.AddTopic(ConsensusVoteTopic)
 }
 
 func (p *LibP2P) AddTopic(topicName string) {
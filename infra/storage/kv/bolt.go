@@ -46,7 +46,8 @@ func (b *boltKV) Get(prefix string, key []byte) ([]byte, error) {
 	var value []byte
 	err := b.db.View(func(tx *bbolt.Tx) error {
 		bu := tx.Bucket(bucket)
-		value = bu.Get(key)
+		// the value is only valid in the transaction.
+		value = bytes.Clone(bu.Get(key))
 		return nil
 	})
 	return value, err
@@ -2,12 +2,16 @@ package trie
 
 import (
 	"crypto/sha256"
+	"errors"
 	. "github.com/yu-org/yu/common"
 )
 
 // MerkleTree represent a Merkle tree
 type MerkleTree struct {
 	RootNode *MerkleNode
+
+	// levels[0] are leaves, the last level is the root.
+	levels [][]*MerkleNode
 }
 
 // MerkleNode represent a Merkle tree node
@@ -17,6 +21,18 @@ type MerkleNode struct {
 	Data  Hash
 }
 
+// ProofNode is the sibling node on the path from a leaf to the root.
+type ProofNode struct {
+	Hash Hash `json:"hash"`
+	// the sibling is on the left
+	Left bool `json:"left"`
+}
+
+// MerkleProof proves a leaf is in the Merkle tree.
+type MerkleProof []ProofNode
+
+var ErrProofIndexOutOfRange = errors.New("merkle proof index out of range")
+
 // NewMerkleTree creates a new Merkle tree from a sequence of data
 func NewMerkleTree(hashes []Hash) *MerkleTree {
 	if len(hashes) == 0 {
@@ -38,7 +54,14 @@ func NewMerkleTree(hashes []Hash) *MerkleTree {
 		nodes = append(nodes, leaf)
 	}
 
-	for {
+	levels := [][]*MerkleNode{nodes}
+	for len(nodes) > 1 {
+		// duplicate the last node of every odd inner level.
+		if len(nodes)%2 != 0 {
+			nodes = append(nodes, nodes[len(nodes)-1])
+			levels[len(levels)-1] = nodes
+		}
+
 		var newLevel []*MerkleNode
 
 		for j := 0; j < len(nodes)-1; j += 2 {
@@ -47,11 +70,41 @@ func NewMerkleTree(hashes []Hash) *MerkleTree {
 			newLevel = append(newLevel, node)
 		}
 		nodes = newLevel
+		levels = append(levels, nodes)
+	}
+
+	return &MerkleTree{RootNode: nodes[0], levels: levels}
+}
+
+// Proof returns the Merkle proof of the leaf at index.
+func (t *MerkleTree) Proof(index int) (MerkleProof, error) {
+	if len(t.levels) == 0 || index < 0 || index >= len(t.levels[0]) {
+		return nil, ErrProofIndexOutOfRange
+	}
+	var proof MerkleProof
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if index%2 == 0 {
+			proof = append(proof, ProofNode{Hash: level[index+1].Data, Left: false})
+		} else {
+			proof = append(proof, ProofNode{Hash: level[index-1].Data, Left: true})
+		}
+		index /= 2
+	}
+	return proof, nil
+}
 
-		if len(nodes) == 1 {
-			return &MerkleTree{RootNode: nodes[0]}
+// VerifyMerkleProof checks whether the hash is a leaf of the Merkle tree with the root.
+func VerifyMerkleProof(root, hash Hash, proof MerkleProof) bool {
+	node := newMerkleNode(nil, nil, hash)
+	for _, sibling := range proof {
+		siblingNode := &MerkleNode{Data: sibling.Hash}
+		if sibling.Left {
+			node = newMerkleNode(siblingNode, node, NullHash)
+		} else {
+			node = newMerkleNode(node, siblingNode, NullHash)
 		}
 	}
+	return node.Data == root
 }
 
 // NewMerkleNode creates a new Merkle tree node
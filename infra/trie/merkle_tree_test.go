@@ -0,0 +1,50 @@
+package trie
+
+import (
+	"crypto/sha256"
+	"github.com/stretchr/testify/assert"
+	. "github.com/yu-org/yu/common"
+	"strconv"
+	"testing"
+)
+
+func TestMerkleProof(t *testing.T) {
+	for _, count := range []int{1, 2, 3, 5, 6, 7, 11} {
+		hashes := make([]Hash, 0)
+		for i := 0; i < count; i++ {
+			hashes = append(hashes, sha256.Sum256([]byte(strconv.Itoa(i))))
+		}
+		tree := NewMerkleTree(hashes)
+		root := tree.RootNode.Data
+
+		for i, hash := range hashes {
+			proof, err := tree.Proof(i)
+			assert.NoError(t, err)
+			assert.True(t, VerifyMerkleProof(root, hash, proof), "count %d, index %d", count, i)
+			assert.False(t, VerifyMerkleProof(root, NullHash, proof))
+		}
+
+		// the last leaf must affect the root.
+		changed := append([]Hash{}, hashes...)
+		changed[count-1] = NullHash
+		assert.NotEqual(t, root, NewMerkleTree(changed).RootNode.Data, "count %d", count)
+	}
+}
+
+// The roots are consensus data, the last node of every odd level is duplicated.
+func TestMerkleRoot(t *testing.T) {
+	roots := map[int]string{
+		1: "0x697ea89f2c0d3383851b4019ba54ce87fd71fa8471b370356b219204e88ca02a",
+		3: "0x21e9038d3657fd513486247370a7f789a323873bfc387f0259784744b8d3ae3d",
+		5: "0x427a857e132b522cbccd8748d26a893ee9fc19dedc02624a34fb51c87b95e163",
+		6: "0x860155897dcb21a21171a2c992f0fb0bee53302fc777d58179b5ae384bc193da",
+	}
+	for count, root := range roots {
+		hashes := make([]Hash, 0)
+		for i := 0; i < count; i++ {
+			hashes = append(hashes, sha256.Sum256([]byte(strconv.Itoa(i))))
+		}
+		assert.Equal(t, HexToHash(root), NewMerkleTree(hashes).RootNode.Data, "count %d", count)
+	}
+	assert.Equal(t, NullHash, NewMerkleTree(nil).RootNode.Data)
+}